@@ -0,0 +1,69 @@
+package buffer
+
+// Point is a location in the buffer expressed in every unit downstream
+// consumers need: rune index for the editor, UTF-8 bytes for tree-sitter and
+// line/column pairs for both tree-sitter (bytes) and LSP (UTF-16 by default).
+type Point struct {
+	Offset   int // rune index
+	Byte     int // UTF-8 byte offset
+	Line     int // 0-based line
+	Col      int // column in runes
+	ByteCol  int // column in UTF-8 bytes
+	UTF16Col int // column in UTF-16 code units
+}
+
+// Edit describes one primitive change. Start and OldEnd are positions in the
+// text before the change, NewEnd is the end of the replacement in the text
+// after it. Insertions have Start == OldEnd, deletions Start == NewEnd.
+type Edit struct {
+	Start  Point
+	OldEnd Point
+	NewEnd Point
+	Text   string // inserted text, empty for deletions
+}
+
+type listener struct {
+	id int
+	fn func(Edit)
+}
+
+// Subscribe registers fn to be called after every change to the buffer,
+// including those replayed by Undo and Redo. Grouped changes are delivered
+// one primitive edit at a time, in the order they are applied. The returned
+// function removes the subscription.
+func (b *Buffer) Subscribe(fn func(Edit)) (unsubscribe func()) {
+	b.nextListener++
+	id := b.nextListener
+	b.listeners = append(b.listeners, listener{id: id, fn: fn})
+	return func() {
+		for i, l := range b.listeners {
+			if l.id == id {
+				b.listeners = append(b.listeners[:i:i], b.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// PointAt returns rune index pos as a Point. pos is clamped to [0, Len()].
+func (b *Buffer) PointAt(pos int) Point {
+	pos = max(0, min(pos, b.Len()))
+	line := b.LineAt(pos)
+	start := b.LineStart(line)
+	startByte := b.ByteOffset(start)
+	byteOff := b.ByteOffset(pos)
+	return Point{
+		Offset:   pos,
+		Byte:     byteOff,
+		Line:     line,
+		Col:      pos - start,
+		ByteCol:  byteOff - startByte,
+		UTF16Col: b.UTF16Offset(pos) - b.UTF16Offset(start),
+	}
+}
+
+func (b *Buffer) notify(ed Edit) {
+	for _, l := range b.listeners {
+		l.fn(ed)
+	}
+}
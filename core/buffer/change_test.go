@@ -0,0 +1,125 @@
+package buffer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// modelPoint computes a Point the slow way from a plain string.
+func modelPoint(text []rune, pos int) Point {
+	before := text[:pos]
+	line := strings.Count(string(before), "\n")
+	lineStart := strings.LastIndex(string(before), "\n") + 1 // byte index
+	col := []rune(string(before)[lineStart:])
+	return Point{
+		Offset:   pos,
+		Byte:     len(string(before)),
+		Line:     line,
+		Col:      len(col),
+		ByteCol:  len(string(col)),
+		UTF16Col: len(utf16.Encode(col)),
+	}
+}
+
+func TestSubscribeDeliversEdits(t *testing.T) {
+	b := NewFromString("héllo\nwörld")
+	var got []Edit
+	unsubscribe := b.Subscribe(func(e Edit) { got = append(got, e) })
+
+	_ = b.Insert(6, "big ")
+	_ = b.Delete(0, 2)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 edits, got %d", len(got))
+	}
+
+	ins := got[0]
+	if ins.Start.Line != 1 || ins.Start.Col != 0 || ins.Start.Byte != 7 || ins.Text != "big " {
+		t.Fatalf("insert start: %+v", ins)
+	}
+	if ins.OldEnd != ins.Start || ins.NewEnd.Offset != 10 || ins.NewEnd.Byte != 11 {
+		t.Fatalf("insert ends: %+v", ins)
+	}
+
+	del := got[1]
+	if del.Start.Offset != 0 || del.OldEnd.Offset != 2 || del.OldEnd.Byte != 3 || del.NewEnd != del.Start {
+		t.Fatalf("delete: %+v", del)
+	}
+
+	unsubscribe()
+	_ = b.Insert(0, "x")
+	if len(got) != 2 {
+		t.Fatalf("edit delivered after unsubscribe")
+	}
+}
+
+func TestSubscribeUndoRedoGroups(t *testing.T) {
+	b := NewFromString("abc")
+	b.BeginUndoGroup()
+	_ = b.Insert(3, "d")
+	_ = b.Insert(4, "e")
+	b.EndUndoGroup()
+
+	var got []Edit
+	b.Subscribe(func(e Edit) { got = append(got, e) })
+
+	b.Undo()
+	// newest first: remove "e", then "d"
+	if len(got) != 2 || got[0].Start.Offset != 4 || got[1].Start.Offset != 3 {
+		t.Fatalf("undo edits: %+v", got)
+	}
+	got = nil
+	b.Redo()
+	if len(got) != 2 || got[0].Text != "d" || got[1].Text != "e" {
+		t.Fatalf("redo edits: %+v", got)
+	}
+}
+
+// Replaying every delivered edit onto a plain model must reproduce the buffer,
+// and every point must agree with one computed from the text before the edit.
+func TestSubscribeRandomEditsMatchModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	alphabet := []rune("ab\né😀")
+	b := NewFromString("")
+	model := []rune{}
+
+	b.Subscribe(func(e Edit) {
+		if want := modelPoint(model, e.Start.Offset); e.Start != want {
+			t.Fatalf("start %+v, want %+v", e.Start, want)
+		}
+		if want := modelPoint(model, e.OldEnd.Offset); e.OldEnd != want {
+			t.Fatalf("old end %+v, want %+v", e.OldEnd, want)
+		}
+		next := append([]rune{}, model[:e.Start.Offset]...)
+		next = append(next, []rune(e.Text)...)
+		next = append(next, model[e.OldEnd.Offset:]...)
+		model = next
+		if want := modelPoint(model, e.NewEnd.Offset); e.NewEnd != want {
+			t.Fatalf("new end %+v, want %+v", e.NewEnd, want)
+		}
+	})
+
+	for i := 0; i < 300; i++ {
+		switch n := b.Len(); {
+		case rng.Intn(6) == 0:
+			if rng.Intn(2) == 0 {
+				b.Undo()
+			} else {
+				b.Redo()
+			}
+		case n == 0 || rng.Intn(2) == 0:
+			text := make([]rune, 1+rng.Intn(4))
+			for j := range text {
+				text[j] = alphabet[rng.Intn(len(alphabet))]
+			}
+			_ = b.Insert(rng.Intn(n+1), string(text))
+		default:
+			start := rng.Intn(n)
+			_ = b.Delete(start, start+1+rng.Intn(min(3, n-start)))
+		}
+		if got := b.String(); got != string(model) {
+			t.Fatalf("step %d: buffer %q, model %q", i, got, string(model))
+		}
+	}
+}
@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"errors"
+	"time"
 )
 
 // Source indicates whether a piece references the original file or the add buffer.
@@ -16,50 +17,59 @@ type piece struct {
 	src   Source
 	start int // rune index into the backing store (original/add)
 	len   int // rune length
+	lf    int // newlines within the piece
+	bytes int // UTF-8 length
+	u16   int // UTF-16 length
 }
 
 type Buffer struct {
-	original []rune
-	add      []rune
-	pieces   []piece
+	original store
+	add      store
 
-	undo []op
-	redo []op
+	root *node
+	seed uint32
+
+	// undo history; every change ever made stays reachable
+	history undoTree
 
 	// For grouping multiple operations into a single undo
-	inGroup  bool
-	groupOps []op
+	inGroup     bool
+	groupOps    []op
+	groupBefore int
+
+	// cursor reports the editor cursor (rune index) so undo states can
+	// remember where a change happened; nil when nothing is tracking
+	cursor func() int
+
+	// change subscribers, see Subscribe
+	listeners    []listener
+	nextListener int
 }
 
 // NewFromString creates a buffer where the initial contents live in "original".
 func NewFromString(s string) *Buffer {
 	r := []rune(s)
 	b := &Buffer{
-		original: r,
-		add:      nil,
-		pieces:   nil,
+		original: newStore(r),
+		add:      newStore(nil),
 	}
-	if len(r) == 0 {
-		b.pieces = []piece{} // empty
-	} else {
-		b.pieces = []piece{{src: SrcOriginal, start: 0, len: len(r)}}
+	if len(r) > 0 {
+		b.root = b.newNode(b.newPiece(SrcOriginal, 0, len(r)))
 	}
+	b.history.init(time.Now())
 	return b
 }
 
+// Len returns the buffer length in runes.
 func (b *Buffer) Len() int {
-	total := 0
-	for _, p := range b.pieces {
-		total += p.len
-	}
-	return total
+	return b.root.sizeOf()
 }
 
 func (b *Buffer) String() string {
 	out := make([]rune, 0, b.Len())
-	for _, p := range b.pieces {
-		out = append(out, b.readPiece(p)...)
-	}
+	b.walk(b.root, 0, 0, b.Len(), func(chunk []rune) {
+		out = append(out, chunk...)
+	})
 	return string(out)
 }
 
@@ -72,30 +82,52 @@ func (b *Buffer) Slice(start, end int) (string, error) {
 		return "", nil
 	}
 	out := make([]rune, 0, end-start)
-	cur := 0
-	for _, p := range b.pieces {
-		if p.len == 0 {
-			continue
-		}
-		pStart := cur
-		pEnd := cur + p.len
-		cur = pEnd
+	b.walk(b.root, 0, start, end, func(chunk []rune) {
+		out = append(out, chunk...)
+	})
+	return string(out), nil
+}
 
-		// no overlap
-		if end <= pStart || start >= pEnd {
-			continue
-		}
+// LineCount returns the number of lines (newlines + 1).
+func (b *Buffer) LineCount() int {
+	return b.root.lfOf() + 1
+}
 
-		// overlap
-		lo := max(start, pStart)
-		hi := min(end, pEnd)
-		offset := lo - pStart
-		length := hi - lo
+// LineStart returns the rune index where line (0-based) begins.
+// The line is clamped to [0, LineCount()-1].
+func (b *Buffer) LineStart(line int) int {
+	if line <= 0 {
+		return 0
+	}
+	line = min(line, b.LineCount()-1)
+	return b.lfOffset(line) + 1
+}
 
-		chunk := b.readPiece(piece{src: p.src, start: p.start + offset, len: length})
-		out = append(out, chunk...)
+// LineEnd returns the rune index of the end of line (0-based), excluding its newline.
+func (b *Buffer) LineEnd(line int) int {
+	line = max(0, min(line, b.LineCount()-1))
+	if line+1 < b.LineCount() {
+		return b.lfOffset(line + 1)
 	}
-	return string(out), nil
+	return b.Len()
+}
+
+// LineAt returns the 0-based line containing rune index pos.
+// pos is clamped to [0, Len()].
+func (b *Buffer) LineAt(pos int) int {
+	pos = max(0, min(pos, b.Len()))
+	return b.lfBefore(pos)
+}
+
+// LineLen returns the length of line y in runes, excluding its newline.
+func (b *Buffer) LineLen(y int) int {
+	return b.LineEnd(y) - b.LineStart(y)
+}
+
+// Line returns the text of line y (0-based) without its trailing newline.
+func (b *Buffer) Line(y int) string {
+	s, _ := b.Slice(b.LineStart(y), b.LineEnd(y))
+	return s
 }
 
 // Insert inserts text at position pos (rune index). Records undo.
@@ -106,19 +138,13 @@ func (b *Buffer) Insert(pos int, text string) error {
 	if text == "" {
 		return nil
 	}
+	before := b.beforeChange()
 	operation := insertOp{pos: pos, text: []rune(text)}
 	inv, err := b.apply(operation)
 	if err != nil {
 		return err
 	}
-
-	if b.inGroup {
-		// Append operations in the order they happen
-		b.groupOps = append(b.groupOps, inv)
-	} else {
-		b.undo = append(b.undo, inv)
-		b.redo = b.redo[:0]
-	}
+	b.record(inv, before)
 	return nil
 }
 
@@ -130,73 +156,68 @@ func (b *Buffer) Delete(start, end int) error {
 	if start == end {
 		return nil
 	}
-	// capture deleted text for undo
-	deleted, err := b.Slice(start, end)
-	if err != nil {
-		return err
-	}
-
+	before := b.beforeChange()
 	operation := deleteOp{start: start, end: end}
 	inv, err := b.apply(operation)
 	if err != nil {
 		return err
 	}
+	b.record(inv, before)
+	return nil
+}
 
-	// our inverse needs the deleted payload to restore precisely
-	// (inv is insertOp but with empty text; fill it)
-	if ins, ok := inv.(insertOp); ok {
-		ins.text = []rune(deleted)
-		inv = ins
-	}
-
+// record files the inverse of an edit into the open group or as a new undo state.
+func (b *Buffer) record(inv op, before int) {
 	if b.inGroup {
 		// Append operations in the order they happen
 		b.groupOps = append(b.groupOps, inv)
-	} else {
-		b.undo = append(b.undo, inv)
-		b.redo = b.redo[:0]
+		return
 	}
-	return nil
+	// the cursor after a single edit is only known once the caller moved it
+	b.commit(inv, before, -1)
 }
 
-func (b *Buffer) Undo() bool {
-	if len(b.undo) == 0 {
-		return false
+// beforeChange settles the previous change and samples the cursor. It must
+// run before the buffer is modified.
+func (b *Buffer) beforeChange() int {
+	if b.inGroup {
+		return b.groupBefore
 	}
-	last := b.undo[len(b.undo)-1]
-	b.undo = b.undo[:len(b.undo)-1]
+	b.SettleCursor()
+	return b.sampleCursor()
+}
 
-	inv, err := b.apply(last)
-	if err != nil {
-		// if this happens, your internal invariants are broken
-		return false
-	}
-	b.redo = append(b.redo, inv)
-	return true
+// SetCursorSource registers fn as the source of the cursor position that
+// undo states record. Passing nil stops tracking.
+func (b *Buffer) SetCursorSource(fn func() int) {
+	b.cursor = fn
 }
 
-func (b *Buffer) Redo() bool {
-	if len(b.redo) == 0 {
-		return false
+func (b *Buffer) sampleCursor() int {
+	if b.cursor == nil {
+		return -1
 	}
-	last := b.redo[len(b.redo)-1]
-	b.redo = b.redo[:len(b.redo)-1]
+	return b.cursor()
+}
 
-	inv, err := b.apply(last)
-	if err != nil {
-		return false
+// SettleCursor records the current cursor as the after-position of the
+// latest change if it does not have one yet. Call it once the command that
+// made the change has placed the cursor.
+func (b *Buffer) SettleCursor() {
+	if cur := b.history.cur; cur != nil && cur.parent != nil && cur.after < 0 {
+		cur.after = b.sampleCursor()
 	}
-	b.undo = append(b.undo, inv)
-	return true
 }
 
 // BeginUndoGroup starts grouping operations into a single undo
 func (b *Buffer) BeginUndoGroup() {
+	b.SettleCursor()
 	b.inGroup = true
 	b.groupOps = nil
+	b.groupBefore = b.sampleCursor()
 }
 
-// EndUndoGroup ends grouping and adds the group to undo stack
+// EndUndoGroup ends grouping and adds the group to the undo tree
 func (b *Buffer) EndUndoGroup() {
 	if !b.inGroup {
 		return
@@ -207,14 +228,14 @@ func (b *Buffer) EndUndoGroup() {
 		return
 	}
 
+	after := b.sampleCursor()
 	if len(b.groupOps) == 1 {
 		// Single operation, no need to wrap
-		b.undo = append(b.undo, b.groupOps[0])
+		b.commit(b.groupOps[0], b.groupBefore, after)
 	} else {
 		// Multiple operations - store them in the order they happened
-		b.undo = append(b.undo, groupOp{ops: b.groupOps})
+		b.commit(groupOp{ops: b.groupOps}, b.groupBefore, after)
 	}
-	b.redo = b.redo[:0]
 	b.groupOps = nil
 }
 
@@ -241,16 +262,25 @@ func (o insertOp) applyTo(b *Buffer) (op, error) {
 		return deleteOp{start: o.pos, end: o.pos}, nil
 	}
 
+	var start Point
+	if len(b.listeners) > 0 {
+		start = b.PointAt(o.pos)
+	}
+
 	// append to add buffer
-	addStart := len(b.add)
-	b.add = append(b.add, o.text...)
-	newPiece := piece{src: SrcAdd, start: addStart, len: len(o.text)}
+	addStart := len(b.add.runes)
+	b.add.append(o.text)
+	newPiece := b.newPiece(SrcAdd, addStart, len(o.text))
 
-	left, right := splitPiecesAt(b.pieces, o.pos)
-	merged := append([]piece{}, left...)
-	merged = append(merged, newPiece)
-	merged = append(merged, right...)
-	b.pieces = coalesce(merged)
+	left, right := b.split(b.root, o.pos)
+	if !growLast(left, addStart, newPiece) {
+		left = merge(left, b.newNode(newPiece))
+	}
+	b.root = merge(left, right)
+
+	if len(b.listeners) > 0 {
+		b.notify(Edit{Start: start, OldEnd: start, NewEnd: b.PointAt(o.pos + len(o.text)), Text: string(o.text)})
+	}
 
 	// inverse is delete of inserted range
 	return deleteOp{start: o.pos, end: o.pos + len(o.text)}, nil
@@ -269,13 +299,28 @@ func (o deleteOp) applyTo(b *Buffer) (op, error) {
 		return insertOp{pos: o.start, text: nil}, nil
 	}
 
+	// capture the payload so the inverse restores it exactly
+	deleted := make([]rune, 0, o.end-o.start)
+	b.walk(b.root, 0, o.start, o.end, func(chunk []rune) {
+		deleted = append(deleted, chunk...)
+	})
+
+	var start, oldEnd Point
+	if len(b.listeners) > 0 {
+		start, oldEnd = b.PointAt(o.start), b.PointAt(o.end)
+	}
+
 	// remove [start,end) by splitting at start and end, then discarding middle
-	left, midRight := splitPiecesAt(b.pieces, o.start)
-	_, right := splitPiecesAt(midRight, o.end-o.start)
-	b.pieces = coalesce(append(left, right...))
+	left, midRight := b.split(b.root, o.start)
+	_, right := b.split(midRight, o.end-o.start)
+	b.root = merge(left, right)
 
-	// inverse is insert, but caller supplies deleted payload for accurate undo
-	return insertOp{pos: o.start, text: nil}, nil
+	if len(b.listeners) > 0 {
+		b.notify(Edit{Start: start, OldEnd: oldEnd, NewEnd: start})
+	}
+
+	// inverse re-inserts the deleted payload
+	return insertOp{pos: o.start, text: deleted}, nil
 }
 
 // groupOp groups multiple operations into one undo/redo unit
@@ -293,29 +338,10 @@ func (o groupOp) applyTo(b *Buffer) (op, error) {
 	// Collect inverses in reverse order too (so redo applies them correctly)
 	inverses := make([]op, 0, len(o.ops))
 	for i := len(o.ops) - 1; i >= 0; i-- {
-		// If this is a deleteOp, we need to capture the text before deleting
-		var deletedText string
-		if delOp, ok := o.ops[i].(deleteOp); ok {
-			text, err := b.Slice(delOp.start, delOp.end)
-			if err != nil {
-				return nil, err
-			}
-			deletedText = text
-		}
-
 		inv, err := b.apply(o.ops[i])
 		if err != nil {
 			return nil, err
 		}
-
-		// Fill in text for insertOp inverses (from deleteOps)
-		if deletedText != "" {
-			if ins, ok := inv.(insertOp); ok {
-				ins.text = []rune(deletedText)
-				inv = ins
-			}
-		}
-
 		inverses = append(inverses, inv)
 	}
 
@@ -328,76 +354,5 @@ func (b *Buffer) readPiece(p piece) []rune {
 	if p.len <= 0 {
 		return nil
 	}
-	switch p.src {
-	case SrcOriginal:
-		return b.original[p.start : p.start+p.len]
-	case SrcAdd:
-		return b.add[p.start : p.start+p.len]
-	default:
-		return nil
-	}
-}
-
-// splitPiecesAt splits pieces into left/right such that left has exactly `pos` runes.
-func splitPiecesAt(pieces []piece, pos int) (left []piece, right []piece) {
-	if pos <= 0 {
-		return []piece{}, append([]piece{}, pieces...)
-	}
-
-	cur := 0
-	for i := 0; i < len(pieces); i++ {
-		p := pieces[i]
-		if p.len == 0 {
-			continue
-		}
-		next := cur + p.len
-
-		// pos lies after this piece
-		if pos >= next {
-			left = append(left, p)
-			cur = next
-			continue
-		}
-
-		// pos lies within this piece -> split
-		within := pos - cur
-		if within <= 0 {
-			// split at piece boundary (before p)
-			right = append(right, pieces[i:]...)
-			return coalesce(left), coalesce(right)
-		}
-
-		pLeft := piece{src: p.src, start: p.start, len: within}
-		pRight := piece{src: p.src, start: p.start + within, len: p.len - within}
-
-		left = append(left, pLeft)
-		right = append(right, pRight)
-		right = append(right, pieces[i+1:]...)
-		return coalesce(left), coalesce(right)
-	}
-
-	// pos beyond end -> all in left
-	return coalesce(left), []piece{}
-}
-
-func coalesce(pieces []piece) []piece {
-	out := make([]piece, 0, len(pieces))
-	for _, p := range pieces {
-		if p.len <= 0 {
-			continue
-		}
-		n := len(out)
-		if n == 0 {
-			out = append(out, p)
-			continue
-		}
-		prev := out[n-1]
-		// merge adjacent pieces from same source and contiguous backing region
-		if prev.src == p.src && prev.start+prev.len == p.start {
-			out[n-1] = piece{src: prev.src, start: prev.start, len: prev.len + p.len}
-			continue
-		}
-		out = append(out, p)
-	}
-	return out
+	return b.store(p.src).runes[p.start : p.start+p.len]
 }
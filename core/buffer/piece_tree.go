@@ -0,0 +1,259 @@
+package buffer
+
+import "sort"
+
+// The piece tree is a treap (randomized balanced binary tree) ordered by
+// document position. Every node holds one piece and caches the rune, newline,
+// UTF-8 and UTF-16 totals of its subtree, so position, line and encoding
+// lookups are O(log n) and never need to materialize the text.
+
+type node struct {
+	p     piece
+	left  *node
+	right *node
+	prio  uint32
+
+	size  int // runes in this subtree
+	lf    int // newlines in this subtree
+	bytes int // UTF-8 bytes in this subtree
+	u16   int // UTF-16 code units in this subtree
+}
+
+func (n *node) sizeOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *node) lfOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.lf
+}
+
+func (n *node) bytesOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.bytes
+}
+
+func (n *node) u16Of() int {
+	if n == nil {
+		return 0
+	}
+	return n.u16
+}
+
+// update recomputes the cached subtree totals from the children.
+func (n *node) update() {
+	n.size = n.left.sizeOf() + n.p.len + n.right.sizeOf()
+	n.lf = n.left.lfOf() + n.p.lf + n.right.lfOf()
+	n.bytes = n.left.bytesOf() + n.p.bytes + n.right.bytesOf()
+	n.u16 = n.left.u16Of() + n.p.u16 + n.right.u16Of()
+}
+
+// nextPrio returns the next pseudo-random treap priority (xorshift32).
+// Seeded per buffer so behavior is deterministic for a given edit sequence.
+func (b *Buffer) nextPrio() uint32 {
+	x := b.seed
+	if x == 0 {
+		x = 2463534242
+	}
+	x ^= x << 13
+	x ^= x >> 17
+	x ^= x << 5
+	b.seed = x
+	return x
+}
+
+func (b *Buffer) newNode(p piece) *node {
+	n := &node{p: p, prio: b.nextPrio()}
+	n.update()
+	return n
+}
+
+// store returns the backing store a piece refers to.
+func (b *Buffer) store(src Source) *store {
+	if src == SrcOriginal {
+		return &b.original
+	}
+	return &b.add
+}
+
+// newPiece builds a piece over [start,start+length) of src with its line and
+// encoding totals.
+func (b *Buffer) newPiece(src Source, start, length int) piece {
+	st := b.store(src)
+	end := start + length
+	return piece{
+		src:   src,
+		start: start,
+		len:   length,
+		lf:    st.countLF(start, end),
+		bytes: st.byteLen(start, end),
+		u16:   st.utf16Len(start, end),
+	}
+}
+
+// countLF counts newlines in [start,end) of a backing store.
+func (b *Buffer) countLF(src Source, start, end int) int {
+	return b.store(src).countLF(start, end)
+}
+
+// nthLF returns the piece-relative offset of the k-th (1-based) newline in p.
+func (b *Buffer) nthLF(p piece, k int) int {
+	lfs := b.store(p.src).lf
+	i := sort.SearchInts(lfs, p.start) + k - 1
+	return lfs[i] - p.start
+}
+
+// splitPiece cuts p into [0,off) and [off,len).
+func (b *Buffer) splitPiece(p piece, off int) (piece, piece) {
+	left := b.newPiece(p.src, p.start, off)
+	right := piece{
+		src:   p.src,
+		start: p.start + off,
+		len:   p.len - off,
+		lf:    p.lf - left.lf,
+		bytes: p.bytes - left.bytes,
+		u16:   p.u16 - left.u16,
+	}
+	return left, right
+}
+
+// split divides the tree so the left result holds exactly pos runes.
+func (b *Buffer) split(n *node, pos int) (*node, *node) {
+	if n == nil {
+		return nil, nil
+	}
+	ls := n.left.sizeOf()
+	switch {
+	case pos <= ls:
+		l, r := b.split(n.left, pos)
+		n.left = r
+		n.update()
+		return l, n
+	case pos >= ls+n.p.len:
+		l, r := b.split(n.right, pos-ls-n.p.len)
+		n.right = l
+		n.update()
+		return n, r
+	default:
+		// pos falls inside this node's piece: keep the head here and move
+		// the tail into a sibling with the same priority so heap order holds.
+		head, tail := b.splitPiece(n.p, pos-ls)
+		rn := &node{p: tail, right: n.right, prio: n.prio}
+		rn.update()
+		n.p = head
+		n.right = nil
+		n.update()
+		return n, rn
+	}
+}
+
+// merge joins two trees where every position in a precedes every position in c.
+func merge(a, c *node) *node {
+	if a == nil {
+		return c
+	}
+	if c == nil {
+		return a
+	}
+	if a.prio > c.prio {
+		a.right = merge(a.right, c)
+		a.update()
+		return a
+	}
+	c.left = merge(a, c.left)
+	c.update()
+	return c
+}
+
+// growLast extends the last piece of the tree in place when it is the add
+// buffer run ending at addEnd. This keeps consecutive typing in one piece.
+func growLast(n *node, addEnd int, p piece) bool {
+	if n == nil {
+		return false
+	}
+	if n.right != nil {
+		if !growLast(n.right, addEnd, p) {
+			return false
+		}
+		n.update()
+		return true
+	}
+	if n.p.src != SrcAdd || n.p.start+n.p.len != addEnd {
+		return false
+	}
+	n.p.len += p.len
+	n.p.lf += p.lf
+	n.p.bytes += p.bytes
+	n.p.u16 += p.u16
+	n.update()
+	return true
+}
+
+// walk visits the pieces overlapping [start,end) in document order.
+func (b *Buffer) walk(n *node, offset, start, end int, fn func(chunk []rune)) {
+	if n == nil || start >= end {
+		return
+	}
+	ls := n.left.sizeOf()
+	pStart := offset + ls
+	pEnd := pStart + n.p.len
+	if start < pStart {
+		b.walk(n.left, offset, start, end, fn)
+	}
+	if lo, hi := max(start, pStart), min(end, pEnd); lo < hi {
+		fn(b.readPiece(piece{src: n.p.src, start: n.p.start + lo - pStart, len: hi - lo}))
+	}
+	if end > pEnd {
+		b.walk(n.right, pEnd, start, end, fn)
+	}
+}
+
+// lfOffset returns the rune offset of the k-th (1-based) newline in the document.
+func (b *Buffer) lfOffset(k int) int {
+	base := 0
+	n := b.root
+	for n != nil {
+		llf := n.left.lfOf()
+		if k <= llf {
+			n = n.left
+			continue
+		}
+		k -= llf
+		if k <= n.p.lf {
+			return base + n.left.sizeOf() + b.nthLF(n.p, k)
+		}
+		k -= n.p.lf
+		base += n.left.sizeOf() + n.p.len
+		n = n.right
+	}
+	return base
+}
+
+// lfBefore counts newlines in [0,pos).
+func (b *Buffer) lfBefore(pos int) int {
+	count := 0
+	n := b.root
+	for n != nil {
+		ls := n.left.sizeOf()
+		if pos <= ls {
+			n = n.left
+			continue
+		}
+		count += n.left.lfOf()
+		pos -= ls
+		if pos <= n.p.len {
+			return count + b.countLF(n.p.src, n.p.start, n.p.start+pos)
+		}
+		count += n.p.lf
+		pos -= n.p.len
+		n = n.right
+	}
+	return count
+}
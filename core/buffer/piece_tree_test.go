@@ -0,0 +1,137 @@
+package buffer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLineAPIs(t *testing.T) {
+	b := NewFromString("one\ntwo\n\nfour")
+
+	if got := b.LineCount(); got != 4 {
+		t.Fatalf("expected 4 lines, got %d", got)
+	}
+
+	wantStarts := []int{0, 4, 8, 9}
+	for y, want := range wantStarts {
+		if got := b.LineStart(y); got != want {
+			t.Fatalf("LineStart(%d): expected %d, got %d", y, want, got)
+		}
+	}
+
+	wantLines := []string{"one", "two", "", "four"}
+	for y, want := range wantLines {
+		if got := b.Line(y); got != want {
+			t.Fatalf("Line(%d): expected %q, got %q", y, want, got)
+		}
+		if got := b.LineLen(y); got != len(want) {
+			t.Fatalf("LineLen(%d): expected %d, got %d", y, len(want), got)
+		}
+	}
+
+	// pos -> line, including positions on newlines and at EOF
+	cases := map[int]int{0: 0, 3: 0, 4: 1, 7: 1, 8: 2, 9: 3, 13: 3, 99: 3}
+	for pos, want := range cases {
+		if got := b.LineAt(pos); got != want {
+			t.Fatalf("LineAt(%d): expected %d, got %d", pos, want, got)
+		}
+	}
+}
+
+func TestLineAPIsAfterEdits(t *testing.T) {
+	b := NewFromString("alpha\nbeta")
+	_ = b.Insert(5, "\nmiddle")
+	_ = b.Insert(0, "top\n")
+
+	if got := b.String(); got != "top\nalpha\nmiddle\nbeta" {
+		t.Fatalf("unexpected text %q", got)
+	}
+	if got := b.LineCount(); got != 4 {
+		t.Fatalf("expected 4 lines, got %d", got)
+	}
+	if got := b.Line(2); got != "middle" {
+		t.Fatalf("expected middle, got %q", got)
+	}
+
+	// Delete across a line boundary
+	_ = b.Delete(b.LineEnd(1), b.LineStart(2)+3)
+	if got := b.Line(1); got != "alphadle" {
+		t.Fatalf("expected alphadle, got %q", got)
+	}
+	if got := b.LineCount(); got != 3 {
+		t.Fatalf("expected 3 lines, got %d", got)
+	}
+
+	if !b.Undo() {
+		t.Fatal("undo should succeed")
+	}
+	if got := b.LineCount(); got != 4 {
+		t.Fatalf("after undo expected 4 lines, got %d", got)
+	}
+}
+
+func TestEmptyBufferLines(t *testing.T) {
+	b := NewFromString("")
+	if b.LineCount() != 1 || b.LineStart(0) != 0 || b.Line(0) != "" || b.LineAt(0) != 0 {
+		t.Fatalf("empty buffer should have a single empty line")
+	}
+}
+
+// TestPieceTreeRandomEdits checks the tree against a plain string model.
+func TestPieceTreeRandomEdits(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []rune("ab\nβ🙂 ")
+
+	model := []rune("seed\ntext")
+	b := NewFromString(string(model))
+
+	for i := 0; i < 2000; i++ {
+		if rng.Intn(3) > 0 || len(model) == 0 {
+			pos := rng.Intn(len(model) + 1)
+			n := 1 + rng.Intn(4)
+			ins := make([]rune, n)
+			for j := range ins {
+				ins[j] = alphabet[rng.Intn(len(alphabet))]
+			}
+			if err := b.Insert(pos, string(ins)); err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+			model = append(model[:pos], append(ins, model[pos:]...)...)
+		} else {
+			start := rng.Intn(len(model))
+			end := start + rng.Intn(len(model)-start+1)
+			if err := b.Delete(start, end); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			model = append(model[:start], model[end:]...)
+		}
+
+		if b.Len() != len(model) {
+			t.Fatalf("step %d: len %d, want %d", i, b.Len(), len(model))
+		}
+	}
+
+	want := string(model)
+	if got := b.String(); got != want {
+		t.Fatalf("content mismatch:\n got %q\nwant %q", got, want)
+	}
+
+	lines := strings.Split(want, "\n")
+	if b.LineCount() != len(lines) {
+		t.Fatalf("line count %d, want %d", b.LineCount(), len(lines))
+	}
+	pos := 0
+	for y, line := range lines {
+		if got := b.LineStart(y); got != pos {
+			t.Fatalf("LineStart(%d) = %d, want %d", y, got, pos)
+		}
+		if got := b.Line(y); got != line {
+			t.Fatalf("Line(%d) = %q, want %q", y, got, line)
+		}
+		if got := b.LineAt(pos); got != y {
+			t.Fatalf("LineAt(%d) = %d, want %d", pos, got, y)
+		}
+		pos += len([]rune(line)) + 1
+	}
+}
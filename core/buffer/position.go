@@ -0,0 +1,174 @@
+package buffer
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Encoding selects the unit used for offsets and columns.
+// Buffer positions are rune indices; tree-sitter works in UTF-8 bytes and
+// LSP defaults to UTF-16 code units.
+type Encoding uint8
+
+const (
+	EncodingRune Encoding = iota
+	EncodingUTF8
+	EncodingUTF16
+)
+
+// subtreeUnits returns the size of a subtree in enc units.
+func (n *node) subtreeUnits(enc Encoding) int {
+	switch enc {
+	case EncodingUTF8:
+		return n.bytesOf()
+	case EncodingUTF16:
+		return n.u16Of()
+	default:
+		return n.sizeOf()
+	}
+}
+
+// units returns the size of a whole piece in enc units.
+func (p piece) units(enc Encoding) int {
+	switch enc {
+	case EncodingUTF8:
+		return p.bytes
+	case EncodingUTF16:
+		return p.u16
+	default:
+		return p.len
+	}
+}
+
+// prefixUnits returns the size in enc units of the first k runes of p.
+func (b *Buffer) prefixUnits(p piece, k int, enc Encoding) int {
+	st := b.store(p.src)
+	switch enc {
+	case EncodingUTF8:
+		return st.byteLen(p.start, p.start+k)
+	case EncodingUTF16:
+		return st.utf16Len(p.start, p.start+k)
+	default:
+		return k
+	}
+}
+
+// UnitLen returns the buffer length in enc units.
+func (b *Buffer) UnitLen(enc Encoding) int {
+	return b.root.subtreeUnits(enc)
+}
+
+// ByteLen returns the buffer length in UTF-8 bytes.
+func (b *Buffer) ByteLen() int {
+	return b.root.bytesOf()
+}
+
+// Offset converts rune index pos into an offset in enc units.
+// pos is clamped to [0, Len()].
+func (b *Buffer) Offset(pos int, enc Encoding) int {
+	pos = max(0, min(pos, b.Len()))
+	units := 0
+	n := b.root
+	for n != nil {
+		ls := n.left.sizeOf()
+		if pos <= ls {
+			n = n.left
+			continue
+		}
+		units += n.left.subtreeUnits(enc)
+		pos -= ls
+		if pos <= n.p.len {
+			return units + b.prefixUnits(n.p, pos, enc)
+		}
+		units += n.p.units(enc)
+		pos -= n.p.len
+		n = n.right
+	}
+	return units
+}
+
+// PosFromOffset converts an offset in enc units back into a rune index.
+// Offsets that land inside a multi-unit rune resolve to that rune's index;
+// off is clamped to [0, UnitLen(enc)].
+func (b *Buffer) PosFromOffset(off int, enc Encoding) int {
+	off = max(0, min(off, b.UnitLen(enc)))
+	pos := 0
+	n := b.root
+	for n != nil {
+		lu := n.left.subtreeUnits(enc)
+		if off <= lu {
+			n = n.left
+			continue
+		}
+		pos += n.left.sizeOf()
+		off -= lu
+		if off <= n.p.units(enc) {
+			// largest k with prefixUnits(k) <= off
+			k := sort.Search(n.p.len+1, func(k int) bool {
+				return b.prefixUnits(n.p, k, enc) > off
+			}) - 1
+			return pos + k
+		}
+		pos += n.p.len
+		off -= n.p.units(enc)
+		n = n.right
+	}
+	return pos
+}
+
+// ByteOffset converts rune index pos into a UTF-8 byte offset.
+func (b *Buffer) ByteOffset(pos int) int {
+	return b.Offset(pos, EncodingUTF8)
+}
+
+// PosFromByte converts a UTF-8 byte offset into a rune index.
+func (b *Buffer) PosFromByte(off int) int {
+	return b.PosFromOffset(off, EncodingUTF8)
+}
+
+// ByteSlice returns the UTF-8 text between byte offsets start and end,
+// encoded straight from the pieces. Offsets are clamped like in PosFromByte
+// and one inside a multi-byte rune resolves to that rune, so a read ending
+// mid-rune stops before it.
+func (b *Buffer) ByteSlice(start, end int) []byte {
+	lo, hi := b.PosFromByte(start), b.PosFromByte(end)
+	if lo >= hi {
+		return nil
+	}
+	out := make([]byte, 0, min(end, b.ByteLen())-max(start, 0))
+	b.walk(b.root, 0, lo, hi, func(chunk []rune) {
+		for _, r := range chunk {
+			out = utf8.AppendRune(out, r)
+		}
+	})
+	return out
+}
+
+// UTF16Offset converts rune index pos into a UTF-16 code unit offset.
+func (b *Buffer) UTF16Offset(pos int) int {
+	return b.Offset(pos, EncodingUTF16)
+}
+
+// PosFromUTF16 converts a UTF-16 code unit offset into a rune index.
+func (b *Buffer) PosFromUTF16(off int) int {
+	return b.PosFromOffset(off, EncodingUTF16)
+}
+
+// LineCol returns the 0-based line of rune index pos and its column in enc units.
+func (b *Buffer) LineCol(pos int, enc Encoding) (line, col int) {
+	pos = max(0, min(pos, b.Len()))
+	line = b.LineAt(pos)
+	return line, b.Offset(pos, enc) - b.Offset(b.LineStart(line), enc)
+}
+
+// PosFromLineCol converts a 0-based line and a column in enc units into a
+// rune index. Columns past the end of the line clamp to the line end.
+func (b *Buffer) PosFromLineCol(line, col int, enc Encoding) int {
+	line = max(0, min(line, b.LineCount()-1))
+	start := b.LineStart(line)
+	if col <= 0 {
+		return start
+	}
+	pos := b.PosFromOffset(b.Offset(start, enc)+col, enc)
+	return min(pos, b.LineEnd(line))
+}
@@ -0,0 +1,113 @@
+package buffer
+
+import (
+	"math/rand"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestOffsetConversions(t *testing.T) {
+	// a(1) β(2 bytes) 🙂(4 bytes, 2 UTF-16 units) c
+	b := NewFromString("aβ🙂c\nx")
+
+	if got := b.ByteLen(); got != len("aβ🙂c\nx") {
+		t.Fatalf("ByteLen: expected %d, got %d", len("aβ🙂c\nx"), got)
+	}
+
+	bytes := []int{0, 1, 3, 7, 8, 9, 10}
+	units := []int{0, 1, 2, 4, 5, 6, 7}
+	for pos := 0; pos <= b.Len(); pos++ {
+		if got := b.ByteOffset(pos); got != bytes[pos] {
+			t.Fatalf("ByteOffset(%d): expected %d, got %d", pos, bytes[pos], got)
+		}
+		if got := b.UTF16Offset(pos); got != units[pos] {
+			t.Fatalf("UTF16Offset(%d): expected %d, got %d", pos, units[pos], got)
+		}
+		if got := b.PosFromByte(bytes[pos]); got != pos {
+			t.Fatalf("PosFromByte(%d): expected %d, got %d", bytes[pos], pos, got)
+		}
+		if got := b.PosFromUTF16(units[pos]); got != pos {
+			t.Fatalf("PosFromUTF16(%d): expected %d, got %d", units[pos], pos, got)
+		}
+	}
+
+	// Offsets inside a multi-unit rune resolve to that rune
+	if got := b.PosFromByte(5); got != 2 {
+		t.Fatalf("PosFromByte inside rune: expected 2, got %d", got)
+	}
+	if got := b.PosFromUTF16(3); got != 2 {
+		t.Fatalf("PosFromUTF16 inside pair: expected 2, got %d", got)
+	}
+
+	if got := string(b.ByteSlice(1, 7)); got != "β🙂" {
+		t.Fatalf("ByteSlice(1, 7): expected %q, got %q", "β🙂", got)
+	}
+	// a read ending inside a rune stops before it
+	if got := string(b.ByteSlice(0, 5)); got != "aβ" {
+		t.Fatalf("ByteSlice(0, 5): expected %q, got %q", "aβ", got)
+	}
+	if got := b.ByteSlice(9, 99); string(got) != "x" {
+		t.Fatalf("ByteSlice past the end: expected %q, got %q", "x", got)
+	}
+}
+
+func TestLineColConversions(t *testing.T) {
+	b := NewFromString("héllo\n🙂x")
+
+	line, col := b.LineCol(7, EncodingUTF16) // 'x'
+	if line != 1 || col != 2 {
+		t.Fatalf("UTF-16 LineCol: expected (1,2), got (%d,%d)", line, col)
+	}
+	line, col = b.LineCol(7, EncodingUTF8)
+	if line != 1 || col != 4 {
+		t.Fatalf("UTF-8 LineCol: expected (1,4), got (%d,%d)", line, col)
+	}
+	if got := b.PosFromLineCol(0, 3, EncodingUTF8); got != 2 {
+		t.Fatalf("PosFromLineCol UTF-8: expected 2, got %d", got)
+	}
+	if got := b.PosFromLineCol(1, 2, EncodingUTF16); got != 7 {
+		t.Fatalf("PosFromLineCol UTF-16: expected 7, got %d", got)
+	}
+	// past end of line clamps to line end
+	if got := b.PosFromLineCol(0, 99, EncodingUTF16); got != 5 {
+		t.Fatalf("PosFromLineCol clamp: expected 5, got %d", got)
+	}
+}
+
+func TestOffsetsAfterRandomEdits(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	alphabet := []rune("aé\n🙂z")
+
+	model := []rune("start")
+	b := NewFromString(string(model))
+	for i := 0; i < 500; i++ {
+		if rng.Intn(3) > 0 || len(model) == 0 {
+			pos := rng.Intn(len(model) + 1)
+			ins := []rune{alphabet[rng.Intn(len(alphabet))], alphabet[rng.Intn(len(alphabet))]}
+			_ = b.Insert(pos, string(ins))
+			model = append(model[:pos], append(ins, model[pos:]...)...)
+		} else {
+			start := rng.Intn(len(model))
+			end := min(len(model), start+1+rng.Intn(3))
+			_ = b.Delete(start, end)
+			model = append(model[:start], model[end:]...)
+		}
+	}
+
+	for pos := 0; pos <= len(model); pos++ {
+		wantBytes := len(string(model[:pos]))
+		wantUnits := len(utf16.Encode(model[:pos]))
+		if got := b.ByteOffset(pos); got != wantBytes {
+			t.Fatalf("ByteOffset(%d) = %d, want %d", pos, got, wantBytes)
+		}
+		if got := b.UTF16Offset(pos); got != wantUnits {
+			t.Fatalf("UTF16Offset(%d) = %d, want %d", pos, got, wantUnits)
+		}
+		if got := b.PosFromByte(wantBytes); got != pos {
+			t.Fatalf("PosFromByte(%d) = %d, want %d", wantBytes, got, pos)
+		}
+	}
+	if got := b.ByteLen(); got != len(string(model)) {
+		t.Fatalf("ByteLen = %d, want %d", got, len(string(model)))
+	}
+}
@@ -0,0 +1,67 @@
+package buffer
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// store is an append-only rune backing store (original or add) with sorted
+// indexes of the runes that matter for line and encoding bookkeeping. Pure
+// ASCII text keeps the wide/astral indexes empty.
+type store struct {
+	runes []rune
+
+	lf      []int // offsets of '\n'
+	wide    []int // offsets of runes longer than one UTF-8 byte
+	wideSum []int // wideSum[i] = extra UTF-8 bytes of wide[:i]
+	astral  []int // offsets of runes outside the BMP (UTF-16 surrogate pairs)
+}
+
+func newStore(r []rune) store {
+	s := store{wideSum: []int{0}}
+	s.append(r)
+	return s
+}
+
+// append adds runes to the store and extends its indexes.
+func (s *store) append(r []rune) {
+	base := len(s.runes)
+	s.runes = append(s.runes, r...)
+	for i, ch := range r {
+		if ch == '\n' {
+			s.lf = append(s.lf, base+i)
+			continue
+		}
+		if ch < utf8.RuneSelf {
+			continue
+		}
+		s.wide = append(s.wide, base+i)
+		s.wideSum = append(s.wideSum, s.wideSum[len(s.wideSum)-1]+runeLen(ch)-1)
+		if ch > 0xFFFF {
+			s.astral = append(s.astral, base+i)
+		}
+	}
+}
+
+// countLF counts newlines in [start,end).
+func (s *store) countLF(start, end int) int {
+	return sort.SearchInts(s.lf, end) - sort.SearchInts(s.lf, start)
+}
+
+// byteLen returns the UTF-8 length of [start,end).
+func (s *store) byteLen(start, end int) int {
+	return end - start + s.wideSum[sort.SearchInts(s.wide, end)] - s.wideSum[sort.SearchInts(s.wide, start)]
+}
+
+// utf16Len returns the UTF-16 length of [start,end).
+func (s *store) utf16Len(start, end int) int {
+	return end - start + sort.SearchInts(s.astral, end) - sort.SearchInts(s.astral, start)
+}
+
+// runeLen is utf8.RuneLen with invalid runes counted as the replacement character.
+func runeLen(r rune) int {
+	if n := utf8.RuneLen(r); n > 0 {
+		return n
+	}
+	return utf8.RuneLen(utf8.RuneError)
+}
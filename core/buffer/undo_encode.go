@@ -0,0 +1,158 @@
+package buffer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The undo tree is serialized as JSON so it can be stored next to state.json
+// and restored when the same file content is opened again. Operations only
+// make sense against the exact text they were recorded on; callers are
+// responsible for checking that (the editor keys undo files by content hash).
+
+type undoFile struct {
+	Cur    int          `json:"cur"`
+	States []undoRecord `json:"states"`
+}
+
+type undoRecord struct {
+	Seq    int       `json:"seq"`
+	Parent int       `json:"parent"`
+	Time   time.Time `json:"time"`
+	Last   int       `json:"last"` // child Redo follows, -1 for none
+	Undo   *opRecord `json:"undo,omitempty"`
+	Redo   *opRecord `json:"redo,omitempty"`
+	Before int       `json:"before"`
+	After  int       `json:"after"`
+}
+
+type opRecord struct {
+	Kind  string     `json:"kind"` // "insert", "delete" or "group"
+	Pos   int        `json:"pos,omitempty"`
+	End   int        `json:"end,omitempty"`
+	Text  string     `json:"text,omitempty"`
+	Group []opRecord `json:"group,omitempty"`
+}
+
+// MarshalUndo encodes the undo tree, including every branch.
+func (b *Buffer) MarshalUndo() ([]byte, error) {
+	b.SettleCursor()
+	t := &b.history
+	f := undoFile{Cur: b.UndoSeq(), States: make([]undoRecord, len(t.states))}
+	for i, s := range t.states {
+		rec := undoRecord{Seq: s.seq, Parent: -1, Time: s.time, Last: -1, Before: s.before, After: s.after}
+		if s.parent != nil {
+			rec.Parent = s.parent.seq
+		}
+		if s.last != nil {
+			rec.Last = s.last.seq
+		}
+		rec.Undo = encodeOp(s.undo)
+		rec.Redo = encodeOp(s.redo)
+		f.States[i] = rec
+	}
+	return json.Marshal(f)
+}
+
+// UnmarshalUndo replaces the undo tree with one produced by MarshalUndo.
+// The buffer text must be the text of the encoded current state. On error the
+// existing history is left untouched.
+func (b *Buffer) UnmarshalUndo(data []byte) error {
+	var f undoFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	if len(f.States) == 0 || f.Cur < 0 || f.Cur >= len(f.States) {
+		return errors.New("undo: no current state")
+	}
+
+	states := make([]*undoState, len(f.States))
+	for i, rec := range f.States {
+		if rec.Seq != i {
+			return fmt.Errorf("undo: state %d out of order", rec.Seq)
+		}
+		s := &undoState{seq: i, time: rec.Time, before: rec.Before, after: rec.After}
+		if i > 0 {
+			if rec.Parent < 0 || rec.Parent >= i {
+				return fmt.Errorf("undo: state %d has invalid parent %d", i, rec.Parent)
+			}
+			s.parent = states[rec.Parent]
+			s.parent.children = append(s.parent.children, s)
+		}
+		var err error
+		if s.undo, err = decodeOp(rec.Undo); err != nil {
+			return err
+		}
+		if s.redo, err = decodeOp(rec.Redo); err != nil {
+			return err
+		}
+		states[i] = s
+	}
+	for i, rec := range f.States {
+		if rec.Last >= 0 {
+			if rec.Last >= len(states) || states[rec.Last].parent != states[i] {
+				return fmt.Errorf("undo: state %d has invalid redo target %d", i, rec.Last)
+			}
+			states[i].last = states[rec.Last]
+		}
+	}
+
+	// states on the current path must be undoable, every other state redoable
+	applied := make(map[*undoState]bool)
+	for s := states[f.Cur]; s.parent != nil; s = s.parent {
+		applied[s] = true
+	}
+	for _, s := range states[1:] {
+		if (applied[s] && s.undo == nil) || (!applied[s] && s.redo == nil) {
+			return fmt.Errorf("undo: state %d cannot be reached", s.seq)
+		}
+	}
+
+	b.history.cur = states[f.Cur]
+	b.history.states = states
+	b.history.cursor = -1
+	return nil
+}
+
+func encodeOp(o op) *opRecord {
+	switch o := o.(type) {
+	case insertOp:
+		return &opRecord{Kind: "insert", Pos: o.pos, Text: string(o.text)}
+	case deleteOp:
+		return &opRecord{Kind: "delete", Pos: o.start, End: o.end}
+	case groupOp:
+		rec := &opRecord{Kind: "group", Group: make([]opRecord, len(o.ops))}
+		for i, sub := range o.ops {
+			rec.Group[i] = *encodeOp(sub)
+		}
+		return rec
+	default:
+		return nil
+	}
+}
+
+func decodeOp(rec *opRecord) (op, error) {
+	if rec == nil {
+		return nil, nil
+	}
+	switch rec.Kind {
+	case "insert":
+		return insertOp{pos: rec.Pos, text: []rune(rec.Text)}, nil
+	case "delete":
+		return deleteOp{start: rec.Pos, end: rec.End}, nil
+	case "group":
+		ops := make([]op, len(rec.Group))
+		for i := range rec.Group {
+			sub, err := decodeOp(&rec.Group[i])
+			if err != nil {
+				return nil, err
+			}
+			ops[i] = sub
+		}
+		return groupOp{ops: ops}, nil
+	default:
+		return nil, fmt.Errorf("undo: unknown op %q", rec.Kind)
+	}
+}
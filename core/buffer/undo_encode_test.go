@@ -0,0 +1,58 @@
+package buffer
+
+import "testing"
+
+func TestUndoMarshalRoundTrip(t *testing.T) {
+	b := NewFromString("hello")
+	_ = b.Insert(5, " world") // 1
+	b.BeginUndoGroup()
+	_ = b.Delete(0, 1)
+	_ = b.Insert(0, "J") // 2: group
+	b.EndUndoGroup()
+	b.Undo()
+	_ = b.Insert(0, ">> ") // 3: branch off 1
+
+	data, err := b.MarshalUndo()
+	if err != nil {
+		t.Fatalf("MarshalUndo: %v", err)
+	}
+
+	// restore into a buffer holding the same text, as on reopen
+	r := NewFromString(b.String())
+	if err := r.UnmarshalUndo(data); err != nil {
+		t.Fatalf("UnmarshalUndo: %v", err)
+	}
+	if r.UndoSeq() != 3 || r.UndoSeqLast() != 3 {
+		t.Fatalf("seq %d last %d", r.UndoSeq(), r.UndoSeqLast())
+	}
+
+	want := map[int]string{0: "hello", 1: "hello world", 2: "Jello world", 3: ">> hello world"}
+	for _, seq := range []int{2, 0, 3, 1, 2} {
+		if !r.GotoUndoSeq(seq) {
+			t.Fatalf("GotoUndoSeq(%d) failed", seq)
+		}
+		if got := r.String(); got != want[seq] {
+			t.Fatalf("seq %d: got %q want %q", seq, got, want[seq])
+		}
+	}
+}
+
+func TestUndoUnmarshalRejectsBadInput(t *testing.T) {
+	b := NewFromString("x")
+	_ = b.Insert(1, "y")
+
+	for _, data := range []string{
+		`not json`,
+		`{"cur":0,"states":[]}`,
+		`{"cur":1,"states":[{"seq":0,"parent":-1,"last":-1},{"seq":1,"parent":0,"last":-1}]}`,
+		`{"cur":0,"states":[{"seq":0,"parent":-1,"last":-1},{"seq":1,"parent":0,"last":-1,"redo":{"kind":"bogus"}}]}`,
+	} {
+		if err := b.UnmarshalUndo([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+	// the existing history survives failed loads
+	if b.UndoSeq() != 1 || !b.Undo() || b.String() != "x" {
+		t.Fatalf("history damaged: seq %d text %q", b.UndoSeq(), b.String())
+	}
+}
@@ -0,0 +1,244 @@
+package buffer
+
+import (
+	"sort"
+	"time"
+)
+
+// undoState is one node of the undo tree: the buffer as it was right after a
+// change. The root is the text the buffer was created with. New changes made
+// after an undo start a new branch instead of discarding the old one.
+type undoState struct {
+	seq      int
+	time     time.Time
+	parent   *undoState
+	children []*undoState
+	last     *undoState // child entered most recently; where Redo goes
+
+	undo op // reverts this state to its parent (valid while applied)
+	redo op // re-applies this state from its parent (valid once undone)
+
+	// cursor (rune index) before and after the change, -1 if unknown
+	before int
+	after  int
+}
+
+type undoTree struct {
+	cur    *undoState
+	states []*undoState // indexed by seq
+	now    func() time.Time
+
+	// cursor to restore after the last Undo/Redo, -1 if unknown
+	cursor int
+}
+
+func (t *undoTree) init(created time.Time) {
+	root := &undoState{seq: 0, time: created, before: -1, after: -1}
+	t.cur = root
+	t.states = []*undoState{root}
+	t.now = time.Now
+	t.cursor = -1
+}
+
+// UndoState describes one state of the undo tree.
+type UndoState struct {
+	Seq     int       // sequence number; 0 is the original text
+	Parent  int       // parent sequence number, -1 for the original text
+	Time    time.Time // when the change was made
+	Changes int       // number of changes from the original text along this branch
+	Leaf    bool      // true if no later change was made on top of this state
+	Current bool      // true if the buffer is currently at this state
+}
+
+// commit adds a new state below the current one holding inv as its undo.
+func (b *Buffer) commit(inv op, before, after int) {
+	t := &b.history
+	s := &undoState{
+		seq:    len(t.states),
+		time:   t.now(),
+		parent: t.cur,
+		undo:   inv,
+		before: before,
+		after:  after,
+	}
+	t.cur.children = append(t.cur.children, s)
+	t.cur.last = s
+	t.states = append(t.states, s)
+	t.cur = s
+}
+
+// Undo moves to the parent of the current state.
+func (b *Buffer) Undo() bool {
+	t := &b.history
+	b.SettleCursor()
+	cur := t.cur
+	if cur == nil || cur.parent == nil {
+		return false
+	}
+	inv, err := b.apply(cur.undo)
+	if err != nil {
+		// if this happens, your internal invariants are broken
+		return false
+	}
+	cur.redo = inv
+	cur.parent.last = cur
+	t.cur = cur.parent
+	t.cursor = cur.before
+	return true
+}
+
+// Redo moves to the most recently visited child of the current state.
+func (b *Buffer) Redo() bool {
+	t := &b.history
+	if t.cur == nil || t.cur.last == nil {
+		return false
+	}
+	next := t.cur.last
+	inv, err := b.apply(next.redo)
+	if err != nil {
+		return false
+	}
+	next.undo = inv
+	t.cur = next
+	t.cursor = next.after
+	return true
+}
+
+// UndoCursor returns the cursor recorded for the change the last Undo, Redo
+// or time travel step crossed: the position before the change when undoing,
+// after it when redoing.
+func (b *Buffer) UndoCursor() (int, bool) {
+	pos := b.history.cursor
+	if pos < 0 {
+		return 0, false
+	}
+	return min(pos, b.Len()), true
+}
+
+// UndoSeq returns the sequence number of the current state.
+func (b *Buffer) UndoSeq() int {
+	if b.history.cur == nil {
+		return 0
+	}
+	return b.history.cur.seq
+}
+
+// UndoSeqLast returns the highest sequence number in the tree.
+func (b *Buffer) UndoSeqLast() int {
+	return len(b.history.states) - 1
+}
+
+// UndoTime returns when the current state was created.
+func (b *Buffer) UndoTime() time.Time {
+	if b.history.cur == nil {
+		return time.Time{}
+	}
+	return b.history.cur.time
+}
+
+// GotoUndoSeq moves the buffer to the state with the given sequence number,
+// undoing up to the common ancestor and redoing down the target branch.
+func (b *Buffer) GotoUndoSeq(seq int) bool {
+	t := &b.history
+	if seq < 0 || seq >= len(t.states) || t.cur == nil {
+		return false
+	}
+	target := t.states[seq]
+	if target == t.cur {
+		return true
+	}
+
+	onPath := make(map[*undoState]bool)
+	for s := target; s != nil; s = s.parent {
+		onPath[s] = true
+	}
+	for !onPath[t.cur] {
+		if !b.Undo() {
+			return false
+		}
+	}
+
+	var path []*undoState
+	for s := target; s != t.cur; s = s.parent {
+		path = append(path, s)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		t.cur.last = path[i]
+		if !b.Redo() {
+			return false
+		}
+	}
+	return true
+}
+
+// Earlier steps n states back in time (g-), crossing branches.
+func (b *Buffer) Earlier(n int) bool {
+	target := max(0, b.UndoSeq()-n)
+	if target == b.UndoSeq() {
+		return false
+	}
+	return b.GotoUndoSeq(target)
+}
+
+// Later steps n states forward in time (g+), crossing branches.
+func (b *Buffer) Later(n int) bool {
+	target := min(b.UndoSeqLast(), b.UndoSeq()+n)
+	if target == b.UndoSeq() {
+		return false
+	}
+	return b.GotoUndoSeq(target)
+}
+
+// EarlierBy moves to the newest state that is at least d older than the
+// current one, or to the original text if there is none.
+func (b *Buffer) EarlierBy(d time.Duration) bool {
+	target := b.seqAt(b.UndoTime().Add(-d))
+	if target >= b.UndoSeq() {
+		target = max(0, b.UndoSeq()-1)
+	}
+	if target == b.UndoSeq() {
+		return false
+	}
+	return b.GotoUndoSeq(target)
+}
+
+// LaterBy moves to the newest state made no more than d after the current one.
+func (b *Buffer) LaterBy(d time.Duration) bool {
+	target := b.seqAt(b.UndoTime().Add(d))
+	if target <= b.UndoSeq() {
+		return false
+	}
+	return b.GotoUndoSeq(target)
+}
+
+// seqAt returns the newest state created at or before t.
+func (b *Buffer) seqAt(t time.Time) int {
+	states := b.history.states
+	i := sort.Search(len(states), func(i int) bool {
+		return states[i].time.After(t)
+	})
+	return max(0, i-1)
+}
+
+// UndoStates returns every state of the undo tree ordered by sequence number.
+func (b *Buffer) UndoStates() []UndoState {
+	states := b.history.states
+	out := make([]UndoState, len(states))
+	for i, s := range states {
+		parent := -1
+		changes := 0
+		if s.parent != nil {
+			parent = s.parent.seq
+			changes = out[parent].Changes + 1
+		}
+		out[i] = UndoState{
+			Seq:     s.seq,
+			Parent:  parent,
+			Time:    s.time,
+			Changes: changes,
+			Leaf:    len(s.children) == 0,
+			Current: s == b.history.cur,
+		}
+	}
+	return out
+}
@@ -0,0 +1,174 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock makes undo timestamps deterministic.
+func fakeClock(b *Buffer, start time.Time) *time.Time {
+	now := start
+	b.history.now = func() time.Time { return now }
+	return &now
+}
+
+func TestUndoTreeKeepsBranches(t *testing.T) {
+	b := NewFromString("")
+	_ = b.Insert(0, "one")    // seq 1
+	_ = b.Insert(3, " two")   // seq 2
+	b.Undo()                  // back to seq 1
+	_ = b.Insert(3, " three") // seq 3, new branch
+
+	if got := b.String(); got != "one three" {
+		t.Fatalf("got %q", got)
+	}
+	if b.UndoSeq() != 3 || b.UndoSeqLast() != 3 {
+		t.Fatalf("seq %d last %d", b.UndoSeq(), b.UndoSeqLast())
+	}
+
+	// the abandoned branch is still reachable
+	if !b.GotoUndoSeq(2) {
+		t.Fatal("GotoUndoSeq(2) failed")
+	}
+	if got := b.String(); got != "one two" {
+		t.Fatalf("seq 2: got %q", got)
+	}
+	if !b.GotoUndoSeq(3) {
+		t.Fatal("GotoUndoSeq(3) failed")
+	}
+	if got := b.String(); got != "one three" {
+		t.Fatalf("seq 3: got %q", got)
+	}
+
+	// plain undo/redo follow the branch last visited
+	b.Undo()
+	b.Redo()
+	if got := b.String(); got != "one three" {
+		t.Fatalf("redo: got %q", got)
+	}
+}
+
+func TestUndoTreeEarlierLater(t *testing.T) {
+	b := NewFromString("x")
+	_ = b.Insert(1, "a") // 1: xa
+	_ = b.Insert(2, "b") // 2: xab
+	b.Undo()
+	_ = b.Insert(2, "c") // 3: xac
+
+	want := []string{"x", "xa", "xab", "xac"}
+	for seq := 3; seq > 0; seq-- {
+		if !b.Earlier(1) {
+			t.Fatalf("Earlier from %d failed", seq)
+		}
+		if got := b.String(); got != want[seq-1] {
+			t.Fatalf("g- to %d: got %q want %q", seq-1, got, want[seq-1])
+		}
+	}
+	if b.Earlier(1) {
+		t.Fatal("Earlier past the original text should fail")
+	}
+	if !b.Later(2) || b.String() != "xab" {
+		t.Fatalf("Later(2): got %q", b.String())
+	}
+	if !b.Later(5) || b.String() != "xac" {
+		t.Fatalf("Later(5): got %q", b.String())
+	}
+	if b.Later(1) {
+		t.Fatal("Later past the newest state should fail")
+	}
+}
+
+func TestUndoTreeByDuration(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewFromString("")
+	now := fakeClock(b, start)
+	b.history.states[0].time = start
+
+	for i, s := range []string{"a", "b", "c", "d"} {
+		*now = start.Add(time.Duration(i+1) * time.Minute)
+		_ = b.Insert(b.Len(), s)
+	}
+
+	// at 12:04; five minutes earlier is before every change
+	if !b.EarlierBy(5*time.Minute) || b.String() != "" {
+		t.Fatalf("EarlierBy(5m): got %q", b.String())
+	}
+	if !b.LaterBy(2*time.Minute) || b.String() != "ab" {
+		t.Fatalf("LaterBy(2m): got %q", b.String())
+	}
+	if !b.EarlierBy(30*time.Second) || b.String() != "a" {
+		t.Fatalf("EarlierBy(30s): got %q", b.String())
+	}
+	if b.LaterBy(30 * time.Second) {
+		t.Fatal("LaterBy(30s) should not reach the next change")
+	}
+	if !b.LaterBy(time.Hour) || b.String() != "abcd" {
+		t.Fatalf("LaterBy(1h): got %q", b.String())
+	}
+}
+
+func TestUndoStates(t *testing.T) {
+	b := NewFromString("")
+	_ = b.Insert(0, "a")
+	_ = b.Insert(1, "b")
+	b.Undo()
+	_ = b.Insert(1, "c")
+
+	states := b.UndoStates()
+	if len(states) != 4 {
+		t.Fatalf("expected 4 states, got %d", len(states))
+	}
+	if states[0].Parent != -1 || states[3].Parent != 1 {
+		t.Fatalf("unexpected parents: %+v", states)
+	}
+	if !states[2].Leaf || !states[3].Leaf || states[1].Leaf {
+		t.Fatalf("unexpected leaves: %+v", states)
+	}
+	if !states[3].Current || states[3].Changes != 2 {
+		t.Fatalf("unexpected current state: %+v", states[3])
+	}
+}
+
+func TestUndoCursorRecorded(t *testing.T) {
+	b := NewFromString("one\ntwo\nthree")
+	cursor := 0
+	b.SetCursorSource(func() int { return cursor })
+
+	// single edit: before is sampled at the edit, after once settled
+	cursor = 4
+	_ = b.Delete(4, 8) // "two\n"
+	cursor = 4
+	b.SettleCursor()
+
+	// grouped edit: before at BeginUndoGroup, after at EndUndoGroup
+	cursor = 0
+	b.BeginUndoGroup()
+	_ = b.Insert(0, "a")
+	_ = b.Insert(1, "b")
+	cursor = 2
+	b.EndUndoGroup()
+
+	cursor = 9 // moved away before undoing
+	b.Undo()
+	if pos, ok := b.UndoCursor(); !ok || pos != 0 {
+		t.Fatalf("undo group: cursor %d, %v", pos, ok)
+	}
+	b.Undo()
+	if pos, ok := b.UndoCursor(); !ok || pos != 4 {
+		t.Fatalf("undo delete: cursor %d, %v", pos, ok)
+	}
+	b.Redo()
+	b.Redo()
+	if pos, ok := b.UndoCursor(); !ok || pos != 2 {
+		t.Fatalf("redo group: cursor %d, %v", pos, ok)
+	}
+}
+
+func TestUndoCursorUnknownWithoutSource(t *testing.T) {
+	b := NewFromString("x")
+	_ = b.Insert(1, "y")
+	b.Undo()
+	if _, ok := b.UndoCursor(); ok {
+		t.Fatal("expected no cursor without a source")
+	}
+}
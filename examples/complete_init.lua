@@ -14,6 +14,7 @@ vb.opt.number = true             -- Show absolute line numbers
 vb.opt.wrap = true               -- Wrap long lines
 vb.opt.scrolloff = 3             -- Lines to keep above/below cursor
 vb.opt.leader = " "              -- Leader key (space is popular)
+vb.opt.undofile = true           -- Persist undo history across sessions
 
 -- Alternative syntax using methods:
 -- vb.opt:set("tabwidth", 4)
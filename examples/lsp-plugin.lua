@@ -64,177 +64,117 @@ local function find_root_dir(filepath, patterns)
 	return filepath:match("(.+)/[^/]+$") or "."
 end
 
--- Start an LSP client for a server
+-- Start an LSP client for a server. Buffers of the server's filetypes are
+-- attached automatically: the editor sends didOpen, didChange, didSave and
+-- didClose for them.
 local function start_client(server_name, config, root_dir)
-	local client_id = server_name .. ":" .. root_dir
-	
-	-- Check if client already exists
-	if M.clients[client_id] then
-		return client_id
+	local client_id, err = lsp.start_client({
+		cmd = config.cmd,
+		args = config.args or {},
+		root_dir = root_dir,
+		filetypes = config.filetypes,
+		on_attach = function(id, filepath)
+			M.documents[filepath] = { client_id = id }
+		end,
+	})
+
+	if not client_id then
+		vb.notify("LSP: Failed to start " .. server_name .. ": " .. err, "error")
+		return nil
 	end
-	
-	-- Start the client
-	local success = pcall(function()
-		lsp.start_client({
-			cmd = config.cmd,
-			args = config.args or {},
-			root_dir = root_dir,
-		})
-	end)
-	
-	if success then
+
+	if not M.clients[client_id] then
 		M.clients[client_id] = {
 			server_name = server_name,
 			config = config,
 			root_dir = root_dir,
 		}
 		vb.notify("LSP: Started " .. server_name, "info")
-	else
-		vb.notify("LSP: Failed to start " .. server_name, "error")
 	end
-	
+
 	return client_id
 end
 
--- Attach LSP to a buffer
-local function attach_buffer(filepath, filetype)
-	-- Find matching server
-	local server_name, server_config
+-- Start the server for a buffer's filetype
+local function start_for_buffer(filepath, filetype)
 	for name, config in pairs(M.servers) do
 		for _, ft in ipairs(config.filetypes) do
 			if ft == filetype then
-				server_name = name
-				server_config = config
-				break
+				return start_client(name, config, find_root_dir(filepath, config.root_patterns))
 			end
 		end
-		if server_name then
-			break
-		end
 	end
-	
-	if not server_name then
-		return nil
-	end
-	
-	-- Find root directory
-	local root_dir = find_root_dir(filepath, server_config.root_patterns)
-	
-	-- Start or get existing client
-	local client_id = start_client(server_name, server_config, root_dir)
-	
-	-- Read file content
-	local file = io.open(filepath, "r")
-	if not file then
-		return nil
-	end
-	local content = file:read("*all")
-	file:close()
-	
-	-- Notify LSP of opened document
-	lsp.did_open(client_id, filepath, content)
-	
-	-- Track document
-	M.documents[filepath] = {
-		client_id = client_id,
-		version = 1,
-	}
-	
-	return client_id
+	return nil
 end
 
 -- Setup function called from init.lua
 function M.setup(opts)
 	opts = opts or {}
-	
+
 	-- Merge user server configs with defaults
 	if opts.servers then
 		for name, config in pairs(opts.servers) do
 			M.servers[name] = config
 		end
 	end
-	
-	-- Hook into buffer events
-	events.on(events.BUFFER_OPENED, function(data)
-		local filetype = get_filetype(data.filepath)
+
+	-- Start servers as files are read; the buffer is attached once the
+	-- server has initialized
+	vb.on("BufRead", function(ctx, data)
+		local filetype = get_filetype(data.file)
 		if filetype then
-			attach_buffer(data.filepath, filetype)
+			start_for_buffer(data.file, filetype)
 		end
 	end)
-	
-	events.on(events.BUFFER_CHANGED, function(data)
-		local doc = M.documents[data.filepath]
-		if doc then
-			doc.version = doc.version + 1
-			lsp.did_change(doc.client_id, data.filepath, data.content)
-		end
-	end)
-	
-	events.on(events.BUFFER_SAVED, function(data)
-		local doc = M.documents[data.filepath]
-		if doc then
-			lsp.did_save(doc.client_id, data.filepath)
-		end
-	end)
-	
-	events.on(events.BUFFER_CLOSED, function(data)
-		local doc = M.documents[data.filepath]
-		if doc then
-			lsp.did_close(doc.client_id, data.filepath)
-			M.documents[data.filepath] = nil
-		end
+
+	vb.on("BufDelete", function(ctx, data)
+		M.documents[data.file] = nil
 	end)
-	
+
 	vb.notify("LSP plugin loaded", "info")
 end
 
--- Public API for keybindings
+-- Public API for keybindings. Results arrive on the main loop, like
+-- functions passed to vb.schedule.
 function M.goto_definition()
-	local ctx = vb.current_context()
-	if not ctx then
-		return
-	end
-	
-	local filepath = ctx.file
-	local line = ctx.line
-	local col = ctx.col
-	
+	local filepath = vb.buf.get_name()
+	local line, col = vb.buf.cursor()
+
 	local doc = M.documents[filepath]
 	if not doc then
 		vb.notify("LSP not attached to this buffer", "warn")
 		return
 	end
-	
-	-- Request definition
-	lsp.goto_definition(doc.client_id, filepath, line, col, function(locations)
+
+	lsp.goto_definition(doc.client_id, filepath, line, col, function(locations, err)
+		if err then
+			vb.notify("LSP: " .. err, "warn")
+			return
+		end
 		if not locations or #locations == 0 then
 			vb.notify("No definition found", "info")
 			return
 		end
-		
-		-- Jump to first location
+
 		local loc = locations[1]
-		-- TODO: Actually open the file and jump to location
-		vb.notify("Found definition at " .. loc.filepath .. ":" .. loc.line, "info")
+		if loc.filepath == vb.buf.get_name() then
+			vb.buf.set_cursor(loc.line, loc.col)
+		else
+			vb.notify("Definition at " .. loc.filepath .. ":" .. (loc.line + 1), "info")
+		end
 	end)
 end
 
 function M.hover()
-	local ctx = vb.current_context()
-	if not ctx then
-		return
-	end
-	
-	local filepath = ctx.file
-	local line = ctx.line
-	local col = ctx.col
-	
+	local filepath = vb.buf.get_name()
+	local line, col = vb.buf.cursor()
+
 	local doc = M.documents[filepath]
 	if not doc then
 		vb.notify("LSP not attached to this buffer", "warn")
 		return
 	end
-	
+
 	lsp.hover(doc.client_id, filepath, line, col, function(info)
 		if info then
 			vb.notify("Hover: " .. info, "info")
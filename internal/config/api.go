@@ -13,6 +13,13 @@ type Options struct {
 	ScrollOff      int
 	Leader         string
 	Number         bool
+	UndoFile       bool
+	FoldMethod     string // syntax, indent, marker or manual
+	AutoView       bool   // save views on quit and restore them on read
+	LSP            bool   // start language servers for opened files
+	FormatOnSave   bool   // format with the language server before writing
+	SemanticTokens bool   // highlight with semantic tokens from the language server
+	InlayHints     bool   // show inlay hints from the language server
 
 	// UI
 	StatusLine string
@@ -29,6 +36,13 @@ func DefaultOptions() *Options {
 		ScrollOff:      0,
 		Leader:         "\\",
 		Number:         true,
+		UndoFile:       false,
+		FoldMethod:     "syntax",
+		AutoView:       false,
+		LSP:            true,
+		FormatOnSave:   false,
+		SemanticTokens: true,
+		InlayHints:     false,
 		StatusLine:     "default",
 	}
 }
@@ -112,9 +126,25 @@ var Features = map[string]bool{
 	"completion.highlighting": true,
 	"undo.grouping":           true,
 	"undo.newline-break":      true,
+	"undo.tree":               true,
+	"undo.time-travel":        true,
+	"undo.persistent":         true,
 	"buffer.rune-offsets":     true,
+	"buffer.byte-offsets":     true,
+	"buffer.utf16-offsets":    true,
+	"buffer.change-events":    true,
 	"buffer.unicode-safe":     true,
+	"syntax.incremental":      true,
+	"syntax.queries":          true,
+	"syntax.grammars":         true,
+	"syntax.injections":       true,
+	"syntax.textobjects":      true,
+	"syntax.node-selection":   true,
 	"opt.tabwidth":            true,
+	"opt.undofile":            true,
+	"opt.foldmethod":          true,
+	"opt.autoview":            true,
+	"state.views":             true,
 	"opt.expandtab":           true,
 	"opt.leader":              true,
 	"opt.property_access":     true,
@@ -122,5 +152,21 @@ var Features = map[string]bool{
 	"state.persistent":        true,
 	"notify":                  true,
 	"schedule":                true,
+	"lsp.client":              true,
+	"lsp.auto-attach":         true,
+	"lsp.incremental-sync":    true,
+	"lsp.servers":             true,
+	"lsp.diagnostics":         true,
+	"lsp.hover":               true,
+	"lsp.signature-help":      true,
+	"lsp.completion":          true,
+	"lsp.references":          true,
+	"lsp.rename":              true,
+	"lsp.code-actions":        true,
+	"lsp.formatting":          true,
+	"opt.lsp":                 true,
+	"opt.formatonsave":        true,
+	"opt.semantictokens":      true,
+	"opt.inlayhints":          true,
 	"callback.safety":         true,
 }
@@ -19,10 +19,12 @@ type Config struct {
 	LoadedPlugins []PluginInfo
 	PluginDir     string
 	State         *State
+	LSPServers    map[string]LSPServer // language servers by filetype
 
-	// Scheduled functions (for vb.schedule)
-	scheduledFns []*lua.LFunction
+	// Scheduled functions (for vb.schedule and async results)
+	scheduledFns []func(L *lua.LState)
 	scheduleMu   sync.Mutex
+	wake         func() // called when a function is scheduled
 
 	// Legacy fields for backwards compatibility
 	TabWidth         int
@@ -53,6 +55,7 @@ func DefaultConfig() *Config {
 		PluginDir:     getDefaultPluginDir(),
 		Plugins:       []string{},
 		State:         state,
+		LSPServers:    DefaultLSPServers(),
 		// Legacy fields
 		TabWidth:         4,
 		RelativeLineNums: false,
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	lua "github.com/yuin/gopher-lua"
 )
@@ -13,6 +14,11 @@ type Loader struct {
 	config        *Config
 	Notifications *NotificationQueue
 	editorCtx     EditorContext // Editor context for buffer operations
+
+	// Language servers started from Lua and the buffers they can attach to
+	lspClients map[string]*lspClient
+	lspBuffers map[string]*lspBuffer
+	lspClosing sync.WaitGroup // clients shutting down, see lspClose
 }
 
 // NewLoader creates a new config loader
@@ -24,11 +30,19 @@ func NewLoader() *Loader {
 	}
 }
 
-// Close closes the Lua state
+// Close stops the language servers started from Lua and closes the Lua
+// state
 func (l *Loader) Close() {
+	l.stopLSPClients()
 	l.L.Close()
 }
 
+// RunScheduled runs the functions queued with vb.schedule and the results of
+// asynchronous requests. It is called once per editor tick.
+func (l *Loader) RunScheduled() {
+	l.config.ProcessScheduledFunctions(l.L)
+}
+
 // Load loads the configuration from init.lua
 func (l *Loader) Load() (*Config, error) {
 	configPath := GetConfigPath()
@@ -0,0 +1,94 @@
+package config
+
+import lua "github.com/yuin/gopher-lua"
+
+// LSPServer describes the language server the editor starts for a filetype
+type LSPServer struct {
+	Command     string
+	Args        []string
+	RootMarkers []string               // files or directories that mark a project root
+	Settings    map[string]interface{} // answers to workspace/configuration
+}
+
+// DefaultLSPServers returns the servers started for common filetypes. A
+// server that is not installed is reported by :LspInfo and otherwise
+// ignored.
+func DefaultLSPServers() map[string]LSPServer {
+	typescript := LSPServer{
+		Command:     "typescript-language-server",
+		Args:        []string{"--stdio"},
+		RootMarkers: []string{"tsconfig.json", "jsconfig.json", "package.json", ".git"},
+	}
+	clangd := LSPServer{
+		Command:     "clangd",
+		RootMarkers: []string{"compile_commands.json", ".clangd", ".git"},
+	}
+	return map[string]LSPServer{
+		"go": {
+			Command:     "gopls",
+			RootMarkers: []string{"go.work", "go.mod", ".git"},
+		},
+		"python": {
+			Command:     "pyright-langserver",
+			Args:        []string{"--stdio"},
+			RootMarkers: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt", ".git"},
+		},
+		"rust": {
+			Command:     "rust-analyzer",
+			RootMarkers: []string{"Cargo.toml", ".git"},
+		},
+		"javascript":      typescript,
+		"typescript":      typescript,
+		"typescriptreact": typescript,
+		"c":               clangd,
+		"cpp":             clangd,
+		"lua": {
+			Command:     "lua-language-server",
+			RootMarkers: []string{".luarc.json", ".git"},
+		},
+	}
+}
+
+// luaLSPSetup implements vb.lsp.setup(servers). Each key is a filetype and
+// each value a server table, or false to start no server for it.
+// Usage: vb.lsp.setup({ go = { cmd = "gopls", args = {}, root_markers = { "go.mod" }, settings = { gopls = {} } }, python = false })
+func (l *Loader) luaLSPSetup(L *lua.LState) int {
+	servers := L.CheckTable(1)
+
+	if l.config.LSPServers == nil {
+		l.config.LSPServers = make(map[string]LSPServer)
+	}
+	servers.ForEach(func(key, value lua.LValue) {
+		filetype := key.String()
+		tbl, ok := value.(*lua.LTable)
+		if !ok {
+			if !lua.LVAsBool(value) {
+				delete(l.config.LSPServers, filetype)
+			}
+			return
+		}
+
+		server := LSPServer{
+			Args:        luaStringList(tbl.RawGetString("args")),
+			RootMarkers: luaStringList(tbl.RawGetString("root_markers")),
+		}
+		if settings, ok := luaToGo(tbl.RawGetString("settings")).(map[string]interface{}); ok {
+			server.Settings = settings
+		}
+		// cmd is a command name or a list of the command and its arguments
+		switch cmd := tbl.RawGetString("cmd").(type) {
+		case lua.LString:
+			server.Command = string(cmd)
+		case *lua.LTable:
+			if words := luaStringList(cmd); len(words) > 0 {
+				server.Command = words[0]
+				server.Args = append(words[1:], server.Args...)
+			}
+		}
+		if server.Command == "" {
+			L.RaiseError("vb.lsp.setup: %s: cmd is required", filetype)
+		}
+		l.config.LSPServers[filetype] = server
+	})
+	return 0
+}
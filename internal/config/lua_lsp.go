@@ -1,165 +1,617 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
-
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
 	lua "github.com/yuin/gopher-lua"
 )
 
-// LSPClientRegistry stores LSP clients created by Lua plugins
-var lspClientRegistry = make(map[string]interface{})
+// lspClient is a language server started from Lua with lsp.start_client.
+// It is only touched on the editor's main loop; results of requests are
+// handed back through Config.Schedule.
+type lspClient struct {
+	id             string
+	client         *lsp.Client
+	filetypes      []string
+	rootDir        string
+	ready          bool                         // initialize has finished
+	queued         []func()                     // document calls made before ready
+	docs           map[string]*lsp.DocumentSync // attached documents by path
+	onNotification *lua.LFunction
+	onAttach       *lua.LFunction
+}
+
+// lspBuffer is a buffer open in the editor that clients attach to
+type lspBuffer struct {
+	filetype string
+	buf      *buffer.Buffer
+}
+
+// setupLSPTable creates the lsp table, available both as vb.lsp and as the
+// global lsp
+func (l *Loader) setupLSPTable(vbTable *lua.LTable) {
+	l.lspClients = make(map[string]*lspClient)
+	l.lspBuffers = make(map[string]*lspBuffer)
 
-// RegisterLSPAPI adds LSP-related functions to the Lua state
-func RegisterLSPAPI(L *lua.LState) {
-	// Create lsp table
-	lspTable := L.NewTable()
+	lspTable := l.L.NewTable()
+
+	// lsp.setup(servers): the servers the editor starts itself
+	l.L.SetField(lspTable, "setup", l.L.NewFunction(l.luaLSPSetup))
 
 	// lsp.start_client(config)
-	L.SetField(lspTable, "start_client", L.NewFunction(luaStartLSPClient))
+	l.L.SetField(lspTable, "start_client", l.L.NewFunction(l.luaStartLSPClient))
 
 	// lsp.stop_client(client_id)
-	L.SetField(lspTable, "stop_client", L.NewFunction(luaStopLSPClient))
+	l.L.SetField(lspTable, "stop_client", l.L.NewFunction(l.luaStopLSPClient))
+
+	// lsp.get_clients()
+	l.L.SetField(lspTable, "get_clients", l.L.NewFunction(l.luaGetLSPClients))
 
-	// lsp.goto_definition(filepath, line, col, callback)
-	L.SetField(lspTable, "goto_definition", L.NewFunction(luaGotoDefinition))
+	// lsp.goto_definition(client_id, filepath, line, col, callback)
+	l.L.SetField(lspTable, "goto_definition", l.L.NewFunction(l.luaGotoDefinition))
 
-	// lsp.hover(filepath, line, col, callback)
-	L.SetField(lspTable, "hover", L.NewFunction(luaHover))
+	// lsp.hover(client_id, filepath, line, col, callback)
+	l.L.SetField(lspTable, "hover", l.L.NewFunction(l.luaHover))
 
 	// lsp.did_open(client_id, filepath, content)
-	L.SetField(lspTable, "did_open", L.NewFunction(luaDidOpen))
+	l.L.SetField(lspTable, "did_open", l.L.NewFunction(l.luaDidOpen))
 
 	// lsp.did_change(client_id, filepath, content)
-	L.SetField(lspTable, "did_change", L.NewFunction(luaDidChange))
+	l.L.SetField(lspTable, "did_change", l.L.NewFunction(l.luaDidChange))
 
 	// lsp.did_save(client_id, filepath)
-	L.SetField(lspTable, "did_save", L.NewFunction(luaDidSave))
+	l.L.SetField(lspTable, "did_save", l.L.NewFunction(l.luaDidSave))
 
 	// lsp.did_close(client_id, filepath)
-	L.SetField(lspTable, "did_close", L.NewFunction(luaDidClose))
+	l.L.SetField(lspTable, "did_close", l.L.NewFunction(l.luaDidClose))
 
-	L.SetGlobal("lsp", lspTable)
+	l.L.SetField(vbTable, "lsp", lspTable)
+	l.L.SetGlobal("lsp", lspTable)
 }
 
-// luaStartLSPClient starts an LSP client
-// Usage: client_id = lsp.start_client({cmd = "gopls", args = {}, root_dir = "/path"})
-func luaStartLSPClient(L *lua.LState) int {
-	config := L.CheckTable(1)
+// luaStartLSPClient starts a language server and initializes it in the
+// background. Buffers matching its filetypes (or, without filetypes, inside
+// root_dir) are attached once it is ready. Starting the same cmd and
+// root_dir twice returns the running client. settings answer the server's
+// workspace/configuration requests.
+// Usage: client_id = lsp.start_client({cmd = "gopls", root_dir = "/path", filetypes = {"go"}, settings = {gopls = {}}})
+func (l *Loader) luaStartLSPClient(L *lua.LState) int {
+	opts := L.CheckTable(1)
+
+	cmd := lua.LVAsString(opts.RawGetString("cmd"))
+	if cmd == "" {
+		L.ArgError(1, "cmd is required")
+		return 0
+	}
+	rootDir := lua.LVAsString(opts.RawGetString("root_dir"))
 
-	cmd := config.RawGetString("cmd").String()
-	rootDir := config.RawGetString("root_dir").String()
+	clientID := cmd + ":" + rootDir
+	if _, ok := l.lspClients[clientID]; ok {
+		L.Push(lua.LString(clientID))
+		return 1
+	}
 
-	// Extract args
-	var args []string
-	if argsVal := config.RawGetString("args"); argsVal != lua.LNil {
-		if argsTable, ok := argsVal.(*lua.LTable); ok {
-			argsTable.ForEach(func(_ lua.LValue, val lua.LValue) {
-				args = append(args, val.String())
-			})
-		}
+	rootURI := ""
+	if rootDir != "" {
+		rootURI = lsp.URIFromPath(rootDir)
+	}
+	client, err := lsp.NewClient(cmd, luaStringList(opts.RawGetString("args")), rootURI)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
 	}
 
-	// This will be implemented to actually start the LSP client
-	// For now, just return a placeholder
-	clientID := cmd + ":" + rootDir
-	lspClientRegistry[clientID] = map[string]interface{}{
-		"cmd":      cmd,
-		"args":     args,
-		"root_dir": rootDir,
+	c := &lspClient{
+		id:        clientID,
+		client:    client,
+		filetypes: luaStringList(opts.RawGetString("filetypes")),
+		rootDir:   rootDir,
+		docs:      make(map[string]*lsp.DocumentSync),
+	}
+	if fn, ok := opts.RawGetString("on_notification").(*lua.LFunction); ok {
+		c.onNotification = fn
+	}
+	if fn, ok := opts.RawGetString("on_attach").(*lua.LFunction); ok {
+		c.onAttach = fn
+	}
+	l.lspClients[clientID] = c
+
+	client.SetNotificationHandler(func(method string, params json.RawMessage) {
+		l.lspNotification(c, method, params)
+	})
+	if settings, ok := luaToGo(opts.RawGetString("settings")).(map[string]interface{}); ok {
+		client.SetSettings(settings)
 	}
+	go func() {
+		err := client.Initialize()
+		l.config.Schedule(func(L *lua.LState) {
+			l.lspInitialized(c, err)
+		})
+	}()
 
 	L.Push(lua.LString(clientID))
 	return 1
 }
 
+// lspInitialized runs on the main loop when a client's initialize request
+// has finished
+func (l *Loader) lspInitialized(c *lspClient, err error) {
+	if l.lspClients[c.id] != c {
+		return // stopped while initializing
+	}
+	if err != nil {
+		l.Notifications.Push(fmt.Sprintf("LSP %s: %v", c.id, err), NotifyError)
+		delete(l.lspClients, c.id)
+		l.lspClose(c)
+		return
+	}
+
+	c.ready = true
+	for _, fn := range c.queued {
+		fn()
+	}
+	c.queued = nil
+
+	paths := make([]string, 0, len(l.lspBuffers))
+	for path := range l.lspBuffers {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if c.matches(path, l.lspBuffers[path].filetype) {
+			l.lspAttach(c, path)
+		}
+	}
+}
+
+// lspNotification runs on the client's read goroutine and hands the
+// notification to the client's on_notification callback
+func (l *Loader) lspNotification(c *lspClient, method string, params json.RawMessage) {
+	if c.onNotification == nil {
+		return
+	}
+	var value interface{}
+	_ = json.Unmarshal(params, &value)
+	l.config.Schedule(func(L *lua.LState) {
+		l.SafeCallLuaFunction(c.onNotification, lua.LString(method), goToLua(L, value))
+	})
+}
+
+// matches reports whether a buffer should be attached to the client
+func (c *lspClient) matches(path, filetype string) bool {
+	if len(c.filetypes) > 0 {
+		return slices.Contains(c.filetypes, filetype)
+	}
+	if c.rootDir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(c.rootDir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// forgetDocs drops the documents of a stopped client
+func (c *lspClient) forgetDocs() {
+	for _, ds := range c.docs {
+		ds.Untrack()
+	}
+	clear(c.docs)
+}
+
+// lspAttach opens the editor buffer at path on the client
+func (l *Loader) lspAttach(c *lspClient, path string) {
+	b := l.lspBuffers[path]
+	if b == nil || c.docs[path] != nil {
+		return
+	}
+	ds := lsp.NewDocumentSync(c.client, path)
+	if err := ds.DidOpen(lsp.LanguageID(b.filetype, path), b.buf.String()); err != nil {
+		l.lspError(c, err)
+		return
+	}
+	ds.Track(b.buf)
+	c.docs[path] = ds
+	if c.onAttach != nil {
+		l.SafeCallLuaFunction(c.onAttach, lua.LString(c.id), lua.LString(path))
+	}
+}
+
+// lspDo runs fn now if the client is ready, or once it is
+func (c *lspClient) lspDo(fn func()) {
+	if c.ready {
+		fn()
+		return
+	}
+	c.queued = append(c.queued, fn)
+}
+
+func (l *Loader) lspError(c *lspClient, err error) {
+	l.Notifications.Push(fmt.Sprintf("LSP %s: %v", c.id, err), NotifyError)
+}
+
+// LSPBufferOpened tells the Lua LSP clients that the editor read the file at
+// path into buf. Ready clients matching its filetype attach to it at once,
+// the others when they finish initializing.
+func (l *Loader) LSPBufferOpened(path, filetype string, buf *buffer.Buffer) {
+	if l.lspBuffers == nil {
+		return
+	}
+	l.lspBuffers[path] = &lspBuffer{filetype: filetype, buf: buf}
+	for _, c := range l.lspClients {
+		if c.ready && c.matches(path, filetype) {
+			l.lspAttach(c, path)
+		}
+	}
+}
+
+// LSPBufferChanged sends the changes of the buffer at path to the clients
+// it is attached to
+func (l *Loader) LSPBufferChanged(path string) {
+	if l.lspBuffers[path] == nil {
+		return
+	}
+	for _, c := range l.lspClients {
+		if ds := c.docs[path]; ds != nil {
+			if err := ds.Flush(); err != nil {
+				l.lspError(c, err)
+			}
+		}
+	}
+}
+
+// LSPBufferSaved tells the clients attached to the buffer at path that it
+// was written
+func (l *Loader) LSPBufferSaved(path string) {
+	b := l.lspBuffers[path]
+	if b == nil {
+		return
+	}
+	text := b.buf.String()
+	for _, c := range l.lspClients {
+		if ds := c.docs[path]; ds != nil {
+			if err := ds.DidSave(text); err != nil {
+				l.lspError(c, err)
+			}
+		}
+	}
+}
+
+// LSPBufferClosed detaches the buffer at path from all clients
+func (l *Loader) LSPBufferClosed(path string) {
+	if l.lspBuffers[path] == nil {
+		return
+	}
+	delete(l.lspBuffers, path)
+	for _, c := range l.lspClients {
+		if ds := c.docs[path]; ds != nil {
+			if err := ds.DidClose(); err != nil {
+				l.lspError(c, err)
+			}
+			delete(c.docs, path)
+		}
+	}
+}
+
+// stopLSPClients shuts down all clients and waits for them to exit
+func (l *Loader) stopLSPClients() {
+	for id, c := range l.lspClients {
+		l.lspClose(c)
+		c.forgetDocs()
+		delete(l.lspClients, id)
+	}
+	l.lspClosing.Wait()
+}
+
+// lspClose shuts the server of c down on its own goroutine, so the editor
+// does not wait for a server that does not answer. Errors are reported on
+// the main loop.
+func (l *Loader) lspClose(c *lspClient) {
+	l.lspClosing.Add(1)
+	go func() {
+		defer l.lspClosing.Done()
+		if err := c.client.Close(); err != nil {
+			l.config.Schedule(func(L *lua.LState) { l.lspError(c, err) })
+		}
+	}()
+}
+
+// checkLSPClient returns the client whose id is argument n
+func (l *Loader) checkLSPClient(L *lua.LState, n int) *lspClient {
+	id := L.CheckString(n)
+	c, ok := l.lspClients[id]
+	if !ok {
+		L.ArgError(n, "unknown LSP client: "+id)
+	}
+	return c
+}
+
 // luaStopLSPClient stops an LSP client
-func luaStopLSPClient(L *lua.LState) int {
-	clientID := L.CheckString(1)
-	delete(lspClientRegistry, clientID)
+func (l *Loader) luaStopLSPClient(L *lua.LState) int {
+	c := l.checkLSPClient(L, 1)
+	delete(l.lspClients, c.id)
+	l.lspClose(c)
+	c.forgetDocs()
 	return 0
 }
 
-// luaGotoDefinition requests definition location from LSP
-// Usage: lsp.goto_definition(client_id, filepath, line, col, function(locations) ... end)
-func luaGotoDefinition(L *lua.LState) int {
-	clientID := L.CheckString(1)
-	filepath := L.CheckString(2)
+// luaGetLSPClients lists the running clients
+// Usage: for _, c in ipairs(lsp.get_clients()) do print(c.id, c.ready, #c.attached) end
+func (l *Loader) luaGetLSPClients(L *lua.LState) int {
+	ids := make([]string, 0, len(l.lspClients))
+	for id := range l.lspClients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	list := L.NewTable()
+	for _, id := range ids {
+		c := l.lspClients[id]
+		attached := make([]string, 0, len(c.docs))
+		for path := range c.docs {
+			attached = append(attached, path)
+		}
+		sort.Strings(attached)
+
+		entry := L.NewTable()
+		entry.RawSetString("id", lua.LString(c.id))
+		entry.RawSetString("root_dir", lua.LString(c.rootDir))
+		entry.RawSetString("ready", lua.LBool(c.ready))
+		paths := L.NewTable()
+		for _, path := range attached {
+			paths.Append(lua.LString(path))
+		}
+		entry.RawSetString("attached", paths)
+		list.Append(entry)
+	}
+	L.Push(list)
+	return 1
+}
+
+// lspTextBuffer returns the text of the file at path: the editor's buffer if
+// it is open, else the file on disk
+func (l *Loader) lspTextBuffer(path string) *buffer.Buffer {
+	if b := l.lspBuffers[path]; b != nil {
+		return b.buf
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return buffer.NewFromString(strings.ReplaceAll(string(data), "\r\n", "\n"))
+}
+
+// lspRequestPosition converts a 0-based line and rune column in the file at
+// path into a server position
+func (l *Loader) lspRequestPosition(c *lspClient, path string, line, col int) lsp.Position {
+	buf := l.lspTextBuffer(path)
+	if buf == nil {
+		return lsp.Position{Line: line, Character: col}
+	}
+	pos := buf.PosFromLineCol(line, col, buffer.EncodingRune)
+	return lsp.PositionAt(buf, pos, c.client.PositionEncoding())
+}
+
+// lspLocationsTable converts server locations into a Lua list of
+// {filepath, line, col, end_line, end_col} with 0-based lines and rune
+// columns
+func (l *Loader) lspLocationsTable(L *lua.LState, c *lspClient, locs []lsp.Location) *lua.LTable {
+	enc := c.client.PositionEncoding()
+	bufs := make(map[string]*buffer.Buffer)
+	list := L.NewTable()
+	for _, loc := range locs {
+		path := lsp.PathFromURI(loc.URI)
+		buf, ok := bufs[path]
+		if !ok {
+			buf = l.lspTextBuffer(path)
+			bufs[path] = buf
+		}
+
+		start, end := loc.Range.Start, loc.Range.End
+		if buf != nil {
+			start.Line, start.Character = buf.LineCol(lsp.OffsetAt(buf, start, enc), buffer.EncodingRune)
+			end.Line, end.Character = buf.LineCol(lsp.OffsetAt(buf, end, enc), buffer.EncodingRune)
+		}
+
+		entry := L.NewTable()
+		entry.RawSetString("filepath", lua.LString(path))
+		entry.RawSetString("uri", lua.LString(loc.URI))
+		entry.RawSetString("line", lua.LNumber(start.Line))
+		entry.RawSetString("col", lua.LNumber(start.Character))
+		entry.RawSetString("end_line", lua.LNumber(end.Line))
+		entry.RawSetString("end_col", lua.LNumber(end.Character))
+		list.Append(entry)
+	}
+	return list
+}
+
+// lspRequestDoc returns the document to send a request about: the attached
+// one, with the changes not yet sent flushed so the request's position is
+// in the text the server has, or one for a file the server has not been
+// told about
+func (l *Loader) lspRequestDoc(c *lspClient, path string) *lsp.DocumentSync {
+	if ds := c.docs[path]; ds != nil {
+		if err := ds.Flush(); err != nil {
+			l.lspError(c, err)
+		}
+		return ds
+	}
+	return lsp.NewDocumentSync(c.client, path)
+}
+
+// luaGotoDefinition requests definition location from LSP. Line and col are
+// 0-based, col in characters. The callback runs on the main loop with a list
+// of locations, or nil and an error message.
+// Usage: lsp.goto_definition(client_id, filepath, line, col, function(locations, err) ... end)
+func (l *Loader) luaGotoDefinition(L *lua.LState) int {
+	c := l.checkLSPClient(L, 1)
+	path := L.CheckString(2)
 	line := L.CheckInt(3)
 	col := L.CheckInt(4)
 	callback := L.CheckFunction(5)
 
-	// This will be implemented to actually call LSP
-	// For now, just call the callback with empty result
+	if !c.ready {
+		l.config.Schedule(func(L *lua.LState) {
+			l.SafeCallLuaFunction(callback, lua.LNil, lua.LString("LSP not ready"))
+		})
+		return 0
+	}
+
+	ds := l.lspRequestDoc(c, path)
+	pos := l.lspRequestPosition(c, path, line, col)
 	go func() {
-		locationsTable := L.NewTable()
-		L.Push(callback)
-		L.Push(locationsTable)
-		if err := L.PCall(1, 0, nil); err != nil {
-			L.RaiseError("goto_definition callback error: %v", err)
-		}
+		locs, err := ds.Definition(context.Background(), pos.Line, pos.Character)
+		l.config.Schedule(func(L *lua.LState) {
+			if err != nil {
+				l.SafeCallLuaFunction(callback, lua.LNil, lua.LString(err.Error()))
+				return
+			}
+			l.SafeCallLuaFunction(callback, l.lspLocationsTable(L, c, locs))
+		})
 	}()
 
-	// Silence unused variable warnings
-	_, _, _, _ = clientID, filepath, line, col
-
 	return 0
 }
 
-// luaHover requests hover information from LSP
-func luaHover(L *lua.LState) int {
-	clientID := L.CheckString(1)
-	filepath := L.CheckString(2)
+// luaHover requests hover information from LSP. The callback runs on the
+// main loop with the hover text (nil when there is none) or nil and an
+// error message.
+// Usage: lsp.hover(client_id, filepath, line, col, function(info, err) ... end)
+func (l *Loader) luaHover(L *lua.LState) int {
+	c := l.checkLSPClient(L, 1)
+	path := L.CheckString(2)
 	line := L.CheckInt(3)
 	col := L.CheckInt(4)
 	callback := L.CheckFunction(5)
 
-	// Will be implemented later
-	_, _, _, _, _ = clientID, filepath, line, col, callback
+	if !c.ready {
+		l.config.Schedule(func(L *lua.LState) {
+			l.SafeCallLuaFunction(callback, lua.LNil, lua.LString("LSP not ready"))
+		})
+		return 0
+	}
+
+	ds := l.lspRequestDoc(c, path)
+	pos := l.lspRequestPosition(c, path, line, col)
+	go func() {
+		text, err := ds.Hover(context.Background(), pos.Line, pos.Character)
+		l.config.Schedule(func(L *lua.LState) {
+			switch {
+			case err != nil:
+				l.SafeCallLuaFunction(callback, lua.LNil, lua.LString(err.Error()))
+			case text == "":
+				l.SafeCallLuaFunction(callback, lua.LNil)
+			default:
+				l.SafeCallLuaFunction(callback, lua.LString(text))
+			}
+		})
+	}()
+
 	return 0
 }
 
-// luaDidOpen notifies LSP of opened document
-func luaDidOpen(L *lua.LState) int {
-	clientID := L.CheckString(1)
-	filepath := L.CheckString(2)
+// luaDidOpen notifies LSP of opened document. Buffers the editor opens are
+// attached automatically; this is for text the editor does not hold.
+func (l *Loader) luaDidOpen(L *lua.LState) int {
+	c := l.checkLSPClient(L, 1)
+	path := L.CheckString(2)
 	content := L.CheckString(3)
 
-	// Will be implemented later
-	_, _, _ = clientID, filepath, content
+	c.lspDo(func() {
+		if ds := c.docs[path]; ds != nil {
+			if err := ds.DidChange(content); err != nil {
+				l.lspError(c, err)
+			}
+			return
+		}
+		filetype := ""
+		if b := l.lspBuffers[path]; b != nil {
+			filetype = b.filetype
+		}
+		ds := lsp.NewDocumentSync(c.client, path)
+		if err := ds.DidOpen(lsp.LanguageID(filetype, path), content); err != nil {
+			l.lspError(c, err)
+			return
+		}
+		c.docs[path] = ds
+	})
 	return 0
 }
 
 // luaDidChange notifies LSP of document changes
-func luaDidChange(L *lua.LState) int {
-	clientID := L.CheckString(1)
-	filepath := L.CheckString(2)
+func (l *Loader) luaDidChange(L *lua.LState) int {
+	c := l.checkLSPClient(L, 1)
+	path := L.CheckString(2)
 	content := L.CheckString(3)
 
-	// Will be implemented later
-	_, _, _ = clientID, filepath, content
+	c.lspDo(func() {
+		if ds := c.docs[path]; ds != nil {
+			if err := ds.DidChange(content); err != nil {
+				l.lspError(c, err)
+			}
+		}
+	})
 	return 0
 }
 
 // luaDidSave notifies LSP of document save
-func luaDidSave(L *lua.LState) int {
-	clientID := L.CheckString(1)
-	filepath := L.CheckString(2)
-
-	// Will be implemented later
-	_, _ = clientID, filepath
+func (l *Loader) luaDidSave(L *lua.LState) int {
+	c := l.checkLSPClient(L, 1)
+	path := L.CheckString(2)
+
+	c.lspDo(func() {
+		ds := c.docs[path]
+		if ds == nil {
+			return
+		}
+		text := ""
+		if buf := l.lspTextBuffer(path); buf != nil {
+			text = buf.String()
+		}
+		if err := ds.DidSave(text); err != nil {
+			l.lspError(c, err)
+		}
+	})
 	return 0
 }
 
 // luaDidClose notifies LSP of closed document
-func luaDidClose(L *lua.LState) int {
-	clientID := L.CheckString(1)
-	filepath := L.CheckString(2)
-
-	// Will be implemented later
-	_, _ = clientID, filepath
+func (l *Loader) luaDidClose(L *lua.LState) int {
+	c := l.checkLSPClient(L, 1)
+	path := L.CheckString(2)
+
+	c.lspDo(func() {
+		if ds := c.docs[path]; ds != nil {
+			if err := ds.DidClose(); err != nil {
+				l.lspError(c, err)
+			}
+			delete(c.docs, path)
+		}
+	})
 	return 0
 }
 
+// luaStringList converts a Lua list of strings into a slice
+func luaStringList(v lua.LValue) []string {
+	tbl, ok := v.(*lua.LTable)
+	if !ok {
+		return nil
+	}
+	var list []string
+	tbl.ForEach(func(_ lua.LValue, val lua.LValue) {
+		list = append(list, val.String())
+	})
+	return list
+}
+
 // Helper to convert Lua table to JSON (for future use)
 func luaTableToJSON(L *lua.LState, table *lua.LTable) (string, error) {
 	data := luaTableToMap(table)
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp/lsptest"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// TestMain runs the test binary as a language server when the tests start
+// it as one
+func TestMain(m *testing.M) {
+	if os.Getenv("VB_LSPTEST_SERVER") == "1" {
+		if err := lsptest.Serve(os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// waitFor runs scheduled functions until cond holds
+func waitFor(t *testing.T, h *TestHarness, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		h.loader.RunScheduled()
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s: logs %v notifs %v", what, luaStrings(h, "logs"), h.GetNotifications())
+}
+
+// luaStrings returns the strings in the global Lua list name
+func luaStrings(h *TestHarness, name string) []string {
+	var list []string
+	if tbl, ok := h.L.GetGlobal(name).(*lua.LTable); ok {
+		tbl.ForEach(func(_, v lua.LValue) {
+			list = append(list, v.String())
+		})
+	}
+	return list
+}
+
+func hasLog(h *TestHarness, msg string) bool {
+	for _, l := range luaStrings(h, "logs") {
+		if l == msg {
+			return true
+		}
+	}
+	return false
+}
+
+const lspTestSource = "package main\n\nfunc answer() int { return 42 }\n\nvar s, x = \"😀é\", answer()\n"
+
+func TestLSPClientAttachesBuffers(t *testing.T) {
+	t.Setenv("VB_LSPTEST_SERVER", "1")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+
+	h := NewTestHarness()
+	defer h.Close()
+
+	// the buffer is open before the server is ready
+	buf := buffer.NewFromString(lspTestSource)
+	h.loader.LSPBufferOpened(path, "go", buf)
+	h.loader.LSPBufferOpened(filepath.Join(dir, "notes.txt"), "text", buffer.NewFromString("notes\n"))
+
+	h.L.SetGlobal("server", lua.LString(exe))
+	h.L.SetGlobal("dir", lua.LString(dir))
+	err = h.LoadString(`
+		logs = {}
+		attached = {}
+		client = vb.lsp.start_client({
+			cmd = server,
+			root_dir = dir,
+			filetypes = { "go" },
+			on_notification = function(method, params)
+				if method == "window/logMessage" then
+					table.insert(logs, params.message)
+				end
+			end,
+			on_attach = function(id, path)
+				table.insert(attached, path)
+			end,
+		})
+	`)
+	if err != nil {
+		t.Fatalf("LoadString failed: %v", err)
+	}
+
+	waitFor(t, h, "didOpen", func() bool { return hasLog(h, "didOpen "+path+" go 1") })
+	if got := luaStrings(h, "attached"); len(got) != 1 || got[0] != path {
+		t.Fatalf("attached = %v, want only %s", got, path)
+	}
+
+	_ = buf.Insert(0, "// edited\n")
+	h.loader.LSPBufferChanged(path)
+	waitFor(t, h, "didChange", func() bool { return hasLog(h, "didChange "+path+" 2") })
+	if !hasLog(h, `change 0:0-0:0 "// edited\n"`) {
+		t.Errorf("the change should be ranged: %v", luaStrings(h, "logs"))
+	}
+	h.loader.LSPBufferSaved(path)
+	waitFor(t, h, "didSave", func() bool { return hasLog(h, "didSave "+path) })
+
+	// positions are 0-based lines and character columns; the server counts
+	// UTF-16 units, so the emoji before "answer" must be converted
+	err = h.LoadString(`
+		lsp.hover(client, dir .. "/main.go", 5, 17, function(text, err)
+			hover_text = text or err
+		end)
+		lsp.goto_definition(client, dir .. "/main.go", 5, 17, function(locs, err)
+			definition = locs and (locs[1].line .. ":" .. locs[1].col) or err
+		end)
+	`)
+	if err != nil {
+		t.Fatalf("LoadString failed: %v", err)
+	}
+	waitFor(t, h, "hover and definition", func() bool {
+		return h.L.GetGlobal("hover_text") != lua.LNil && h.L.GetGlobal("definition") != lua.LNil
+	})
+	if got := h.L.GetGlobal("hover_text").String(); got != "`answer`" {
+		t.Errorf("hover = %q", got)
+	}
+	if got := h.L.GetGlobal("definition").String(); got != "3:5" {
+		t.Errorf("definition = %q, want 3:5", got)
+	}
+
+	// edits since the last change are sent in order, with UTF-16 columns
+	start := buf.LineStart(5)
+	_ = buf.Delete(start+13, start+14) // é after the emoji
+	_ = buf.Insert(0, "!")
+	h.loader.LSPBufferChanged(path)
+	waitFor(t, h, "second didChange", func() bool { return hasLog(h, "didChange "+path+" 3") })
+	logs := strings.Join(luaStrings(h, "logs"), "\n")
+	if !strings.Contains(logs, "didChange "+path+" 3\n"+`change 5:14-5:15 ""`+"\n"+`change 0:0-0:0 "!"`) {
+		t.Errorf("changes = %s", logs)
+	}
+
+	// requests send the changes made since first
+	_ = buf.Insert(0, "\n")
+	h.L.SetGlobal("hover_text", lua.LNil)
+	if err := h.LoadString(`lsp.hover(client, dir .. "/main.go", 6, 16, function(text, err) hover_text = text or err end)`); err != nil {
+		t.Fatalf("LoadString failed: %v", err)
+	}
+	waitFor(t, h, "hover after the edit", func() bool { return h.L.GetGlobal("hover_text") != lua.LNil })
+	if got := h.L.GetGlobal("hover_text").String(); got != "`answer`" {
+		t.Errorf("hover after the edit = %q", got)
+	}
+	if !hasLog(h, "didChange "+path+" 4") {
+		t.Errorf("the edit should be sent before the hover: %v", luaStrings(h, "logs"))
+	}
+
+	h.loader.LSPBufferClosed(path)
+	waitFor(t, h, "didClose", func() bool { return hasLog(h, "didClose "+path) })
+
+	if err := h.LoadString(`lsp.stop_client(client); n = #lsp.get_clients()`); err != nil {
+		t.Fatalf("LoadString failed: %v", err)
+	}
+	if n := h.L.GetGlobal("n").String(); n != "0" {
+		t.Errorf("%s clients left after stop_client", n)
+	}
+}
+
+func TestLSPClientErrors(t *testing.T) {
+	h := NewTestHarness()
+	defer h.Close()
+
+	err := h.LoadString(`id, err = lsp.start_client({ cmd = "voidabyss-no-such-server" })`)
+	if err != nil {
+		t.Fatalf("LoadString failed: %v", err)
+	}
+	if h.L.GetGlobal("id") != lua.LNil || h.L.GetGlobal("err") == lua.LNil {
+		t.Error("start_client should return nil and an error for a missing server")
+	}
+
+	err = h.LoadString(`lsp.hover("nope", "/tmp/x.go", 0, 0, function() end)`)
+	if err == nil || !strings.Contains(err.Error(), "unknown LSP client") {
+		t.Errorf("expected unknown client error, got %v", err)
+	}
+}
+
+func TestLSPSetup(t *testing.T) {
+	h := NewTestHarness()
+	defer h.Close()
+
+	if _, ok := h.config.LSPServers["go"]; !ok {
+		t.Fatal("gopls should be configured by default")
+	}
+
+	err := h.LoadString(`
+		vb.lsp.setup({
+			go = { cmd = "gopls", args = { "-remote=auto" }, root_markers = { "go.work", "go.mod" } },
+			python = false,
+			zig = { cmd = { "zls", "--enable-debug-log" } },
+		})
+	`)
+	if err != nil {
+		t.Fatalf("LoadString failed: %v", err)
+	}
+
+	gopls := h.config.LSPServers["go"]
+	if gopls.Command != "gopls" || len(gopls.Args) != 1 || gopls.Args[0] != "-remote=auto" || len(gopls.RootMarkers) != 2 {
+		t.Errorf("go = %+v", gopls)
+	}
+	if _, ok := h.config.LSPServers["python"]; ok {
+		t.Error("python = false should remove the server")
+	}
+	if zls := h.config.LSPServers["zig"]; zls.Command != "zls" || len(zls.Args) != 1 {
+		t.Errorf("zig = %+v", zls)
+	}
+
+	if err := h.LoadString(`vb.lsp.setup({ go = { args = {} } })`); err == nil {
+		t.Error("a server without cmd should be an error")
+	}
+}
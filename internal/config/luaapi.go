@@ -52,8 +52,8 @@ func (l *Loader) SetupVbAPI() {
 	// Register event system API
 	RegisterEventAPI(l.L)
 
-	// Register LSP API
-	RegisterLSPAPI(l.L)
+	// vb.lsp and the global lsp (language server clients)
+	l.setupLSPTable(vbTable)
 
 	// vb.plugins (for legacy compatibility)
 	pluginsTable := l.L.NewTable()
@@ -136,6 +136,20 @@ func (l *Loader) getOption(key string) lua.LValue {
 		return lua.LBool(opts.Wrap)
 	case "scrolloff":
 		return lua.LNumber(opts.ScrollOff)
+	case "undofile":
+		return lua.LBool(opts.UndoFile)
+	case "foldmethod":
+		return lua.LString(opts.FoldMethod)
+	case "autoview":
+		return lua.LBool(opts.AutoView)
+	case "lsp":
+		return lua.LBool(opts.LSP)
+	case "formatonsave":
+		return lua.LBool(opts.FormatOnSave)
+	case "semantictokens":
+		return lua.LBool(opts.SemanticTokens)
+	case "inlayhints":
+		return lua.LBool(opts.InlayHints)
 	case "leader":
 		return lua.LString(opts.Leader)
 	case "statusline":
@@ -180,6 +194,35 @@ func (l *Loader) setOption(key string, value lua.LValue) {
 		if num, ok := value.(lua.LNumber); ok {
 			opts.ScrollOff = int(num)
 		}
+	case "undofile":
+		if b, ok := value.(lua.LBool); ok {
+			opts.UndoFile = bool(b)
+		}
+	case "autoview":
+		if b, ok := value.(lua.LBool); ok {
+			opts.AutoView = bool(b)
+		}
+	case "lsp":
+		if b, ok := value.(lua.LBool); ok {
+			opts.LSP = bool(b)
+		}
+	case "formatonsave":
+		if b, ok := value.(lua.LBool); ok {
+			opts.FormatOnSave = bool(b)
+		}
+	case "semantictokens":
+		if b, ok := value.(lua.LBool); ok {
+			opts.SemanticTokens = bool(b)
+		}
+	case "inlayhints":
+		if b, ok := value.(lua.LBool); ok {
+			opts.InlayHints = bool(b)
+		}
+	case "foldmethod":
+		switch str, _ := value.(lua.LString); str {
+		case "syntax", "indent", "marker", "manual":
+			opts.FoldMethod = string(str)
+		}
 	case "leader":
 		if str, ok := value.(lua.LString); ok {
 			opts.Leader = string(str)
@@ -894,14 +937,39 @@ func (l *Loader) luaCheckHealth(L *lua.LState) int {
 func (l *Loader) luaSchedule(L *lua.LState) int {
 	fn := L.CheckFunction(1)
 
-	// Store the function in the scheduled queue (thread-safe)
-	l.config.scheduleMu.Lock()
-	l.config.scheduledFns = append(l.config.scheduledFns, fn)
-	l.config.scheduleMu.Unlock()
+	l.config.Schedule(func(L *lua.LState) {
+		L.Push(fn)
+
+		// Call with protection (no error handler - errors will be printed)
+		if err := L.PCall(0, 0, nil); err != nil {
+			fmt.Printf("Error executing scheduled function: %v\n", err)
+		}
+	})
 
 	return 0
 }
 
+// Schedule queues fn to run on the editor's main loop with the Lua state.
+// It is safe to call from any goroutine.
+func (c *Config) Schedule(fn func(L *lua.LState)) {
+	c.scheduleMu.Lock()
+	c.scheduledFns = append(c.scheduledFns, fn)
+	wake := c.wake
+	c.scheduleMu.Unlock()
+
+	if wake != nil {
+		wake()
+	}
+}
+
+// SetWakeFunc sets the function Schedule calls to wake up the editor's main
+// loop so scheduled functions run without waiting for input.
+func (l *Loader) SetWakeFunc(fn func()) {
+	l.config.scheduleMu.Lock()
+	l.config.wake = fn
+	l.config.scheduleMu.Unlock()
+}
+
 // SafeCallLuaFunction safely calls a Lua function with error handling
 // Returns (success bool, error string)
 func (l *Loader) SafeCallLuaFunction(fn *lua.LFunction, args ...lua.LValue) (bool, string) {
@@ -941,13 +1009,7 @@ func (c *Config) ProcessScheduledFunctions(L *lua.LState) {
 		return
 	}
 
-	// Execute each function safely
 	for _, fn := range fns {
-		L.Push(fn)
-
-		// Call with protection (no error handler - errors will be printed)
-		if err := L.PCall(0, 0, nil); err != nil {
-			fmt.Printf("Error executing scheduled function: %v\n", err)
-		}
+		fn(L)
 	}
 }
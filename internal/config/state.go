@@ -29,6 +29,11 @@ func GetStatePath() string {
 	return filepath.Join(stateDir, "state.json")
 }
 
+// GetUndoDir returns the directory holding persistent undo files, next to state.json
+func GetUndoDir() string {
+	return filepath.Join(filepath.Dir(GetStatePath()), "undo")
+}
+
 // Load loads state from disk with corruption recovery
 func (s *State) Load() error {
 	s.mu.Lock()
@@ -65,6 +65,20 @@ func (h *TestHarness) GetOption(name string) interface{} {
 		return h.config.Options.RelativeNumber
 	case "leader":
 		return h.config.Options.Leader
+	case "undofile":
+		return h.config.Options.UndoFile
+	case "foldmethod":
+		return h.config.Options.FoldMethod
+	case "autoview":
+		return h.config.Options.AutoView
+	case "lsp":
+		return h.config.Options.LSP
+	case "formatonsave":
+		return h.config.Options.FormatOnSave
+	case "semantictokens":
+		return h.config.Options.SemanticTokens
+	case "inlayhints":
+		return h.config.Options.InlayHints
 	default:
 		return nil
 	}
@@ -199,6 +213,13 @@ func TestHarness_Options(t *testing.T) {
 		vb.opt.expandtab = true
 		vb.opt.number = false
 		vb.opt.leader = ","
+		vb.opt.undofile = true
+		vb.opt.foldmethod = "marker"
+		vb.opt.autoview = true
+		vb.opt.lsp = false
+		vb.opt.formatonsave = true
+		vb.opt.semantictokens = false
+		vb.opt.inlayhints = true
 	`)
 	if err != nil {
 		t.Fatalf("LoadString failed: %v", err)
@@ -208,6 +229,19 @@ func TestHarness_Options(t *testing.T) {
 	h.AssertOption(t, "expandtab", true)
 	h.AssertOption(t, "number", false)
 	h.AssertOption(t, "leader", ",")
+	h.AssertOption(t, "undofile", true)
+	h.AssertOption(t, "foldmethod", "marker")
+	h.AssertOption(t, "autoview", true)
+	h.AssertOption(t, "lsp", false)
+	h.AssertOption(t, "formatonsave", true)
+	h.AssertOption(t, "semantictokens", false)
+	h.AssertOption(t, "inlayhints", true)
+
+	// unknown fold methods are ignored
+	if err := h.LoadString(`vb.opt.foldmethod = "expr"`); err != nil {
+		t.Fatalf("LoadString failed: %v", err)
+	}
+	h.AssertOption(t, "foldmethod", "marker")
 }
 
 func TestHarness_Keymaps(t *testing.T) {
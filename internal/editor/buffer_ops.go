@@ -75,14 +75,7 @@ func (e *Editor) undo() {
 		e.statusMsg = "Already at oldest change"
 		return
 	}
-	pos := min(e.posFromCursor(), e.buffer.Len())
-	e.setCursorFromPos(pos)
-	e.wantX = e.cx
-	e.dirty = true
-	e.statusMsg = "undo"
-	e.clearPending()
-	e.reparseBuffer()
-	e.FireTextChanged()
+	e.afterUndoMove("undo")
 }
 
 func (e *Editor) redo() {
@@ -93,14 +86,7 @@ func (e *Editor) redo() {
 		e.statusMsg = "Already at newest change"
 		return
 	}
-	pos := min(e.posFromCursor(), e.buffer.Len())
-	e.setCursorFromPos(pos)
-	e.wantX = e.cx
-	e.dirty = true
-	e.statusMsg = "redo"
-	e.clearPending()
-	e.reparseBuffer()
-	e.FireTextChanged()
+	e.afterUndoMove("redo")
 }
 
 /* linewise */
@@ -108,18 +94,13 @@ func (e *Editor) deleteLines(n int) {
 	if n <= 0 {
 		return
 	}
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		return
-	}
-
 	startLine := e.cy
 	endLine := min(e.lineCount(), e.cy+n)
 
-	startPos := starts[startLine]
+	startPos := e.lineStartPos(startLine)
 	endPos := e.buffer.Len()
 	if endLine < e.lineCount() {
-		endPos = starts[endLine]
+		endPos = e.lineStartPos(endLine)
 	}
 
 	deleted, _ := e.buffer.Slice(startPos, endPos)
@@ -133,18 +114,13 @@ func (e *Editor) deleteLines(n int) {
 }
 
 func (e *Editor) yankLines(n int) {
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		return
-	}
-
 	startLine := e.cy
 	endLine := min(e.lineCount(), e.cy+n)
 
-	startPos := starts[startLine]
+	startPos := e.lineStartPos(startLine)
 	endPos := e.buffer.Len()
 	if endLine < e.lineCount() {
-		endPos = starts[endLine]
+		endPos = e.lineStartPos(endLine)
 	}
 
 	s, _ := e.buffer.Slice(startPos, endPos)
@@ -154,8 +130,7 @@ func (e *Editor) yankLines(n int) {
 
 /* bol/eol */
 func (e *Editor) deleteToBOL() {
-	starts := e.lineStarts()
-	lineStart := starts[e.cy]
+	lineStart := e.lineStartPos(e.cy)
 	pos := e.posFromCursor()
 	if pos > lineStart {
 		deleted, _ := e.buffer.Slice(lineStart, pos)
@@ -169,8 +144,7 @@ func (e *Editor) deleteToBOL() {
 }
 
 func (e *Editor) deleteToEOL() {
-	starts := e.lineStarts()
-	lineStart := starts[e.cy]
+	lineStart := e.lineStartPos(e.cy)
 	pos := e.posFromCursor()
 	eol := lineStart + e.lineLen(e.cy)
 	if pos < eol {
@@ -184,8 +158,7 @@ func (e *Editor) deleteToEOL() {
 }
 
 func (e *Editor) yankToBOL() {
-	starts := e.lineStarts()
-	lineStart := starts[e.cy]
+	lineStart := e.lineStartPos(e.cy)
 	pos := e.posFromCursor()
 	s, _ := e.buffer.Slice(lineStart, pos)
 	e.writeYank(Register{kind: RegCharwise, text: s})
@@ -193,8 +166,7 @@ func (e *Editor) yankToBOL() {
 }
 
 func (e *Editor) yankToEOL() {
-	starts := e.lineStarts()
-	lineStart := starts[e.cy]
+	lineStart := e.lineStartPos(e.cy)
 	pos := e.posFromCursor()
 	eol := lineStart + e.lineLen(e.cy)
 	s, _ := e.buffer.Slice(pos, eol)
@@ -28,15 +28,34 @@ type BufferView struct {
 	jumpListIndex int
 
 	// tree-sitter parser for syntax highlighting
-	parser *TreeSitterParser
+	parser            *TreeSitterParser
+	unsubscribeParser func()
 
 	// fold ranges for code folding
 	foldRanges map[int]*FoldRange
+	foldMethod string // fold method foldRanges were computed with
+	foldsStale bool   // text changed since foldRanges were computed
+	foldsDirty span   // lines changed since then
+
+	// changes made to the buffer, and how many language servers have seen
+	changeTick   int
+	lspTick      int // changeTick when the changes were last sent
+	lspScheduled int // changeTick when a sync was last scheduled
+
+	// diagnostics published by language servers, sorted by start
+	diagnostics []diagnostic
+
+	// semantic tokens and inlay hints from language servers, sorted by
+	// position, and the requests they came from
+	semanticTokens []semanticToken
+	inlayHints     []inlayHint
+	tokensReq      decorationRequest
+	hintsReq       decorationRequest
 }
 
 // NewBufferView creates a new buffer view from content and filename
 func NewBufferView(content, filename string) *BufferView {
-	return &BufferView{
+	bv := &BufferView{
 		buffer:        buffer.NewFromString(content),
 		filename:      filename,
 		dirty:         false,
@@ -45,4 +64,21 @@ func NewBufferView(content, filename string) *BufferView {
 		jumpListIndex: -1,
 		foldRanges:    make(map[int]*FoldRange),
 	}
+	bv.buffer.Subscribe(bv.shiftFolds)
+	bv.buffer.Subscribe(bv.countChange)
+	bv.buffer.Subscribe(bv.shiftDiagnostics)
+	bv.buffer.Subscribe(bv.shiftDecorations)
+	return bv
+}
+
+// closeParser stops feeding edits to the parser and releases it
+func (bv *BufferView) closeParser() {
+	if bv.unsubscribeParser != nil {
+		bv.unsubscribeParser()
+		bv.unsubscribeParser = nil
+	}
+	if bv.parser != nil {
+		bv.parser.Close()
+		bv.parser = nil
+	}
 }
@@ -52,6 +52,7 @@ func (e *Editor) openFile(path string) {
 
 	// Create new buffer
 	bufView := NewBufferView(txt, abs)
+	e.loadUndoFile(bufView)
 	e.FireBufLeave()
 	e.syncToBuffer() // save current buffer state first
 	e.buffers = append(e.buffers, bufView)
@@ -88,6 +89,8 @@ func (e *Editor) openFile(path string) {
 		}
 		bv.foldRanges = e.foldRanges
 	}
+	e.autoLoadView()
+	e.lspBufferOpened()
 
 	// Fire FileType event
 	if ft := e.getFiletype(); ft != nil {
@@ -195,6 +198,8 @@ func (e *Editor) deleteBuffer() {
 
 	// Fire BufDelete event
 	e.FireBufDelete(e.currentBuffer)
+	e.autoWriteView()
+	e.lspBufferClosed(e.buf())
 
 	// Remove current buffer
 	e.buffers = append(e.buffers[:e.currentBuffer], e.buffers[e.currentBuffer+1:]...)
@@ -220,6 +225,8 @@ func (e *Editor) deleteBufferForce() {
 
 	// Fire BufDelete event
 	e.FireBufDelete(e.currentBuffer)
+	e.autoWriteView()
+	e.lspBufferClosed(e.buf())
 
 	// Remove current buffer
 	e.buffers = append(e.buffers[:e.currentBuffer], e.buffers[e.currentBuffer+1:]...)
@@ -39,241 +39,279 @@ type ColorScheme struct {
 	TreeCursor    tcell.Color
 	TreeCursorBg  tcell.Color
 	TreeBorder    tcell.Color
+
+	// Diagnostics
+	DiagnosticError tcell.Color
+	DiagnosticWarn  tcell.Color
+	DiagnosticInfo  tcell.Color
+	DiagnosticHint  tcell.Color
 }
 
 // Built-in color schemes
 var colorSchemes = map[string]*ColorScheme{
 	"default": {
-		Name:          "default",
-		Background:    tcell.ColorBlack,
-		Foreground:    tcell.ColorWhite,
-		LineNumber:    tcell.ColorYellow,
-		StatusLine:    tcell.ColorWhite,
-		StatusLineBg:  tcell.ColorBlue,
-		Visual:        tcell.ColorWhite,
-		VisualBg:      tcell.ColorBlue,
-		Search:        tcell.ColorBlack,
-		SearchBg:      tcell.ColorYellow,
-		Cursor:        tcell.ColorWhite,
-		CursorBg:      tcell.ColorBlack,
-		Keyword:       tcell.ColorPurple,
-		Function:      tcell.ColorBlue,
-		Type:          tcell.NewRGBColor(0, 200, 200), // Cyan
-		String:        tcell.ColorGreen,
-		Number:        tcell.NewRGBColor(255, 165, 0), // Orange
-		Comment:       tcell.ColorGray,
-		Constant:      tcell.ColorRed,
-		Property:      tcell.NewRGBColor(173, 216, 230), // Light blue
-		Operator:      tcell.ColorWhite,
-		Variable:      tcell.ColorWhite,
-		TreeDirectory: tcell.ColorGreen,
-		TreeFile:      tcell.ColorWhite,
-		TreeCursor:    tcell.ColorWhite,
-		TreeCursorBg:  tcell.ColorDarkGreen,
-		TreeBorder:    tcell.ColorGray,
+		Name:            "default",
+		Background:      tcell.ColorBlack,
+		Foreground:      tcell.ColorWhite,
+		LineNumber:      tcell.ColorYellow,
+		StatusLine:      tcell.ColorWhite,
+		StatusLineBg:    tcell.ColorBlue,
+		Visual:          tcell.ColorWhite,
+		VisualBg:        tcell.ColorBlue,
+		Search:          tcell.ColorBlack,
+		SearchBg:        tcell.ColorYellow,
+		Cursor:          tcell.ColorWhite,
+		CursorBg:        tcell.ColorBlack,
+		Keyword:         tcell.ColorPurple,
+		Function:        tcell.ColorBlue,
+		Type:            tcell.NewRGBColor(0, 200, 200), // Cyan
+		String:          tcell.ColorGreen,
+		Number:          tcell.NewRGBColor(255, 165, 0), // Orange
+		Comment:         tcell.ColorGray,
+		Constant:        tcell.ColorRed,
+		Property:        tcell.NewRGBColor(173, 216, 230), // Light blue
+		Operator:        tcell.ColorWhite,
+		Variable:        tcell.ColorWhite,
+		TreeDirectory:   tcell.ColorGreen,
+		TreeFile:        tcell.ColorWhite,
+		TreeCursor:      tcell.ColorWhite,
+		TreeCursorBg:    tcell.ColorDarkGreen,
+		TreeBorder:      tcell.ColorGray,
+		DiagnosticError: tcell.ColorRed,
+		DiagnosticWarn:  tcell.ColorYellow,
+		DiagnosticInfo:  tcell.ColorBlue,
+		DiagnosticHint:  tcell.ColorGray,
 	},
 	"monokai": {
-		Name:          "monokai",
-		Background:    tcell.NewRGBColor(39, 40, 34),
-		Foreground:    tcell.NewRGBColor(248, 248, 242),
-		LineNumber:    tcell.NewRGBColor(144, 144, 144),
-		StatusLine:    tcell.NewRGBColor(248, 248, 242),
-		StatusLineBg:  tcell.NewRGBColor(39, 40, 34),
-		Visual:        tcell.NewRGBColor(248, 248, 242),
-		VisualBg:      tcell.NewRGBColor(73, 72, 62),
-		Search:        tcell.NewRGBColor(0, 0, 0),
-		SearchBg:      tcell.NewRGBColor(255, 255, 0),
-		Cursor:        tcell.NewRGBColor(248, 248, 242),
-		CursorBg:      tcell.NewRGBColor(249, 38, 114),
-		Keyword:       tcell.NewRGBColor(249, 38, 114),
-		Function:      tcell.NewRGBColor(166, 226, 46),
-		Type:          tcell.NewRGBColor(102, 217, 239),
-		String:        tcell.NewRGBColor(230, 219, 116),
-		Number:        tcell.NewRGBColor(174, 129, 255),
-		Comment:       tcell.NewRGBColor(117, 113, 94),
-		Constant:      tcell.NewRGBColor(174, 129, 255),
-		Property:      tcell.NewRGBColor(166, 226, 46),
-		Operator:      tcell.NewRGBColor(249, 38, 114),
-		Variable:      tcell.NewRGBColor(248, 248, 242),
-		TreeDirectory: tcell.NewRGBColor(166, 226, 46),
-		TreeFile:      tcell.NewRGBColor(248, 248, 242),
-		TreeCursor:    tcell.NewRGBColor(248, 248, 242),
-		TreeCursorBg:  tcell.NewRGBColor(73, 72, 62),
-		TreeBorder:    tcell.NewRGBColor(117, 113, 94),
+		Name:            "monokai",
+		Background:      tcell.NewRGBColor(39, 40, 34),
+		Foreground:      tcell.NewRGBColor(248, 248, 242),
+		LineNumber:      tcell.NewRGBColor(144, 144, 144),
+		StatusLine:      tcell.NewRGBColor(248, 248, 242),
+		StatusLineBg:    tcell.NewRGBColor(39, 40, 34),
+		Visual:          tcell.NewRGBColor(248, 248, 242),
+		VisualBg:        tcell.NewRGBColor(73, 72, 62),
+		Search:          tcell.NewRGBColor(0, 0, 0),
+		SearchBg:        tcell.NewRGBColor(255, 255, 0),
+		Cursor:          tcell.NewRGBColor(248, 248, 242),
+		CursorBg:        tcell.NewRGBColor(249, 38, 114),
+		Keyword:         tcell.NewRGBColor(249, 38, 114),
+		Function:        tcell.NewRGBColor(166, 226, 46),
+		Type:            tcell.NewRGBColor(102, 217, 239),
+		String:          tcell.NewRGBColor(230, 219, 116),
+		Number:          tcell.NewRGBColor(174, 129, 255),
+		Comment:         tcell.NewRGBColor(117, 113, 94),
+		Constant:        tcell.NewRGBColor(174, 129, 255),
+		Property:        tcell.NewRGBColor(166, 226, 46),
+		Operator:        tcell.NewRGBColor(249, 38, 114),
+		Variable:        tcell.NewRGBColor(248, 248, 242),
+		TreeDirectory:   tcell.NewRGBColor(166, 226, 46),
+		TreeFile:        tcell.NewRGBColor(248, 248, 242),
+		TreeCursor:      tcell.NewRGBColor(248, 248, 242),
+		TreeCursorBg:    tcell.NewRGBColor(73, 72, 62),
+		TreeBorder:      tcell.NewRGBColor(117, 113, 94),
+		DiagnosticError: tcell.NewRGBColor(249, 38, 114),
+		DiagnosticWarn:  tcell.NewRGBColor(230, 219, 116),
+		DiagnosticInfo:  tcell.NewRGBColor(102, 217, 239),
+		DiagnosticHint:  tcell.NewRGBColor(117, 113, 94),
 	},
 	"gruvbox": {
-		Name:          "gruvbox",
-		Background:    tcell.NewRGBColor(40, 40, 40),
-		Foreground:    tcell.NewRGBColor(235, 219, 178),
-		LineNumber:    tcell.NewRGBColor(124, 111, 100),
-		StatusLine:    tcell.NewRGBColor(235, 219, 178),
-		StatusLineBg:  tcell.NewRGBColor(60, 56, 54),
-		Visual:        tcell.NewRGBColor(235, 219, 178),
-		VisualBg:      tcell.NewRGBColor(102, 92, 84),
-		Search:        tcell.NewRGBColor(40, 40, 40),
-		SearchBg:      tcell.NewRGBColor(250, 189, 47),
-		Cursor:        tcell.NewRGBColor(40, 40, 40),
-		CursorBg:      tcell.NewRGBColor(235, 219, 178),
-		Keyword:       tcell.NewRGBColor(251, 73, 52),
-		Function:      tcell.NewRGBColor(184, 187, 38),
-		Type:          tcell.NewRGBColor(250, 189, 47),
-		String:        tcell.NewRGBColor(184, 187, 38),
-		Number:        tcell.NewRGBColor(211, 134, 155),
-		Comment:       tcell.NewRGBColor(146, 131, 116),
-		Constant:      tcell.NewRGBColor(211, 134, 155),
-		Property:      tcell.NewRGBColor(142, 192, 124),
-		Operator:      tcell.NewRGBColor(235, 219, 178),
-		Variable:      tcell.NewRGBColor(131, 165, 152),
-		TreeDirectory: tcell.NewRGBColor(142, 192, 124),
-		TreeFile:      tcell.NewRGBColor(235, 219, 178),
-		TreeCursor:    tcell.NewRGBColor(235, 219, 178),
-		TreeCursorBg:  tcell.NewRGBColor(102, 92, 84),
-		TreeBorder:    tcell.NewRGBColor(124, 111, 100),
+		Name:            "gruvbox",
+		Background:      tcell.NewRGBColor(40, 40, 40),
+		Foreground:      tcell.NewRGBColor(235, 219, 178),
+		LineNumber:      tcell.NewRGBColor(124, 111, 100),
+		StatusLine:      tcell.NewRGBColor(235, 219, 178),
+		StatusLineBg:    tcell.NewRGBColor(60, 56, 54),
+		Visual:          tcell.NewRGBColor(235, 219, 178),
+		VisualBg:        tcell.NewRGBColor(102, 92, 84),
+		Search:          tcell.NewRGBColor(40, 40, 40),
+		SearchBg:        tcell.NewRGBColor(250, 189, 47),
+		Cursor:          tcell.NewRGBColor(40, 40, 40),
+		CursorBg:        tcell.NewRGBColor(235, 219, 178),
+		Keyword:         tcell.NewRGBColor(251, 73, 52),
+		Function:        tcell.NewRGBColor(184, 187, 38),
+		Type:            tcell.NewRGBColor(250, 189, 47),
+		String:          tcell.NewRGBColor(184, 187, 38),
+		Number:          tcell.NewRGBColor(211, 134, 155),
+		Comment:         tcell.NewRGBColor(146, 131, 116),
+		Constant:        tcell.NewRGBColor(211, 134, 155),
+		Property:        tcell.NewRGBColor(142, 192, 124),
+		Operator:        tcell.NewRGBColor(235, 219, 178),
+		Variable:        tcell.NewRGBColor(131, 165, 152),
+		TreeDirectory:   tcell.NewRGBColor(142, 192, 124),
+		TreeFile:        tcell.NewRGBColor(235, 219, 178),
+		TreeCursor:      tcell.NewRGBColor(235, 219, 178),
+		TreeCursorBg:    tcell.NewRGBColor(102, 92, 84),
+		TreeBorder:      tcell.NewRGBColor(124, 111, 100),
+		DiagnosticError: tcell.NewRGBColor(251, 73, 52),
+		DiagnosticWarn:  tcell.NewRGBColor(250, 189, 47),
+		DiagnosticInfo:  tcell.NewRGBColor(131, 165, 152),
+		DiagnosticHint:  tcell.NewRGBColor(142, 192, 124),
 	},
 	"solarized-dark": {
-		Name:          "solarized-dark",
-		Background:    tcell.NewRGBColor(0, 43, 54),
-		Foreground:    tcell.NewRGBColor(131, 148, 150),
-		LineNumber:    tcell.NewRGBColor(88, 110, 117),
-		StatusLine:    tcell.NewRGBColor(131, 148, 150),
-		StatusLineBg:  tcell.NewRGBColor(7, 54, 66),
-		Visual:        tcell.NewRGBColor(131, 148, 150),
-		VisualBg:      tcell.NewRGBColor(7, 54, 66),
-		Search:        tcell.NewRGBColor(0, 0, 0),
-		SearchBg:      tcell.NewRGBColor(181, 137, 0),
-		Cursor:        tcell.NewRGBColor(0, 43, 54),
-		CursorBg:      tcell.NewRGBColor(131, 148, 150),
-		Keyword:       tcell.NewRGBColor(133, 153, 0),
-		Function:      tcell.NewRGBColor(38, 139, 210),
-		Type:          tcell.NewRGBColor(181, 137, 0),
-		String:        tcell.NewRGBColor(42, 161, 152),
-		Number:        tcell.NewRGBColor(203, 75, 22),
-		Comment:       tcell.NewRGBColor(88, 110, 117),
-		Constant:      tcell.NewRGBColor(203, 75, 22),
-		Property:      tcell.NewRGBColor(42, 161, 152),
-		Operator:      tcell.NewRGBColor(131, 148, 150),
-		Variable:      tcell.NewRGBColor(38, 139, 210),
-		TreeDirectory: tcell.NewRGBColor(38, 139, 210),
-		TreeFile:      tcell.NewRGBColor(131, 148, 150),
-		TreeCursor:    tcell.NewRGBColor(131, 148, 150),
-		TreeCursorBg:  tcell.NewRGBColor(7, 54, 66),
-		TreeBorder:    tcell.NewRGBColor(88, 110, 117),
+		Name:            "solarized-dark",
+		Background:      tcell.NewRGBColor(0, 43, 54),
+		Foreground:      tcell.NewRGBColor(131, 148, 150),
+		LineNumber:      tcell.NewRGBColor(88, 110, 117),
+		StatusLine:      tcell.NewRGBColor(131, 148, 150),
+		StatusLineBg:    tcell.NewRGBColor(7, 54, 66),
+		Visual:          tcell.NewRGBColor(131, 148, 150),
+		VisualBg:        tcell.NewRGBColor(7, 54, 66),
+		Search:          tcell.NewRGBColor(0, 0, 0),
+		SearchBg:        tcell.NewRGBColor(181, 137, 0),
+		Cursor:          tcell.NewRGBColor(0, 43, 54),
+		CursorBg:        tcell.NewRGBColor(131, 148, 150),
+		Keyword:         tcell.NewRGBColor(133, 153, 0),
+		Function:        tcell.NewRGBColor(38, 139, 210),
+		Type:            tcell.NewRGBColor(181, 137, 0),
+		String:          tcell.NewRGBColor(42, 161, 152),
+		Number:          tcell.NewRGBColor(203, 75, 22),
+		Comment:         tcell.NewRGBColor(88, 110, 117),
+		Constant:        tcell.NewRGBColor(203, 75, 22),
+		Property:        tcell.NewRGBColor(42, 161, 152),
+		Operator:        tcell.NewRGBColor(131, 148, 150),
+		Variable:        tcell.NewRGBColor(38, 139, 210),
+		TreeDirectory:   tcell.NewRGBColor(38, 139, 210),
+		TreeFile:        tcell.NewRGBColor(131, 148, 150),
+		TreeCursor:      tcell.NewRGBColor(131, 148, 150),
+		TreeCursorBg:    tcell.NewRGBColor(7, 54, 66),
+		TreeBorder:      tcell.NewRGBColor(88, 110, 117),
+		DiagnosticError: tcell.NewRGBColor(220, 50, 47),
+		DiagnosticWarn:  tcell.NewRGBColor(181, 137, 0),
+		DiagnosticInfo:  tcell.NewRGBColor(38, 139, 210),
+		DiagnosticHint:  tcell.NewRGBColor(42, 161, 152),
 	},
 	"dracula": {
-		Name:          "dracula",
-		Background:    tcell.NewRGBColor(40, 42, 54),
-		Foreground:    tcell.NewRGBColor(248, 248, 242),
-		LineNumber:    tcell.NewRGBColor(98, 114, 164),
-		StatusLine:    tcell.NewRGBColor(248, 248, 242),
-		StatusLineBg:  tcell.NewRGBColor(68, 71, 90),
-		Visual:        tcell.NewRGBColor(248, 248, 242),
-		VisualBg:      tcell.NewRGBColor(68, 71, 90),
-		Search:        tcell.NewRGBColor(40, 42, 54),
-		SearchBg:      tcell.NewRGBColor(241, 250, 140),
-		Cursor:        tcell.NewRGBColor(40, 42, 54),
-		CursorBg:      tcell.NewRGBColor(248, 248, 242),
-		Keyword:       tcell.NewRGBColor(255, 121, 198),
-		Function:      tcell.NewRGBColor(80, 250, 123),
-		Type:          tcell.NewRGBColor(139, 233, 253),
-		String:        tcell.NewRGBColor(241, 250, 140),
-		Number:        tcell.NewRGBColor(189, 147, 249),
-		Comment:       tcell.NewRGBColor(98, 114, 164),
-		Constant:      tcell.NewRGBColor(189, 147, 249),
-		Property:      tcell.NewRGBColor(80, 250, 123),
-		Operator:      tcell.NewRGBColor(255, 121, 198),
-		Variable:      tcell.NewRGBColor(248, 248, 242),
-		TreeDirectory: tcell.NewRGBColor(139, 233, 253),
-		TreeFile:      tcell.NewRGBColor(248, 248, 242),
-		TreeCursor:    tcell.NewRGBColor(248, 248, 242),
-		TreeCursorBg:  tcell.NewRGBColor(68, 71, 90),
-		TreeBorder:    tcell.NewRGBColor(98, 114, 164),
+		Name:            "dracula",
+		Background:      tcell.NewRGBColor(40, 42, 54),
+		Foreground:      tcell.NewRGBColor(248, 248, 242),
+		LineNumber:      tcell.NewRGBColor(98, 114, 164),
+		StatusLine:      tcell.NewRGBColor(248, 248, 242),
+		StatusLineBg:    tcell.NewRGBColor(68, 71, 90),
+		Visual:          tcell.NewRGBColor(248, 248, 242),
+		VisualBg:        tcell.NewRGBColor(68, 71, 90),
+		Search:          tcell.NewRGBColor(40, 42, 54),
+		SearchBg:        tcell.NewRGBColor(241, 250, 140),
+		Cursor:          tcell.NewRGBColor(40, 42, 54),
+		CursorBg:        tcell.NewRGBColor(248, 248, 242),
+		Keyword:         tcell.NewRGBColor(255, 121, 198),
+		Function:        tcell.NewRGBColor(80, 250, 123),
+		Type:            tcell.NewRGBColor(139, 233, 253),
+		String:          tcell.NewRGBColor(241, 250, 140),
+		Number:          tcell.NewRGBColor(189, 147, 249),
+		Comment:         tcell.NewRGBColor(98, 114, 164),
+		Constant:        tcell.NewRGBColor(189, 147, 249),
+		Property:        tcell.NewRGBColor(80, 250, 123),
+		Operator:        tcell.NewRGBColor(255, 121, 198),
+		Variable:        tcell.NewRGBColor(248, 248, 242),
+		TreeDirectory:   tcell.NewRGBColor(139, 233, 253),
+		TreeFile:        tcell.NewRGBColor(248, 248, 242),
+		TreeCursor:      tcell.NewRGBColor(248, 248, 242),
+		TreeCursorBg:    tcell.NewRGBColor(68, 71, 90),
+		TreeBorder:      tcell.NewRGBColor(98, 114, 164),
+		DiagnosticError: tcell.NewRGBColor(255, 85, 85),
+		DiagnosticWarn:  tcell.NewRGBColor(255, 184, 108),
+		DiagnosticInfo:  tcell.NewRGBColor(139, 233, 253),
+		DiagnosticHint:  tcell.NewRGBColor(98, 114, 164),
 	},
 	"rose-pine": {
-		Name:          "rose-pine",
-		Background:    tcell.NewRGBColor(25, 23, 36),    // base
-		Foreground:    tcell.NewRGBColor(224, 222, 244), // text
-		LineNumber:    tcell.NewRGBColor(110, 106, 134), // muted
-		StatusLine:    tcell.NewRGBColor(224, 222, 244), // text
-		StatusLineBg:  tcell.NewRGBColor(35, 33, 54),    // surface
-		Visual:        tcell.NewRGBColor(224, 222, 244), // text
-		VisualBg:      tcell.NewRGBColor(42, 39, 63),    // highlight med
-		Search:        tcell.NewRGBColor(25, 23, 36),    // base
-		SearchBg:      tcell.NewRGBColor(246, 193, 119), // gold
-		Cursor:        tcell.NewRGBColor(25, 23, 36),    // base
-		CursorBg:      tcell.NewRGBColor(235, 188, 186), // rose
-		Keyword:       tcell.NewRGBColor(234, 154, 151), // love
-		Function:      tcell.NewRGBColor(156, 207, 216), // foam
-		Type:          tcell.NewRGBColor(249, 226, 175), // gold
-		String:        tcell.NewRGBColor(246, 193, 119), // gold
-		Number:        tcell.NewRGBColor(235, 188, 186), // rose
-		Comment:       tcell.NewRGBColor(110, 106, 134), // muted
-		Constant:      tcell.NewRGBColor(235, 111, 146), // love
-		Property:      tcell.NewRGBColor(156, 207, 216), // foam
-		Operator:      tcell.NewRGBColor(144, 140, 170), // subtle
-		Variable:      tcell.NewRGBColor(224, 222, 244), // text
-		TreeDirectory: tcell.NewRGBColor(156, 207, 216), // foam
-		TreeFile:      tcell.NewRGBColor(224, 222, 244), // text
-		TreeCursor:    tcell.NewRGBColor(224, 222, 244), // text
-		TreeCursorBg:  tcell.NewRGBColor(42, 39, 63),    // highlight med
-		TreeBorder:    tcell.NewRGBColor(110, 106, 134), // muted
+		Name:            "rose-pine",
+		Background:      tcell.NewRGBColor(25, 23, 36),    // base
+		Foreground:      tcell.NewRGBColor(224, 222, 244), // text
+		LineNumber:      tcell.NewRGBColor(110, 106, 134), // muted
+		StatusLine:      tcell.NewRGBColor(224, 222, 244), // text
+		StatusLineBg:    tcell.NewRGBColor(35, 33, 54),    // surface
+		Visual:          tcell.NewRGBColor(224, 222, 244), // text
+		VisualBg:        tcell.NewRGBColor(42, 39, 63),    // highlight med
+		Search:          tcell.NewRGBColor(25, 23, 36),    // base
+		SearchBg:        tcell.NewRGBColor(246, 193, 119), // gold
+		Cursor:          tcell.NewRGBColor(25, 23, 36),    // base
+		CursorBg:        tcell.NewRGBColor(235, 188, 186), // rose
+		Keyword:         tcell.NewRGBColor(234, 154, 151), // love
+		Function:        tcell.NewRGBColor(156, 207, 216), // foam
+		Type:            tcell.NewRGBColor(249, 226, 175), // gold
+		String:          tcell.NewRGBColor(246, 193, 119), // gold
+		Number:          tcell.NewRGBColor(235, 188, 186), // rose
+		Comment:         tcell.NewRGBColor(110, 106, 134), // muted
+		Constant:        tcell.NewRGBColor(235, 111, 146), // love
+		Property:        tcell.NewRGBColor(156, 207, 216), // foam
+		Operator:        tcell.NewRGBColor(144, 140, 170), // subtle
+		Variable:        tcell.NewRGBColor(224, 222, 244), // text
+		TreeDirectory:   tcell.NewRGBColor(156, 207, 216), // foam
+		TreeFile:        tcell.NewRGBColor(224, 222, 244), // text
+		TreeCursor:      tcell.NewRGBColor(224, 222, 244), // text
+		TreeCursorBg:    tcell.NewRGBColor(42, 39, 63),    // highlight med
+		TreeBorder:      tcell.NewRGBColor(110, 106, 134), // muted
+		DiagnosticError: tcell.NewRGBColor(235, 111, 146), // love
+		DiagnosticWarn:  tcell.NewRGBColor(246, 193, 119), // gold
+		DiagnosticInfo:  tcell.NewRGBColor(156, 207, 216), // foam
+		DiagnosticHint:  tcell.NewRGBColor(196, 167, 231), // iris
 	},
 	"rose-pine-moon": {
-		Name:          "rose-pine-moon",
-		Background:    tcell.NewRGBColor(35, 33, 54),    // base
-		Foreground:    tcell.NewRGBColor(224, 222, 244), // text
-		LineNumber:    tcell.NewRGBColor(110, 106, 134), // muted
-		StatusLine:    tcell.NewRGBColor(224, 222, 244), // text
-		StatusLineBg:  tcell.NewRGBColor(42, 39, 63),    // surface
-		Visual:        tcell.NewRGBColor(224, 222, 244), // text
-		VisualBg:      tcell.NewRGBColor(57, 53, 82),    // highlight med
-		Search:        tcell.NewRGBColor(35, 33, 54),    // base
-		SearchBg:      tcell.NewRGBColor(246, 193, 119), // gold
-		Cursor:        tcell.NewRGBColor(35, 33, 54),    // base
-		CursorBg:      tcell.NewRGBColor(235, 188, 186), // rose
-		Keyword:       tcell.NewRGBColor(234, 154, 151), // love
-		Function:      tcell.NewRGBColor(156, 207, 216), // foam
-		Type:          tcell.NewRGBColor(249, 226, 175), // gold
-		String:        tcell.NewRGBColor(246, 193, 119), // gold
-		Number:        tcell.NewRGBColor(235, 188, 186), // rose
-		Comment:       tcell.NewRGBColor(110, 106, 134), // muted
-		Constant:      tcell.NewRGBColor(235, 111, 146), // love
-		Property:      tcell.NewRGBColor(156, 207, 216), // foam
-		Operator:      tcell.NewRGBColor(144, 140, 170), // subtle
-		Variable:      tcell.NewRGBColor(224, 222, 244), // text
-		TreeDirectory: tcell.NewRGBColor(156, 207, 216), // foam
-		TreeFile:      tcell.NewRGBColor(224, 222, 244), // text
-		TreeCursor:    tcell.NewRGBColor(224, 222, 244), // text
-		TreeCursorBg:  tcell.NewRGBColor(57, 53, 82),    // highlight med
-		TreeBorder:    tcell.NewRGBColor(110, 106, 134), // muted
+		Name:            "rose-pine-moon",
+		Background:      tcell.NewRGBColor(35, 33, 54),    // base
+		Foreground:      tcell.NewRGBColor(224, 222, 244), // text
+		LineNumber:      tcell.NewRGBColor(110, 106, 134), // muted
+		StatusLine:      tcell.NewRGBColor(224, 222, 244), // text
+		StatusLineBg:    tcell.NewRGBColor(42, 39, 63),    // surface
+		Visual:          tcell.NewRGBColor(224, 222, 244), // text
+		VisualBg:        tcell.NewRGBColor(57, 53, 82),    // highlight med
+		Search:          tcell.NewRGBColor(35, 33, 54),    // base
+		SearchBg:        tcell.NewRGBColor(246, 193, 119), // gold
+		Cursor:          tcell.NewRGBColor(35, 33, 54),    // base
+		CursorBg:        tcell.NewRGBColor(235, 188, 186), // rose
+		Keyword:         tcell.NewRGBColor(234, 154, 151), // love
+		Function:        tcell.NewRGBColor(156, 207, 216), // foam
+		Type:            tcell.NewRGBColor(249, 226, 175), // gold
+		String:          tcell.NewRGBColor(246, 193, 119), // gold
+		Number:          tcell.NewRGBColor(235, 188, 186), // rose
+		Comment:         tcell.NewRGBColor(110, 106, 134), // muted
+		Constant:        tcell.NewRGBColor(235, 111, 146), // love
+		Property:        tcell.NewRGBColor(156, 207, 216), // foam
+		Operator:        tcell.NewRGBColor(144, 140, 170), // subtle
+		Variable:        tcell.NewRGBColor(224, 222, 244), // text
+		TreeDirectory:   tcell.NewRGBColor(156, 207, 216), // foam
+		TreeFile:        tcell.NewRGBColor(224, 222, 244), // text
+		TreeCursor:      tcell.NewRGBColor(224, 222, 244), // text
+		TreeCursorBg:    tcell.NewRGBColor(57, 53, 82),    // highlight med
+		TreeBorder:      tcell.NewRGBColor(110, 106, 134), // muted
+		DiagnosticError: tcell.NewRGBColor(235, 111, 146), // love
+		DiagnosticWarn:  tcell.NewRGBColor(246, 193, 119), // gold
+		DiagnosticInfo:  tcell.NewRGBColor(156, 207, 216), // foam
+		DiagnosticHint:  tcell.NewRGBColor(196, 167, 231), // iris
 	},
 	"rose-pine-dawn": {
-		Name:          "rose-pine-dawn",
-		Background:    tcell.NewRGBColor(250, 244, 237), // base
-		Foreground:    tcell.NewRGBColor(87, 82, 121),   // text
-		LineNumber:    tcell.NewRGBColor(152, 147, 165), // muted
-		StatusLine:    tcell.NewRGBColor(87, 82, 121),   // text
-		StatusLineBg:  tcell.NewRGBColor(255, 250, 243), // surface
-		Visual:        tcell.NewRGBColor(87, 82, 121),   // text
-		VisualBg:      tcell.NewRGBColor(242, 233, 222), // highlight med
-		Search:        tcell.NewRGBColor(250, 244, 237), // base
-		SearchBg:      tcell.NewRGBColor(234, 157, 52),  // gold
-		Cursor:        tcell.NewRGBColor(250, 244, 237), // base
-		CursorBg:      tcell.NewRGBColor(215, 130, 126), // rose
-		Keyword:       tcell.NewRGBColor(180, 99, 122),  // love
-		Function:      tcell.NewRGBColor(86, 148, 159),  // foam
-		Type:          tcell.NewRGBColor(234, 157, 52),  // gold
-		String:        tcell.NewRGBColor(234, 157, 52),  // gold
-		Number:        tcell.NewRGBColor(215, 130, 126), // rose
-		Comment:       tcell.NewRGBColor(152, 147, 165), // muted
-		Constant:      tcell.NewRGBColor(180, 99, 122),  // love
-		Property:      tcell.NewRGBColor(86, 148, 159),  // foam
-		Operator:      tcell.NewRGBColor(121, 117, 147), // subtle
-		Variable:      tcell.NewRGBColor(87, 82, 121),   // text
-		TreeDirectory: tcell.NewRGBColor(86, 148, 159),  // foam
-		TreeFile:      tcell.NewRGBColor(87, 82, 121),   // text
-		TreeCursor:    tcell.NewRGBColor(87, 82, 121),   // text
-		TreeCursorBg:  tcell.NewRGBColor(242, 233, 222), // highlight med
-		TreeBorder:    tcell.NewRGBColor(152, 147, 165), // muted
+		Name:            "rose-pine-dawn",
+		Background:      tcell.NewRGBColor(250, 244, 237), // base
+		Foreground:      tcell.NewRGBColor(87, 82, 121),   // text
+		LineNumber:      tcell.NewRGBColor(152, 147, 165), // muted
+		StatusLine:      tcell.NewRGBColor(87, 82, 121),   // text
+		StatusLineBg:    tcell.NewRGBColor(255, 250, 243), // surface
+		Visual:          tcell.NewRGBColor(87, 82, 121),   // text
+		VisualBg:        tcell.NewRGBColor(242, 233, 222), // highlight med
+		Search:          tcell.NewRGBColor(250, 244, 237), // base
+		SearchBg:        tcell.NewRGBColor(234, 157, 52),  // gold
+		Cursor:          tcell.NewRGBColor(250, 244, 237), // base
+		CursorBg:        tcell.NewRGBColor(215, 130, 126), // rose
+		Keyword:         tcell.NewRGBColor(180, 99, 122),  // love
+		Function:        tcell.NewRGBColor(86, 148, 159),  // foam
+		Type:            tcell.NewRGBColor(234, 157, 52),  // gold
+		String:          tcell.NewRGBColor(234, 157, 52),  // gold
+		Number:          tcell.NewRGBColor(215, 130, 126), // rose
+		Comment:         tcell.NewRGBColor(152, 147, 165), // muted
+		Constant:        tcell.NewRGBColor(180, 99, 122),  // love
+		Property:        tcell.NewRGBColor(86, 148, 159),  // foam
+		Operator:        tcell.NewRGBColor(121, 117, 147), // subtle
+		Variable:        tcell.NewRGBColor(87, 82, 121),   // text
+		TreeDirectory:   tcell.NewRGBColor(86, 148, 159),  // foam
+		TreeFile:        tcell.NewRGBColor(87, 82, 121),   // text
+		TreeCursor:      tcell.NewRGBColor(87, 82, 121),   // text
+		TreeCursorBg:    tcell.NewRGBColor(242, 233, 222), // highlight med
+		TreeBorder:      tcell.NewRGBColor(152, 147, 165), // muted
+		DiagnosticError: tcell.NewRGBColor(180, 99, 122),  // love
+		DiagnosticWarn:  tcell.NewRGBColor(234, 157, 52),  // gold
+		DiagnosticInfo:  tcell.NewRGBColor(86, 148, 159),  // foam
+		DiagnosticHint:  tcell.NewRGBColor(144, 122, 169), // iris
 	},
 }
 
@@ -22,6 +22,8 @@ func (e *Editor) getAllCommands() []string {
 		"Explore", "Ex",
 		"reg", "registers",
 		"macros",
+		"undolist", "undol",
+		"earlier", "later",
 		"noh", "nohlsearch",
 		"fold",
 		"foldopen", "fo",
@@ -29,6 +31,10 @@ func (e *Editor) getAllCommands() []string {
 		"foldall", "fca",
 		"unfoldall", "ufa",
 		"foldinfo",
+		"mkview", "loadview",
+		"LspInfo", "LspRestart", "LspStop", "LspLog",
+		"diagnostics", "references", "rename", "codeaction", "format",
+		"outline", "symbols", "incoming", "outgoing", "supertypes", "subtypes",
 		"colorscheme", "colorschemes",
 		"set",
 		"help",
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func (e *Editor) exec(cmd string) bool {
@@ -44,6 +45,32 @@ func (e *Editor) exec(cmd string) bool {
 		return false
 	}
 
+	// Handle :earlier / :later {N | Ns | Nm | Nh | Nd}, :LspRestart / :LspStop /
+	// :LspLog [all], :rename {newname} and :symbols [query]
+	switch name, arg, _ := strings.Cut(cmd, " "); name {
+	case "earlier", "ea":
+		e.earlier(arg)
+		return false
+	case "later", "lat":
+		e.later(arg)
+		return false
+	case "LspRestart":
+		e.lspRestartCommand(arg)
+		return false
+	case "LspStop":
+		e.lspStopCommand(arg)
+		return false
+	case "LspLog":
+		e.lspLog(arg)
+		return false
+	case "rename":
+		e.lspRename(arg)
+		return false
+	case "symbols":
+		e.openSymbolPicker(arg)
+		return false
+	}
+
 	// Handle :help [topic]
 	if cmd == "help" || (len(cmd) > 5 && cmd[0:5] == "help ") {
 		topic := ""
@@ -102,41 +129,57 @@ func (e *Editor) exec(cmd string) bool {
 		e.popupFixedH = 10
 		e.openPopup("MACROS", e.formatMacros())
 		return false
+	case "undolist", "undol":
+		e.showUndoTree()
+		return false
 	case "noh", "nohlsearch":
 		e.searchMatches = nil
 		e.statusMsg = "search highlight cleared"
 	case "fold":
 		e.ToggleFold()
 	case "foldopen", "fo":
-		fold := e.findFoldAtLine(e.cy)
-		if fold != nil {
-			fold.folded = false
-			e.statusMsg = "unfolded"
-		} else {
-			e.statusMsg = "no fold at cursor"
-		}
+		e.updateFolds()
+		e.openFold(1)
 	case "foldclose", "fc":
-		fold := e.findFoldAtLine(e.cy)
-		if fold != nil {
-			fold.folded = true
-			e.statusMsg = "folded"
-		} else {
-			e.statusMsg = "no fold at cursor"
-		}
+		e.updateFolds()
+		e.closeFold(1)
 	case "foldall", "fca":
 		e.FoldAll()
 	case "unfoldall", "ufa":
 		e.UnfoldAll()
 	case "foldinfo":
 		// Debug command to show fold information
-		if e.parser == nil {
-			e.statusMsg = "no parser available"
-		} else if e.foldRanges == nil {
-			e.statusMsg = "fold ranges not initialized"
+		e.updateFolds()
+		e.statusMsg = fmt.Sprintf("foldmethod: %s, folds: %d", e.foldMethod(), len(e.foldRanges))
+		return false
+	case "LspInfo":
+		e.lspInfo()
+	case "diagnostics", "diag":
+		e.diagnosticsList()
+	case "references", "refs":
+		e.lspReferences()
+	case "codeaction", "ca":
+		e.lspCursorCodeActions()
+	case "format":
+		e.lspFormat(false, 0, 0)
+	case "outline":
+		e.toggleOutline()
+	case "incoming":
+		e.openHierarchy(hierarchyIncoming)
+	case "outgoing":
+		e.openHierarchy(hierarchyOutgoing)
+	case "supertypes":
+		e.openHierarchy(hierarchySupertypes)
+	case "subtypes":
+		e.openHierarchy(hierarchySubtypes)
+	case "mkview", "mkvie":
+		e.makeView()
+	case "loadview", "lo":
+		if err := e.loadView(); err != nil {
+			e.statusMsg = "loadview: " + err.Error()
 		} else {
-			e.statusMsg = fmt.Sprintf("parser: yes, folds: %d", len(e.foldRanges))
+			e.statusMsg = "view loaded"
 		}
-		return false
 	case "colorschemes":
 		// List available color schemes
 		schemes := ListColorSchemes()
@@ -176,6 +219,11 @@ func (e *Editor) save() {
 	_ = os.WriteFile(outPath, []byte(e.buffer.String()), 0644)
 	e.dirty = false
 	e.statusMsg = "written"
+	if err := e.writeUndoFile(e.buf()); err != nil {
+		e.statusMsg = "written; undofile: " + err.Error()
+	}
+	e.autoWriteView()
+	e.lspBufferSaved()
 
 	// Fire BufWritePost event
 	e.FireBufWritePost()
@@ -1,8 +1,12 @@
 package editor
 
 import (
+	"fmt"
 	"sort"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
 )
 
 // buildWordIndex extracts all words from the buffer, excluding the word at cursor
@@ -84,13 +88,20 @@ func (e *Editor) getCurrentWord() (word string, startPos int) {
 	return string(runes[start:pos]), start
 }
 
-// startCompletion initiates completion mode with initial index
+// startCompletion initiates completion mode with initial index. When the
+// buffer's language server offers completion, its candidates are requested
+// too and put in front of the buffer words when they arrive.
 func (e *Editor) startCompletion(initialIndex int) {
 	prefix, startPos := e.getCurrentWord()
-	if prefix == "" {
+	srv, ds := e.lspCompletionDocument()
+	if prefix == "" && ds == nil {
 		e.statusMsg = "no word to complete"
 		return
 	}
+	if ds != nil {
+		ctx := &lsp.CompletionContext{TriggerKind: lsp.CompletionInvoked}
+		e.lspRequestCompletion(srv, ds, startPos, prefix, initialIndex, false, ctx)
+	}
 
 	// Build word index, excluding the word at cursor
 	cursorPos := e.posFromCursor()
@@ -99,13 +110,15 @@ func (e *Editor) startCompletion(initialIndex int) {
 	// Filter words that start with prefix
 	var candidates []string
 	for _, word := range allWords {
-		if strings.HasPrefix(word, prefix) && word != prefix {
+		if prefix != "" && strings.HasPrefix(word, prefix) && word != prefix {
 			candidates = append(candidates, word)
 		}
 	}
 
 	if len(candidates) == 0 {
-		e.statusMsg = "no completions found"
+		if ds == nil {
+			e.statusMsg = "no completions found"
+		}
 		return
 	}
 
@@ -122,6 +135,7 @@ func (e *Editor) startCompletion(initialIndex int) {
 
 	e.completionActive = true
 	e.completionCandidates = candidates
+	e.completionItems = make([]*lspCompletion, len(candidates))
 	// Set initial index (0 for forward/Ctrl-N, last for backward/Ctrl-P)
 	if initialIndex < 0 {
 		e.completionIndex = len(candidates) - 1
@@ -166,7 +180,7 @@ func (e *Editor) cycleCompletion(forward bool) {
 
 // applyCompletion replaces the current word with the selected candidate
 func (e *Editor) applyCompletion() {
-	if !e.completionActive || len(e.completionCandidates) == 0 {
+	if !e.completionActive || e.completionIndex < 0 || e.completionIndex >= len(e.completionCandidates) {
 		return
 	}
 
@@ -183,12 +197,18 @@ func (e *Editor) applyCompletion() {
 
 	// Insert the new completion
 	_ = e.buffer.Insert(deleteStart, candidate)
-	e.setCursorFromPos(deleteStart + len(candidate))
+	e.setCursorFromPos(deleteStart + utf8.RuneCountInString(candidate))
 	e.wantX = e.cx
 
 	// Update status with completion info
 	e.statusMsg = ""
 	e.updateCompletionPopup()
+
+	if item := e.completionItem(e.completionIndex); item != nil {
+		e.lspCompletionSelected(item)
+	} else if e.float != nil && e.float.kind == floatCompletion {
+		e.closeFloat()
+	}
 }
 
 // updateCompletionPopup shows the completion candidates
@@ -198,22 +218,36 @@ func (e *Editor) updateCompletionPopup() {
 		return
 	}
 
-	lines := make([]string, 0, len(e.completionCandidates))
-	for i, candidate := range e.completionCandidates {
+	words := make([]string, 0, len(e.completionCandidates))
+	width := 0
+	for _, candidate := range e.completionCandidates {
+		// Highlight matching prefix by surrounding it with brackets
+		// e.g., if prefix="hel" and candidate="hello", show "[hel]lo"
+		runes := []rune(candidate)
+		matchLen := utf8.RuneCountInString(e.completionPrefix)
+		if matchLen > 0 && matchLen <= len(runes) {
+			candidate = "[" + string(runes[:matchLen]) + "]" + string(runes[matchLen:])
+		}
+		words = append(words, candidate)
+		width = max(width, utf8.RuneCountInString(candidate))
+	}
+
+	lines := make([]string, 0, len(words))
+	for i, word := range words {
 		prefix := "  "
 		if i == e.completionIndex {
 			prefix = "> "
 		}
-
-		// Highlight matching prefix by surrounding it with brackets
-		// e.g., if prefix="hel" and candidate="hello", show "[hel]lo"
-		matchLen := len(e.completionPrefix)
-		if matchLen > 0 && matchLen <= len(candidate) {
-			highlighted := "[" + candidate[:matchLen] + "]" + candidate[matchLen:]
-			lines = append(lines, prefix+highlighted)
-		} else {
-			lines = append(lines, prefix+candidate)
+		line := prefix + word
+		// server candidates show their kind and detail
+		if item := e.completionItem(i); item != nil {
+			detail, _, _ := strings.Cut(item.item.Detail, "\n")
+			if r := []rune(detail); len(r) > 40 {
+				detail = string(r[:39]) + "…"
+			}
+			line = strings.TrimRight(fmt.Sprintf("%s%-*s  %-10s %s", prefix, width, word, item.item.Kind, detail), " ")
 		}
+		lines = append(lines, line)
 	}
 
 	e.popupActive = true
@@ -227,10 +261,28 @@ func (e *Editor) updateCompletionPopup() {
 	}
 }
 
-// cancelCompletion exits completion mode
+// completionItem returns the server item of candidate i, or nil for buffer
+// words
+func (e *Editor) completionItem(i int) *lspCompletion {
+	if i < 0 || i >= len(e.completionItems) {
+		return nil
+	}
+	return e.completionItems[i]
+}
+
+// cancelCompletion exits completion mode, keeping the inserted candidate.
+// A server candidate gets its additional edits applied.
 func (e *Editor) cancelCompletion() {
+	if item := e.completionItem(e.completionIndex); e.completionActive && item != nil {
+		e.lspCompletionAccepted(item)
+	}
+	if e.float != nil && e.float.kind == floatCompletion {
+		e.closeFloat()
+	}
 	e.completionActive = false
 	e.completionCandidates = nil
+	e.completionItems = nil
+	e.completionLSP = nil
 	e.completionIndex = 0
 	e.completionPrefix = ""
 	e.popupActive = false
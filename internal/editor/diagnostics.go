@@ -0,0 +1,250 @@
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+	"github.com/gdamore/tcell/v2"
+)
+
+// diagnostic is a message a language server published about a span of a
+// buffer. The span is in rune offsets and moves with edits until the server
+// publishes again.
+type diagnostic struct {
+	start, end int
+	severity   lsp.DiagnosticSeverity
+	message    string
+	source     string
+	published  lsp.Diagnostic // as the server sent it, for code actions
+}
+
+// severitySigns are the gutter signs of the diagnostic severities
+var severitySigns = map[lsp.DiagnosticSeverity]rune{
+	lsp.SeverityError:       'E',
+	lsp.SeverityWarning:     'W',
+	lsp.SeverityInformation: 'I',
+	lsp.SeverityHint:        'H',
+}
+
+// severityColor returns the color of severity in scheme
+func severityColor(scheme *ColorScheme, severity lsp.DiagnosticSeverity) tcell.Color {
+	switch severity {
+	case lsp.SeverityWarning:
+		return scheme.DiagnosticWarn
+	case lsp.SeverityInformation:
+		return scheme.DiagnosticInfo
+	case lsp.SeverityHint:
+		return scheme.DiagnosticHint
+	default:
+		return scheme.DiagnosticError
+	}
+}
+
+// lspHandleNotification is the notification handler of the clients the
+// editor starts. It runs on the client's read goroutine.
+func (e *Editor) lspHandleNotification(srv *lspServer, client *lsp.Client, method string, params json.RawMessage) {
+	if method != "textDocument/publishDiagnostics" {
+		return
+	}
+	var p lsp.PublishDiagnosticsParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return
+	}
+	e.post(func() { e.lspPublishDiagnostics(srv, client, p) })
+}
+
+// lspPublishDiagnostics replaces the diagnostics of the buffer p is about.
+// Diagnostics for a version of the text that was already replaced are
+// dropped; the server publishes again for the new one.
+func (e *Editor) lspPublishDiagnostics(srv *lspServer, client *lsp.Client, p lsp.PublishDiagnosticsParams) {
+	if srv.client != client {
+		return
+	}
+	path := lsp.PathFromURI(p.URI)
+	ds := srv.docs[path]
+	bv := e.bufferByName(path)
+	if ds == nil || bv == nil {
+		return
+	}
+	if p.Version != nil && *p.Version != ds.Version() {
+		return
+	}
+
+	diags := make([]diagnostic, 0, len(p.Diagnostics))
+	for _, d := range p.Diagnostics {
+		severity := d.Severity
+		if severity < lsp.SeverityError || severity > lsp.SeverityHint {
+			severity = lsp.SeverityError
+		}
+		diags = append(diags, diagnostic{
+			start:     ds.Offset(bv.buffer, d.Range.Start),
+			end:       ds.Offset(bv.buffer, d.Range.End),
+			severity:  severity,
+			message:   d.Message,
+			source:    d.Source,
+			published: d,
+		})
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].start < diags[j].start })
+	bv.diagnostics = diags
+}
+
+// lspClearDiagnostics drops the diagnostics of the buffers attached to srv
+func (e *Editor) lspClearDiagnostics(srv *lspServer) {
+	for path := range srv.docs {
+		if bv := e.bufferByName(path); bv != nil {
+			bv.diagnostics = nil
+		}
+	}
+}
+
+// bufferByName returns the open buffer of the file at path
+func (e *Editor) bufferByName(path string) *BufferView {
+	for _, bv := range e.buffers {
+		if bv.filename == path {
+			return bv
+		}
+	}
+	return nil
+}
+
+// shiftDiagnostics is subscribed to the buffer to move diagnostics with
+// the text around them. Diagnostics inside replaced text shrink to its
+// start.
+func (bv *BufferView) shiftDiagnostics(ed buffer.Edit) {
+	for i := range bv.diagnostics {
+		d := &bv.diagnostics[i]
+		d.start, d.end = shiftOffset(d.start, ed), shiftOffset(d.end, ed)
+	}
+}
+
+// shiftOffset returns where rune offset pos moves with ed. Offsets inside
+// replaced text move to its start.
+func shiftOffset(pos int, ed buffer.Edit) int {
+	switch {
+	case pos <= ed.Start.Offset:
+		return pos
+	case pos >= ed.OldEnd.Offset:
+		return pos + ed.NewEnd.Offset - ed.OldEnd.Offset
+	default:
+		return ed.Start.Offset
+	}
+}
+
+// lineDiagnostics returns the diagnostics starting on line, most severe
+// first
+func lineDiagnostics(bv *BufferView, line int) []diagnostic {
+	var diags []diagnostic
+	for _, d := range bv.diagnostics {
+		if bv.buffer.LineAt(d.start) == line {
+			diags = append(diags, d)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].severity < diags[j].severity })
+	return diags
+}
+
+// diagnosticAt returns the most severe diagnostic covering pos. An empty
+// span covers the character it starts at.
+func diagnosticAt(bv *BufferView, pos int) (diagnostic, bool) {
+	var found diagnostic
+	ok := false
+	for _, d := range bv.diagnostics {
+		if d.start > pos {
+			break
+		}
+		if pos < max(d.end, d.start+1) && (!ok || d.severity < found.severity) {
+			found, ok = d, true
+		}
+	}
+	return found, ok
+}
+
+// gotoDiagnostic implements ]d and [d, moving to the start of the count'th
+// next or previous diagnostic and showing its message
+func (e *Editor) gotoDiagnostic(forward bool, count int) {
+	bv := e.buf()
+	if bv == nil || len(bv.diagnostics) == 0 {
+		e.statusMsg = "no diagnostics"
+		return
+	}
+	pos := e.posFromCursor()
+	var target *diagnostic
+	for n := 0; n < max(1, count); n++ {
+		var next *diagnostic
+		if forward {
+			for i := range bv.diagnostics {
+				if bv.diagnostics[i].start > pos {
+					next = &bv.diagnostics[i]
+					break
+				}
+			}
+		} else {
+			for i := len(bv.diagnostics) - 1; i >= 0; i-- {
+				if bv.diagnostics[i].start < pos {
+					next = &bv.diagnostics[i]
+					break
+				}
+			}
+		}
+		if next == nil {
+			break
+		}
+		pos, target = next.start, next
+	}
+	if target == nil {
+		e.statusMsg = "no more diagnostics"
+		return
+	}
+	e.setCursorFromPos(target.start)
+	e.wantX = e.cx
+	e.statusMsg = diagnosticMessage(*target)
+}
+
+// diagnosticMessage returns the first line of the message of d with its
+// source
+func diagnosticMessage(d diagnostic) string {
+	msg, _, _ := strings.Cut(d.message, "\n")
+	if d.source != "" {
+		msg = d.source + ": " + msg
+	}
+	return msg
+}
+
+// diagnosticsList implements :diagnostics, listing the diagnostics of all
+// buffers. Enter jumps to the selected one.
+func (e *Editor) diagnosticsList() {
+	e.syncToBuffer()
+	type entry struct {
+		bv  *BufferView
+		pos int
+	}
+	var lines []string
+	var entries []entry
+	for _, bv := range e.buffers {
+		name := relativePath(bv.filename)
+		for _, d := range bv.diagnostics {
+			line, col := bv.buffer.LineCol(d.start, buffer.EncodingRune)
+			lines = append(lines, fmt.Sprintf("%s:%d:%d: %c %s", name, line+1, col+1, severitySigns[d.severity], diagnosticMessage(d)))
+			entries = append(entries, entry{bv, d.start})
+		}
+	}
+	if len(entries) == 0 {
+		e.statusMsg = "no diagnostics"
+		return
+	}
+
+	e.popupFixedH = 0 // auto-size
+	e.openPopupList("DIAGNOSTICS", lines, func(i int) {
+		target := entries[i]
+		if target.bv != e.buf() {
+			e.openFile(target.bv.filename)
+		}
+		e.setCursorFromPos(target.pos)
+		e.wantX = e.cx
+	})
+}
@@ -0,0 +1,87 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestLSPDiagnostics(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{
+		"go.mod":  "module example\n",
+		"main.go": "package main\n\nvar a = ERROR\nvar b = WARNING\n",
+	})
+	e.openFile(filepath.Join(dir, "main.go"))
+	bv := e.buf()
+	waitForLSP(t, e, "diagnostics", func() bool { return len(bv.diagnostics) == 2 })
+
+	// signs in the gutter, underlines and the message on the cursor line
+	e.cy, e.cx = 2, 0
+	e.syncToBuffer()
+	scheme := GetColorScheme("default")
+	e.renderBufferRegion(bv, 0, 0, 80, 5, scheme)
+	sim := e.s.(tcell.SimulationScreen)
+	if r, _, _, _ := sim.GetContent(0, 2); r != 'E' {
+		t.Errorf("sign of line 3 = %q, want E", r)
+	}
+	if r, _, _, _ := sim.GetContent(0, 3); r != 'W' {
+		t.Errorf("sign of line 4 = %q, want W", r)
+	}
+	_, _, style, _ := sim.GetContent(2+8, 2) // the E of ERROR after the sign column
+	if style.GetUnderlineStyle() != tcell.UnderlineStyleCurly || style.GetUnderlineColor() != scheme.DiagnosticError {
+		t.Error("ERROR should be underlined in the error color")
+	}
+	msg := ""
+	for x := 2 + len("var a = ERROR") + 2; x < 40; x++ {
+		r, _, _, _ := sim.GetContent(x, 2)
+		msg += string(r)
+	}
+	if want := "■ lsptest: error here"; msg[:len(want)] != want {
+		t.Errorf("cursor line shows %q", msg)
+	}
+
+	e.cy, e.cx = 0, 0
+	pressKeys(e, "]d")
+	if e.cy != 2 || e.cx != 8 || e.statusMsg != "lsptest: error here" {
+		t.Fatalf("]d: at (%d,%d) with %q", e.cy, e.cx, e.statusMsg)
+	}
+	pressKeys(e, "]d")
+	pressKeys(e, "]d")
+	if e.cy != 3 || e.statusMsg != "no more diagnostics" {
+		t.Fatalf("]d past the last: at line %d with %q", e.cy, e.statusMsg)
+	}
+	pressKeys(e, "[d")
+	if e.cy != 2 {
+		t.Fatalf("[d: at line %d", e.cy)
+	}
+
+	// diagnostics move with edits and are replaced when the server
+	// publishes for the new text
+	e.cy, e.cx = 0, 0
+	pressKeys(e, "Ovar c = HINT\x1b")
+	if line := bv.buffer.LineAt(bv.diagnostics[0].start); line != 3 {
+		t.Fatalf("the error moved to line %d, want 3", line)
+	}
+	waitForLSP(t, e, "new diagnostics", func() bool {
+		e.lspSyncChanges()
+		return len(bv.diagnostics) == 3
+	})
+
+	e.exec("diagnostics")
+	if len(e.popupLines) != 3 || !strings.HasSuffix(e.popupLines[0], "main.go:1:9: H lsptest: hint here") {
+		t.Fatalf(":diagnostics = %q", e.popupLines)
+	}
+	pressKeys(e, "jj")
+	e.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	if e.popupActive || e.cy != 4 || e.cx != 8 {
+		t.Fatalf("Enter should jump to the warning, at (%d,%d)", e.cy, e.cx)
+	}
+
+	e.exec("LspStop")
+	if len(bv.diagnostics) != 0 {
+		t.Error("stopping the server should clear its diagnostics")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dragonbytelabs/voidabyss/core/buffer"
@@ -149,16 +150,17 @@ type Editor struct {
 	visualKind   VisualKind
 	visualAnchor int // absolute rune pos
 	visualActive bool
+	visualStack  []visualSelection // selections before each syntax node expansion
 
 	// dot repeat
 	last          RepeatAction
 	insertCapture []rune // text typed during current insert session
 
 	// search
-	searchQuery   string // current search pattern
-	searchForward bool   // true for /, false for ?
-	searchBuf     []rune // input buffer while typing search
-	searchMatches []int  // positions of matches in viewport (for highlighting)
+	searchQuery   string        // current search pattern
+	searchForward bool          // true for /, false for ?
+	searchBuf     []rune        // input buffer while typing search
+	searchMatches []searchMatch // matches in the buffer (for highlighting)
 
 	// character find (f/F/t/T)
 	lastCharFind     rune // character to find
@@ -166,11 +168,15 @@ type Editor struct {
 	awaitingCharFind rune // waiting for character after f/F/t/T
 
 	// completion
-	completionActive     bool     // true when cycling through completions
-	completionCandidates []string // all word candidates
-	completionIndex      int      // current selection index
-	completionPrefix     string   // the partial word being completed
-	completionStartPos   int      // position where completion started
+	completionActive     bool             // true when cycling through completions
+	completionCandidates []string         // all word candidates
+	completionIndex      int              // current selection index, -1 before one is selected
+	completionPrefix     string           // the partial word being completed
+	completionStartPos   int              // position where completion started
+	completionItems      []*lspCompletion // server item of each candidate, nil for buffer words
+	completionLSP        []*lspCompletion // all items of the last server response
+	completionRequest    int              // sequence number of the last completion request
+	completionCancel     func()           // cancels the last completion request
 
 	// marks - awaitingMarkSet/Jump for current operation
 	awaitingMarkSet  bool // waiting for mark name after 'm'
@@ -199,6 +205,11 @@ type Editor struct {
 	popupLines  []string
 	popupScroll int
 	popupFixedH int
+	popupCursor int       // selected line of a list popup
+	popupSelect func(int) // called with the selected line on Enter; nil for plain popups
+
+	// floating window at the cursor (hover, signature help)
+	float *floatWindow
 
 	// file tree
 	fileTree       *FileTree
@@ -206,6 +217,16 @@ type Editor struct {
 	treePanelWidth int
 	focusTree      bool // true if tree has focus, false if buffer has focus
 
+	// symbol outline panel on the right
+	outline           *Outline
+	outlineOpen       bool
+	outlinePanelWidth int
+	focusOutline      bool // true if the outline has focus
+
+	// workspace symbol picker and call or type hierarchy, open in the popup
+	symbolPicker *symbolPicker
+	hierarchy    *HierarchyTree
+
 	// splits
 	splits         []*Split // list of splits
 	currentSplit   int      // index of focused split
@@ -214,6 +235,20 @@ type Editor struct {
 	// folding
 	foldRanges map[int]*FoldRange // map of start line to fold range
 	parser     *TreeSitterParser  // tree-sitter parser for current buffer
+
+	// language servers started by the editor, by command and root
+	lspServers   map[string]*lspServer
+	lspSyncTimer *time.Timer    // sends buffer changes, see lspScheduleSync
+	lspClosing   sync.WaitGroup // servers shutting down, see lspClose
+
+	// the last reference list, stepped through with ]q and [q
+	locations     []lspLocation
+	locationIndex int
+
+	// functions posted from other goroutines to run on the main loop
+	asyncMu   sync.Mutex
+	asyncFns  []func()
+	asyncWake chan struct{} // signalled by post while runAsyncUntil waits
 }
 
 func newEditorFromFile(path string, cfg *config.Config, loader *config.Loader) (*Editor, error) {
@@ -259,6 +294,7 @@ func newEditorFromFile(path string, cfg *config.Config, loader *config.Loader) (
 	}
 	ed.regs.named = make(map[rune]Register)
 	ed.macros = make(map[rune]Macro)
+	ed.loadUndoFile(bufView)
 	ed.syncFromBuffer()
 
 	// Initialize splits
@@ -272,9 +308,11 @@ func newEditorFromFile(path string, cfg *config.Config, loader *config.Loader) (
 		ed.parser = bv.parser
 		ed.UpdateFoldStates()
 	}
+	ed.autoLoadView()
 
 	// Register editor as context for Lua buffer operations
 	ed.RegisterWithLoader()
+	ed.lspBufferOpened()
 
 	if readErr != nil && !os.IsNotExist(readErr) {
 		ed.statusMsg = "read failed: " + readErr.Error()
@@ -332,6 +370,7 @@ func newEditorFromProject(path string, cfg *config.Config, loader *config.Loader
 	ed.cmdCompletionIdx = -1
 	ed.jumpList = make([]JumpListEntry, 0, 100)
 	ed.jumpListIndex = -1
+	ed.trackUndoCursor()
 
 	// Initialize splits
 	ed.initSplits()
@@ -349,13 +388,35 @@ func newEditorFromProject(path string, cfg *config.Config, loader *config.Loader
 func (e *Editor) run() error {
 	defer e.s.Fini()
 	defer e.FireVimLeave()
+	defer e.autoWriteViews()
+	defer e.lspStopAll()
+
+	if e.loader != nil {
+		// results of asynchronous requests wake the loop up to run
+		e.loader.SetWakeFunc(func() {
+			_ = e.s.PostEvent(tcell.NewEventInterrupt(nil))
+		})
+		defer e.loader.SetWakeFunc(nil)
+	}
 
 	for {
+		e.runAsync()
+
+		// Run functions scheduled by Lua and async callbacks
+		if e.loader != nil {
+			e.loader.RunScheduled()
+		}
+
 		// Process notifications from Lua
 		e.processNotifications()
 
+		e.lspScheduleSync()
+		e.updateOutline()
+
+		e.updateFolds()
 		e.ensureCursorValid()
 		e.ensureCursorVisible()
+		e.updateLSPDecorations()
 
 		// Save current editor state to split before rendering
 		e.saveSplitState()
@@ -379,6 +440,53 @@ func (e *Editor) run() error {
 	}
 }
 
+// post queues fn to run on the main loop and wakes the loop up. It is safe
+// to call from any goroutine.
+func (e *Editor) post(fn func()) {
+	e.asyncMu.Lock()
+	e.asyncFns = append(e.asyncFns, fn)
+	if e.asyncWake != nil {
+		select {
+		case e.asyncWake <- struct{}{}:
+		default:
+		}
+	}
+	e.asyncMu.Unlock()
+	_ = e.s.PostEvent(tcell.NewEventInterrupt(nil))
+}
+
+// runAsyncUntil blocks the main loop until done is closed, running the
+// functions posted meanwhile so goroutines waiting on the main loop, such
+// as a server's workspace/applyEdit request, are not stuck behind it
+func (e *Editor) runAsyncUntil(done <-chan struct{}) {
+	e.asyncMu.Lock()
+	if e.asyncWake == nil {
+		e.asyncWake = make(chan struct{}, 1)
+	}
+	wake := e.asyncWake
+	e.asyncMu.Unlock()
+	for {
+		e.runAsync()
+		select {
+		case <-done:
+			return
+		case <-wake:
+		}
+	}
+}
+
+// runAsync runs the functions queued with post
+func (e *Editor) runAsync() {
+	e.asyncMu.Lock()
+	fns := e.asyncFns
+	e.asyncFns = nil
+	e.asyncMu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
 // buf returns the current BufferView
 func (e *Editor) buf() *BufferView {
 	if len(e.buffers) == 0 || e.currentBuffer < 0 || e.currentBuffer >= len(e.buffers) {
@@ -409,6 +517,7 @@ func (e *Editor) syncToBuffer() {
 func (e *Editor) syncFromBuffer() {
 	if b := e.buf(); b != nil {
 		e.buffer = b.buffer
+		e.trackUndoCursor()
 		e.filename = b.filename
 		e.dirty = b.dirty
 		e.cx = b.cx
@@ -55,21 +55,13 @@ func (e *Editor) Line(y int) string {
 
 // SetLine sets the text of line y (0-indexed)
 func (e *Editor) SetLine(y int, text string) {
-	starts := e.lineStarts()
-	if y < 0 || y >= len(starts) {
+	if y < 0 || y >= e.lineCount() {
 		return
 	}
 
-	// Get line boundaries
-	start := starts[y]
-	runes := e.textRunes()
-	end := len(runes)
-	if y+1 < len(starts) {
-		end = starts[y+1] - 1 // Exclude newline
-		if end < start {
-			end = start
-		}
-	}
+	// Get line boundaries (end excludes newline)
+	start := e.buffer.LineStart(y)
+	end := e.buffer.LineEnd(y)
 
 	// Delete old line content
 	if end > start {
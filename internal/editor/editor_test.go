@@ -1,9 +1,12 @@
 package editor
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/config"
 	"github.com/gdamore/tcell/v2"
 )
 
@@ -33,6 +36,39 @@ func newTestEditor(t *testing.T, txt string) *Editor {
 	e.cmdCompletionIdx = -1
 	e.jumpList = make([]JumpListEntry, 0, 100)
 	e.jumpListIndex = -1
+	e.trackUndoCursor()
+	return e
+}
+
+// writeTestFile points HOME to a new directory, so no user config or state
+// is read, and writes src to a file called name in another. It returns the
+// path of the file.
+func writeTestFile(t *testing.T, name, src string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newFileTestEditor opens path in a test editor with the default options,
+// changed by configure when it is not nil, and the state saved under HOME,
+// as after a restart.
+func newFileTestEditor(t *testing.T, path string, configure func(*config.Options)) *Editor {
+	t.Helper()
+	state := config.NewState()
+	if err := state.Load(); err != nil {
+		t.Fatal(err)
+	}
+	opts := config.DefaultOptions()
+	if configure != nil {
+		configure(opts)
+	}
+	e := newTestEditor(t, "")
+	e.config = &config.Config{ColorScheme: "default", Options: opts, State: state}
+	e.openFile(path)
 	return e
 }
 
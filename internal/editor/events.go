@@ -87,8 +87,10 @@ func (e *Editor) FireBufRead() {
 	})
 }
 
-// FireBufWritePre fires before saving a buffer
+// FireBufWritePre fires before saving a buffer, after formatting it when
+// vb.opt.formatonsave is set
 func (e *Editor) FireBufWritePre() {
+	e.lspFormatOnSave()
 	e.FireEvent("BufWritePre", map[string]interface{}{
 		"file": e.filename,
 	})
@@ -3,6 +3,8 @@ package editor
 import (
 	"path/filepath"
 	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
 )
 
 // Filetype represents a detected file type
@@ -30,7 +32,12 @@ var filetypes = []Filetype{
 	},
 	{
 		Name:       "typescript",
-		Extensions: []string{".ts", ".tsx"},
+		Extensions: []string{".ts", ".mts", ".cts"},
+		Comment:    "//",
+	},
+	{
+		Name:       "typescriptreact",
+		Extensions: []string{".tsx"},
 		Comment:    "//",
 	},
 	{
@@ -174,7 +181,7 @@ func (e *Editor) setFiletypeOptions() {
 	case "python", "yaml", "ruby":
 		// These typically use 4 spaces
 		e.indentWidth = 4
-	case "javascript", "typescript", "json", "html", "css":
+	case "javascript", "typescript", "typescriptreact", "json", "html", "css":
 		// These typically use 2 spaces
 		e.indentWidth = 2
 	}
@@ -197,10 +204,7 @@ func (e *Editor) initTreeSitterParser() {
 	}
 
 	// Close existing parser if any
-	if bv.parser != nil {
-		bv.parser.Close()
-		bv.parser = nil
-	}
+	bv.closeParser()
 
 	// Create new parser for supported languages
 	parser, err := NewTreeSitterParser(ft.Name)
@@ -210,13 +214,19 @@ func (e *Editor) initTreeSitterParser() {
 	}
 
 	// Parse current buffer content
-	content := e.buffer.String()
-	if err := parser.Parse(content); err != nil {
+	if err := parser.ParseBuffer(bv.buffer); err != nil {
 		parser.Close()
 		return
 	}
 
 	bv.parser = parser
+	if err := parser.QueryError(); err != nil {
+		e.statusMsg = "query error: " + err.Error()
+	}
+	// Feed every change to the tree so reparses are incremental
+	bv.unsubscribeParser = bv.buffer.Subscribe(func(ed buffer.Edit) {
+		parser.Edit(ed)
+	})
 
 	// Initialize fold ranges
 	e.UpdateFoldStates()
@@ -229,11 +239,10 @@ func (e *Editor) reparseBuffer() {
 		return
 	}
 
-	content := e.buffer.String()
-	bv.parser.Parse(content)
+	bv.parser.ParseBuffer(bv.buffer)
 
 	// Update fold states after reparse
-	e.UpdateFoldStates()
+	e.refreshFolds()
 }
 
 // getCommentPrefix returns the comment prefix for the current filetype
@@ -11,7 +11,8 @@ func TestFiletypeDetection(t *testing.T) {
 		{"test.go", "go", "//"},
 		{"script.py", "python", "#"},
 		{"app.js", "javascript", "//"},
-		{"component.tsx", "typescript", "//"},
+		{"index.ts", "typescript", "//"},
+		{"component.tsx", "typescriptreact", "//"},
 		{"main.c", "c", "//"},
 		{"program.cpp", "cpp", "//"},
 		{"lib.rs", "rust", "//"},
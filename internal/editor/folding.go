@@ -1,8 +1,11 @@
 package editor
 
 import (
+	"maps"
 	"sort"
+	"strings"
 
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
 	sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
@@ -13,8 +16,52 @@ type FoldRange struct {
 	folded    bool // whether this range is currently folded
 }
 
-// GetFoldableRanges returns all foldable regions in the buffer using tree-sitter
+// Fold methods selected with the foldmethod option
+const (
+	foldSyntax = "syntax" // tree-sitter nodes
+	foldIndent = "indent" // lines indented deeper than the one before them
+	foldMarker = "marker" // {{{ and }}} in the text
+	foldManual = "manual" // created with zf
+)
+
+// foldMethod returns the fold method in effect for the current buffer.
+// Buffers without a tree-sitter parser fold by indent under "syntax".
+func (e *Editor) foldMethod() string {
+	method := foldSyntax
+	if e.config != nil && e.config.Options != nil && e.config.Options.FoldMethod != "" {
+		method = e.config.Options.FoldMethod
+	}
+	if method == foldSyntax && e.parser == nil {
+		return foldIndent
+	}
+	return method
+}
+
+// GetFoldableRanges returns all foldable regions in the buffer for the
+// current fold method
 func (e *Editor) GetFoldableRanges() []FoldRange {
+	var ranges []FoldRange
+	switch e.foldMethod() {
+	case foldSyntax:
+		ranges = e.syntaxFolds()
+	case foldIndent:
+		ranges, _ = e.indentFolds(0, nil)
+	case foldMarker:
+		ranges, _ = e.markerFolds(0, nil)
+	default:
+		return nil
+	}
+
+	// Sort by start line
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].startLine < ranges[j].startLine
+	})
+
+	return ranges
+}
+
+// syntaxFolds returns the foldable regions of the tree-sitter tree
+func (e *Editor) syntaxFolds() []FoldRange {
 	if e.parser == nil || e.parser.GetTree() == nil {
 		return nil
 	}
@@ -26,14 +73,159 @@ func (e *Editor) GetFoldableRanges() []FoldRange {
 	}
 
 	ranges := []FoldRange{}
+	if spans, ok := e.parser.FoldRanges(0, e.lineCount()); ok {
+		for _, sp := range spans {
+			ranges = append(ranges, FoldRange{startLine: sp.start, endLine: sp.end})
+		}
+		return ranges
+	}
 	e.collectFoldableNodes(root, &ranges)
+	return ranges
+}
 
-	// Sort by start line
-	sort.Slice(ranges, func(i, j int) bool {
-		return ranges[i].startLine < ranges[j].startLine
-	})
+// FoldRanges returns the line spans of the multi-line nodes the folds.scm
+// query captures as @fold that overlap the lines from startRow to endRow.
+// ok is false when the grammar has no folds query; its folds then come
+// from the node types isFoldableNodeType knows.
+func (p *TreeSitterParser) FoldRanges(startRow, endRow int) (spans []span, ok bool) {
+	if p == nil {
+		return nil, false
+	}
 
-	return ranges
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.foldsLoaded {
+		p.foldsLoaded = true
+		p.folds, _ = loadQuery(p.language, p.lang, "folds")
+	}
+	if p.folds == nil || p.tree == nil {
+		return nil, p.folds != nil
+	}
+
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	cursor.SetPointRange(sitter.Point{Row: uint(startRow)}, sitter.Point{Row: uint(endRow + 1)})
+
+	names := p.folds.CaptureNames()
+	captures := cursor.Captures(p.folds.Query, p.tree.RootNode(), nil)
+	for {
+		match, idx := p.folds.nextCapture(&captures, p.src)
+		if match == nil {
+			break
+		}
+		c := match.Captures[idx]
+		start, end := int(c.Node.StartPosition().Row), int(c.Node.EndPosition().Row)
+		if c.Node.EndPosition().Column == 0 {
+			// the node ends with its last newline
+			end--
+		}
+		if names[c.Index] == "fold" && end > start {
+			spans = append(spans, span{start, end})
+		}
+	}
+	return spans, true
+}
+
+// indentFolds returns a fold for every line followed by lines indented
+// deeper than it. The fold runs from that line to the last deeper one;
+// blank lines count as part of the block around them.
+//
+// The scan starts at line from, which is the first line or a top-level
+// one, and ends before the first top-level line y for which stop(y) holds,
+// or at the end of the buffer. It returns the line it ended at.
+func (e *Editor) indentFolds(from int, stop func(y int) bool) (ranges []FoldRange, end int) {
+	type open struct{ line, indent int }
+	var stack []open
+	last := -1 // last non-blank line
+	closeTo := func(indent int) {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if last > top.line {
+				ranges = append(ranges, FoldRange{startLine: top.line, endLine: last})
+			}
+		}
+	}
+	for y := from; y < e.lineCount(); y++ {
+		line := e.buffer.Line(y)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := e.indentColumns(line)
+		closeTo(indent)
+		if indent == 0 && y > from && stop != nil && stop(y) {
+			return ranges, y
+		}
+		stack = append(stack, open{y, indent})
+		last = y
+	}
+	closeTo(0)
+	return ranges, e.lineCount()
+}
+
+// indentColumns returns the width of the leading whitespace of line, with
+// tabs counted as the indent width.
+func (e *Editor) indentColumns(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += max(1, e.indentWidth)
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// markerFolds returns a fold from every line containing {{{ to the line
+// with its matching }}}. Markers nest; an unclosed one folds to the end of
+// the buffer.
+//
+// The scan starts at line from, outside any marker, and ends before the
+// first line y outside all markers for which stop(y) holds, or at the end
+// of the buffer. It returns the line it ended at.
+func (e *Editor) markerFolds(from int, stop func(y int) bool) (ranges []FoldRange, end int) {
+	var stack []int
+	lines := e.lineCount()
+	for y := from; y < lines; y++ {
+		if len(stack) == 0 && y > from && stop != nil && stop(y) {
+			return ranges, y
+		}
+		line := e.buffer.Line(y)
+		for i := 0; i < len(line); {
+			switch {
+			case strings.HasPrefix(line[i:], "{{{"):
+				stack = append(stack, y)
+				i += 3
+			case strings.HasPrefix(line[i:], "}}}"):
+				if len(stack) > 0 {
+					start := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					if y > start {
+						ranges = append(ranges, FoldRange{startLine: start, endLine: y})
+					}
+				}
+				i += 3
+			default:
+				i++
+			}
+		}
+	}
+	last := lines - 1
+	if last > 0 && e.buffer.Line(last) == "" {
+		// the empty line after a final newline
+		last--
+	}
+	for _, start := range stack {
+		if last > start {
+			ranges = append(ranges, FoldRange{startLine: start, endLine: last})
+		}
+	}
+	return ranges, lines
 }
 
 // collectFoldableNodes recursively collects foldable nodes from the AST
@@ -104,111 +296,543 @@ func (e *Editor) isFoldableNodeType(nodeType string) bool {
 		"class":                 true,
 		"export_statement":      true,
 		"interface_declaration": true,
+
+		// TypeScript
+		"enum_declaration":       true,
+		"type_alias_declaration": true,
+		"internal_module":        true,
+		"object_type":            true,
+
+		// Rust
+		"function_item":          true,
+		"impl_item":              true,
+		"trait_item":             true,
+		"struct_item":            true,
+		"enum_item":              true,
+		"mod_item":               true,
+		"match_expression":       true,
+		"macro_definition":       true,
+		"field_declaration_list": true,
+
+		// C
+		"struct_specifier":   true,
+		"enum_specifier":     true,
+		"union_specifier":    true,
+		"compound_statement": true,
+		"preproc_if":         true,
+		"preproc_ifdef":      true,
+
+		// Bash
+		"case_statement": true,
+		"do_group":       true,
+
+		// HTML/CSS
+		"element":  true,
+		"rule_set": true,
 	}
 
 	return foldableTypes[nodeType]
 }
 
-// UpdateFoldStates updates fold states from the existing foldRanges
+// UpdateFoldStates recomputes the fold ranges for the current fold method,
+// keeping the state of folds that start on the same line as before. Manual
+// folds are left as they are.
 func (e *Editor) UpdateFoldStates() {
 	if e.foldRanges == nil {
 		e.foldRanges = make(map[int]*FoldRange)
 	}
+	method := e.foldMethod()
+	if bv := e.buf(); bv != nil {
+		bv.foldRanges = e.foldRanges
+		bv.foldMethod = method
+		bv.foldsStale = false
+		bv.foldsDirty = span{}
+	}
+	if method == foldManual {
+		return
+	}
 
 	// Get fresh foldable ranges
 	newRanges := e.GetFoldableRanges()
 
 	// Preserve existing fold states
-	newFoldMap := make(map[int]*FoldRange)
+	folded := make(map[int]bool, len(e.foldRanges))
+	for line, f := range e.foldRanges {
+		folded[line] = f.folded
+	}
+	clear(e.foldRanges)
 	for i := range newRanges {
-		startLine := newRanges[i].startLine
-		// Check if we had this fold before
-		if oldFold, exists := e.foldRanges[startLine]; exists {
-			newRanges[i].folded = oldFold.folded
+		newRanges[i].folded = folded[newRanges[i].startLine]
+		addFold(e.foldRanges, &newRanges[i])
+	}
+}
+
+// updateFolds brings the current buffer's folds up to date before they are
+// used: after the fold method changed, after indent or marker folds saw
+// their text change, and after operators left the syntax tree behind. It
+// runs once per turn of the main loop, so the edits of a turn are handled
+// together.
+func (e *Editor) updateFolds() {
+	bv := e.buf()
+	if bv == nil {
+		if e.foldRanges == nil {
+			e.UpdateFoldStates()
+		}
+		return
+	}
+	method := e.foldMethod()
+	switch {
+	case method != bv.foldMethod:
+		e.UpdateFoldStates()
+	case method == foldSyntax:
+		e.syntaxParser()
+	case bv.foldsStale && method != foldManual:
+		e.refreshTextFolds(bv)
+	}
+}
+
+// refreshTextFolds brings indent and marker folds up to date after edits
+// to the lines in bv.foldsDirty. The scan starts at the last line before
+// them outside every fold and stops at the first one after them, so only
+// the blocks around the edits are recomputed; folds elsewhere were moved
+// by shiftFolds and keep their state.
+func (e *Editor) refreshTextFolds(bv *BufferView) {
+	dirty := bv.foldsDirty
+	bv.foldsStale = false
+	bv.foldsDirty = span{}
+
+	// inside reports whether a fold spans from before line y into it
+	inside := func(y int) bool {
+		for _, f := range e.foldRanges {
+			if f.startLine < y && y <= f.endLine {
+				return true
+			}
 		}
-		newFoldMap[startLine] = &newRanges[i]
+		return false
+	}
+	var (
+		ranges []FoldRange
+		from   int
+		end    int
+	)
+	switch bv.foldMethod {
+	case foldIndent:
+		// a top-level line above the edits closes every block before it
+		for from = dirty.start - 1; from > 0; from-- {
+			if line := e.buffer.Line(from); strings.TrimSpace(line) != "" && e.indentColumns(line) == 0 {
+				break
+			}
+		}
+		from = max(from, 0)
+		ranges, end = e.indentFolds(from, func(y int) bool { return y > dirty.end })
+	case foldMarker:
+		for from = dirty.start; from > 0 && inside(from); {
+			for _, f := range e.foldRanges {
+				if f.startLine < from && from <= f.endLine {
+					from = f.startLine
+				}
+			}
+		}
+		ranges, end = e.markerFolds(from, func(y int) bool { return y > dirty.end && !inside(y) })
 	}
 
-	e.foldRanges = newFoldMap
+	folded := make(map[int]bool)
+	for line, f := range e.foldRanges {
+		if line >= from && line < end {
+			folded[line] = f.folded
+			delete(e.foldRanges, line)
+		}
+	}
+	for i := range ranges {
+		ranges[i].folded = folded[ranges[i].startLine]
+		addFold(e.foldRanges, &ranges[i])
+	}
 }
 
-// ToggleFold toggles the fold at the current cursor line
-func (e *Editor) ToggleFold() {
-	if e.foldRanges == nil {
-		e.UpdateFoldStates()
+// addFold stores f unless a fold starting on the same line already covers
+// more lines; the outermost construct wins.
+func addFold(folds map[int]*FoldRange, f *FoldRange) {
+	if old, ok := folds[f.startLine]; ok && old.endLine >= f.endLine {
+		return
 	}
+	folds[f.startLine] = f
+}
 
-	// Find fold at or containing current line
-	fold := e.findFoldAtLine(e.cy)
-	if fold != nil {
-		fold.folded = !fold.folded
-		if fold.folded {
-			e.statusMsg = "folded"
-		} else {
-			e.statusMsg = "unfolded"
+// shiftFolds moves the buffer's folds along with an edit so manual folds
+// stay on their lines and indent and marker folds keep their state when
+// they are recomputed. Syntax folds are shifted by refreshFolds.
+func (bv *BufferView) shiftFolds(ed buffer.Edit) {
+	start, oldEnd, newEnd := ed.Start.Line, ed.OldEnd.Line, ed.NewEnd.Line
+	if d := bv.foldsDirty; bv.foldsStale {
+		if d.end > oldEnd {
+			d.end += newEnd - oldEnd
 		}
+		bv.foldsDirty = span{min(d.start, start), max(d.end, newEnd)}
 	} else {
-		e.statusMsg = "no fold at cursor"
+		bv.foldsDirty = span{start, newEnd}
+	}
+	bv.foldsStale = true
+	if bv.foldMethod == foldSyntax || len(bv.foldRanges) == 0 || oldEnd == newEnd {
+		return
+	}
+
+	move := func(line int, end bool) int {
+		switch {
+		case line < start:
+			return line
+		case line > oldEnd:
+			return line + newEnd - oldEnd
+		case line == oldEnd && (end || ed.Start.Col == 0 && start == oldEnd):
+			// the rest of the last line moves to the end of the new text
+			return newEnd
+		default:
+			return min(line, newEnd)
+		}
+	}
+	folds := make([]*FoldRange, 0, len(bv.foldRanges))
+	for _, f := range bv.foldRanges {
+		folds = append(folds, f)
+	}
+	clear(bv.foldRanges)
+	for _, f := range folds {
+		f.startLine, f.endLine = move(f.startLine, false), move(f.endLine, true)
+		if f.endLine > f.startLine {
+			addFold(bv.foldRanges, f)
+		}
 	}
 }
 
-// findFoldAtLine finds a fold that contains or starts at the given line
-func (e *Editor) findFoldAtLine(line int) *FoldRange {
-	// Look for a fold starting at this line first
-	if fold, exists := e.foldRanges[line]; exists {
-		return fold
+// refreshFolds brings fold ranges up to date after a reparse. After an
+// incremental parse only the lines the edits and the reparse touched are
+// re-examined; folds elsewhere are shifted and keep their state.
+func (e *Editor) refreshFolds() {
+	if e.foldMethod() != foldSyntax {
+		// other methods are brought up to date by updateFolds
+		return
+	}
+	edits, changed, incremental := e.parser.LastParse()
+	if !incremental || e.foldRanges == nil || (e.buf() != nil && e.buf().foldMethod != foldSyntax) {
+		e.UpdateFoldStates()
+		return
 	}
 
-	// Search for any fold that contains this line
-	// Find the innermost (smallest) fold containing the cursor
-	var bestFold *FoldRange
-	bestSize := 999999
+	var dirty []span // line ranges
+	folded := make(map[int]bool)
+	for _, ed := range edits {
+		start, oldEnd, newEnd := int(ed.StartPosition.Row), int(ed.OldEndPosition.Row), int(ed.NewEndPosition.Row)
+		delta := newEnd - oldEnd
+
+		shifted := make(map[int]*FoldRange, len(e.foldRanges))
+		for _, f := range e.foldRanges {
+			switch {
+			case f.endLine < start:
+				shifted[f.startLine] = f
+			case f.startLine > oldEnd:
+				f.startLine += delta
+				f.endLine += delta
+				shifted[f.startLine] = f
+			default:
+				// touched by the edit; recollected below
+				if f.startLine <= start {
+					folded[f.startLine] = f.folded
+				}
+			}
+		}
+		clear(e.foldRanges)
+		maps.Copy(e.foldRanges, shifted)
+
+		for i, d := range dirty {
+			switch {
+			case d.end < start:
+			case d.start > oldEnd:
+				dirty[i] = span{d.start + delta, d.end + delta}
+			default:
+				dirty[i] = span{min(d.start, start), max(d.end+delta, newEnd)}
+			}
+		}
+		dirty = append(dirty, span{start, newEnd})
+	}
+	for _, r := range changed {
+		dirty = append(dirty, span{int(r.StartPoint.Row), int(r.EndPoint.Row)})
+	}
 
-	for _, fold := range e.foldRanges {
-		if line >= fold.startLine && line <= fold.endLine {
-			size := fold.endLine - fold.startLine
-			if size < bestSize {
-				bestSize = size
-				bestFold = fold
+	touches := func(start, end int) bool {
+		for _, d := range dirty {
+			if d.overlaps(start, end) {
+				return true
 			}
 		}
+		return false
+	}
+	for line, f := range e.foldRanges {
+		if touches(f.startLine, f.endLine) {
+			if _, ok := folded[line]; !ok {
+				folded[line] = f.folded
+			}
+			delete(e.foldRanges, line)
+		}
+	}
+
+	var spans []span
+	fromQuery := true
+	for _, d := range dirty {
+		s, ok := e.parser.FoldRanges(d.start, d.end)
+		if !ok {
+			fromQuery = false
+			break
+		}
+		spans = append(spans, s...)
+	}
+	if fromQuery {
+		for _, sp := range spans {
+			addFold(e.foldRanges, &FoldRange{startLine: sp.start, endLine: sp.end, folded: folded[sp.start]})
+		}
+		return
 	}
 
-	return bestFold
+	root := e.parser.GetTree().RootNode()
+	var walk func(n *sitter.Node)
+	walk = func(n *sitter.Node) {
+		startLine := int(n.StartPosition().Row)
+		endLine := int(n.EndPosition().Row)
+		if !touches(startLine, endLine) {
+			return
+		}
+		if endLine > startLine && e.isFoldableNodeType(n.GrammarName()) {
+			addFold(e.foldRanges, &FoldRange{startLine: startLine, endLine: endLine, folded: folded[startLine]})
+		}
+		for i := uint(0); i < n.ChildCount(); i++ {
+			if child := n.Child(i); child != nil {
+				walk(child)
+			}
+		}
+	}
+	if root != nil {
+		walk(root)
+	}
 }
 
-// FoldAll folds all foldable regions
-func (e *Editor) FoldAll() {
-	if e.foldRanges == nil {
-		e.UpdateFoldStates()
+// foldCommand runs the fold command z{r} with a count.
+func (e *Editor) foldCommand(r rune, count int) {
+	e.updateFolds()
+	switch r {
+	case 'a':
+		// za - toggle fold
+		e.ToggleFold()
+	case 'c':
+		// zc - close fold
+		e.closeFold(count)
+	case 'o':
+		// zo - open fold
+		e.openFold(count)
+	case 'M':
+		// zM - fold all
+		e.FoldAll()
+	case 'R':
+		// zR - unfold all
+		e.UnfoldAll()
+	case 'j':
+		// zj - start of the next fold
+		e.moveToFold(true, count)
+	case 'k':
+		// zk - end of the previous fold
+		e.moveToFold(false, count)
+	case 'd':
+		// zd - delete manual fold
+		e.deleteFold()
+	case 'E':
+		// zE - delete all manual folds
+		if e.foldMethod() != foldManual {
+			e.statusMsg = "zE needs foldmethod=manual"
+			return
+		}
+		clear(e.foldRanges)
+		e.statusMsg = "deleted all folds"
+	default:
+		e.statusMsg = "unknown fold command: z" + string(r)
 	}
+}
 
-	count := 0
-	for _, fold := range e.foldRanges {
-		if !fold.folded {
-			fold.folded = true
-			count++
+// foldsAt returns the folds containing line, outermost first.
+func (e *Editor) foldsAt(line int) []*FoldRange {
+	var folds []*FoldRange
+	for _, f := range e.foldRanges {
+		if line >= f.startLine && line <= f.endLine {
+			folds = append(folds, f)
+		}
+	}
+	sort.Slice(folds, func(i, j int) bool {
+		if folds[i].startLine != folds[j].startLine {
+			return folds[i].startLine < folds[j].startLine
+		}
+		return folds[i].endLine > folds[j].endLine
+	})
+	return folds
+}
+
+// ToggleFold toggles the fold at the current cursor line: a closed fold is
+// opened, otherwise the innermost open fold is closed
+func (e *Editor) ToggleFold() {
+	e.updateFolds()
+	for _, f := range e.foldsAt(e.cy) {
+		if f.folded {
+			e.openFold(1)
+			return
 		}
 	}
+	e.closeFold(1)
+}
 
+// openFold implements zo: it opens the outermost closed fold at the cursor,
+// the one showing on screen, and count-1 closed folds inside it.
+func (e *Editor) openFold(count int) {
+	folds := e.foldsAt(e.cy)
+	if len(folds) == 0 {
+		e.statusMsg = "no fold at cursor"
+		return
+	}
+	opened := 0
+	for _, f := range folds {
+		if f.folded && opened < max(1, count) {
+			f.folded = false
+			opened++
+		}
+	}
+	if opened == 0 {
+		e.statusMsg = "no closed fold at cursor"
+		return
+	}
+	e.statusMsg = "unfolded"
+}
+
+// closeFold implements zc: it closes the innermost open fold at the cursor
+// and count-1 open folds around it, and moves the cursor to the first line
+// of the outermost one it closed.
+func (e *Editor) closeFold(count int) {
+	folds := e.foldsAt(e.cy)
+	if len(folds) == 0 {
+		e.statusMsg = "no fold at cursor"
+		return
+	}
+	closed := 0
+	for i := len(folds) - 1; i >= 0 && closed < max(1, count); i-- {
+		if !folds[i].folded {
+			folds[i].folded = true
+			closed++
+		}
+	}
+	if closed == 0 {
+		e.statusMsg = "no open fold at cursor"
+		return
+	}
+	e.cursorOutOfFolds()
+	e.statusMsg = "folded"
+}
+
+// cursorOutOfFolds moves the cursor from a line hidden by a closed fold to
+// the first line of the outermost closed fold around it.
+func (e *Editor) cursorOutOfFolds() {
+	for _, f := range e.foldsAt(e.cy) {
+		if f.folded && e.cy > f.startLine {
+			e.cy, e.cx, e.wantX = f.startLine, 0, 0
+			return
+		}
+	}
+}
+
+// FoldAll folds all foldable regions
+func (e *Editor) FoldAll() {
+	e.updateFolds()
+	for _, fold := range e.foldRanges {
+		fold.folded = true
+	}
+	e.cursorOutOfFolds()
 	e.statusMsg = "folded all"
 }
 
 // UnfoldAll unfolds all foldable regions
 func (e *Editor) UnfoldAll() {
-	if e.foldRanges == nil {
+	e.updateFolds()
+	for _, fold := range e.foldRanges {
+		fold.folded = false
+	}
+	e.statusMsg = "unfolded all"
+}
+
+// createFold implements zf in visual mode: it makes a closed manual fold of
+// the lines from start to end.
+func (e *Editor) createFold(start, end int) {
+	e.updateFolds()
+	if method := e.foldMethod(); method != foldManual {
+		e.statusMsg = "cannot create folds with foldmethod=" + method
+		return
+	}
+	if end <= start {
+		e.statusMsg = "a fold needs at least two lines"
 		return
 	}
+	e.foldRanges[start] = &FoldRange{startLine: start, endLine: end, folded: true}
+	e.cy, e.cx, e.wantX = start, 0, 0
+	e.cursorOutOfFolds()
+	e.statusMsg = "folded"
+}
 
-	count := 0
-	for _, fold := range e.foldRanges {
-		if fold.folded {
-			fold.folded = false
-			count++
+// deleteFold implements zd: it removes the innermost manual fold at the
+// cursor. Lines inside folds around it stay folded.
+func (e *Editor) deleteFold() {
+	if method := e.foldMethod(); method != foldManual {
+		e.statusMsg = "cannot delete folds with foldmethod=" + method
+		return
+	}
+	folds := e.foldsAt(e.cy)
+	if len(folds) == 0 {
+		e.statusMsg = "no fold at cursor"
+		return
+	}
+	delete(e.foldRanges, folds[len(folds)-1].startLine)
+	e.statusMsg = "fold deleted"
+}
+
+// moveToFold implements zj and zk: zj moves down to the start of the next
+// fold, zk up to the end of the previous one. Folds hidden inside a closed
+// fold are skipped; a closed fold counts as one.
+func (e *Editor) moveToFold(forward bool, count int) {
+	line := e.cy
+	for n := 0; n < max(1, count); n++ {
+		next := -1
+		for _, f := range e.foldRanges {
+			target := f.startLine
+			if !forward {
+				target = e.visibleLine(f.endLine)
+			}
+			if e.isLineFolded(f.startLine) {
+				continue
+			}
+			if forward && target > line && (next < 0 || target < next) ||
+				!forward && target < line && target > next {
+				next = target
+			}
+		}
+		if next < 0 {
+			break
 		}
+		line = next
 	}
+	if line == e.cy {
+		e.statusMsg = "no more folds"
+		return
+	}
+	e.cy, e.cx, e.wantX = line, 0, 0
+}
 
-	e.statusMsg = "unfolded all"
+// visibleLine returns line, or the first line of the closed fold hiding it.
+func (e *Editor) visibleLine(line int) int {
+	for _, f := range e.foldsAt(line) {
+		if f.folded && line > f.startLine {
+			return f.startLine
+		}
+	}
+	return line
 }
 
 // isLineFolded checks if a line is currently folded (hidden)
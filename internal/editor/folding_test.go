@@ -0,0 +1,232 @@
+package editor
+
+import (
+	"maps"
+	"math/rand"
+	"testing"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+)
+
+const foldYAMLSource = `server:
+  host: localhost
+  tls:
+    cert: a.pem
+    key: a.key
+
+  port: 8080
+logging:
+  level: debug
+`
+
+// withFoldMethod configures a test editor with the given foldmethod.
+func withFoldMethod(method string) func(*config.Options) {
+	return func(o *config.Options) { o.FoldMethod = method }
+}
+
+// foldSpans returns the folds as start and end lines keyed by start line.
+func foldSpans(e *Editor) map[int]int {
+	e.updateFolds()
+	spans := make(map[int]int)
+	for line, f := range e.foldRanges {
+		spans[line] = f.endLine
+	}
+	return spans
+}
+
+func assertFolds(t *testing.T, e *Editor, want map[int]int) {
+	t.Helper()
+	got := foldSpans(e)
+	if len(got) != len(want) {
+		t.Fatalf("folds = %v, want %v", got, want)
+	}
+	for start, end := range want {
+		if got[start] != end {
+			t.Fatalf("folds = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestIndentFolds(t *testing.T) {
+	// without a parser, syntax folding falls back to indent
+	e := newFileTestEditor(t, writeTestFile(t, "config.txt", foldYAMLSource), withFoldMethod("syntax"))
+	if e.foldMethod() != foldIndent {
+		t.Fatalf("foldmethod = %s, want indent", e.foldMethod())
+	}
+	assertFolds(t, e, map[int]int{0: 6, 2: 4, 7: 8})
+
+	// indent folds also apply to files with a parser
+	e = newFileTestEditor(t, writeTestFile(t, "main.go", "package main\n\nfunc f() {\n\tif x {\n\t\ty()\n\t}\n}\n"), withFoldMethod("indent"))
+	assertFolds(t, e, map[int]int{2: 5, 3: 4})
+}
+
+func TestMarkerFolds(t *testing.T) {
+	src := "# settings {{{\na = 1\n# nested {{{\nb = 2\n# }}}\n# }}}\nc = 3\n# open {{{\nd = 4\n"
+	e := newFileTestEditor(t, writeTestFile(t, "settings.conf", src), withFoldMethod("marker"))
+	assertFolds(t, e, map[int]int{0: 5, 2: 4, 7: 8})
+}
+
+func TestNestedFoldKeys(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "config.yaml", foldYAMLSource), withFoldMethod("indent"))
+	e.cy = 3 // cert, inside tls inside server
+
+	pressKeys(e, "zc")
+	if !e.foldRanges[2].folded || e.foldRanges[0].folded {
+		t.Fatal("zc should close only the innermost fold")
+	}
+	if e.cy != 2 {
+		t.Fatalf("zc should move the cursor to the fold, at line %d", e.cy)
+	}
+	pressKeys(e, "zc")
+	if !e.foldRanges[0].folded || e.cy != 0 {
+		t.Fatal("a second zc should close the enclosing fold")
+	}
+	pressKeys(e, "zo")
+	if e.foldRanges[0].folded || !e.foldRanges[2].folded {
+		t.Fatal("zo should open one level")
+	}
+	e.cy = 2
+	pressKeys(e, "za")
+	if e.foldRanges[2].folded {
+		t.Fatal("za should open a closed fold")
+	}
+	pressKeys(e, "za")
+	if !e.foldRanges[2].folded {
+		t.Fatal("za should close an open fold")
+	}
+
+	pressKeys(e, "zR")
+	for line, f := range e.foldRanges {
+		if f.folded {
+			t.Fatalf("zR left the fold at %d closed", line)
+		}
+	}
+	e.cy = 4
+	pressKeys(e, "zM")
+	for line, f := range e.foldRanges {
+		if !f.folded {
+			t.Fatalf("zM left the fold at %d open", line)
+		}
+	}
+	if e.cy != 0 {
+		t.Fatalf("zM should move the cursor out of the fold, at line %d", e.cy)
+	}
+}
+
+func TestFoldMotions(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "config.yaml", foldYAMLSource), withFoldMethod("indent"))
+	e.cy = 0
+
+	pressKeys(e, "zj")
+	if e.cy != 2 {
+		t.Fatalf("zj: at line %d, want 2", e.cy)
+	}
+	pressKeys(e, "zj")
+	if e.cy != 7 {
+		t.Fatalf("second zj: at line %d, want 7", e.cy)
+	}
+	pressKeys(e, "zk")
+	if e.cy != 6 {
+		t.Fatalf("zk: at line %d, want 6", e.cy)
+	}
+	pressKeys(e, "zk")
+	if e.cy != 4 {
+		t.Fatalf("second zk: at line %d, want 4", e.cy)
+	}
+
+	// folds inside a closed fold are skipped
+	e.cy = 0
+	pressKeys(e, "zc")
+	pressKeys(e, "zj")
+	if e.cy != 7 {
+		t.Fatalf("zj over a closed fold: at line %d, want 7", e.cy)
+	}
+}
+
+func TestManualFolds(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "notes.txt", "one\ntwo\nthree\nfour\nfive\n"), withFoldMethod("manual"))
+	assertFolds(t, e, map[int]int{})
+
+	e.cy = 1
+	pressKeys(e, "Vjjzf")
+	assertFolds(t, e, map[int]int{1: 3})
+	if !e.foldRanges[1].folded || e.mode != ModeNormal {
+		t.Fatal("zf should make a closed fold and leave visual mode")
+	}
+
+	// manual folds move with the text
+	_ = e.buffer.Insert(0, "zero\n")
+	assertFolds(t, e, map[int]int{2: 4})
+	if !e.foldRanges[2].folded {
+		t.Fatal("the moved fold should stay closed")
+	}
+
+	e.cy = 2
+	pressKeys(e, "zd")
+	assertFolds(t, e, map[int]int{})
+
+	// zf is refused for computed folds
+	e.config.Options.FoldMethod = "indent"
+	pressKeys(e, "Vjzf")
+	if e.statusMsg != "cannot create folds with foldmethod=indent" {
+		t.Fatalf("got status %q", e.statusMsg)
+	}
+}
+
+func TestIndentFoldsFollowEdits(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "config.yaml", foldYAMLSource), withFoldMethod("indent"))
+	e.cy = 7
+	pressKeys(e, "zc")
+
+	// a new top-level key above moves the closed logging fold down
+	e.cy = 0
+	pressKeys(e, "Oname: app\x1b")
+	assertFolds(t, e, map[int]int{1: 7, 3: 5, 8: 9})
+	if !e.foldRanges[8].folded || e.foldRanges[1].folded {
+		t.Fatalf("fold state did not follow the edit: %v", foldSpans(e))
+	}
+
+	// a deeper line extends the fold it belongs to
+	e.cy, e.cx = 9, 0
+	pressKeys(e, "o  format: json\x1b")
+	assertFolds(t, e, map[int]int{1: 7, 3: 5, 8: 10})
+}
+
+// TestTextFoldsFollowRandomEdits checks that indent and marker folds
+// recomputed around the edited lines match a full recomputation.
+func TestTextFoldsFollowRandomEdits(t *testing.T) {
+	for _, tc := range []struct {
+		method   string
+		snippets []string
+	}{
+		{foldIndent, []string{"\n", "\n  ", "\n    x", "\nkey:", "  ", "y", "\n\n"}},
+		{foldMarker, []string{"\n", "{{{", "}}}", "\n{{{ a", "\n}}}", "x"}},
+	} {
+		t.Run(tc.method, func(t *testing.T) {
+			e := newFileTestEditor(t, writeTestFile(t, "notes.txt", foldYAMLSource+"a {{{\n  b {{{\n  }}}\n}}}\n"), withFoldMethod(tc.method))
+			e.updateFolds()
+			rng := rand.New(rand.NewSource(1))
+			for step := 0; step < 300; step++ {
+				for i := rng.Intn(3); i >= 0; i-- {
+					n := e.buffer.Len()
+					if n > 0 && rng.Intn(3) == 0 {
+						start := rng.Intn(n)
+						_ = e.buffer.Delete(start, start+1+rng.Intn(min(8, n-start)))
+					} else {
+						_ = e.buffer.Insert(rng.Intn(n+1), tc.snippets[rng.Intn(len(tc.snippets))])
+					}
+				}
+				got := foldSpans(e)
+				want := make(map[int]int)
+				for _, f := range e.GetFoldableRanges() {
+					if end, ok := want[f.startLine]; !ok || f.endLine > end {
+						want[f.startLine] = f.endLine
+					}
+				}
+				if !maps.Equal(got, want) {
+					t.Fatalf("step %d: folds of %q = %v, want %v", step, e.buffer.String(), got, want)
+				}
+			}
+		})
+	}
+}
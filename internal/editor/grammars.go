@@ -0,0 +1,74 @@
+package editor
+
+import (
+	"sort"
+	"unsafe"
+
+	tree_sitter_markdown "github.com/tree-sitter-grammars/tree-sitter-markdown/bindings/go"
+	tree_sitter_yaml "github.com/tree-sitter-grammars/tree-sitter-yaml/bindings/go"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+	tree_sitter_bash "github.com/tree-sitter/tree-sitter-bash/bindings/go"
+	tree_sitter_c "github.com/tree-sitter/tree-sitter-c/bindings/go"
+	tree_sitter_css "github.com/tree-sitter/tree-sitter-css/bindings/go"
+	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
+	tree_sitter_html "github.com/tree-sitter/tree-sitter-html/bindings/go"
+	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
+	tree_sitter_json "github.com/tree-sitter/tree-sitter-json/bindings/go"
+	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
+	tree_sitter_rust "github.com/tree-sitter/tree-sitter-rust/bindings/go"
+	tree_sitter_typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
+)
+
+// grammar is a tree-sitter grammar bundled with the editor.
+type grammar struct {
+	name     string // query directory under queries/
+	language func() unsafe.Pointer
+}
+
+// grammars maps filetype names (see filetypes) and common aliases to the
+// bundled grammars.
+var grammars = map[string]grammar{
+	"go":              {"go", tree_sitter_go.Language},
+	"python":          {"python", tree_sitter_python.Language},
+	"py":              {"python", tree_sitter_python.Language},
+	"javascript":      {"javascript", tree_sitter_javascript.Language},
+	"jsx":             {"javascript", tree_sitter_javascript.Language},
+	"js":              {"javascript", tree_sitter_javascript.Language},
+	"typescript":      {"typescript", tree_sitter_typescript.LanguageTypescript},
+	"ts":              {"typescript", tree_sitter_typescript.LanguageTypescript},
+	"typescriptreact": {"tsx", tree_sitter_typescript.LanguageTSX},
+	"tsx":             {"tsx", tree_sitter_typescript.LanguageTSX},
+	"rust":            {"rust", tree_sitter_rust.Language},
+	"c":               {"c", tree_sitter_c.Language},
+	"shell":           {"bash", tree_sitter_bash.Language},
+	"bash":            {"bash", tree_sitter_bash.Language},
+	"sh":              {"bash", tree_sitter_bash.Language},
+	"json":            {"json", tree_sitter_json.Language},
+	"html":            {"html", tree_sitter_html.Language},
+	"css":             {"css", tree_sitter_css.Language},
+	"yaml":            {"yaml", tree_sitter_yaml.Language},
+	"yml":             {"yaml", tree_sitter_yaml.Language},
+	"markdown":        {"markdown", tree_sitter_markdown.Language},
+	// the inline grammar parses emphasis, code spans and links; markdown
+	// injects it into the text of every block
+	"markdown_inline": {"markdown_inline", tree_sitter_markdown.InlineLanguage},
+}
+
+// lookupGrammar returns the grammar for a filetype or alias.
+func lookupGrammar(name string) (grammar, *sitter.Language, bool) {
+	g, ok := grammars[name]
+	if !ok {
+		return grammar{}, nil, false
+	}
+	return g, sitter.NewLanguage(g.language()), true
+}
+
+// GrammarNames lists the filetypes that have a bundled grammar.
+func GrammarNames() []string {
+	names := make([]string, 0, len(grammars))
+	for name := range grammars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
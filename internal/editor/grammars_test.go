@@ -0,0 +1,99 @@
+package editor
+
+import "testing"
+
+func TestBundledGrammarsHighlight(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cases := []struct {
+		lang, src, sub, want string
+	}{
+		{"go", "package main\n", "package", "keyword"},
+		{"python", "def f():\n    pass\n", "def", "keyword"},
+		{"javascript", "const x = <div id=\"a\" />;\n", "div", "tag"},
+		{"typescript", "let n: number = f<T>(x);\n", "number", "type.builtin"},
+		{"typescriptreact", "const el = <App<string> x={1} />;\n", "string", "type.builtin"},
+		{"rust", "fn main() { let s = String::new(); }\n", "fn", "keyword"},
+		{"c", "#include <stdio.h>\nint main(void) { return 0; }\n", "main", "function"},
+		{"shell", "echo \"$HOME\" # hi\n", "# hi", "comment"},
+		{"json", "{\"key\": [1, true]}\n", "\"key\"", "string.special.key"},
+		{"yaml", "name: vb # the editor\nport: 8080\n", "name", "property"},
+		{"yaml", "name: vb # the editor\nport: 8080\n", "8080", "number"},
+		{"markdown", "# Title\n\nSome *emphasis* and `code`.\n", "Title", "markup.heading"},
+		{"markdown", "# Title\n\nSome *emphasis* and `code`.\n", "emphasis*", "markup.italic"},
+		{"markdown", "# Title\n\nSome *emphasis* and `code`.\n", "code`", "markup.raw"},
+	}
+	for _, c := range cases {
+		t.Run(c.lang+"/"+c.sub, func(t *testing.T) {
+			p, err := NewTreeSitterParser(c.lang)
+			if err != nil || p == nil {
+				t.Fatalf("parser: %v", err)
+			}
+			defer p.Close()
+			if p.QueryError() != nil {
+				t.Fatalf("query: %v", p.QueryError())
+			}
+			p.Parse(c.src)
+			if root := p.GetTree().RootNode(); root.HasError() {
+				t.Fatalf("parse error: %s", root.ToSexp())
+			}
+			if got := captureAt(t, p, c.src, c.sub); got != c.want {
+				t.Errorf("%q: got capture %q, want %q", c.sub, got, c.want)
+			}
+		})
+	}
+}
+
+func TestEveryGrammarQueryCompiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, name := range GrammarNames() {
+		p, err := NewTreeSitterParser(name)
+		if err != nil || p == nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if p.highlights == nil {
+			t.Errorf("%s: no highlights query", name)
+		}
+		p.Close()
+	}
+}
+
+func TestRustFolds(t *testing.T) {
+	src := "struct P {\n    x: i32,\n}\n\nimpl P {\n    fn x(&self) -> i32 {\n        self.x\n    }\n}\n"
+	e := newFileTestEditor(t, writeTestFile(t, "lib.rs", src), nil)
+	if e.buf().parser == nil {
+		t.Fatal("expected a rust parser")
+	}
+	var starts []int
+	for _, f := range e.buf().foldRanges {
+		starts = append(starts, f.startLine)
+	}
+	want := map[int]bool{0: true, 4: true, 5: true}
+	if len(starts) != len(want) {
+		t.Fatalf("fold starts = %v, want lines 0, 4 and 5", starts)
+	}
+	for _, s := range starts {
+		if !want[s] {
+			t.Fatalf("fold starts = %v, want lines 0, 4 and 5", starts)
+		}
+	}
+}
+
+func TestYAMLFolds(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "config.yaml", foldYAMLSource), withFoldMethod("syntax"))
+	if e.buf().parser == nil || e.foldMethod() != foldSyntax {
+		t.Fatal("expected syntax folds from a yaml parser")
+	}
+	assertFolds(t, e, map[int]int{0: 6, 2: 4, 7: 8})
+}
+
+func TestMarkdownFolds(t *testing.T) {
+	src := "# A\n\ntext\n\n## B\n\n```sh\nls\n```\n\n# C\nend\n"
+	e := newFileTestEditor(t, writeTestFile(t, "README.md", src), nil)
+	if e.buf().parser == nil {
+		t.Fatal("expected a markdown parser")
+	}
+	// sections run to the next heading of their level, without the
+	// newline ending them
+	assertFolds(t, e, map[int]int{0: 9, 4: 9, 6: 8, 10: 11})
+}
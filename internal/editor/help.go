@@ -15,6 +15,9 @@ var helpTopics = map[string]string{
 	"undo":            helpUndo,
 	"macros":          helpMacros,
 	"splits":          helpSplits,
+	"folding":         helpFolding,
+	"folds":           helpFolding, // Alias for folding
+	"lsp":             helpLSP,
 	"windows":         helpSplits, // Alias for splits
 }
 
@@ -29,6 +32,7 @@ QUICK START
   :help completion        - Word completion
   :help undo              - Undo system
   :help macros            - Macro recording/playback
+  :help lsp               - Language servers
 
 BASIC COMMANDS
   :e file     - Open file
@@ -86,16 +90,45 @@ Normal Mode:
   / n N       - Search
   q{a-z}      - Record macro
   @{a-z}      - Play macro
+  ]f [f       - Next/prev function
+  ]c [c       - Next/prev class
+  ]d [d       - Next/prev diagnostic
+  K           - Hover documentation from the language server
+  gr          - List references (]q [q step through them)
+  gR          - Rename the symbol under the cursor
+  ga          - Code actions
+  gO          - Toggle the symbol outline
+  za zo zc    - Toggle, open, close fold
+  zR zM       - Open, close all folds
+  zj zk       - Next/prev fold
+
+Text Objects (after d c y or in visual mode):
+  iw aw ip ap - Word, paragraph
+  i( a( i" a" - Pairs and quotes
+  if af       - Function body, function
+  ic ac       - Class body, class
+  ia aa       - Argument, argument with comma
+
+Visual Mode:
+  +           - Grow selection to the enclosing syntax node
+  -           - Shrink back to the previous selection
+  zf          - Fold the selected lines (foldmethod=manual)
+  ga          - Code actions for the selection
+  gq          - Format the selected lines with the language server
 
 Insert Mode:
   Esc         - Exit insert
   Ctrl-N/P    - Completion
+  Ctrl-Y      - Accept the completion
+  Ctrl-S      - Signature help
 
 Commands:
   :w :q :wq   - Save, quit
   :e file     - Open file
   :bn :bp     - Next/prev buffer
   :macros     - View recorded macros
+  :mkview     - Save cursor, scroll, folds and marks
+  :loadview   - Restore them
 `
 
 const helpVimDifferences = `DIFFERENCES FROM VIM
@@ -162,13 +195,39 @@ Usage:
   1. Type a few characters
   2. Press Ctrl-N for next match
   3. Press Ctrl-P for previous match
-  4. Esc to cancel
+  4. Ctrl-Y or Esc to accept
+
+Language Servers:
+  Candidates from the buffer's language server come first, with their
+  kind and detail; the selected one's documentation shows in a float.
+  Trigger characters such as . list the server's candidates without
+  selecting one, and typing narrows the list. Accepting a candidate
+  applies its other edits, such as an import.
 `
 
 const helpUndo = `UNDO AND REDO SYSTEM
 
 u           - Undo last change
 Ctrl-R      - Redo
+g-          - Go to the previous state in time (crosses branches)
+g+          - Go to the next state in time (crosses branches)
+
+Undo Tree:
+  Changes made after an undo start a new branch; nothing is discarded.
+  Every state has a sequence number and a timestamp.
+  :earlier {N}     - Go N states back in time
+  :earlier {N}s    - Go back N seconds (also m, h, d)
+  :later {N}       - Go N states forward in time
+  :later {N}m      - Go forward N minutes (also s, h, d)
+  :undolist        - Show the branches of the undo tree
+
+Persistent Undo:
+  vb.opt.undofile = true saves the undo tree on write and restores it
+  when the file is reopened unchanged.
+
+Cursor:
+  u puts the cursor back where it was before the change,
+  Ctrl-R where the change left it.
 
 Transaction Grouping:
   - Each insert session = one undo
@@ -223,6 +282,106 @@ Tips:
 See also: :help buffers
 `
 
+const helpFolding = `FOLDING
+
+Fold Methods (vb.opt.foldmethod):
+  syntax      - Functions, blocks and other tree-sitter nodes (default);
+                files without a parser fold by indent
+  indent      - A line and the lines below it indented deeper
+  marker      - From a line containing {{{ to its matching }}}
+  manual      - Folds made with zf
+
+Folds nest. Keys act on the folds around the cursor line:
+  za          - Open the closed fold, else close the innermost open one
+  zo          - Open one level ({count}zo opens more)
+  zc          - Close the innermost open fold ({count}zc closes more)
+  zR          - Open all folds
+  zM          - Close all folds
+  zj          - Move to the start of the next fold
+  zk          - Move to the end of the previous fold
+
+Manual Folds:
+  zf          - Fold the lines of the visual selection
+  zd          - Delete the innermost fold at the cursor
+  zE          - Delete all folds
+  Manual folds move with the text when lines are added or removed above.
+
+Commands:
+  :foldopen :foldclose :foldall :unfoldall
+  :foldinfo   - Show the fold method and the number of folds
+
+Views:
+  :mkview     - Save the fold state, cursor, scroll position and marks
+  :loadview   - Restore them
+  vb.opt.autoview = true saves views on write, :bd and quit and restores
+  them when the file is opened.
+`
+
+const helpLSP = `LANGUAGE SERVERS
+
+Reading a file starts the language server configured for its filetype
+(vb.lsp.setup) and attaches the buffer to it. One server runs per command
+and project root; the root is the nearest directory with one of the
+server's root markers (go.mod, package.json, ...). Crashed servers are
+restarted up to three times. Edits are sent when typing pauses, as changed
+ranges to servers that sync incrementally.
+
+Commands:
+  :LspInfo            - Show servers, their state, root and attached buffers
+  :LspRestart [all]   - Restart the current buffer's server (or all)
+  :LspStop [all]      - Stop the current buffer's server (or all)
+  :LspLog [all]       - Show the server's stderr and messages (or all servers')
+  :diagnostics        - List the diagnostics of all buffers (Enter jumps)
+
+Diagnostics:
+  Published diagnostics get a sign in the gutter (E W I H), an underline
+  in the severity's color, and the cursor line shows the worst message.
+  ]d [d               - Next/prev diagnostic
+
+Documentation:
+  K                   - Show the hover documentation of the symbol under
+                        the cursor in a float; moving the cursor closes it
+  Ctrl-S (insert)     - Show the signature of the call at the cursor; it
+                        also opens when typing the server's trigger
+                        characters, such as (, and follows the argument
+  Ctrl-F Ctrl-B       - Scroll the float
+  Esc                 - Close the float
+
+Completion:
+  Ctrl-N Ctrl-P       - Complete with the server's candidates and buffer
+                        words (:help completion)
+  .                   - The server's trigger characters list its
+                        candidates; typing narrows them
+
+Navigation and Refactoring:
+  gr  :references     - List the references to the symbol under the
+                        cursor; Enter jumps to one
+  ]q [q               - Next/prev entry of the reference list
+  gR  :rename {name}  - Rename the symbol across the project; open buffers
+                        are changed, other files are written
+  ga  :codeaction     - List the code actions at the cursor (or for the
+                        visual selection); * marks the preferred ones
+  :format             - Format the buffer; visual gq formats the lines
+  gO  :outline        - Toggle the outline of the buffer's symbols on the
+                        right; it follows the cursor, Ctrl-W l focuses it,
+                        j k move, Enter jumps, q closes
+  :symbols [query]    - Search the workspace symbols; typing refines the
+                        query, Enter jumps to the selected one
+  :incoming :outgoing - Show the callers or callees of the function under
+                        the cursor as a tree; l expands (asking the
+                        server), h collapses, Tab toggles, Enter jumps
+  :supertypes :subtypes - The same for the types the type under the
+                        cursor builds on, or that build on it
+
+Options:
+  vb.opt.lsp = false  - Start no language servers
+  vb.opt.formatonsave - Format the buffer before writing it
+  vb.opt.semantictokens - Layer the server's semantic tokens over the
+                        tree-sitter highlighting (default true)
+  vb.opt.inlayhints   - Show the server's inlay hints, such as parameter
+                        names, as virtual text (default false)
+`
+
 // GetHelp returns help content for a given topic
 func GetHelp(topic string) (string, bool) {
 	topic = strings.TrimSpace(strings.ToLower(topic))
@@ -0,0 +1,292 @@
+package editor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+	"github.com/gdamore/tcell/v2"
+)
+
+// hierarchyKind is the relation a hierarchy view follows
+type hierarchyKind int
+
+const (
+	hierarchyIncoming   hierarchyKind = iota // callers
+	hierarchyOutgoing                        // functions called
+	hierarchySupertypes                      // types it is built on
+	hierarchySubtypes                        // types built on it
+)
+
+// hierarchyTitles are the popup titles of the hierarchy kinds
+var hierarchyTitles = map[hierarchyKind]string{
+	hierarchyIncoming:   "INCOMING CALLS",
+	hierarchyOutgoing:   "OUTGOING CALLS",
+	hierarchySupertypes: "SUPERTYPES",
+	hierarchySubtypes:   "SUBTYPES",
+}
+
+// HierarchyNode is a symbol in a call or type hierarchy
+type HierarchyNode struct {
+	item     lsp.HierarchyItem
+	calls    int // number of calls it stands for, in call hierarchies
+	children []*HierarchyNode
+	expanded bool
+	loaded   bool   // children were asked from the server
+	message  string // shown after it while loading or after an error
+	parent   *HierarchyNode
+}
+
+// HierarchyTree is the call or type hierarchy of a symbol. Like the file
+// tree it is flattened to the visible nodes; the children of a node are
+// asked from the server the first time it is expanded.
+type HierarchyTree struct {
+	srv    *lspServer
+	kind   hierarchyKind
+	roots  []*HierarchyNode
+	flat   []*HierarchyNode // flattened visible nodes
+	cursor int              // current selection index in flat list
+	ctx    context.Context  // of the requests, cancelled on close
+	cancel func()
+}
+
+// rebuildFlat creates a flat list of visible nodes
+func (t *HierarchyTree) rebuildFlat() {
+	t.flat = t.flat[:0]
+	for _, root := range t.roots {
+		t.flattenNode(root)
+	}
+	t.cursor = clamp(t.cursor, 0, max(0, len(t.flat)-1))
+}
+
+// flattenNode recursively flattens visible nodes
+func (t *HierarchyTree) flattenNode(node *HierarchyNode) {
+	t.flat = append(t.flat, node)
+	if node.expanded {
+		for _, child := range node.children {
+			t.flattenNode(child)
+		}
+	}
+}
+
+// getCurrentNode returns the currently selected node
+func (t *HierarchyTree) getCurrentNode() *HierarchyNode {
+	if t.cursor >= 0 && t.cursor < len(t.flat) {
+		return t.flat[t.cursor]
+	}
+	return nil
+}
+
+// getDepth returns the depth of a node in the tree
+func (t *HierarchyTree) getDepth(node *HierarchyNode) int {
+	depth := 0
+	for current := node.parent; current != nil; current = current.parent {
+		depth++
+	}
+	return depth
+}
+
+// getDisplayLines returns the lines of the visible nodes: the symbol, its
+// kind and location, and how many calls it stands for
+func (t *HierarchyTree) getDisplayLines() []string {
+	lines := make([]string, len(t.flat))
+	for i, node := range t.flat {
+		prefix := "▶"
+		switch {
+		case node.loaded && len(node.children) == 0:
+			prefix = " "
+		case node.expanded:
+			prefix = "▼"
+		}
+		line := fmt.Sprintf("%s%s %s %s  %s:%d", strings.Repeat("  ", t.getDepth(node)), prefix,
+			node.item.Name, node.item.Kind, relativePath(lsp.PathFromURI(node.item.URI)),
+			node.item.SelectionRange.Start.Line+1)
+		if node.calls > 1 {
+			line += fmt.Sprintf(" (%d calls)", node.calls)
+		}
+		if node.message != "" {
+			line += "  " + node.message
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+// fetch asks client for the children of item
+func (t *HierarchyTree) fetch(client *lsp.Client, item lsp.HierarchyItem) ([]*HierarchyNode, error) {
+	var nodes []*HierarchyNode
+	switch t.kind {
+	case hierarchyIncoming:
+		calls, err := client.IncomingCalls(t.ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range calls {
+			nodes = append(nodes, &HierarchyNode{item: call.From, calls: len(call.FromRanges)})
+		}
+	case hierarchyOutgoing:
+		calls, err := client.OutgoingCalls(t.ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		for _, call := range calls {
+			nodes = append(nodes, &HierarchyNode{item: call.To, calls: len(call.FromRanges)})
+		}
+	default:
+		fetchTypes := client.Supertypes
+		if t.kind == hierarchySubtypes {
+			fetchTypes = client.Subtypes
+		}
+		items, err := fetchTypes(t.ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			nodes = append(nodes, &HierarchyNode{item: it})
+		}
+	}
+	return nodes, nil
+}
+
+// openHierarchy implements :incoming, :outgoing, :supertypes and
+// :subtypes, showing the hierarchy of the symbol under the cursor as a
+// tree in a popup
+func (e *Editor) openHierarchy(kind hierarchyKind) {
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	calls := kind == hierarchyIncoming || kind == hierarchyOutgoing
+	caps := srv.client.Capabilities()
+	switch {
+	case calls && !bool(caps.CallHierarchyProvider):
+		e.statusMsg = srv.cfg.Command + " does not support call hierarchies"
+		return
+	case !calls && !bool(caps.TypeHierarchyProvider):
+		e.statusMsg = srv.cfg.Command + " does not support type hierarchies"
+		return
+	}
+	e.lspSyncChanges()
+	pos := ds.Position(e.buffer, e.posFromCursor())
+	bv := e.buf()
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &HierarchyTree{srv: srv, kind: kind, ctx: ctx, cancel: cancel}
+
+	go func() {
+		prepare := ds.PrepareCallHierarchy
+		if !calls {
+			prepare = ds.PrepareTypeHierarchy
+		}
+		items, err := prepare(ctx, pos.Line, pos.Character)
+		e.post(func() {
+			if e.buf() != bv || e.mode != ModeNormal || e.popupActive {
+				cancel()
+				return
+			}
+			switch {
+			case err != nil:
+				cancel()
+				e.statusMsg = "lsp: " + err.Error()
+			case len(items) == 0:
+				cancel()
+				e.statusMsg = "no symbol with a hierarchy at the cursor"
+			default:
+				for _, item := range items {
+					t.roots = append(t.roots, &HierarchyNode{item: item})
+				}
+				e.popupFixedH = 15
+				e.openPopupList(hierarchyTitles[kind]+": "+items[0].Name, nil, func(i int) {
+					if i < len(t.flat) {
+						item := t.flat[i].item
+						e.jumpToLocation(lspLocation{
+							path: lsp.PathFromURI(item.URI),
+							pos:  item.SelectionRange.Start,
+							enc:  t.srv.client.PositionEncoding(),
+						})
+					}
+				})
+				e.hierarchy = t
+				for _, root := range t.roots {
+					e.expandHierarchyNode(t, root)
+				}
+			}
+		})
+	}()
+}
+
+// expandHierarchyNode shows the children of node, asking the server for
+// them the first time
+func (e *Editor) expandHierarchyNode(t *HierarchyTree, node *HierarchyNode) {
+	node.expanded = true
+	if client := t.srv.client; client == nil {
+		node.message = t.srv.cfg.Command + " stopped"
+	} else if !node.loaded && node.message == "" {
+		node.message = "loading"
+		go func() {
+			children, err := t.fetch(client, node.item)
+			e.post(func() {
+				if e.hierarchy != t {
+					return
+				}
+				node.loaded, node.message = true, ""
+				if err != nil {
+					node.message = "lsp: " + err.Error()
+				}
+				for _, child := range children {
+					child.parent = node
+				}
+				node.children = children
+				e.refreshHierarchy()
+			})
+		}()
+	}
+	e.refreshHierarchy()
+}
+
+// refreshHierarchy shows the visible nodes of the hierarchy in the popup
+func (e *Editor) refreshHierarchy() {
+	t := e.hierarchy
+	t.rebuildFlat()
+	e.popupLines = t.getDisplayLines()
+	e.popupCursor = t.cursor
+}
+
+// handleHierarchyInput handles a key while the hierarchy view is open: j
+// and k move, l expands a node, h collapses it or moves to its parent, Tab
+// toggles it, Enter jumps to its symbol and q or Esc closes the view
+func (e *Editor) handleHierarchyInput(k *tcell.EventKey) {
+	t := e.hierarchy
+	node := t.getCurrentNode()
+	switch {
+	case k.Key() == tcell.KeyEnter || k.Key() == tcell.KeyEsc ||
+		k.Key() == tcell.KeyRune && k.Rune() == 'q':
+		e.popupCursor = t.cursor
+		e.handlePopupList(k)
+		return
+	case k.Key() == tcell.KeyDown || k.Key() == tcell.KeyRune && k.Rune() == 'j':
+		t.cursor = min(t.cursor+1, max(0, len(t.flat)-1))
+	case k.Key() == tcell.KeyUp || k.Key() == tcell.KeyRune && k.Rune() == 'k':
+		t.cursor = max(t.cursor-1, 0)
+	case node == nil:
+	case k.Key() == tcell.KeyRight || k.Key() == tcell.KeyRune && k.Rune() == 'l':
+		e.expandHierarchyNode(t, node)
+	case k.Key() == tcell.KeyLeft || k.Key() == tcell.KeyRune && k.Rune() == 'h':
+		if node.expanded {
+			node.expanded = false
+		} else if node.parent != nil {
+			for i, n := range t.flat {
+				if n == node.parent {
+					t.cursor = i
+				}
+			}
+		}
+	case k.Key() == tcell.KeyTab:
+		if node.expanded {
+			node.expanded = false
+		} else {
+			e.expandHierarchyNode(t, node)
+		}
+	}
+	e.refreshHierarchy()
+}
@@ -0,0 +1,105 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const hierarchySource = "package main\n\ntype base struct {\n\tid int\n}\n\ntype server struct {\n\tbase\n\taddr string\n}\n\nfunc main() {\n\tstart()\n\tstart()\n}\n\nfunc start() {\n\tlisten()\n}\n\nfunc listen() {}\n"
+
+// openTestHierarchy runs cmd with the cursor at line:col and waits until
+// the hierarchy shows n nodes
+func openTestHierarchy(t *testing.T, e *Editor, cmd string, line, col, n int) {
+	t.Helper()
+	e.cy, e.cx = line, col
+	e.exec(cmd)
+	waitForLSP(t, e, cmd, func() bool { return e.hierarchy != nil && len(e.hierarchy.flat) == n })
+}
+
+// checkPopupLines fails unless the popup lines start with the prefixes
+func checkPopupLines(t *testing.T, e *Editor, prefixes ...string) {
+	t.Helper()
+	if len(e.popupLines) != len(prefixes) {
+		t.Fatalf("popup lines = %q, want %q", e.popupLines, prefixes)
+	}
+	for i, prefix := range prefixes {
+		if !strings.HasPrefix(e.popupLines[i], prefix) {
+			t.Fatalf("popup lines = %q, want %q", e.popupLines, prefixes)
+		}
+	}
+}
+
+func TestCallHierarchy(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": hierarchySource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	openTestHierarchy(t, e, "incoming", 20, 5, 2)
+	if e.popupTitle != "INCOMING CALLS: listen" {
+		t.Errorf("title = %q", e.popupTitle)
+	}
+	checkPopupLines(t, e, "▼ listen function", "  ▶ start function")
+
+	// l asks for the callers of start
+	pressKeys(e, "jl")
+	waitForLSP(t, e, "the callers of start", func() bool { return len(e.hierarchy.flat) == 3 })
+	checkPopupLines(t, e, "▼ listen function", "  ▼ start function", "    ▶ main function")
+	if !strings.HasSuffix(e.popupLines[2], "(2 calls)") {
+		t.Errorf("main should count its two calls: %q", e.popupLines[2])
+	}
+
+	// h collapses, then moves to the parent; Tab toggles
+	pressKeys(e, "h")
+	checkPopupLines(t, e, "▼ listen function", "  ▶ start function")
+	pressKeys(e, "h")
+	if e.popupCursor != 0 {
+		t.Errorf("h on a collapsed node should select its parent, cursor %d", e.popupCursor)
+	}
+	e.handleKey(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	checkPopupLines(t, e, "▶ listen function")
+	e.handleKey(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+	checkPopupLines(t, e, "▼ listen function", "  ▶ start function")
+
+	// Enter jumps to the selected symbol
+	pressKeys(e, "j")
+	e.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	if e.popupActive || e.hierarchy != nil {
+		t.Fatal("Enter should close the hierarchy")
+	}
+	if e.cy != 16 || e.cx != 5 {
+		t.Errorf("jumped to %d:%d, want start at 16:5", e.cy, e.cx)
+	}
+
+	openTestHierarchy(t, e, "outgoing", 11, 5, 2)
+	checkPopupLines(t, e, "▼ main function", "  ▶ start function")
+	e.handleKey(tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone))
+	if e.popupActive || e.hierarchy != nil {
+		t.Error("Esc should close the hierarchy")
+	}
+}
+
+func TestTypeHierarchy(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": hierarchySource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	openTestHierarchy(t, e, "supertypes", 6, 5, 2)
+	checkPopupLines(t, e, "▼ server struct", "  ▶ base struct")
+	pressKeys(e, "q")
+
+	openTestHierarchy(t, e, "subtypes", 2, 5, 2)
+	checkPopupLines(t, e, "▼ base struct", "  ▶ server struct")
+	pressKeys(e, "q")
+
+	// no hierarchy off a symbol
+	e.cy, e.cx = 1, 0
+	e.exec("subtypes")
+	waitForLSP(t, e, "the status", func() bool { return e.statusMsg == "no symbol with a hierarchy at the cursor" })
+}
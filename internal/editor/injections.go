@@ -0,0 +1,373 @@
+package editor
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// maxInjectionDepth bounds how deeply injected languages may nest, e.g.
+// HTML in a JavaScript template string in an HTML script element.
+const maxInjectionDepth = 4
+
+// injectionLayer is an embedded region of the source parsed with its own
+// language, e.g. the contents of a <script> element.
+type injectionLayer struct {
+	lang   string
+	ranges []sitter.Range
+	parser *TreeSitterParser
+}
+
+func (l *injectionLayer) overlaps(start, end int) bool {
+	for _, r := range l.ranges {
+		if int(r.EndByte) >= start && int(r.StartByte) <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// updateInjections brings the injection layers up to date after a parse.
+// After an incremental parse only the byte ranges the edits and the
+// reparse touched are searched again; layers elsewhere were moved by the
+// edits and are kept as they are. A layer found again in an edited region
+// reparses incrementally from the tree of the layer it replaces. Child
+// parsers are reused by language. Callers hold p.mu.
+func (p *TreeSitterParser) updateInjections() {
+	old := p.layers
+	p.layers = nil
+	p.layersStale = false
+	if p.injections == nil || p.tree == nil || p.layer >= maxInjectionDepth {
+		closeLayers(old)
+		return
+	}
+
+	var dirty []span
+	if p.incremental && !p.injections.hasProperty("injection.combined") {
+		dirty = p.injectionDirty()
+		stale := old[:0:0]
+		for _, l := range old {
+			if l.touches(dirty) {
+				stale = append(stale, l)
+			} else {
+				l.parser.settle()
+				p.layers = append(p.layers, l)
+			}
+		}
+		old = stale
+	}
+
+	kept := p.layers
+	pool := make(map[string][]*TreeSitterParser)
+	for _, l := range old {
+		pool[l.lang] = append(pool[l.lang], l.parser)
+	}
+	for _, l := range p.findInjections(dirty) {
+		if slices.ContainsFunc(kept, l.equal) {
+			continue
+		}
+		incremental := false
+		if i := slices.IndexFunc(old, l.follows); i >= 0 {
+			l.parser, incremental = old[i].parser, true
+			old = slices.Delete(old, i, i+1)
+			pool[l.lang] = slices.DeleteFunc(pool[l.lang], func(c *TreeSitterParser) bool { return c == l.parser })
+		} else if free := pool[l.lang]; len(free) > 0 {
+			l.parser, pool[l.lang] = free[len(free)-1], free[:len(free)-1]
+			old = slices.DeleteFunc(old, func(o *injectionLayer) bool { return o.parser == l.parser })
+		} else {
+			child, err := NewTreeSitterParser(l.lang)
+			if err != nil || child == nil {
+				continue
+			}
+			child.layer = p.layer + 1
+			l.parser = child
+		}
+		if !l.parser.parseRanges(p.src, l.ranges, incremental) {
+			l.parser.Close()
+			continue
+		}
+		p.layers = append(p.layers, l)
+	}
+	for _, free := range pool {
+		for _, child := range free {
+			child.Close()
+		}
+	}
+}
+
+// injectionDirty returns the byte ranges of the text the last parse's
+// edits replaced and of the syntax it changed. Callers hold p.mu.
+func (p *TreeSitterParser) injectionDirty() []span {
+	var dirty []span
+	for _, ed := range p.lastEdits {
+		start, oldEnd, newEnd := int(ed.StartByte), int(ed.OldEndByte), int(ed.NewEndByte)
+		for i, d := range dirty {
+			switch {
+			case d.end < start:
+			case d.start > oldEnd:
+				dirty[i] = span{d.start + newEnd - oldEnd, d.end + newEnd - oldEnd}
+			default:
+				dirty[i] = span{min(d.start, start), max(d.end+newEnd-oldEnd, newEnd)}
+			}
+		}
+		dirty = append(dirty, span{start, newEnd})
+	}
+	for _, r := range p.changed {
+		dirty = append(dirty, span{int(r.StartByte), int(r.EndByte)})
+	}
+	return dirty
+}
+
+// touches reports whether a range of the layer overlaps one of spans.
+func (l *injectionLayer) touches(spans []span) bool {
+	for _, s := range spans {
+		if l.overlaps(s.start, s.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal reports whether o is the same region as l, in the same language.
+func (l *injectionLayer) equal(o *injectionLayer) bool {
+	return l.lang == o.lang && slices.Equal(l.ranges, o.ranges)
+}
+
+// follows reports whether l is the edited successor of the old layer o: the
+// same language over ranges overlapping its moved ones. o's tree can then
+// be reparsed incrementally for l.
+func (l *injectionLayer) follows(o *injectionLayer) bool {
+	if l.lang != o.lang {
+		return false
+	}
+	for _, r := range l.ranges {
+		if o.overlaps(int(r.StartByte), int(r.EndByte)) {
+			return true
+		}
+	}
+	return false
+}
+
+// settle marks the tree of a layer no edit reached as up to date: the edits
+// only moved it, which applyEdit did. Nested layers are settled with it.
+func (p *TreeSitterParser) settle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastEdits, p.changed, p.incremental = p.edits, nil, true
+	p.edits = nil
+	p.layersStale = false
+	for _, l := range p.layers {
+		l.parser.settle()
+	}
+}
+
+// shiftRange moves r past edit. An end inside the replaced text moves to
+// the end of the new text, as does a start; callers reparse such ranges.
+func shiftRange(r sitter.Range, edit sitter.InputEdit) sitter.Range {
+	r.StartByte, r.StartPoint = shiftPoint(r.StartByte, r.StartPoint, edit)
+	r.EndByte, r.EndPoint = shiftPoint(r.EndByte, r.EndPoint, edit)
+	return r
+}
+
+func shiftPoint(b uint, pt sitter.Point, edit sitter.InputEdit) (uint, sitter.Point) {
+	switch {
+	case b < edit.StartByte:
+		return b, pt
+	case b < edit.OldEndByte:
+		return edit.NewEndByte, edit.NewEndPosition
+	}
+	moved := sitter.Point{Row: pt.Row - edit.OldEndPosition.Row + edit.NewEndPosition.Row, Column: pt.Column}
+	if pt.Row == edit.OldEndPosition.Row {
+		moved.Column = pt.Column - edit.OldEndPosition.Column + edit.NewEndPosition.Column
+	}
+	return b - edit.OldEndByte + edit.NewEndByte, moved
+}
+
+// findInjections collects the regions the injections query marks, grouped
+// into one layer per match, or per pattern and language for patterns with
+// injection.combined. With dirty set, only matches overlapping those byte
+// ranges are collected.
+func (p *TreeSitterParser) findInjections(dirty []span) []*injectionLayer {
+	if dirty == nil {
+		return p.collectInjections(-1, -1)
+	}
+	var layers []*injectionLayer
+	for _, d := range normalizeSpans(dirty) {
+		for _, l := range p.collectInjections(d.start, d.end) {
+			if !slices.ContainsFunc(layers, l.equal) {
+				layers = append(layers, l)
+			}
+		}
+	}
+	return layers
+}
+
+// collectInjections collects the injections overlapping the bytes from
+// start to end, or all of them when start is negative.
+func (p *TreeSitterParser) collectInjections(start, end int) []*injectionLayer {
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	if start >= 0 {
+		// the cursor's range is half-open and skips nodes ending at its start
+		cursor.SetByteRange(uint(max(0, start-1)), uint(end+1))
+	}
+
+	type combinedKey struct {
+		lang    string
+		pattern uint
+	}
+	names := p.injections.CaptureNames()
+	combined := make(map[combinedKey]*injectionLayer)
+	var layers []*injectionLayer
+
+	matches := cursor.Matches(p.injections.Query, p.tree.RootNode(), nil)
+	for {
+		match := p.injections.nextMatch(&matches, p.src)
+		if match == nil {
+			break
+		}
+		var lang string
+		var isCombined, includeChildren bool
+		for _, prop := range p.injections.PropertySettings(match.PatternIndex) {
+			switch prop.Key {
+			case "injection.language":
+				if prop.Value != nil {
+					lang = *prop.Value
+				}
+			case "injection.combined":
+				isCombined = true
+			case "injection.include-children":
+				includeChildren = true
+			}
+		}
+
+		var ranges []sitter.Range
+		for _, c := range match.Captures {
+			switch names[c.Index] {
+			case "injection.language":
+				lang = string(p.src(int(c.Node.StartByte()), int(c.Node.EndByte())))
+			case "injection.content":
+				ranges = append(ranges, contentRanges(&c.Node, includeChildren)...)
+			}
+		}
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if _, ok := grammars[lang]; !ok || len(ranges) == 0 {
+			continue
+		}
+
+		if !isCombined {
+			layers = append(layers, &injectionLayer{lang: lang, ranges: ranges})
+			continue
+		}
+		key := combinedKey{lang, match.PatternIndex}
+		if l := combined[key]; l != nil {
+			l.ranges = append(l.ranges, ranges...)
+			continue
+		}
+		l := &injectionLayer{lang: lang, ranges: ranges}
+		combined[key] = l
+		layers = append(layers, l)
+	}
+
+	for _, l := range layers {
+		l.ranges = normalizeRanges(l.ranges)
+	}
+	return layers
+}
+
+// contentRanges returns the range of an injection.content node. Unless
+// includeChildren is set, the ranges of its children are left out, so only
+// the node's own text is parsed.
+func contentRanges(node *sitter.Node, includeChildren bool) []sitter.Range {
+	if includeChildren || node.ChildCount() == 0 {
+		return []sitter.Range{node.Range()}
+	}
+	var ranges []sitter.Range
+	r := sitter.Range{StartByte: node.StartByte(), StartPoint: node.StartPosition()}
+	for i := uint(0); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child.StartByte() > r.StartByte {
+			r.EndByte, r.EndPoint = child.StartByte(), child.StartPosition()
+			ranges = append(ranges, r)
+		}
+		r.StartByte, r.StartPoint = child.EndByte(), child.EndPosition()
+	}
+	if node.EndByte() > r.StartByte {
+		r.EndByte, r.EndPoint = node.EndByte(), node.EndPosition()
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// normalizeRanges sorts ranges and merges overlapping ones, as
+// SetIncludedRanges requires.
+func normalizeRanges(ranges []sitter.Range) []sitter.Range {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].StartByte < ranges[j].StartByte })
+	out := ranges[:0]
+	for _, r := range ranges {
+		if n := len(out); n > 0 && r.StartByte <= out[n-1].EndByte {
+			if r.EndByte > out[n-1].EndByte {
+				out[n-1].EndByte, out[n-1].EndPoint = r.EndByte, r.EndPoint
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// parseRanges parses only ranges of the text src reads. It is how
+// injection layers are parsed: incrementally from the edited tree of the
+// layer's previous parse, or from scratch.
+func (p *TreeSitterParser) parseRanges(src textSource, ranges []sitter.Range, incremental bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.parser == nil || p.parser.SetIncludedRanges(ranges) != nil {
+		return false
+	}
+	p.src = src
+	if !incremental {
+		p.edits = nil
+	}
+	p.parse()
+	return true
+}
+
+// normalizeSpans sorts spans and merges overlapping ones.
+func normalizeSpans(spans []span) []span {
+	slices.SortFunc(spans, func(a, b span) int { return a.start - b.start })
+	out := spans[:0]
+	for _, s := range spans {
+		if n := len(out); n > 0 && s.start <= out[n-1].end {
+			out[n-1].end = max(out[n-1].end, s.end)
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// injectedHighlights returns the highlights of every injection layer that
+// overlaps [startByte, endByte]. Callers hold p.mu.
+func (p *TreeSitterParser) injectedHighlights(startByte, endByte int) []Highlight {
+	if p.layersStale {
+		return nil
+	}
+	var hls []Highlight
+	for _, l := range p.layers {
+		if l.overlaps(startByte, endByte) {
+			hls = append(hls, l.parser.GetHighlights(startByte, endByte)...)
+		}
+	}
+	return hls
+}
+
+func closeLayers(layers []*injectionLayer) {
+	for _, l := range layers {
+		l.parser.Close()
+	}
+}
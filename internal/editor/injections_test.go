@@ -0,0 +1,218 @@
+package editor
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+)
+
+func TestInjectionsHTML(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := "<html>\n<style>p { color: red; }</style>\n<script>const n = 42;</script>\n</html>\n"
+	p, err := NewTreeSitterParser("html")
+	if err != nil || p == nil {
+		t.Fatalf("html parser: %v", err)
+	}
+	defer p.Close()
+	p.Parse(src)
+
+	cases := []struct{ sub, want string }{
+		{"html>", "tag"},
+		{"color", "property"},
+		{"const", "keyword"},
+		{"42", "number"},
+	}
+	for _, c := range cases {
+		if got := captureAt(t, p, src, c.sub); got != c.want {
+			t.Errorf("%q: got capture %q, want %q", c.sub, got, c.want)
+		}
+	}
+}
+
+func TestInjectionsTaggedTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := "const el = html`<ul>${items}</ul>`;\nconst s = `<ul></ul>`;\n"
+	p, _ := NewTreeSitterParser("javascript")
+	defer p.Close()
+	p.Parse(src)
+
+	if got := captureAt(t, p, src, "ul>${"); got != "tag" {
+		t.Errorf("tagged template: got %q, want tag", got)
+	}
+	if got := captureAt(t, p, src, "items"); got != "variable" {
+		t.Errorf("substitution: got %q, want variable", got)
+	}
+	// untagged templates stay plain strings
+	plain := strings.LastIndex(src, "ul></ul>")
+	for _, hl := range p.GetHighlights(0, len(src)) {
+		if plain >= hl.StartByte && plain < hl.EndByte && hl.Capture != "string" {
+			t.Errorf("untagged template: unexpected capture %q", hl.Capture)
+		}
+	}
+}
+
+func TestInjectionsGoTemplate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := "package main\n\nvar page = template.Must(template.New(\"p\").Parse(`<p class=\"x\">{{.Title}}</p>`))\nvar s = strings.TrimSpace(`<b>`)\n"
+	p, _ := NewTreeSitterParser("go")
+	defer p.Close()
+	p.Parse(src)
+
+	if got := captureAt(t, p, src, "p class"); got != "tag" {
+		t.Errorf("template: got %q, want tag", got)
+	}
+	if got := captureAt(t, p, src, "class"); got != "attribute" {
+		t.Errorf("template attribute: got %q, want attribute", got)
+	}
+	// strings not handed to Parse stay plain strings
+	if got := captureAt(t, p, src, "b>"); got != "string" {
+		t.Errorf("other string: got %q, want string", got)
+	}
+}
+
+func TestInjectionsMarkdown(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := "---\ntitle: Notes\n---\n\n# Code\n\n```go\nfunc f() {}\n```\n\n```py\nreturn 42\n```\n\n<div>x</div>\n\nSee <b>this</b>.\n"
+	p, _ := NewTreeSitterParser("markdown")
+	defer p.Close()
+	p.Parse(src)
+
+	cases := []struct{ sub, want string }{
+		{"title", "property"},
+		{"func", "keyword"},
+		{"42", "number"},
+		{"div>x", "tag"},
+		{"b>this", "tag"},
+	}
+	for _, c := range cases {
+		if got := captureAt(t, p, src, c.sub); got != c.want {
+			t.Errorf("%q: got capture %q, want %q", c.sub, got, c.want)
+		}
+	}
+}
+
+func TestInjectionsFollowEdits(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	b := buffer.NewFromString("<script>let a = 1;</script>\n")
+	p, _ := NewTreeSitterParser("html")
+	defer p.Close()
+	p.ParseBuffer(b)
+	b.Subscribe(func(ed buffer.Edit) { p.Edit(ed) })
+
+	if err := b.Insert(0, "<p>hi</p>\n"); err != nil {
+		t.Fatal(err)
+	}
+	p.ParseBuffer(b)
+	src := b.String()
+	if got := captureAt(t, p, src, "let"); got != "keyword" {
+		t.Errorf("after edit: got %q, want keyword", got)
+	}
+
+	// an unparsed edit must not shift injected highlights onto the wrong text
+	b.Insert(0, "xxxxxxxx")
+	for _, hl := range p.GetHighlights(0, b.ByteLen()) {
+		if hl.layer > 0 {
+			t.Fatalf("stale injected highlight %v", hl)
+		}
+	}
+}
+
+func TestInjectionsReparseOnlyEditedLayers(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	b := buffer.NewFromString("<script>let a = 1;</script>\n<p>hi</p>\n<script>let b = 2;</script>\n")
+	p, _ := NewTreeSitterParser("html")
+	defer p.Close()
+	p.ParseBuffer(b)
+	b.Subscribe(func(ed buffer.Edit) { p.Edit(ed) })
+	if len(p.layers) != 2 {
+		t.Fatalf("layers = %d, want 2", len(p.layers))
+	}
+	first, second := p.layers[0].parser, p.layers[1].parser
+	firstTree := first.GetTree()
+
+	// edit the second script
+	src := b.String()
+	_ = b.Insert(strings.Index(src, "2;"), "4")
+	p.ParseBuffer(b)
+	if len(p.layers) != 2 || !slices.ContainsFunc(p.layers, func(l *injectionLayer) bool { return l.parser == second }) {
+		t.Fatal("the edited layer should keep its parser")
+	}
+	if _, _, incremental := second.LastParse(); !incremental {
+		t.Error("the edited layer should be reparsed incrementally")
+	}
+	if first.GetTree() != firstTree {
+		t.Error("the layer before the edit should not be reparsed")
+	}
+	if got := captureAt(t, p, b.String(), "42"); got != "number" {
+		t.Errorf("42: got %q, want number", got)
+	}
+
+	// a line inserted above moves both layers without reparsing them
+	firstTree, secondTree := first.GetTree(), second.GetTree()
+	_ = b.Insert(0, "<h1>title</h1>\n")
+	p.ParseBuffer(b)
+	if first.GetTree() != firstTree || second.GetTree() != secondTree {
+		t.Error("layers only moved by an edit should not be reparsed")
+	}
+	if got := captureAt(t, p, b.String(), "let b"); got != "keyword" {
+		t.Errorf("let b: got %q, want keyword", got)
+	}
+}
+
+// randomHTMLEdit makes a small edit biased towards opening and closing
+// injected regions.
+func randomHTMLEdit(rng *rand.Rand, b *buffer.Buffer) {
+	snippets := []string{"x", "<script>", "</script>", "<style>", "</style>", "\n", "let a = 1;", "{", "}", "é", "`", "p { color: red; }"}
+	n := b.Len()
+	if n > 0 && rng.Intn(3) == 0 {
+		start := rng.Intn(n)
+		_ = b.Delete(start, start+1+rng.Intn(min(6, n-start)))
+		return
+	}
+	_ = b.Insert(rng.Intn(n+1), snippets[rng.Intn(len(snippets))])
+}
+
+func TestInjectionsIncrementalMatchFullParse(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := "<html>\n<style>p { color: red; }</style>\n<script>const n = 42;</script>\n<p>text</p>\n<script>\nfunction f() { return `a`; }\n</script>\n</html>\n"
+	b := buffer.NewFromString(src)
+	p, _ := NewTreeSitterParser("html")
+	defer p.Close()
+	p.ParseBuffer(b)
+	b.Subscribe(func(ed buffer.Edit) { p.Edit(ed) })
+
+	rng := rand.New(rand.NewSource(3))
+	for step := 0; step < 60; step++ {
+		for i := rng.Intn(3); i >= 0; i-- {
+			randomHTMLEdit(rng, b)
+		}
+		p.ParseBuffer(b)
+
+		fresh, _ := NewTreeSitterParser("html")
+		fresh.Parse(b.String())
+		if got, want := p.GetHighlights(0, b.ByteLen()), fresh.GetHighlights(0, b.ByteLen()); !reflect.DeepEqual(got, want) {
+			t.Fatalf("step %d: highlights of %q differ\ngot  %v\nwant %v", step, b.String(), got, want)
+		}
+		fresh.Close()
+	}
+}
+
+func TestUserInjectionQuery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeUserQueryFile(t, "python", "injections", "((string_content) @injection.content\n (#set! injection.language \"json\"))\n")
+
+	src := "data = '{\"key\": 1}'\n"
+	p, _ := NewTreeSitterParser("python")
+	defer p.Close()
+	if p.QueryError() != nil {
+		t.Fatalf("query: %v", p.QueryError())
+	}
+	p.Parse(src)
+	if got := captureAt(t, p, src, "\"key\""); got != "string.special.key" {
+		t.Errorf("got %q, want string.special.key", got)
+	}
+}
@@ -3,12 +3,17 @@ package editor
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
 	"github.com/gdamore/tcell/v2"
 )
 
 func (e *Editor) handleKey(k *tcell.EventKey) bool {
+	defer e.settleUndoCursor()
+	defer e.closeFloatIfMoved()
+
 	// Record key for macro (do this early, before processing)
 	// Record in ALL modes (normal, insert, visual), but skip the 'q' that stops recording
 	if e.recordingMacro {
@@ -56,9 +61,9 @@ func (e *Editor) handleKey(k *tcell.EventKey) bool {
 				var keepSplits []*Split
 				currentSplitNewIndex := 0
 
-				// Keep file tree if present
+				// Keep file tree and outline if present
 				for i, s := range e.splits {
-					if s.splitType == SplitFileTree {
+					if s.splitType == SplitFileTree || s.splitType == SplitOutline {
 						keepSplits = append(keepSplits, s)
 					} else if i == e.currentSplit {
 						keepSplits = append(keepSplits, s)
@@ -94,10 +99,30 @@ func (e *Editor) handleKey(k *tcell.EventKey) bool {
 		return false
 	}
 
+	// If the outline has focus, handle outline input
+	if e.outlineOpen && e.focusOutline {
+		e.handleOutlineInput(k)
+		return false
+	}
+
+	// The symbol picker takes typed keys as its query
+	if e.popupActive && e.symbolPicker != nil {
+		e.handleSymbolPicker(k)
+		return false
+	}
+
+	// The hierarchy view expands and collapses its nodes
+	if e.popupActive && e.hierarchy != nil {
+		e.handleHierarchyInput(k)
+		return false
+	}
+
 	// Allow completion-related keys to work even when popup is active
 	if e.popupActive && e.mode == ModeInsert && e.completionActive {
-		// Allow Ctrl-N/Ctrl-P for cycling
-		if isCtrlN(k) || isCtrlP(k) {
+		// Allow Ctrl-N/Ctrl-P for cycling, Ctrl-Y to accept and
+		// Backspace to narrow completions that have no selection yet
+		if isCtrlN(k) || isCtrlP(k) || k.Key() == tcell.KeyCtrlY ||
+			k.Key() == tcell.KeyBackspace || k.Key() == tcell.KeyBackspace2 {
 			e.handleInsert(k)
 			return false
 		}
@@ -111,6 +136,9 @@ func (e *Editor) handleKey(k *tcell.EventKey) bool {
 		} else {
 			// Other keys go through popup handler
 		}
+	} else if e.popupActive && e.popupSelect != nil {
+		e.handlePopupList(k)
+		return false
 	} else if e.popupActive {
 		// Normal popup handling when completion is NOT active
 		switch k.Key() {
@@ -137,6 +165,21 @@ func (e *Editor) handleKey(k *tcell.EventKey) bool {
 		return false
 	}
 
+	// Ctrl+F/Ctrl+B scroll the float at the cursor, Esc closes it
+	if e.float != nil {
+		switch {
+		case k.Key() == tcell.KeyCtrlF:
+			e.scrollFloat(true)
+			return false
+		case k.Key() == tcell.KeyCtrlB:
+			e.scrollFloat(false)
+			return false
+		case k.Key() == tcell.KeyEsc && e.mode == ModeNormal:
+			e.closeFloat()
+			return false
+		}
+	}
+
 	// Ctrl+R redo (support multiple terminal encodings)
 	if e.mode == ModeNormal && isCtrlR(k) {
 		e.redo()
@@ -170,15 +213,16 @@ func (e *Editor) handleKey(k *tcell.EventKey) bool {
 
 		// End undo group when leaving insert mode
 		if e.mode == ModeInsert {
+			// Cancel completion if active, before the undo group ends
+			// so edits of the accepted candidate are part of it
+			if e.completionActive {
+				e.cancelCompletion()
+			}
 			e.buffer.EndUndoGroup()
 			// Save captured text for dot-repeat
 			if e.last.kind == RepeatInsert {
 				e.last.insertText = append([]rune{}, e.insertCapture...)
 			}
-			// Cancel completion if active
-			if e.completionActive {
-				e.cancelCompletion()
-			}
 		}
 
 		e.mode = ModeNormal
@@ -355,6 +399,9 @@ func (e *Editor) handleNormal(k *tcell.EventKey) {
 		case '"', '(', ')', '{', '}', '[', ']':
 			// Paired delimiter text objects
 			e.applyOperatorTextObject(op, prefix, r, cnt)
+		case 'f', 'c', 'a':
+			// Tree-sitter text objects: function, class, argument
+			e.applyOperatorTextObject(op, prefix, r, cnt)
 		default:
 			e.statusMsg = "unsupported text object"
 		}
@@ -383,36 +430,36 @@ func (e *Editor) handleNormal(k *tcell.EventKey) {
 
 		// folding commands (z prefix) - check BEFORE text objects
 		if op == 'z' {
+			e.foldCommand(r, cnt)
+			return
+		}
+
+		// ]f [f ]c [c - jump between functions and classes, ]d [d
+		// between diagnostics
+		if op == ']' || op == '[' {
 			switch r {
-			case 'a':
-				// za - toggle fold
-				e.ToggleFold()
-			case 'c':
-				// zc - close fold
-				fold := e.findFoldAtLine(e.cy)
-				if fold != nil {
-					fold.folded = true
-					e.statusMsg = "folded"
-				} else {
-					e.statusMsg = "no fold at cursor"
-				}
-			case 'o':
-				// zo - open fold
-				fold := e.findFoldAtLine(e.cy)
-				if fold != nil {
-					fold.folded = false
-					e.statusMsg = "unfolded"
-				} else {
-					e.statusMsg = "no fold at cursor"
-				}
-			case 'M':
-				// zM - fold all
-				e.FoldAll()
-			case 'R':
-				// zR - unfold all
-				e.UnfoldAll()
+			case 'd':
+				e.gotoDiagnostic(op == ']', cnt)
+			case 'q':
+				e.gotoLocation(op == ']', cnt)
 			default:
-				e.statusMsg = "unknown fold command: z" + string(r)
+				e.gotoSyntaxObject(r, op == ']', cnt)
+			}
+			return
+		}
+
+		// gr references, gR rename, ga code actions, gO the symbol
+		// outline from the language server
+		if op == 'g' && (r == 'r' || r == 'R' || r == 'a' || r == 'O') {
+			switch r {
+			case 'r':
+				e.lspReferences()
+			case 'R':
+				e.lspRenamePrompt()
+			case 'a':
+				e.lspCursorCodeActions()
+			case 'O':
+				e.toggleOutline()
 			}
 			return
 		}
@@ -425,6 +472,16 @@ func (e *Editor) handleNormal(k *tcell.EventKey) {
 			return
 		}
 
+		// g- / g+ step through the undo tree chronologically
+		if op == 'g' && (r == '-' || r == '+') {
+			if r == '-' {
+				e.earlier(strconv.Itoa(cnt))
+			} else {
+				e.later(strconv.Itoa(cnt))
+			}
+			return
+		}
+
 		// special case: gg as motion within operator (e.g., dgg)
 		if op == 'g' && r == 'g' {
 			e.moveToFirstLine()
@@ -584,6 +641,8 @@ func (e *Editor) handleNormal(k *tcell.EventKey) {
 
 	case 'u':
 		e.undo()
+	case 'K':
+		e.lspHover()
 	case ':':
 		e.mode = ModeCommand
 		e.cmdBuf = nil
@@ -699,6 +758,12 @@ func (e *Editor) handleNormal(k *tcell.EventKey) {
 		e.pendingOpCount = e.consumeCountOr1()
 		return
 
+	// syntax and diagnostic motions (]f, [c, ]d, ...)
+	case ']', '[':
+		e.pendingOp = r
+		e.pendingOpCount = e.consumeCountOr1()
+		return
+
 	default:
 		log.Printf("unknown normal key: %q", r)
 		e.clearPending()
@@ -708,7 +773,68 @@ func (e *Editor) handleNormal(k *tcell.EventKey) {
 func (e *Editor) handleVisual(k *tcell.EventKey) {
 	if k.Key() == tcell.KeyRune {
 		r := k.Rune()
+
+		// second key of a text object (iw, af, ...) or syntax motion (]f, ...)
+		if e.pendingTextObj != 0 {
+			prefix := e.pendingTextObj
+			e.pendingTextObj = 0
+			e.visualSelectTextObject(prefix, r)
+			return
+		}
+		if e.pendingOp == 'z' {
+			e.pendingOp = 0
+			if r == 'f' {
+				// zf - fold the selected lines
+				startLine, endLine := e.visualGetLineRange()
+				e.visualExit()
+				e.createFold(startLine, endLine)
+			} else {
+				e.statusMsg = "unknown fold command: z" + string(r)
+			}
+			return
+		}
+		if e.pendingOp == 'g' {
+			e.pendingOp = 0
+			switch r {
+			case 'a':
+				// ga - code actions for the selection
+				start, end, _ := e.visualRange()
+				e.visualExit()
+				e.lspCodeActions(start, end)
+			case 'q':
+				// gq - format the selected lines
+				startLine, endLine := e.visualGetLineRange()
+				e.visualExit()
+				e.lspFormat(true, startLine, endLine)
+			default:
+				e.statusMsg = "unknown command: g" + string(r)
+			}
+			return
+		}
+		if e.pendingOp == ']' || e.pendingOp == '[' {
+			op := e.pendingOp
+			e.pendingOp = 0
+			if r == 'd' {
+				e.gotoDiagnostic(op == ']', 1)
+				return
+			}
+			e.gotoSyntaxObject(r, op == ']', 1)
+			return
+		}
+
 		switch r {
+		case 'i', 'a':
+			e.pendingTextObj = r
+			return
+		case '+':
+			e.expandSelection()
+			return
+		case '-':
+			e.shrinkSelection()
+			return
+		case ']', '[', 'z', 'g':
+			e.pendingOp = r
+			return
 		case 'v':
 			e.visualExit()
 			return
@@ -846,6 +972,30 @@ func (e *Editor) handleInsert(k *tcell.EventKey) {
 		return
 	}
 
+	if e.completionActive && e.completionIndex < 0 {
+		// Nothing is selected yet: the list narrows as the word is typed
+		switch {
+		case k.Key() == tcell.KeyRune && isWordChar(k.Rune()):
+			e.insertRune(k.Rune())
+			e.insertCapture = append(e.insertCapture, k.Rune())
+			e.filterCompletion()
+			return
+		case (k.Key() == tcell.KeyBackspace || k.Key() == tcell.KeyBackspace2) && e.posFromCursor() > e.completionStartPos:
+			e.backspace()
+			if len(e.insertCapture) > 0 {
+				e.insertCapture = e.insertCapture[:len(e.insertCapture)-1]
+			}
+			e.filterCompletion()
+			return
+		}
+	}
+
+	// Ctrl-Y accepts the selected candidate
+	if k.Key() == tcell.KeyCtrlY && e.completionActive {
+		e.cancelCompletion()
+		return
+	}
+
 	// Any other key cancels completion (but still processes the key)
 	if e.completionActive {
 		e.cancelCompletion()
@@ -855,6 +1005,10 @@ func (e *Editor) handleInsert(k *tcell.EventKey) {
 	case tcell.KeyRune:
 		e.insertRune(k.Rune())
 		e.insertCapture = append(e.insertCapture, k.Rune())
+		e.lspSignatureTrigger(k.Rune())
+		e.lspCompletionTrigger(k.Rune())
+	case tcell.KeyCtrlS:
+		e.lspSignatureHelp(&lsp.SignatureHelpContext{TriggerKind: lsp.SignatureHelpInvoked})
 	case tcell.KeyBackspace, tcell.KeyBackspace2:
 		e.backspace()
 		// Remove last char from capture if there is one
@@ -1013,3 +1167,31 @@ func (e *Editor) handleSearch(k *tcell.EventKey) bool {
 	}
 	return false
 }
+
+// handlePopupList handles keys while a list popup is open
+func (e *Editor) handlePopupList(k *tcell.EventKey) {
+	switch k.Key() {
+	case tcell.KeyEsc:
+		e.closePopup()
+	case tcell.KeyEnter:
+		idx, onSelect := e.popupCursor, e.popupSelect
+		inRange := idx >= 0 && idx < len(e.popupLines)
+		e.closePopup()
+		if inRange {
+			onSelect(idx)
+		}
+	case tcell.KeyUp:
+		e.popupCursor = max(0, e.popupCursor-1)
+	case tcell.KeyDown:
+		e.popupCursor = min(len(e.popupLines)-1, e.popupCursor+1)
+	case tcell.KeyRune:
+		switch k.Rune() {
+		case 'q':
+			e.closePopup()
+		case 'k':
+			e.popupCursor = max(0, e.popupCursor-1)
+		case 'j':
+			e.popupCursor = min(len(e.popupLines)-1, e.popupCursor+1)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package editor
+
+import (
+	"time"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+)
+
+// lspSyncDelay is how long buffer changes wait to be sent to the language
+// servers, to batch the keys typed in a row
+const lspSyncDelay = 150 * time.Millisecond
+
+// lspBufferOpened attaches the current buffer to its language server,
+// starting the server on the first buffer of its language and root, and
+// hands the buffer to the servers started from Lua
+func (e *Editor) lspBufferOpened() {
+	bv := e.buf()
+	if bv == nil || bv.filename == "" {
+		return
+	}
+	bv.lspTick = bv.changeTick
+	bv.lspScheduled = bv.changeTick
+	e.lspStartFor(bv)
+
+	if e.loader != nil {
+		filetype := ""
+		if ft := e.getFiletype(); ft != nil {
+			filetype = ft.Name
+		}
+		e.loader.LSPBufferOpened(bv.filename, filetype, bv.buffer)
+	}
+}
+
+// lspSyncChanges sends the changes of every buffer changed since the last
+// call to its language servers, one didChange per buffer. Requests call it
+// first so the server sees the text they are about; otherwise changes wait
+// for lspScheduleSync.
+func (e *Editor) lspSyncChanges() {
+	for _, bv := range e.buffers {
+		if bv.lspTick == bv.changeTick {
+			continue
+		}
+		bv.lspTick = bv.changeTick
+
+		for _, srv := range e.lspServers {
+			if ds := srv.docs[bv.filename]; ds != nil {
+				_ = ds.Flush()
+			}
+		}
+		if e.loader != nil {
+			e.loader.LSPBufferChanged(bv.filename)
+		}
+	}
+}
+
+// lspScheduleSync runs on every turn of the main loop. Once a buffer
+// changed, it syncs the changes after lspSyncDelay without further
+// changes, so typing a word is one didChange.
+func (e *Editor) lspScheduleSync() {
+	changed := false
+	for _, bv := range e.buffers {
+		if bv.lspScheduled != bv.changeTick {
+			bv.lspScheduled = bv.changeTick
+			changed = changed || bv.lspTick != bv.changeTick
+		}
+	}
+	if !changed {
+		return
+	}
+	if e.lspSyncTimer != nil {
+		e.lspSyncTimer.Stop()
+	}
+	e.lspSyncTimer = time.AfterFunc(lspSyncDelay, func() {
+		e.post(e.lspSyncChanges)
+	})
+}
+
+// lspBufferSaved tells the language servers the current buffer was written
+func (e *Editor) lspBufferSaved() {
+	e.lspSyncChanges()
+	for _, srv := range e.lspServers {
+		if ds := srv.docs[e.filename]; ds != nil {
+			_ = ds.DidSave(e.buffer.String())
+		}
+	}
+	if e.loader != nil {
+		e.loader.LSPBufferSaved(e.filename)
+	}
+}
+
+// lspBufferClosed detaches bv from its language servers
+func (e *Editor) lspBufferClosed(bv *BufferView) {
+	if bv == nil {
+		return
+	}
+	for _, srv := range e.lspServers {
+		if ds := srv.docs[bv.filename]; ds != nil {
+			_ = ds.DidClose()
+			delete(srv.docs, bv.filename)
+		}
+	}
+	if e.loader != nil {
+		e.loader.LSPBufferClosed(bv.filename)
+	}
+}
+
+// countChange is subscribed to every buffer to mark it changed for
+// lspSyncChanges
+func (bv *BufferView) countChange(buffer.Edit) {
+	bv.changeTick++
+}
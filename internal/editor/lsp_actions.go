@@ -0,0 +1,439 @@
+package editor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+)
+
+// lspFormatTimeout is how long writing a file waits for the language
+// server to format it
+const lspFormatTimeout = 2 * time.Second
+
+// lspLocation is a place in a file from a language server's result
+type lspLocation struct {
+	path string
+	pos  lsp.Position
+	enc  buffer.Encoding // of the columns of pos
+	line string          // text of the line, for the list
+}
+
+// lspReferences implements gr and :references, listing the references to
+// the symbol under the cursor. Enter jumps to one; ]q and [q step through
+// them afterwards.
+func (e *Editor) lspReferences() {
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	if !srv.client.Capabilities().ReferencesProvider {
+		e.statusMsg = srv.cfg.Command + " does not support references"
+		return
+	}
+	e.lspSyncChanges()
+	pos := ds.Position(e.buffer, e.posFromCursor())
+	bv, enc := e.buf(), ds.Encoding()
+
+	go func() {
+		locs, err := ds.References(context.Background(), pos.Line, pos.Character, true)
+		e.post(func() {
+			if e.buf() != bv || e.mode != ModeNormal {
+				return
+			}
+			switch {
+			case err != nil:
+				e.statusMsg = "lsp: " + err.Error()
+			case len(locs) == 0:
+				e.statusMsg = "no references found"
+			default:
+				e.showLocations("REFERENCES", locs, enc)
+			}
+		})
+	}()
+}
+
+// showLocations makes locs the list ]q and [q step through and shows it in
+// a popup list
+func (e *Editor) showLocations(title string, locs []lsp.Location, enc buffer.Encoding) {
+	e.syncToBuffer()
+	files := make(map[string]*buffer.Buffer)
+	e.locations = e.locations[:0]
+	e.locationIndex = -1
+	lines := make([]string, 0, len(locs))
+	for _, loc := range locs {
+		path := lsp.PathFromURI(loc.URI)
+		buf, ok := files[path]
+		if !ok {
+			if bv := e.bufferByName(path); bv != nil {
+				buf = bv.buffer
+			} else if data, err := os.ReadFile(path); err == nil {
+				buf = buffer.NewFromString(strings.ReplaceAll(string(data), "\r\n", "\n"))
+			}
+			files[path] = buf
+		}
+		text := ""
+		line, col := loc.Range.Start.Line, loc.Range.Start.Character
+		if buf != nil && line < buf.LineCount() {
+			text = strings.TrimSpace(buf.Line(line))
+			line, col = buf.LineCol(lsp.OffsetAt(buf, loc.Range.Start, enc), buffer.EncodingRune)
+		}
+		e.locations = append(e.locations, lspLocation{path: path, pos: loc.Range.Start, enc: enc, line: text})
+		lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", relativePath(path), line+1, col+1, text))
+	}
+
+	e.popupFixedH = 0 // auto-size
+	e.openPopupList(title, lines, func(i int) {
+		e.locationIndex = i
+		e.jumpToLocation(e.locations[i])
+	})
+}
+
+// relativePath returns path relative to the working directory when it is
+// inside it
+func relativePath(path string) string {
+	if cwd, err := filepath.Abs("."); err == nil {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// jumpToLocation opens the file of loc and moves the cursor to it
+func (e *Editor) jumpToLocation(loc lspLocation) {
+	e.addToJumpList(e.cy, e.cx)
+	if loc.path != e.filename {
+		e.openFile(loc.path)
+		if e.filename != loc.path {
+			return // the status tells why
+		}
+	}
+	e.setCursorFromPos(lsp.OffsetAt(e.buffer, loc.pos, loc.enc))
+	e.wantX = e.cx
+}
+
+// gotoLocation implements ]q and [q, jumping to the count'th next or
+// previous entry of the last reference list
+func (e *Editor) gotoLocation(forward bool, count int) {
+	if len(e.locations) == 0 {
+		e.statusMsg = "no reference list"
+		return
+	}
+	i := e.locationIndex
+	if forward {
+		if i >= len(e.locations)-1 {
+			e.statusMsg = "no more references"
+			return
+		}
+		i = min(i+max(1, count), len(e.locations)-1)
+	} else {
+		if i <= 0 {
+			e.statusMsg = "no more references"
+			return
+		}
+		i = max(i-max(1, count), 0)
+	}
+	e.locationIndex = i
+	e.jumpToLocation(e.locations[i])
+	e.statusMsg = fmt.Sprintf("(%d of %d) %s", i+1, len(e.locations), e.locations[i].line)
+}
+
+// lspRenamePrompt implements gR, opening the command line with :rename and
+// the word under the cursor
+func (e *Editor) lspRenamePrompt() {
+	word := ""
+	if start, end, _, ok := e.textObjectRange('i', 'w'); ok {
+		word, _ = e.buffer.Slice(start, end)
+	}
+	e.mode = ModeCommand
+	e.cmdBuf = []rune("rename " + strings.TrimSpace(word))
+}
+
+// lspRename implements :rename {newname}, renaming the symbol under the
+// cursor across the project. Files that are not open are changed on disk.
+func (e *Editor) lspRename(newName string) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		e.statusMsg = "usage: :rename {newname}"
+		return
+	}
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	if !srv.client.Capabilities().RenameProvider {
+		e.statusMsg = srv.cfg.Command + " does not support rename"
+		return
+	}
+	e.lspSyncChanges()
+	pos := ds.Position(e.buffer, e.posFromCursor())
+	bv := e.buf()
+	tick := bv.changeTick
+
+	go func() {
+		edit, err := ds.Rename(context.Background(), pos.Line, pos.Character, newName)
+		e.post(func() {
+			if srv.docs[bv.filename] != ds {
+				return
+			}
+			switch {
+			case err != nil:
+				e.statusMsg = "lsp: " + err.Error()
+				return
+			case edit == nil:
+				e.statusMsg = "nothing to rename"
+				return
+			case bv.changeTick != tick:
+				e.statusMsg = "rename: " + filepath.Base(bv.filename) + " changed since the rename was requested"
+				return
+			}
+			files, err := e.applyWorkspaceEdit(srv, edit)
+			if err != nil {
+				e.statusMsg = "rename: " + err.Error()
+				return
+			}
+			e.statusMsg = fmt.Sprintf("renamed to %s in %d %s", newName, files, plural(files, "file"))
+		})
+	}()
+}
+
+// plural returns word, with an s unless n is 1
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// lspCodeActions implements ga and :codeaction, listing the code actions
+// for the rune range start to end of the current buffer and the
+// diagnostics overlapping it. Enter applies the selected action.
+func (e *Editor) lspCodeActions(start, end int) {
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	if !srv.client.Capabilities().CodeActionProvider.Supported {
+		e.statusMsg = srv.cfg.Command + " does not support code actions"
+		return
+	}
+	e.lspSyncChanges()
+	bv := e.buf()
+	r := lsp.Range{Start: ds.Position(e.buffer, start), End: ds.Position(e.buffer, end)}
+	var diags []lsp.Diagnostic
+	for _, d := range bv.diagnostics {
+		if d.start <= end && max(d.end, d.start+1) > start {
+			pd := d.published
+			pd.Range = lsp.Range{Start: ds.Position(e.buffer, d.start), End: ds.Position(e.buffer, d.end)}
+			diags = append(diags, pd)
+		}
+	}
+	tick := bv.changeTick
+
+	go func() {
+		actions, err := ds.CodeActions(context.Background(), r, diags)
+		e.post(func() {
+			if e.buf() != bv || bv.changeTick != tick || e.mode != ModeNormal {
+				return
+			}
+			switch {
+			case err != nil:
+				e.statusMsg = "lsp: " + err.Error()
+				return
+			case len(actions) == 0:
+				e.statusMsg = "no code actions"
+				return
+			}
+
+			lines := make([]string, 0, len(actions))
+			for _, a := range actions {
+				mark := " "
+				if a.IsPreferred {
+					mark = "*"
+				}
+				line := mark + " " + a.Title
+				if a.Kind != "" {
+					line += " [" + a.Kind + "]"
+				}
+				if a.Disabled != nil {
+					line += " (disabled: " + a.Disabled.Reason + ")"
+				}
+				lines = append(lines, line)
+			}
+			e.popupFixedH = 0 // auto-size
+			e.openPopupList("CODE ACTIONS", lines, func(i int) {
+				e.lspApplyCodeAction(srv, ds, actions[i], tick)
+			})
+		})
+	}()
+}
+
+// lspCursorCodeActions lists the code actions at the cursor
+func (e *Editor) lspCursorCodeActions() {
+	pos := e.posFromCursor()
+	e.lspCodeActions(pos, pos)
+}
+
+// lspApplyCodeAction applies the edit of action and runs its command. When
+// the server leaves the edit out of the list, it is resolved first. tick
+// is the change tick of the buffer the action was computed for.
+func (e *Editor) lspApplyCodeAction(srv *lspServer, ds *lsp.DocumentSync, action lsp.CodeAction, tick int) {
+	if action.Disabled != nil {
+		e.statusMsg = "code action disabled: " + action.Disabled.Reason
+		return
+	}
+	if action.Edit == nil && srv.client.Capabilities().CodeActionProvider.ResolveProvider {
+		go func() {
+			resolved, err := ds.ResolveCodeAction(context.Background(), action)
+			e.post(func() {
+				if srv.docs[lsp.PathFromURI(ds.URI())] != ds {
+					return
+				}
+				if err != nil {
+					e.statusMsg = "lsp: " + err.Error()
+					return
+				}
+				if resolved.Edit == nil && resolved.Command == nil {
+					resolved.Command = action.Command
+				}
+				e.lspRunCodeAction(srv, ds, resolved, tick)
+			})
+		}()
+		return
+	}
+	e.lspRunCodeAction(srv, ds, action, tick)
+}
+
+// lspRunCodeAction applies the edit of a resolved code action and asks the
+// server to run its command
+func (e *Editor) lspRunCodeAction(srv *lspServer, ds *lsp.DocumentSync, action lsp.CodeAction, tick int) {
+	if action.Edit != nil {
+		bv := e.bufferByName(lsp.PathFromURI(ds.URI()))
+		if bv != nil && bv.changeTick != tick {
+			e.statusMsg = "code action: " + filepath.Base(bv.filename) + " changed since the action was listed"
+			return
+		}
+		if _, err := e.applyWorkspaceEdit(srv, action.Edit); err != nil {
+			e.statusMsg = "code action: " + err.Error()
+			return
+		}
+	}
+	if action.Command != nil {
+		client, cmd := srv.client, *action.Command
+		go func() {
+			if err := client.ExecuteCommand(context.Background(), cmd); err != nil {
+				e.post(func() { e.statusMsg = "lsp: " + err.Error() })
+			}
+		}()
+	}
+	e.statusMsg = action.Title
+}
+
+// lspFormattingOptions returns the formatting options of the current
+// buffer
+func (e *Editor) lspFormattingOptions() lsp.FormattingOptions {
+	return lsp.FormattingOptions{
+		TabSize:      e.indentWidth,
+		InsertSpaces: e.config != nil && e.config.Options != nil && e.config.Options.ExpandTab,
+	}
+}
+
+// lspFormat implements :format and visual gq, formatting the current
+// buffer, or only the lines startLine to endLine when ranged is set
+func (e *Editor) lspFormat(ranged bool, startLine, endLine int) {
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	caps := srv.client.Capabilities()
+	switch {
+	case ranged && !bool(caps.DocumentRangeFormattingProvider):
+		e.statusMsg = srv.cfg.Command + " does not support range formatting"
+		return
+	case !ranged && !bool(caps.DocumentFormattingProvider):
+		e.statusMsg = srv.cfg.Command + " does not support formatting"
+		return
+	}
+	e.lspSyncChanges()
+	bv, opts := e.buf(), e.lspFormattingOptions()
+	tick := bv.changeTick
+	r := lsp.Range{Start: lsp.Position{Line: startLine}, End: lsp.Position{Line: endLine + 1}}
+
+	go func() {
+		var edits []lsp.TextEdit
+		var err error
+		if ranged {
+			edits, err = ds.RangeFormatting(context.Background(), r, opts)
+		} else {
+			edits, err = ds.Formatting(context.Background(), opts)
+		}
+		e.post(func() {
+			if srv.docs[bv.filename] != ds || bv.changeTick != tick {
+				return // formatting edits for older text
+			}
+			switch {
+			case err != nil:
+				e.statusMsg = "lsp: " + err.Error()
+			case len(edits) == 0:
+				e.statusMsg = "already formatted"
+			default:
+				e.syncToBuffer()
+				e.applyTextEdits(bv, ds.Encoding(), edits)
+				e.statusMsg = "formatted"
+			}
+		})
+	}()
+}
+
+// lspFormatOnSave formats the current buffer before it is written when
+// vb.opt.formatonsave is set. It waits up to lspFormatTimeout for the
+// server and writes the text unformatted when the server is slower. While
+// it waits it runs what other goroutines post, so edits the server asks
+// for with workspace/applyEdit are applied; the formatting is dropped
+// when they changed the buffer.
+func (e *Editor) lspFormatOnSave() {
+	if e.config == nil || e.config.Options == nil || !e.config.Options.FormatOnSave {
+		return
+	}
+	srv := e.lspCurrentServer()
+	if srv == nil || srv.state != lspRunning || srv.docs[e.filename] == nil {
+		return
+	}
+	if !srv.client.Capabilities().DocumentFormattingProvider {
+		return
+	}
+	ds := srv.docs[e.filename]
+	e.lspSyncChanges()
+	bv := e.buf()
+	tick := bv.changeTick
+
+	ctx, cancel := context.WithTimeout(context.Background(), lspFormatTimeout)
+	defer cancel()
+	opts := e.lspFormattingOptions()
+	var edits []lsp.TextEdit
+	var err error
+	done := make(chan struct{})
+	go func() {
+		edits, err = ds.Formatting(ctx, opts)
+		close(done)
+	}()
+	e.runAsyncUntil(done)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		e.statusMsg = "lsp: formatting timed out"
+	case err != nil:
+		e.statusMsg = "lsp: " + err.Error()
+	case e.buf() != bv || bv.changeTick != tick:
+		e.statusMsg = "lsp: formatting dropped, the buffer changed"
+	default:
+		e.syncToBuffer()
+		e.applyTextEdits(bv, ds.Encoding(), edits)
+	}
+}
@@ -0,0 +1,157 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const lspActionsSource = "package main\n\nfunc greet() {} \t\n\nfunc main() {\n\tgreet()   \n\thelper()\n\tx := ERROR\n}\n"
+
+func TestLSPReferencesAndRename(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{
+		"go.mod":   "module example\n",
+		"main.go":  lspActionsSource,
+		"other.go": "package main\n\nvar h = helper\n",
+		"util.go":  "package main\n\nfunc helper() { greet() }\n",
+	})
+	main, other, util := filepath.Join(dir, "main.go"), filepath.Join(dir, "other.go"), filepath.Join(dir, "util.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+	enter := func() { e.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)) }
+
+	// gr lists the references across files; Enter and ]q [q jump
+	e.cy, e.cx = 2, 6
+	pressKeys(e, "gr")
+	waitForLSP(t, e, "references", func() bool { return e.popupActive })
+	if len(e.popupLines) != 3 || !strings.HasSuffix(e.popupLines[0], "main.go:3:6: func greet() {}") ||
+		!strings.HasSuffix(e.popupLines[2], "util.go:3:17: func helper() { greet() }") {
+		t.Fatalf("references = %q", e.popupLines)
+	}
+	enter()
+	if e.popupActive || e.cy != 2 || e.cx != 5 {
+		t.Fatalf("Enter should jump to the first reference, at (%d,%d)", e.cy, e.cx)
+	}
+	pressKeys(e, "]q")
+	if e.cy != 5 || e.cx != 1 || e.statusMsg != "(2 of 3) greet()" {
+		t.Fatalf("]q: at (%d,%d) with %q", e.cy, e.cx, e.statusMsg)
+	}
+	pressKeys(e, "]q")
+	if e.filename != util || e.cy != 2 || e.cx != 16 {
+		t.Fatalf("]q should open util.go: %s at (%d,%d)", e.filename, e.cy, e.cx)
+	}
+	pressKeys(e, "]q")
+	if e.statusMsg != "no more references" {
+		t.Errorf("]q past the last: %q", e.statusMsg)
+	}
+	pressKeys(e, "2[q")
+	if e.filename != main || e.cy != 2 {
+		t.Fatalf("2[q: %s at line %d", e.filename, e.cy)
+	}
+
+	// rename edits the open buffers and writes the unopened file
+	e.cy, e.cx = 6, 2
+	pressKeys(e, "gR")
+	if e.mode != ModeCommand || string(e.cmdBuf) != "rename helper" {
+		t.Fatalf("gR: mode %v, command %q", e.mode, string(e.cmdBuf))
+	}
+	pressKeys(e, "\x1b")
+	e.exec("rename assist")
+	waitForLSP(t, e, "rename", func() bool { return strings.HasPrefix(e.statusMsg, "renamed") })
+	if e.statusMsg != "renamed to assist in 3 files" {
+		t.Fatalf("status = %q", e.statusMsg)
+	}
+	if e.buffer.Line(6) != "\tassist()" || !e.dirty {
+		t.Errorf("main.go line = %q, dirty %v", e.buffer.Line(6), e.dirty)
+	}
+	if bv := e.bufferByName(util); bv.buffer.Line(2) != "func assist() { greet() }" || !bv.dirty {
+		t.Errorf("util.go line = %q, dirty %v", bv.buffer.Line(2), bv.dirty)
+	}
+	if data, _ := os.ReadFile(other); string(data) != "package main\n\nvar h = assist\n" {
+		t.Errorf("other.go on disk = %q", data)
+	}
+	if data, _ := os.ReadFile(util); !strings.Contains(string(data), "helper") {
+		t.Errorf("the open util.go should not be written: %q", data)
+	}
+	pressKeys(e, "u")
+	if e.buffer.Line(6) != "\thelper()" {
+		t.Errorf("undo should revert the rename in one step: %q", e.buffer.Line(6))
+	}
+}
+
+func TestLSPCodeActionsAndFormatting(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": lspActionsSource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "diagnostics", func() bool { return len(e.buf().diagnostics) == 1 })
+	enter := func() { e.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)) }
+
+	// the quick fix of the diagnostic under the cursor
+	e.cy, e.cx = 7, 7
+	pressKeys(e, "ga")
+	waitForLSP(t, e, "code actions", func() bool { return e.popupActive })
+	if len(e.popupLines) != 1 || !strings.HasSuffix(e.popupLines[0], "* Replace ERROR with nil [quickfix]") {
+		t.Fatalf("code actions = %q", e.popupLines)
+	}
+	enter()
+	if e.buffer.Line(7) != "\tx := nil" {
+		t.Fatalf("after the quick fix: %q", e.buffer.Line(7))
+	}
+
+	// an action without an edit is resolved before it is applied
+	e.cx = 1
+	e.exec("codeaction")
+	waitForLSP(t, e, "code actions", func() bool { return e.popupActive })
+	if len(e.popupLines) != 1 || !strings.HasSuffix(e.popupLines[0], "  Uppercase x [refactor.rewrite]") {
+		t.Fatalf("code actions = %q", e.popupLines)
+	}
+	enter()
+	waitForLSP(t, e, "the resolved edit", func() bool { return e.buffer.Line(7) == "\tX := nil" })
+
+	// visual gq formats the selected lines, :format the buffer
+	e.cy, e.cx = 5, 0
+	pressKeys(e, "Vgq")
+	waitForLSP(t, e, "range formatting", func() bool { return e.buffer.Line(5) == "\tgreet()" })
+	if e.buffer.Line(2) != "func greet() {} \t" {
+		t.Fatalf("range formatting changed line 3: %q", e.buffer.Line(2))
+	}
+	e.exec("format")
+	waitForLSP(t, e, "formatting", func() bool { return e.buffer.Line(2) == "func greet() {}" })
+
+	// format on save
+	e.config.Options.FormatOnSave = true
+	e.cy = 8
+	pressKeys(e, "A  \x1b")
+	e.save()
+	want := "package main\n\nfunc greet() {}\n\nfunc main() {\n\tgreet()\n\thelper()\n\tX := nil\n}\n"
+	if data, _ := os.ReadFile(main); string(data) != want {
+		t.Errorf("written with format on save:\n%s", data)
+	}
+}
+
+func TestLSPFormatOnSaveServesApplyEdit(t *testing.T) {
+	t.Setenv("VB_LSPTEST_FORMAT", "apply")
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": lspActionsSource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	// the server asks for an edit before it answers; the formatting, made
+	// for the text before that edit, is dropped
+	e.config.Options.FormatOnSave = true
+	start := time.Now()
+	e.save()
+	if d := time.Since(start); d >= lspFormatTimeout {
+		t.Errorf("save waited %v for the formatting", d)
+	}
+	if data, _ := os.ReadFile(main); string(data) != "// formatted\n"+lspActionsSource {
+		t.Errorf("written:\n%s", data)
+	}
+}
@@ -0,0 +1,315 @@
+package editor
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+)
+
+// lspCompletion is a completion candidate from a language server
+type lspCompletion struct {
+	item  lsp.CompletionItem
+	word  string // the text that replaces the completed word
+	start int    // rune offset of the completed word
+	srv   *lspServer
+	ds    *lsp.DocumentSync
+
+	resolving bool // completionItem/resolve was sent and has not returned
+	resolved  bool
+	accepted  bool // completion ended with the item inserted
+	applied   bool // its additional edits were applied
+}
+
+// lspCompletionDocument returns the current buffer's language server and
+// document when the server offers completion, or nils
+func (e *Editor) lspCompletionDocument() (*lspServer, *lsp.DocumentSync) {
+	srv := e.lspCurrentServer()
+	if srv == nil || srv.state != lspRunning || srv.docs[e.filename] == nil {
+		return nil, nil
+	}
+	if srv.client.Capabilities().CompletionProvider == nil {
+		return nil, nil
+	}
+	return srv, srv.docs[e.filename]
+}
+
+// lspCompletionTrigger runs after r was typed in insert mode. When r is one
+// of the server's trigger characters, such as '.', it opens the server's
+// completions of the word after it without selecting one.
+func (e *Editor) lspCompletionTrigger(r rune) {
+	srv, ds := e.lspCompletionDocument()
+	if ds == nil || e.completionActive {
+		return
+	}
+	if !slices.Contains(srv.client.Capabilities().CompletionProvider.TriggerCharacters, string(r)) {
+		return
+	}
+	trigger := &lsp.CompletionContext{TriggerKind: lsp.CompletionTriggerCharacter, TriggerCharacter: string(r)}
+	e.lspRequestCompletion(srv, ds, e.posFromCursor(), "", 0, true, trigger)
+}
+
+// lspRequestCompletion asks the server for completions of the word at
+// start, of which prefix was typed. Buffer-word completion already shown
+// gets the server's candidates in front of its own. Otherwise the
+// candidates open a new completion when the word was not changed, or, for
+// auto, as long as only more of the word was typed; auto completions start
+// without a selection and initialIndex is ignored. A request still running
+// is cancelled.
+func (e *Editor) lspRequestCompletion(srv *lspServer, ds *lsp.DocumentSync, start int, prefix string, initialIndex int, auto bool, trigger *lsp.CompletionContext) {
+	e.lspSyncChanges()
+	pos := ds.Position(e.buffer, e.posFromCursor())
+	e.completionRequest++
+	seq, bv := e.completionRequest, e.buf()
+	if e.completionCancel != nil {
+		e.completionCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.completionCancel = cancel
+
+	go func() {
+		list, err := ds.Completion(ctx, pos.Line, pos.Character, trigger)
+		cancel()
+		e.post(func() {
+			if seq != e.completionRequest || e.buf() != bv || e.mode != ModeInsert || srv.docs[e.filename] != ds {
+				return
+			}
+			if err != nil {
+				e.statusMsg = "lsp: " + err.Error()
+				return
+			}
+
+			typed, ok := e.typedWord(start)
+			switch {
+			case e.completionActive && e.completionStartPos == start:
+				e.mergeLSPCompletion(srv, ds, list.Items)
+			case e.completionActive || !ok || !auto && typed != prefix:
+				// the completion moved on
+			default:
+				e.completionStartPos = start
+				e.completionPrefix = typed
+				e.completionLSP = e.lspCompletions(srv, ds, list.Items, start)
+				e.filterCompletion()
+				if !e.completionActive {
+					if !auto {
+						e.statusMsg = "no completions found"
+					}
+					return
+				}
+				if !auto {
+					e.completionIndex = initialIndex
+					if initialIndex < 0 {
+						e.completionIndex = len(e.completionCandidates) - 1
+					}
+					e.applyCompletion()
+				}
+			}
+		})
+	}()
+}
+
+// typedWord returns the text between start and the cursor when it is part
+// of a word on the cursor line
+func (e *Editor) typedWord(start int) (string, bool) {
+	pos := e.posFromCursor()
+	if start > pos || e.buffer.LineAt(start) != e.cy {
+		return "", false
+	}
+	word, _ := e.buffer.Slice(start, pos)
+	for _, r := range word {
+		if !isWordChar(r) {
+			return "", false
+		}
+	}
+	return word, true
+}
+
+// lspCompletions converts the items of a completion result for the word at
+// start, in the server's order
+func (e *Editor) lspCompletions(srv *lspServer, ds *lsp.DocumentSync, items []lsp.CompletionItem, start int) []*lspCompletion {
+	out := make([]*lspCompletion, 0, len(items))
+	for _, item := range items {
+		word := item.Text()
+		if item.TextEdit != nil {
+			// the edit may start before or after the completed word
+			editStart := ds.Offset(e.buffer, item.TextEdit.Range.Start)
+			switch {
+			case editStart < start:
+				before, _ := e.buffer.Slice(editStart, start)
+				word, _ = strings.CutPrefix(word, before)
+			case editStart > start && editStart <= e.posFromCursor():
+				before, _ := e.buffer.Slice(start, editStart)
+				word = before + word
+			}
+		}
+		if word == "" {
+			continue
+		}
+		out = append(out, &lspCompletion{item: item, word: word, start: start, srv: srv, ds: ds})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return completionSortKey(out[i].item) < completionSortKey(out[j].item)
+	})
+	return out
+}
+
+func completionSortKey(item lsp.CompletionItem) string {
+	if item.SortText != "" {
+		return item.SortText
+	}
+	return item.Label
+}
+
+// matchCompletion reports whether the server's item c completes prefix.
+// Servers filter loosely, so matching ignores case.
+func matchCompletion(c *lspCompletion, prefix string) bool {
+	text := c.item.FilterText
+	if text == "" {
+		text = c.word
+	}
+	return strings.HasPrefix(strings.ToLower(text), strings.ToLower(prefix)) && c.word != prefix
+}
+
+// filterCompletion shows the server candidates matching the word typed so
+// far, without selecting one, and ends completion when none is left
+func (e *Editor) filterCompletion() {
+	if typed, ok := e.typedWord(e.completionStartPos); ok {
+		e.completionPrefix = typed
+	}
+	var words []string
+	var items []*lspCompletion
+	for _, c := range e.completionLSP {
+		if matchCompletion(c, e.completionPrefix) {
+			words = append(words, c.word)
+			items = append(items, c)
+		}
+	}
+	if len(words) == 0 {
+		e.cancelCompletion()
+		return
+	}
+	e.completionActive = true
+	e.completionCandidates = words
+	e.completionItems = items
+	e.completionIndex = -1
+	e.updateCompletionPopup()
+}
+
+// mergeLSPCompletion puts the server's candidates in front of the buffer
+// words shown, dropping words the server also offers, and keeps the
+// selected word selected
+func (e *Editor) mergeLSPCompletion(srv *lspServer, ds *lsp.DocumentSync, items []lsp.CompletionItem) {
+	e.completionLSP = e.lspCompletions(srv, ds, items, e.completionStartPos)
+	selected := ""
+	if e.completionIndex >= 0 && e.completionIndex < len(e.completionCandidates) {
+		selected = e.completionCandidates[e.completionIndex]
+	}
+
+	var words []string
+	var merged []*lspCompletion
+	seen := make(map[string]bool)
+	for _, c := range e.completionLSP {
+		if matchCompletion(c, e.completionPrefix) && !seen[c.word] {
+			seen[c.word] = true
+			words = append(words, c.word)
+			merged = append(merged, c)
+		}
+	}
+	for i, word := range e.completionCandidates {
+		if e.completionItem(i) == nil && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+			merged = append(merged, nil)
+		}
+	}
+
+	e.completionCandidates = words
+	e.completionItems = merged
+	if selected != "" {
+		e.completionIndex = slices.Index(words, selected)
+	}
+	e.updateCompletionPopup()
+	if item := e.completionItem(e.completionIndex); item != nil {
+		e.lspCompletionSelected(item)
+	}
+}
+
+// lspCompletionSelected shows the documentation of the selected server
+// candidate in a float, resolving it first when the server fills items in
+// lazily
+func (e *Editor) lspCompletionSelected(c *lspCompletion) {
+	if c.resolving {
+		return // shown when it returns
+	}
+	if opts := c.srv.client.Capabilities().CompletionProvider; !c.resolved && opts != nil && opts.ResolveProvider {
+		c.resolving = true
+		client := c.srv.client
+		go func() {
+			item, err := c.ds.ResolveCompletion(context.Background(), c.item)
+			e.post(func() {
+				c.resolving, c.resolved = false, true
+				if c.srv.client != client {
+					return
+				}
+				if err == nil {
+					c.item = item
+				}
+				if c.accepted {
+					e.lspApplyAdditionalEdits(c)
+				} else if e.completionActive && e.completionItem(e.completionIndex) == c {
+					e.lspCompletionSelected(c)
+				}
+			})
+		}()
+		return
+	}
+
+	lines := markdownLines(lsp.HoverText(c.item.Documentation))
+	if len(lines) == 0 {
+		if e.float != nil && e.float.kind == floatCompletion {
+			e.closeFloat()
+		}
+		return
+	}
+	e.openFloat(floatCompletion, lines)
+}
+
+// lspCompletionAccepted runs when completion ends with the server candidate
+// c inserted. It applies the edits the item makes elsewhere, such as adding
+// an import, once any resolve request has returned.
+func (e *Editor) lspCompletionAccepted(c *lspCompletion) {
+	c.accepted = true
+	opts := c.srv.client.Capabilities().CompletionProvider
+	switch {
+	case c.resolving:
+		// applied when it returns
+	case c.resolved || opts == nil || !opts.ResolveProvider:
+		e.lspApplyAdditionalEdits(c)
+	default:
+		// the edits may only come with the resolved item
+		e.lspCompletionSelected(c)
+	}
+}
+
+// lspApplyAdditionalEdits applies the additional edits of c once. Edits
+// reaching into the completed word are dropped; they were positioned in
+// the text before the word was inserted.
+func (e *Editor) lspApplyAdditionalEdits(c *lspCompletion) {
+	if c.applied {
+		return
+	}
+	bv := e.bufferByName(lsp.PathFromURI(c.ds.URI()))
+	if bv == nil {
+		return
+	}
+	c.applied = true
+	var edits []lsp.TextEdit
+	for _, ed := range c.item.AdditionalTextEdits {
+		if c.ds.Offset(bv.buffer, ed.Range.End) <= c.start {
+			edits = append(edits, ed)
+		}
+	}
+	e.applyTextEdits(bv, c.ds.Encoding(), edits)
+}
@@ -0,0 +1,74 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const lspCompletionSource = "package main\n\nfunc greeting() string {\n\treturn \"hi\"\n}\n\nvar greet = 1\n"
+
+func TestLSPCompletion(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": lspCompletionSource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+	ctrl := func(key tcell.Key) { e.handleKey(tcell.NewEventKey(key, 0, tcell.ModNone)) }
+
+	// Ctrl-N shows buffer words at once and the server's when they arrive
+	e.cy = 6
+	pressKeys(e, "ogre")
+	ctrl(tcell.KeyCtrlN)
+	if e.buffer.Line(7) != "greet" {
+		t.Fatalf("Ctrl-N inserted %q", e.buffer.Line(7))
+	}
+	waitForLSP(t, e, "server candidates", func() bool { return e.completionItem(0) != nil })
+	if len(e.completionCandidates) != 2 || e.completionIndex != 0 {
+		t.Fatalf("merged candidates = %q, index %d", e.completionCandidates, e.completionIndex)
+	}
+	if want := "> [gre]et     variable   lsptest"; e.popupLines[0] != want {
+		t.Errorf("popup line = %q, want %q", e.popupLines[0], want)
+	}
+	waitForLSP(t, e, "resolved documentation", func() bool {
+		return e.float != nil && e.float.kind == floatCompletion
+	})
+	if e.float.lines[0].text != "Documentation of greet." {
+		t.Errorf("documentation = %v", e.float.lines)
+	}
+	ctrl(tcell.KeyCtrlN)
+	pressKeys(e, "\x1b")
+	if e.buffer.Line(7) != "greeting" || e.float != nil {
+		t.Fatalf("after Esc: %q, float %v", e.buffer.Line(7), e.float)
+	}
+
+	// a trigger character opens the server's candidates without selecting
+	// one; typing narrows them
+	pressKeys(e, "ofmt.")
+	waitForLSP(t, e, "triggered completion", func() bool { return e.completionActive })
+	if len(e.completionCandidates) != 2 || e.completionIndex != -1 || e.buffer.Line(8) != "fmt." {
+		t.Fatalf("candidates %q, index %d, line %q", e.completionCandidates, e.completionIndex, e.buffer.Line(8))
+	}
+	if !strings.Contains(e.popupLines[0], "function") {
+		t.Errorf("popup line = %q", e.popupLines[0])
+	}
+	pressKeys(e, "s")
+	if len(e.completionCandidates) != 1 || e.completionCandidates[0] != "Sprintf" {
+		t.Fatalf("narrowed to %q", e.completionCandidates)
+	}
+
+	// accepting applies the additional edit importing the package
+	ctrl(tcell.KeyCtrlN)
+	ctrl(tcell.KeyCtrlY)
+	waitForLSP(t, e, "the import", func() bool { return e.buffer.Line(1) == `import "fmt"` })
+	if e.buffer.Line(9) != "fmt.Sprintf" || e.cy != 9 || e.cx != len("fmt.Sprintf") {
+		t.Fatalf("line %q with the cursor at (%d,%d)", e.buffer.Line(9), e.cy, e.cx)
+	}
+	pressKeys(e, "\x1b")
+	pressKeys(e, "u")
+	if strings.Contains(e.buffer.String(), "fmt") {
+		t.Errorf("undo should remove the completion and its import:\n%s", e.buffer.String())
+	}
+}
@@ -0,0 +1,277 @@
+package editor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+	"github.com/gdamore/tcell/v2"
+)
+
+// semanticToken is a span of a buffer a language server classified. The
+// span is in rune offsets and moves with edits until the tokens are
+// requested again.
+type semanticToken struct {
+	start, end int
+	typ        string
+	modifiers  []string
+}
+
+// inlayHint is text a language server suggests showing before a position
+// of a buffer. It is drawn between the characters and takes no place in
+// the buffer.
+type inlayHint struct {
+	pos  int    // rune offset of the character it is drawn before
+	text string // label with its padding
+}
+
+// decorationRequest is the last request for a kind of decoration of a
+// buffer, made again when the document or the text its server has
+// changes, or when a request of the visible lines scrolled out of view
+type decorationRequest struct {
+	ds     *lsp.DocumentSync
+	tick   int    // lspTick of the buffer it was made at
+	top    int    // first line of the range asked for, -1 for all of it
+	cancel func() // cancels it while it runs
+}
+
+// reset cancels the request and forgets it
+func (req *decorationRequest) reset() {
+	if req.cancel != nil {
+		req.cancel()
+	}
+	*req = decorationRequest{}
+}
+
+// updateLSPDecorations runs on every turn of the main loop, once the view
+// is scrolled to the cursor. It requests the semantic tokens and inlay
+// hints of the current buffer as vb.opt.semantictokens and
+// vb.opt.inlayhints ask, and drops them from buffers when the options are
+// off.
+func (e *Editor) updateLSPDecorations() {
+	tokensOn := e.config != nil && e.config.Options != nil && e.config.Options.SemanticTokens
+	hintsOn := e.config != nil && e.config.Options != nil && e.config.Options.InlayHints
+	for _, bv := range e.buffers {
+		if !tokensOn {
+			bv.tokensReq.reset()
+			bv.semanticTokens = nil
+		}
+		if !hintsOn {
+			bv.hintsReq.reset()
+			bv.inlayHints = nil
+		}
+	}
+
+	bv := e.buf()
+	if bv == nil || !tokensOn && !hintsOn {
+		return
+	}
+	var ds *lsp.DocumentSync
+	var caps lsp.ServerCapabilities
+	if srv := e.lspCurrentServer(); srv != nil && srv.docs[bv.filename] != nil {
+		ds = srv.docs[bv.filename]
+		caps = srv.client.Capabilities()
+	}
+
+	// the lines in view
+	_, h := e.s.Size()
+	top := e.rowOffset
+	visible := lsp.Range{
+		Start: lsp.Position{Line: top},
+		End:   lsp.Position{Line: top + max(1, h-1)},
+	}
+	if ds != nil && visible.End.Line >= bv.buffer.LineCount() {
+		visible.End = ds.Position(bv.buffer, bv.buffer.Len())
+	}
+
+	if tokensOn {
+		switch opts := caps.SemanticTokensProvider; {
+		case opts == nil || !bool(opts.Full) && !bool(opts.Range):
+			bv.tokensReq.reset()
+			bv.semanticTokens = nil
+		case bool(opts.Full):
+			e.requestDecoration(bv, ds, &bv.tokensReq, -1, func(ctx context.Context) (func(), error) {
+				tokens, err := ds.SemanticTokens(ctx)
+				return func() { bv.setSemanticTokens(ds, tokens) }, err
+			})
+		default:
+			e.requestDecoration(bv, ds, &bv.tokensReq, top, func(ctx context.Context) (func(), error) {
+				tokens, err := ds.SemanticTokensRange(ctx, visible)
+				return func() { bv.setSemanticTokens(ds, tokens) }, err
+			})
+		}
+	}
+
+	if hintsOn {
+		if !caps.InlayHintProvider {
+			bv.hintsReq.reset()
+			bv.inlayHints = nil
+		} else {
+			e.requestDecoration(bv, ds, &bv.hintsReq, top, func(ctx context.Context) (func(), error) {
+				hints, err := ds.InlayHints(ctx, visible)
+				return func() { bv.setInlayHints(ds, hints) }, err
+			})
+		}
+	}
+}
+
+// requestDecoration makes req for ds and the range starting at line top
+// unless it was made for them at the current lspTick. Changes not yet sent
+// to the server are waited for, so the positions of the result are in the
+// text of the buffer. fetch runs on a goroutine; the function it returns
+// stores the result and runs on the main loop when the buffer did not
+// change meanwhile.
+func (e *Editor) requestDecoration(bv *BufferView, ds *lsp.DocumentSync, req *decorationRequest, top int, fetch func(ctx context.Context) (func(), error)) {
+	if req.ds == ds && req.tick == bv.lspTick && req.top == top || bv.changeTick != bv.lspTick {
+		return
+	}
+	req.reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	*req = decorationRequest{ds: ds, tick: bv.lspTick, top: top, cancel: cancel}
+	tick := bv.lspTick
+	go func() {
+		apply, err := fetch(ctx)
+		cancel()
+		e.post(func() {
+			if req.ds != ds || req.tick != tick || req.top != top {
+				return // a newer request replaced this one
+			}
+			req.cancel = nil
+			if err == nil && bv.changeTick == tick {
+				apply()
+			}
+		})
+	}()
+}
+
+// setSemanticTokens replaces the semantic tokens of bv
+func (bv *BufferView) setSemanticTokens(ds *lsp.DocumentSync, tokens []lsp.SemanticToken) {
+	bv.semanticTokens = bv.semanticTokens[:0]
+	for _, tok := range tokens {
+		start := lsp.Position{Line: tok.Line, Character: tok.Start}
+		end := lsp.Position{Line: tok.Line, Character: tok.Start + tok.Length}
+		bv.semanticTokens = append(bv.semanticTokens, semanticToken{
+			start:     ds.Offset(bv.buffer, start),
+			end:       ds.Offset(bv.buffer, end),
+			typ:       tok.Type,
+			modifiers: tok.Modifiers,
+		})
+	}
+	sort.SliceStable(bv.semanticTokens, func(i, j int) bool {
+		return bv.semanticTokens[i].start < bv.semanticTokens[j].start
+	})
+}
+
+// setInlayHints replaces the inlay hints of bv
+func (bv *BufferView) setInlayHints(ds *lsp.DocumentSync, hints []lsp.InlayHint) {
+	bv.inlayHints = bv.inlayHints[:0]
+	for _, h := range hints {
+		bv.inlayHints = append(bv.inlayHints, inlayHint{
+			pos:  ds.Offset(bv.buffer, h.Position),
+			text: h.Text(),
+		})
+	}
+	sort.SliceStable(bv.inlayHints, func(i, j int) bool { return bv.inlayHints[i].pos < bv.inlayHints[j].pos })
+}
+
+// shiftDecorations is subscribed to the buffer to move semantic tokens and
+// inlay hints with the text around them
+func (bv *BufferView) shiftDecorations(ed buffer.Edit) {
+	for i := range bv.semanticTokens {
+		tok := &bv.semanticTokens[i]
+		tok.start, tok.end = shiftOffset(tok.start, ed), shiftOffset(tok.end, ed)
+	}
+	for i := range bv.inlayHints {
+		bv.inlayHints[i].pos = shiftOffset(bv.inlayHints[i].pos, ed)
+	}
+}
+
+// semanticTokenAt returns the semantic token covering pos
+func semanticTokenAt(bv *BufferView, pos int) (semanticToken, bool) {
+	i := sort.Search(len(bv.semanticTokens), func(i int) bool { return bv.semanticTokens[i].start > pos }) - 1
+	if i < 0 || pos >= bv.semanticTokens[i].end {
+		return semanticToken{}, false
+	}
+	return bv.semanticTokens[i], true
+}
+
+// lineInlayHints returns the inlay hints of bv drawn from rune offset from
+// to offset to, both included
+func lineInlayHints(bv *BufferView, from, to int) []inlayHint {
+	i := sort.Search(len(bv.inlayHints), func(i int) bool { return bv.inlayHints[i].pos >= from })
+	j := sort.Search(len(bv.inlayHints), func(i int) bool { return bv.inlayHints[i].pos > to })
+	return bv.inlayHints[i:max(i, j)]
+}
+
+// inlayHintsWidth returns the number of cells the inlay hints of bv from
+// rune offset from to offset to take
+func inlayHintsWidth(bv *BufferView, from, to int) int {
+	width := 0
+	for _, h := range lineInlayHints(bv, from, to) {
+		width += len([]rune(h.text))
+	}
+	return width
+}
+
+// semanticCaptures maps semantic token types to the highlight captures
+// whose styles they are drawn in. Types missing keep the tree-sitter
+// style.
+var semanticCaptures = map[string]string{
+	"type":          "type",
+	"class":         "type",
+	"enum":          "type",
+	"interface":     "type",
+	"struct":        "type",
+	"typeParameter": "type",
+	"parameter":     "variable.parameter",
+	"variable":      "variable",
+	"property":      "property",
+	"enumMember":    "constant",
+	"event":         "property",
+	"function":      "function",
+	"method":        "function",
+	"macro":         "function.builtin",
+	"keyword":       "keyword",
+	"modifier":      "keyword",
+	"comment":       "comment",
+	"string":        "string",
+	"regexp":        "string",
+	"number":        "number",
+	"operator":      "operator",
+	"decorator":     "attribute",
+}
+
+// semanticStyle returns the style of a cell of tok, layered over the
+// tree-sitter style of the cell. plain is the style of text without
+// highlighting. Constants, builtins, deprecated and mutable symbols are
+// told apart by the token's modifiers.
+func (e *Editor) semanticStyle(tok semanticToken, syntax, plain tcell.Style) tcell.Style {
+	capture := semanticCaptures[tok.typ]
+	style := syntax
+	for _, mod := range tok.modifiers {
+		switch {
+		case mod == "readonly" && tok.typ == "variable":
+			capture = "constant"
+		case mod == "defaultLibrary" && capture != "":
+			capture += ".builtin"
+		}
+	}
+	if capture != "" {
+		if st, ok := e.captureStyle(capture); ok {
+			style = plain
+			if st != nil {
+				style = *st
+			}
+		}
+	}
+	for _, mod := range tok.modifiers {
+		switch mod {
+		case "deprecated":
+			style = style.StrikeThrough(true)
+		case "mutable":
+			style = style.Underline(true)
+		}
+	}
+	return style
+}
@@ -0,0 +1,147 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const decorationsSource = "package main\n\n// Deprecated: use greet.\nfunc hello(name string) {\n\tgreet(name)\n}\n\nfunc greet(name string) {}\n\nvar x = greet(\"a\")\n"
+
+// screenRow returns the text drawn on row y of the screen
+func screenRow(e *Editor, y int) string {
+	sim := e.s.(tcell.SimulationScreen)
+	w, _ := sim.Size()
+	var b strings.Builder
+	for x := 0; x < w; x++ {
+		r, _, _, _ := sim.GetContent(x, y)
+		b.WriteRune(r)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func TestSemanticTokens(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": decorationsSource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+	waitForLSP(t, e, "the tokens", func() bool {
+		e.updateLSPDecorations()
+		return len(e.buf().semanticTokens) > 0
+	})
+
+	tok, ok := semanticTokenAt(e.buf(), e.buffer.LineStart(3)+11)
+	if !ok || tok.typ != "parameter" {
+		t.Fatalf("token at name = %+v, %v", tok, ok)
+	}
+
+	e.draw()
+	sim := e.s.(tcell.SimulationScreen)
+	scheme := GetColorScheme(e.config.ColorScheme)
+	_, _, style, _ := sim.GetContent(5, 3) // hello, deprecated
+	if fg, _, attrs := style.Decompose(); fg != scheme.Function || attrs&tcell.AttrStrikeThrough == 0 {
+		t.Errorf("hello: fg %v attrs %v, want function color struck through", fg, attrs)
+	}
+	_, _, style, _ = sim.GetContent(11, 3) // the name parameter
+	if _, _, attrs := style.Decompose(); attrs&tcell.AttrItalic == 0 {
+		t.Errorf("parameter: attrs %v, want italic", attrs)
+	}
+
+	// tokens move with edits, and are requested again once they are sent
+	e.cy, e.cx = 3, 0
+	pressKeys(e, "i \x1b")
+	if tok, ok := semanticTokenAt(e.buf(), e.buffer.LineStart(3)+12); !ok || tok.typ != "parameter" {
+		t.Errorf("token after the edit = %+v, %v", tok, ok)
+	}
+	e.lspSyncChanges()
+	req := e.buf().tokensReq
+	e.updateLSPDecorations()
+	if e.buf().tokensReq.tick == req.tick {
+		t.Error("the tokens should be requested again after the changes were sent")
+	}
+
+	e.config.Options.SemanticTokens = false
+	e.updateLSPDecorations()
+	if e.buf().semanticTokens != nil {
+		t.Error("vb.opt.semantictokens = false should drop the tokens")
+	}
+}
+
+func TestSemanticTokensRange(t *testing.T) {
+	t.Setenv("VB_LSPTEST_SEMANTIC", "range")
+	e := newLSPTestEditor(t)
+	var src strings.Builder
+	src.WriteString("package main\n\nfunc f(a int) {}\n")
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&src, "var v%d = f(%d)\n", i, i)
+	}
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": src.String()})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+	waitForLSP(t, e, "the tokens", func() bool {
+		e.updateLSPDecorations()
+		return len(e.buf().semanticTokens) > 0
+	})
+	last := e.buffer.LineAt(e.buf().semanticTokens[len(e.buf().semanticTokens)-1].start)
+	if last > 24 {
+		t.Errorf("tokens reach line %d, want only the lines in view", last)
+	}
+
+	// scrolling asks for the lines now in view
+	e.cy = 55
+	e.ensureCursorVisible()
+	waitForLSP(t, e, "the scrolled tokens", func() bool {
+		e.updateLSPDecorations()
+		toks := e.buf().semanticTokens
+		return len(toks) > 0 && e.buffer.LineAt(toks[0].start) >= e.rowOffset
+	})
+}
+
+func TestInlayHints(t *testing.T) {
+	e := newLSPTestEditor(t)
+	e.config.Options.InlayHints = true
+	src := "package main\n\nfunc add(a, b int) int { return a + b }\n\nvar x = add(1, 2)\n"
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": src})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+	waitForLSP(t, e, "the hints", func() bool {
+		e.updateLSPDecorations()
+		return len(e.buf().inlayHints) == 2
+	})
+
+	e.cy, e.cx = 4, 15 // on the 2
+	e.draw()
+	if got := screenRow(e, 4); got != "var x = add(a: 1, b: 2)" {
+		t.Errorf("row = %q", got)
+	}
+	sim := e.s.(tcell.SimulationScreen)
+	_, _, style, _ := sim.GetContent(12, 4)
+	if fg, _, _ := style.Decompose(); fg != GetColorScheme(e.config.ColorScheme).Comment {
+		t.Errorf("hint color %v, want the comment color", fg)
+	}
+	if x, _, _ := e.cursorScreenPos(); x != 21 {
+		t.Errorf("cursor at screen column %d, want 21 after both hints", x)
+	}
+	if line := e.buffer.Line(4); line != "var x = add(1, 2)" {
+		t.Errorf("hints changed the buffer: %q", line)
+	}
+
+	// hints move with the text
+	pressKeys(e, "0ivar \x1b")
+	if pos := e.buf().inlayHints[0].pos; pos != e.buffer.LineStart(4)+16 {
+		t.Errorf("hint at %d after the edit, want %d", pos, e.buffer.LineStart(4)+16)
+	}
+
+	e.config.Options.InlayHints = false
+	e.updateLSPDecorations()
+	e.draw()
+	if got := screenRow(e, 4); got != "var var x = add(1, 2)" {
+		t.Errorf("row without hints = %q", got)
+	}
+}
@@ -0,0 +1,135 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+)
+
+// applyTextEdits applies edits from a language server, with positions in
+// enc units, to bv as one undo step. The cursor of the current buffer stays
+// on the text it was on.
+func (e *Editor) applyTextEdits(bv *BufferView, enc buffer.Encoding, edits []lsp.TextEdit) {
+	if len(edits) == 0 {
+		return
+	}
+	current := bv == e.buf()
+	cursor := -1
+	if current {
+		cursor = e.posFromCursor()
+	}
+	// an insert session is already one undo step
+	group := !current || e.mode != ModeInsert
+	if group {
+		bv.buffer.BeginUndoGroup()
+	}
+	cursor = applyEditsToBuffer(bv.buffer, enc, edits, cursor)
+	if group {
+		bv.buffer.EndUndoGroup()
+	}
+
+	if current {
+		e.setCursorFromPos(cursor)
+		e.wantX = e.cx
+		e.dirty = true
+		e.reparseBuffer()
+	} else {
+		bv.dirty = true
+	}
+}
+
+// applyEditsToBuffer applies edits to buf and returns where cursor moved
+// to. The ranges of the edits refer to the text before any of them is
+// applied, so they go in from the end of the buffer backwards.
+func applyEditsToBuffer(buf *buffer.Buffer, enc buffer.Encoding, edits []lsp.TextEdit, cursor int) int {
+	type change struct {
+		start, end, index int
+		text              string
+	}
+	changes := make([]change, 0, len(edits))
+	for i, ed := range edits {
+		start, end := lsp.OffsetAt(buf, ed.Range.Start, enc), lsp.OffsetAt(buf, ed.Range.End, enc)
+		changes = append(changes, change{start: start, end: max(start, end), index: i, text: ed.NewText})
+	}
+	// inserts at the same position go in in the order they were given
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].start != changes[j].start {
+			return changes[i].start > changes[j].start
+		}
+		return changes[i].index > changes[j].index
+	})
+
+	for _, c := range changes {
+		if c.end > c.start {
+			_ = buf.Delete(c.start, c.end)
+		}
+		if c.text != "" {
+			_ = buf.Insert(c.start, c.text)
+		}
+		n := utf8.RuneCountInString(c.text)
+		switch {
+		case cursor >= c.end:
+			cursor += n - (c.end - c.start)
+		case cursor > c.start:
+			cursor = c.start + n
+		}
+	}
+	return cursor
+}
+
+// applyWorkspaceEdit applies an edit from the language server srv across
+// files. Open buffers are edited and left modified; other files are edited
+// on disk. When a buffer changed since the server computed its edits,
+// nothing is applied. It returns the number of files changed.
+func (e *Editor) applyWorkspaceEdit(srv *lspServer, we *lsp.WorkspaceEdit) (int, error) {
+	docs, err := we.DocumentEdits()
+	if err != nil {
+		return 0, err
+	}
+	enc := srv.client.PositionEncoding()
+
+	e.syncToBuffer()
+	for _, doc := range docs {
+		path := lsp.PathFromURI(doc.TextDocument.URI)
+		bv, ds := e.bufferByName(path), srv.docs[path]
+		if doc.TextDocument.Version == nil || bv == nil || ds == nil {
+			continue
+		}
+		if *doc.TextDocument.Version != ds.Version() || bv.lspTick != bv.changeTick {
+			return 0, fmt.Errorf("%s changed since the edit was made", filepath.Base(path))
+		}
+	}
+
+	files := 0
+	for _, doc := range docs {
+		if len(doc.Edits) == 0 {
+			continue
+		}
+		path := lsp.PathFromURI(doc.TextDocument.URI)
+		if bv := e.bufferByName(path); bv != nil {
+			e.applyTextEdits(bv, enc, doc.Edits)
+			files++
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return files, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return files, err
+		}
+		buf := buffer.NewFromString(string(data))
+		applyEditsToBuffer(buf, enc, doc.Edits, -1)
+		if err := os.WriteFile(path, []byte(buf.String()), info.Mode().Perm()); err != nil {
+			return files, err
+		}
+		files++
+	}
+	return files, nil
+}
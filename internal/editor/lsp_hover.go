@@ -0,0 +1,150 @@
+package editor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+)
+
+// lspDocument returns the current buffer's language server and the
+// document open on it. Without one it sets the status and returns nils.
+func (e *Editor) lspDocument() (*lspServer, *lsp.DocumentSync) {
+	srv := e.lspCurrentServer()
+	switch {
+	case srv == nil:
+		e.statusMsg = "no language server for this buffer"
+	case srv.state == lspStarting:
+		e.statusMsg = "lsp: " + srv.cfg.Command + " is starting"
+	case srv.state == lspFailed:
+		e.statusMsg = fmt.Sprintf("lsp: %s: %v", srv.cfg.Command, srv.err)
+	case srv.docs[e.filename] == nil:
+		e.statusMsg = "buffer is not attached to " + srv.cfg.Command
+	default:
+		return srv, srv.docs[e.filename]
+	}
+	return nil, nil
+}
+
+// lspHover implements K, showing the documentation of the symbol under the
+// cursor in a float
+func (e *Editor) lspHover() {
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	if !srv.client.Capabilities().HoverProvider {
+		e.statusMsg = srv.cfg.Command + " does not support hover"
+		return
+	}
+	e.lspSyncChanges()
+	pos := ds.Position(e.buffer, e.posFromCursor())
+	bv, line, col := e.buf(), e.cy, e.cx
+
+	go func() {
+		text, err := ds.Hover(context.Background(), pos.Line, pos.Character)
+		e.post(func() {
+			if e.buf() != bv || e.cy != line || e.cx != col || e.mode != ModeNormal {
+				return // the cursor moved on
+			}
+			lines := markdownLines(text)
+			switch {
+			case err != nil:
+				e.statusMsg = "lsp: " + err.Error()
+			case len(lines) == 0:
+				e.statusMsg = "no hover information"
+			default:
+				e.openFloat(floatHover, lines)
+			}
+		})
+	}()
+}
+
+// lspSignatureTrigger runs after r was typed in insert mode. It requests
+// signature help when r is one of the server's trigger characters, and
+// updates signature help already shown on retrigger characters and ).
+func (e *Editor) lspSignatureTrigger(r rune) {
+	srv := e.lspCurrentServer()
+	if srv == nil || srv.state != lspRunning || srv.docs[e.filename] == nil {
+		return
+	}
+	opts := srv.client.Capabilities().SignatureHelpProvider
+	if opts == nil {
+		return
+	}
+	shown := e.float != nil && e.float.kind == floatSignature
+	ch := string(r)
+	if !slices.Contains(opts.TriggerCharacters, ch) &&
+		!(shown && (slices.Contains(opts.RetriggerCharacters, ch) || r == ')')) {
+		return
+	}
+	e.lspSignatureHelp(&lsp.SignatureHelpContext{
+		TriggerKind:      lsp.SignatureHelpTriggerCharacter,
+		TriggerCharacter: ch,
+		IsRetrigger:      shown,
+	})
+}
+
+// lspSignatureHelp shows the signature of the call the cursor is in above
+// the cursor line, or closes the one shown when the cursor left the call
+func (e *Editor) lspSignatureHelp(ctx *lsp.SignatureHelpContext) {
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	if srv.client.Capabilities().SignatureHelpProvider == nil {
+		e.statusMsg = srv.cfg.Command + " does not support signature help"
+		return
+	}
+	e.lspSyncChanges()
+	pos := ds.Position(e.buffer, e.posFromCursor())
+	bv, line, mode := e.buf(), e.cy, e.mode
+
+	go func() {
+		help, err := ds.SignatureHelp(context.Background(), pos.Line, pos.Character, ctx)
+		e.post(func() {
+			if e.buf() != bv || e.cy != line || e.mode != mode {
+				return
+			}
+			if err != nil || help == nil {
+				if e.float != nil && e.float.kind == floatSignature {
+					e.closeFloat()
+				}
+				if err != nil {
+					e.statusMsg = "lsp: " + err.Error()
+				}
+				return
+			}
+			e.openFloat(floatSignature, signatureLines(help))
+		})
+	}()
+}
+
+// signatureLines returns the float lines of the active signature of help:
+// its label with the active parameter highlighted, then the documentation
+// of the parameter and of the signature
+func signatureLines(help *lsp.SignatureHelp) []floatLine {
+	sig, param := help.Active()
+	label := floatLine{text: sig.Label, kind: floatCode}
+	if start, end, ok := sig.ParameterSpan(param); ok {
+		label.hlStart, label.hlEnd = start, end
+	}
+	lines := []floatLine{label}
+
+	var doc []floatLine
+	if param >= 0 {
+		doc = append(doc, markdownLines(lsp.HoverText(sig.Parameters[param].Documentation))...)
+	}
+	if sigDoc := markdownLines(lsp.HoverText(sig.Documentation)); len(sigDoc) > 0 {
+		if len(doc) > 0 {
+			doc = append(doc, floatLine{})
+		}
+		doc = append(doc, sigDoc...)
+	}
+	if len(doc) > 0 {
+		lines = append(lines, floatLine{kind: floatRule})
+		lines = append(lines, doc...)
+	}
+	return lines
+}
@@ -0,0 +1,143 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestMarkdownLines(t *testing.T) {
+	md := "```go\nfunc Println(a ...any) (n int, err error)\n```\n\n---\n\n\n# Println\n" +
+		"Println formats using the **default** formats for `a`. See [fmt](https://pkg.go.dev/fmt) and \\*args.\n\n"
+	got := markdownLines(md)
+	want := []floatLine{
+		{text: "func Println(a ...any) (n int, err error)", kind: floatCode},
+		{},
+		{kind: floatRule},
+		{},
+		{text: "Println", kind: floatHeading},
+		{text: "Println formats using the default formats for a. See fmt and *args."},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got  %v\nwant %v", got, want)
+	}
+
+	wrapped := wrapFloatLines([]floatLine{{text: "func f(alpha int, beta int)", hlStart: 18, hlEnd: 26}}, 20)
+	if len(wrapped) != 2 || wrapped[1].text != "beta int)" || wrapped[1].hlStart != 0 || wrapped[1].hlEnd != 8 {
+		t.Fatalf("wrapped = %v", wrapped)
+	}
+}
+
+const lspHoverSource = "package main\n\nfunc add(a int, b int) int {\n\treturn a + b\n}\n\nvar x = add(1, 2)\n"
+
+func TestLSPHover(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": lspHoverSource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	e.cy, e.cx = 6, 9 // on add
+	pressKeys(e, "K")
+	waitForLSP(t, e, "hover", func() bool { return e.float != nil })
+	if e.float.kind != floatHover || len(e.float.lines) != 1 || e.float.lines[0].text != "add" {
+		t.Fatalf("float = %+v", e.float)
+	}
+
+	// the float is drawn below the cursor line
+	e.draw()
+	sim := e.s.(tcell.SimulationScreen)
+	x, y, _ := e.cursorScreenPos()
+	row := ""
+	for col := x; col < x+6; col++ {
+		r, _, _, _ := sim.GetContent(col, y+2)
+		row += string(r)
+	}
+	if row != "│ add " {
+		t.Errorf("float row = %q", row)
+	}
+
+	pressKeys(e, "l")
+	if e.float != nil {
+		t.Fatal("moving the cursor should close the hover")
+	}
+
+	// nothing to show
+	e.cy, e.cx = 1, 0
+	pressKeys(e, "K")
+	waitForLSP(t, e, "empty hover", func() bool { return e.statusMsg == "no hover information" })
+}
+
+func TestFloatScroll(t *testing.T) {
+	e := newTestEditor(t, "one\ntwo\n")
+	e.config = &config.Config{ColorScheme: "default"}
+	var md []string
+	for i := 1; i <= 30; i++ {
+		md = append(md, fmt.Sprintf("line %d\n", i))
+	}
+	e.openFloat(floatHover, markdownLines(strings.Join(md, "\n")))
+	e.draw()
+	if e.float.height != floatMaxHeight {
+		t.Fatalf("float height = %d", e.float.height)
+	}
+
+	e.handleKey(tcell.NewEventKey(tcell.KeyCtrlF, 0, tcell.ModNone))
+	e.draw()
+	if e.float.scroll != floatMaxHeight-1 {
+		t.Fatalf("scroll after Ctrl-F = %d", e.float.scroll)
+	}
+	for i := 0; i < 10; i++ {
+		e.handleKey(tcell.NewEventKey(tcell.KeyCtrlF, 0, tcell.ModNone))
+	}
+	e.draw()
+	maxScroll := len(e.float.lines) - floatMaxHeight
+	if e.float.scroll != maxScroll {
+		t.Fatalf("scroll past the end = %d, want %d", e.float.scroll, maxScroll)
+	}
+	e.handleKey(tcell.NewEventKey(tcell.KeyCtrlB, 0, tcell.ModNone))
+	e.draw()
+	if e.float.scroll != maxScroll-(floatMaxHeight-1) {
+		t.Fatalf("scroll after Ctrl-B = %d", e.float.scroll)
+	}
+
+	pressKeys(e, "\x1b")
+	if e.float != nil {
+		t.Fatal("Esc should close the float")
+	}
+}
+
+func TestLSPSignatureHelp(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": lspHoverSource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	activeParam := func() string {
+		if e.float == nil || e.float.kind != floatSignature {
+			return ""
+		}
+		label := e.float.lines[0]
+		return string([]rune(label.text)[label.hlStart:label.hlEnd])
+	}
+
+	e.cy = 6
+	pressKeys(e, "oadd(")
+	waitForLSP(t, e, "signature help", func() bool { return activeParam() == "a int" })
+	if e.float.lines[0].text != "func add(a int, b int)" || e.float.lines[2].text != "Calls add." {
+		t.Fatalf("signature float = %v", e.float.lines)
+	}
+	pressKeys(e, "1,")
+	waitForLSP(t, e, "the second parameter", func() bool { return activeParam() == "b int" })
+	pressKeys(e, " 2)")
+	waitForLSP(t, e, "the float to close", func() bool { return e.float == nil })
+
+	pressKeys(e, "\x1b")
+	if e.mode != ModeNormal {
+		t.Fatal("Esc should leave insert mode")
+	}
+}
@@ -0,0 +1,461 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+)
+
+// lspMaxRestarts is how many times a crashed server is restarted before it
+// is given up on
+const lspMaxRestarts = 3
+
+type lspServerState int
+
+const (
+	lspStarting lspServerState = iota
+	lspRunning
+	lspFailed
+)
+
+func (s lspServerState) String() string {
+	switch s {
+	case lspStarting:
+		return "starting"
+	case lspRunning:
+		return "running"
+	default:
+		return "failed"
+	}
+}
+
+// lspServer is a language server the editor started for the buffers of one
+// language under one project root. Filetypes configured with the same
+// command share it.
+type lspServer struct {
+	key      string
+	cfg      config.LSPServer
+	root     string
+	client   *lsp.Client // nil once stopped
+	state    lspServerState
+	err      error
+	restarts int
+	docs     map[string]*lsp.DocumentSync // attached buffers by path
+}
+
+// lspServerConfig returns the server configured for filetype
+func (e *Editor) lspServerConfig(filetype string) (config.LSPServer, bool) {
+	if e.config == nil || e.config.Options == nil || !e.config.Options.LSP {
+		return config.LSPServer{}, false
+	}
+	server, ok := e.config.LSPServers[filetype]
+	return server, ok && server.Command != ""
+}
+
+// lspFindRoot returns the nearest directory above path containing one of
+// markers, or the directory of path when there is none
+func lspFindRoot(path string, markers []string) string {
+	dir := filepath.Dir(path)
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d
+			}
+		}
+		if filepath.Dir(d) == d {
+			return dir
+		}
+	}
+}
+
+// lspServerFor returns the key and configuration of the server that serves
+// bv, and its filetype
+func (e *Editor) lspServerFor(bv *BufferView) (key string, cfg config.LSPServer, filetype string, ok bool) {
+	if bv == nil || bv.filename == "" {
+		return "", cfg, "", false
+	}
+	ft := detectFiletype(bv.filename)
+	if ft == nil {
+		return "", cfg, "", false
+	}
+	cfg, ok = e.lspServerConfig(ft.Name)
+	if !ok {
+		return "", cfg, "", false
+	}
+	root := lspFindRoot(bv.filename, cfg.RootMarkers)
+	return cfg.Command + "@" + root, cfg, ft.Name, true
+}
+
+// lspStartFor attaches bv to its language server, starting the server if
+// this is the first buffer of its language and root
+func (e *Editor) lspStartFor(bv *BufferView) {
+	key, cfg, _, ok := e.lspServerFor(bv)
+	if !ok {
+		return
+	}
+	srv := e.lspServers[key]
+	if srv == nil {
+		if e.lspServers == nil {
+			e.lspServers = make(map[string]*lspServer)
+		}
+		srv = &lspServer{
+			key:  key,
+			cfg:  cfg,
+			root: lspFindRoot(bv.filename, cfg.RootMarkers),
+			docs: make(map[string]*lsp.DocumentSync),
+		}
+		e.lspServers[key] = srv
+		e.lspStart(srv)
+		return
+	}
+	if srv.state == lspRunning {
+		e.lspAttach(srv, bv)
+	}
+}
+
+// lspStart spawns the server process and initializes it in the background
+func (e *Editor) lspStart(srv *lspServer) {
+	client, err := lsp.NewClient(srv.cfg.Command, srv.cfg.Args, lsp.URIFromPath(srv.root))
+	if err != nil {
+		srv.state = lspFailed
+		srv.err = err
+		if !errors.Is(err, exec.ErrNotFound) {
+			e.statusMsg = fmt.Sprintf("lsp: %s: %v", srv.cfg.Command, err)
+		}
+		return
+	}
+	srv.client = client
+	srv.state = lspStarting
+	srv.err = nil
+	client.SetNotificationHandler(func(method string, params json.RawMessage) {
+		e.lspHandleNotification(srv, client, method, params)
+	})
+	client.SetSettings(srv.cfg.Settings)
+	client.HandleRequest("workspace/applyEdit", func(params json.RawMessage) (interface{}, error) {
+		return e.lspHandleApplyEdit(srv, client, params)
+	})
+	client.HandleRequest("window/showMessageRequest", func(params json.RawMessage) (interface{}, error) {
+		var p lsp.ShowMessageRequestParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		e.post(func() { e.statusMsg = srv.cfg.Command + ": " + p.Message })
+		return nil, nil // no action chosen
+	})
+
+	go func() {
+		err := client.Initialize()
+		e.post(func() { e.lspInitialized(srv, client, err) })
+	}()
+	go func() {
+		<-client.Done()
+		e.post(func() { e.lspExited(srv, client) })
+	}()
+}
+
+// lspInitialized runs when the initialize request of client has finished
+// and attaches the open buffers the server serves
+func (e *Editor) lspInitialized(srv *lspServer, client *lsp.Client, err error) {
+	if srv.client != client {
+		return // stopped or restarted meanwhile
+	}
+	if err != nil {
+		srv.client = nil
+		srv.state = lspFailed
+		srv.err = err
+		e.statusMsg = fmt.Sprintf("lsp: %s: %v", srv.cfg.Command, err)
+		e.lspClose(client)
+		return
+	}
+
+	srv.state = lspRunning
+	for _, bv := range e.buffers {
+		if key, _, _, ok := e.lspServerFor(bv); ok && key == srv.key {
+			e.lspAttach(srv, bv)
+		}
+	}
+}
+
+// lspExited runs when the process of client has exited. A server that was
+// not stopped has crashed and is restarted.
+func (e *Editor) lspExited(srv *lspServer, client *lsp.Client) {
+	if srv.client != client || e.lspServers[srv.key] != srv {
+		return
+	}
+	srv.client = nil
+	e.lspClearDiagnostics(srv)
+	lspForgetDocs(srv)
+
+	srv.restarts++
+	if srv.restarts > lspMaxRestarts {
+		srv.state = lspFailed
+		srv.err = fmt.Errorf("exited %d times: %v", srv.restarts, client.ExitError())
+		e.statusMsg = fmt.Sprintf("lsp: %s %v", srv.cfg.Command, srv.err)
+		return
+	}
+	e.statusMsg = fmt.Sprintf("lsp: %s exited, restarting", srv.cfg.Command)
+	e.lspStart(srv)
+}
+
+// lspHandleApplyEdit answers a workspace/applyEdit request of client. It
+// runs on the client's goroutine and waits for the edit to be applied on
+// the editor's.
+func (e *Editor) lspHandleApplyEdit(srv *lspServer, client *lsp.Client, params json.RawMessage) (interface{}, error) {
+	var p lsp.ApplyWorkspaceEditParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	done := make(chan lsp.ApplyWorkspaceEditResult, 1)
+	e.post(func() {
+		if srv.client != client {
+			done <- lsp.ApplyWorkspaceEditResult{FailureReason: "server stopped"}
+			return
+		}
+		if _, err := e.applyWorkspaceEdit(srv, &p.Edit); err != nil {
+			e.statusMsg = "lsp: " + err.Error()
+			done <- lsp.ApplyWorkspaceEditResult{FailureReason: err.Error()}
+			return
+		}
+		done <- lsp.ApplyWorkspaceEditResult{Applied: true}
+	})
+	select {
+	case result := <-done:
+		return result, nil
+	case <-client.Done():
+		return nil, errors.New("server exited")
+	}
+}
+
+// lspAttach opens bv on the server
+func (e *Editor) lspAttach(srv *lspServer, bv *BufferView) {
+	if srv.client == nil || srv.docs[bv.filename] != nil {
+		return
+	}
+	filetype := ""
+	if ft := detectFiletype(bv.filename); ft != nil {
+		filetype = ft.Name
+	}
+	ds := lsp.NewDocumentSync(srv.client, bv.filename)
+	if err := ds.DidOpen(lsp.LanguageID(filetype, bv.filename), bv.buffer.String()); err != nil {
+		return // the exit handler restarts the server
+	}
+	ds.Track(bv.buffer)
+	srv.docs[bv.filename] = ds
+}
+
+// lspStop shuts srv down and forgets it
+func (e *Editor) lspStop(srv *lspServer) {
+	delete(e.lspServers, srv.key)
+	if srv.client != nil {
+		e.lspClose(srv.client)
+		srv.client = nil
+	}
+	e.lspClearDiagnostics(srv)
+	lspForgetDocs(srv)
+}
+
+// lspForgetDocs drops the documents open on srv, whose process is gone
+func lspForgetDocs(srv *lspServer) {
+	for _, ds := range srv.docs {
+		ds.Untrack()
+	}
+	clear(srv.docs)
+}
+
+// lspStopAll shuts all servers down
+func (e *Editor) lspStopAll() {
+	if e.lspSyncTimer != nil {
+		e.lspSyncTimer.Stop()
+	}
+	for _, srv := range e.lspServers {
+		e.lspStop(srv)
+	}
+	e.lspClosing.Wait()
+}
+
+// lspClose shuts client down on its own goroutine, since a server that
+// does not answer holds Close for up to a second. lspStopAll waits for the
+// servers still shutting down when the editor exits.
+func (e *Editor) lspClose(client *lsp.Client) {
+	e.lspClosing.Add(1)
+	go func() {
+		defer e.lspClosing.Done()
+		_ = client.Close()
+	}()
+}
+
+// lspCurrentServer returns the server of the current buffer
+func (e *Editor) lspCurrentServer() *lspServer {
+	key, _, _, ok := e.lspServerFor(e.buf())
+	if !ok {
+		return nil
+	}
+	return e.lspServers[key]
+}
+
+// lspServerList returns the servers sorted by key
+func (e *Editor) lspServerList() []*lspServer {
+	list := make([]*lspServer, 0, len(e.lspServers))
+	for _, srv := range e.lspServers {
+		list = append(list, srv)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].key < list[j].key })
+	return list
+}
+
+// lspRestartCommand implements :LspRestart [all]. Restarted servers start
+// with a clean crash count and attach their buffers again once ready.
+// Without a server, the current buffer's is started.
+func (e *Editor) lspRestartCommand(arg string) {
+	var servers []*lspServer
+	if arg == "all" {
+		servers = e.lspServerList()
+	} else if srv := e.lspCurrentServer(); srv != nil {
+		servers = []*lspServer{srv}
+	}
+
+	if len(servers) == 0 {
+		if _, _, _, ok := e.lspServerFor(e.buf()); !ok {
+			e.statusMsg = "no language server for this buffer"
+			return
+		}
+		e.lspStartFor(e.buf())
+		servers = []*lspServer{e.lspCurrentServer()}
+	} else {
+		for i, srv := range servers {
+			e.lspStop(srv)
+			fresh := &lspServer{
+				key:  srv.key,
+				cfg:  srv.cfg,
+				root: srv.root,
+				docs: make(map[string]*lsp.DocumentSync),
+			}
+			e.lspServers[fresh.key] = fresh
+			e.lspStart(fresh)
+			servers[i] = fresh
+		}
+	}
+
+	names := make([]string, 0, len(servers))
+	for _, srv := range servers {
+		if srv.state == lspFailed {
+			e.statusMsg = fmt.Sprintf("lsp: %s: %v", srv.cfg.Command, srv.err)
+			return
+		}
+		names = append(names, srv.cfg.Command)
+	}
+	e.statusMsg = "lsp: starting " + strings.Join(names, ", ")
+}
+
+// lspStopCommand implements :LspStop [all]
+func (e *Editor) lspStopCommand(arg string) {
+	var servers []*lspServer
+	if arg == "all" {
+		servers = e.lspServerList()
+	} else if srv := e.lspCurrentServer(); srv != nil {
+		servers = []*lspServer{srv}
+	}
+	if len(servers) == 0 {
+		e.statusMsg = "no language server running"
+		return
+	}
+	names := make([]string, 0, len(servers))
+	for _, srv := range servers {
+		e.lspStop(srv)
+		names = append(names, srv.cfg.Command)
+	}
+	e.statusMsg = "lsp: stopped " + strings.Join(names, ", ")
+}
+
+// lspLog implements :LspLog [all], showing the stderr of the current
+// buffer's server (or all) and the messages exchanged with it, scrolled to
+// the end
+func (e *Editor) lspLog(arg string) {
+	var servers []*lspServer
+	if arg == "all" {
+		servers = e.lspServerList()
+	} else if srv := e.lspCurrentServer(); srv != nil {
+		servers = []*lspServer{srv}
+	}
+
+	var lines []string
+	for _, srv := range servers {
+		if srv.client == nil {
+			continue
+		}
+		if len(servers) > 1 {
+			lines = append(lines, "== "+srv.key)
+		}
+		lines = append(lines, srv.client.Log()...)
+	}
+	if len(lines) == 0 {
+		e.statusMsg = "no language server running"
+		return
+	}
+
+	e.popupFixedH = 20
+	e.openPopup("LSP LOG", lines)
+	e.popupScroll = len(lines) // clamped to the last page
+}
+
+// lspInfo implements :LspInfo, listing the servers and the buffers
+// attached to them
+func (e *Editor) lspInfo() {
+	var lines []string
+
+	if bv := e.buf(); bv != nil {
+		ft := detectFiletype(bv.filename)
+		switch {
+		case ft == nil:
+			lines = append(lines, "current buffer: no filetype")
+		default:
+			if cfg, ok := e.lspServerConfig(ft.Name); ok {
+				lines = append(lines, fmt.Sprintf("current buffer: %s, server %s", ft.Name, strings.Join(append([]string{cfg.Command}, cfg.Args...), " ")))
+			} else {
+				lines = append(lines, fmt.Sprintf("current buffer: %s, no server configured", ft.Name))
+			}
+		}
+		lines = append(lines, "")
+	}
+
+	servers := e.lspServerList()
+	if len(servers) == 0 {
+		lines = append(lines, "no language servers")
+	}
+	for _, srv := range servers {
+		status := srv.state.String()
+		if srv.client != nil {
+			status += fmt.Sprintf(", pid %d", srv.client.Pid())
+		}
+		if srv.restarts > 0 {
+			status += fmt.Sprintf(", restarted %d times", srv.restarts)
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s)", srv.cfg.Command, status))
+		lines = append(lines, "  root: "+srv.root)
+		if srv.err != nil {
+			lines = append(lines, "  error: "+srv.err.Error())
+		}
+		paths := make([]string, 0, len(srv.docs))
+		for path := range srv.docs {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if rel, err := filepath.Rel(srv.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			lines = append(lines, "  attached: "+path)
+		}
+	}
+
+	e.popupFixedH = 0 // auto-size
+	e.openPopup("LSP", lines)
+}
@@ -0,0 +1,288 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp/lsptest"
+	"github.com/gdamore/tcell/v2"
+)
+
+// TestMain runs the test binary as a language server when the tests start
+// it as one
+func TestMain(m *testing.M) {
+	if os.Getenv("VB_LSPTEST_SERVER") == "1" {
+		if err := lsptest.Serve(os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		if os.Getenv("VB_LSPTEST_HANG") == "shutdown" {
+			// a server stuck shutting down, until it is killed
+			time.Sleep(10 * time.Second)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// newLSPTestEditor returns an editor that starts the test binary as the
+// language server of go files
+func newLSPTestEditor(t *testing.T) *Editor {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("VB_LSPTEST_SERVER", "1")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEditor(t, "")
+	e.config = &config.Config{
+		ColorScheme: "default",
+		Options:     config.DefaultOptions(),
+		LSPServers: map[string]config.LSPServer{
+			"go": {Command: exe, RootMarkers: []string{"go.mod"}},
+		},
+	}
+	t.Cleanup(e.lspStopAll)
+	return e
+}
+
+// writeLSPTestFiles writes files relative to a new directory and returns it
+func writeLSPTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// waitForLSP runs posted functions until cond holds
+func waitForLSP(t *testing.T, e *Editor, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		e.runAsync()
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// attached reports whether the server has the file at path open
+func attached(srv *lspServer, path string) bool {
+	return srv != nil && srv.state == lspRunning && srv.docs[path] != nil
+}
+
+func TestLSPServerPerRoot(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{
+		"go.mod":       "module example\n",
+		"main.go":      "package main\n",
+		"pkg/util.go":  "package pkg\n",
+		"other/x.go":   "package x\n",
+		"other/go.mod": "module other\n",
+		"notes.txt":    "notes\n",
+	})
+
+	main := filepath.Join(dir, "main.go")
+	util := filepath.Join(dir, "pkg", "util.go")
+	e.openFile(main)
+	e.openFile(util)
+	e.openFile(filepath.Join(dir, "notes.txt"))
+	if len(e.lspServers) != 1 {
+		t.Fatalf("got %d servers, want one for the module", len(e.lspServers))
+	}
+	srv := e.lspServerList()[0]
+	if srv.root != dir {
+		t.Errorf("root = %s, want %s", srv.root, dir)
+	}
+	waitForLSP(t, e, "both buffers to attach", func() bool {
+		return attached(srv, main) && attached(srv, util)
+	})
+
+	// a nested module is its own root
+	e.openFile(filepath.Join(dir, "other", "x.go"))
+	if len(e.lspServers) != 2 {
+		t.Fatalf("got %d servers, want a second one for the nested module", len(e.lspServers))
+	}
+
+	// buffers opened after the server is ready attach at once
+	e.exec("bd")
+	e.openFile(filepath.Join(dir, "other", "x.go"))
+	srv = e.lspCurrentServer()
+	waitForLSP(t, e, "the nested module to attach", func() bool {
+		return attached(srv, filepath.Join(dir, "other", "x.go"))
+	})
+	e.exec("bd")
+	if len(srv.docs) != 0 {
+		t.Error(":bd should detach the buffer")
+	}
+}
+
+func TestLSPServerRestartsAfterCrash(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": "package main\n"})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	srv := e.lspCurrentServer()
+	waitForLSP(t, e, "attach", func() bool { return attached(srv, main) })
+
+	pid := srv.client.Pid()
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = proc.Kill()
+
+	waitForLSP(t, e, "restart", func() bool {
+		return srv.restarts == 1 && srv.client != nil && srv.client.Pid() != pid && attached(srv, main)
+	})
+	if !strings.Contains(e.statusMsg, "restarting") {
+		t.Errorf("status = %q", e.statusMsg)
+	}
+}
+
+func TestLSPCommands(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": "package main\n"})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	e.exec("LspInfo")
+	info := strings.Join(e.popupLines, "\n")
+	for _, want := range []string{"current buffer: go", "running", "root: " + dir, "attached: main.go"} {
+		if !strings.Contains(info, want) {
+			t.Errorf(":LspInfo lacks %q:\n%s", want, info)
+		}
+	}
+
+	e.exec("LspStop")
+	if len(e.lspServers) != 0 {
+		t.Fatal(":LspStop left the server running")
+	}
+	e.exec("LspRestart")
+	waitForLSP(t, e, "attach after :LspRestart", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	// a server that cannot start is reported
+	e.config.LSPServers["go"] = config.LSPServer{Command: "voidabyss-no-such-server"}
+	e.exec("LspStop")
+	e.exec("LspRestart")
+	e.exec("LspInfo")
+	if info := strings.Join(e.popupLines, "\n"); !strings.Contains(info, "failed") {
+		t.Errorf(":LspInfo should show the failed server:\n%s", info)
+	}
+
+	e.config.Options.LSP = false
+	e.exec("LspRestart")
+	if e.statusMsg != "no language server for this buffer" {
+		t.Errorf("status = %q", e.statusMsg)
+	}
+}
+
+func TestLSPStopDoesNotWait(t *testing.T) {
+	t.Setenv("VB_LSPTEST_HANG", "shutdown")
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": "package main\n"})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	// the server never answers shutdown; it is closed in the background
+	start := time.Now()
+	e.exec("LspRestart")
+	e.exec("LspStop")
+	if d := time.Since(start); d > lsp.CloseTimeout/2 {
+		t.Errorf(":LspRestart and :LspStop took %v", d)
+	}
+	if len(e.lspServers) != 0 {
+		t.Fatal(":LspStop left the server running")
+	}
+}
+
+func TestLSPSyncChanges(t *testing.T) {
+	for _, kind := range []string{"incremental", "full"} {
+		t.Run(kind, func(t *testing.T) {
+			t.Setenv("VB_LSPTEST_SYNC", kind)
+			e := newLSPTestEditor(t)
+			dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": "package main\n\nvar s = \"😀\"\n"})
+			main := filepath.Join(dir, "main.go")
+			e.openFile(main)
+			srv := e.lspCurrentServer()
+			waitForLSP(t, e, "attach", func() bool { return attached(srv, main) })
+			ds, bv := srv.docs[main], e.buf()
+
+			// the keys typed in a row are sent together once they stop
+			e.cy = 2
+			pressKeys(e, "A // ERROR\x1b")
+			e.lspScheduleSync()
+			if ds.Version() != 1 {
+				t.Fatalf("changes were sent at once, version %d", ds.Version())
+			}
+			waitForLSP(t, e, "the diagnostic", func() bool { return len(bv.diagnostics) == 1 })
+			if ds.Version() != 2 {
+				t.Errorf("version = %d, want one didChange", ds.Version())
+			}
+			if line, col := bv.buffer.LineCol(bv.diagnostics[0].start, buffer.EncodingRune); line != 2 || col != 15 {
+				t.Errorf("diagnostic at %d:%d, want 2:15", line, col)
+			}
+
+			// undo is synced like any other change
+			pressKeys(e, "u")
+			e.lspSyncChanges()
+			waitForLSP(t, e, "the diagnostic to go", func() bool { return len(bv.diagnostics) == 0 })
+			if ds.Version() != 3 {
+				t.Errorf("version = %d after undo", ds.Version())
+			}
+		})
+	}
+}
+
+func TestLSPServerRequests(t *testing.T) {
+	e := newLSPTestEditor(t)
+	cfg := e.config.LSPServers["go"]
+	cfg.Settings = map[string]interface{}{"lsptest": map[string]interface{}{"level": "strict"}}
+	e.config.LSPServers["go"] = cfg
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": "package main\n\nvar x = WARNING\n"})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "diagnostics", func() bool { return len(e.buf().diagnostics) == 1 })
+
+	// workspace/configuration is answered from the settings, and the
+	// server's stderr is logged
+	e.exec("LspLog")
+	log := strings.Join(e.popupLines, "\n")
+	for _, want := range []string{"stderr: lsptest: initialize " + dir, `{\"level\":\"strict\"}`} {
+		if !strings.Contains(log, want) {
+			t.Errorf(":LspLog lacks %s:\n%s", want, log)
+		}
+	}
+	e.closePopup()
+
+	// the command of a code action edits the buffer with workspace/applyEdit
+	e.cy, e.cx = 2, 8
+	pressKeys(e, "ga")
+	waitForLSP(t, e, "code actions", func() bool { return e.popupActive })
+	if len(e.popupLines) != 1 || !strings.HasSuffix(e.popupLines[0], "Remove WARNING [quickfix]") {
+		t.Fatalf("code actions = %q", e.popupLines)
+	}
+	e.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	waitForLSP(t, e, "the applied edit", func() bool { return e.buffer.Line(2) == "var x = " })
+	waitForLSP(t, e, "the answer to workspace/applyEdit", func() bool {
+		return strings.Contains(strings.Join(e.lspCurrentServer().client.Log(), "\n"), `\"applied\":true`)
+	})
+}
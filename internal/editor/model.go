@@ -1,61 +1,62 @@
 package editor
 
-// NOTE: this recomputes a lot by calling buffer.String(). It's fine for now.
+import "github.com/dragonbytelabs/voidabyss/core/buffer"
+
+// Line queries go through the buffer's line index; motions that scan
+// across lines read the text a line at a time with a runeReader.
+
+// runeReader reads the runes of a buffer by offset, loading the line
+// holding the offset, so scans around the cursor copy only the lines they
+// pass.
+type runeReader struct {
+	buf   *buffer.Buffer
+	n     int    // length of the buffer in runes
+	start int    // offset of the loaded line
+	line  []rune // the loaded line with its newline
+}
 
-func (e *Editor) textRunes() []rune { return []rune(e.buffer.String()) }
+func (e *Editor) runeReader() *runeReader {
+	return &runeReader{buf: e.buffer, n: e.buffer.Len()}
+}
 
+// at returns the rune at pos, which must be in [0, n)
+func (r *runeReader) at(pos int) rune {
+	if pos < r.start || pos >= r.start+len(r.line) {
+		y := r.buf.LineAt(pos)
+		r.start = r.buf.LineStart(y)
+		s, _ := r.buf.Slice(r.start, min(r.buf.LineEnd(y)+1, r.n))
+		r.line = []rune(s)
+	}
+	return r.line[pos-r.start]
+}
+
+// lineStarts returns the start offset of every line.
+// Prefer lineStartPos for single lookups; this walks every line.
 func (e *Editor) lineStarts() []int {
-	r := e.textRunes()
-	starts := []int{0}
-	for i, ch := range r {
-		if ch == '\n' {
-			if i+1 <= len(r) {
-				starts = append(starts, i+1)
-			}
-		}
+	n := e.buffer.LineCount()
+	starts := make([]int, n)
+	for y := 0; y < n; y++ {
+		starts[y] = e.buffer.LineStart(y)
 	}
 	return starts
 }
 
 func (e *Editor) lineCount() int {
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		return 1
-	}
-	return len(starts)
+	return e.buffer.LineCount()
 }
 
 func (e *Editor) getLine(y int) string {
-	r := e.textRunes()
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		return ""
-	}
-	y = clamp(y, 0, len(starts)-1)
-
-	start := starts[y]
-	end := len(r)
-	if y+1 < len(starts) {
-		end = starts[y+1] - 1
-		if end < start {
-			end = start
-		}
-	}
-	return string(r[start:end])
+	return e.buffer.Line(y)
 }
 
 func (e *Editor) lineLen(y int) int {
-	return len([]rune(e.getLine(y)))
+	return e.buffer.LineLen(y)
 }
 
 func (e *Editor) posFromCursor() int {
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		return 0
-	}
-	e.cy = clamp(e.cy, 0, len(starts)-1)
+	e.cy = clamp(e.cy, 0, e.lineCount()-1)
 
-	lineStart := starts[e.cy]
+	lineStart := e.buffer.LineStart(e.cy)
 	ll := e.lineLen(e.cy)
 	e.cx = clamp(e.cx, 0, ll)
 	return lineStart + e.cx
@@ -69,26 +70,8 @@ func (e *Editor) setCursorFromPos(pos int) {
 		pos = e.buffer.Len()
 	}
 
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		e.cy, e.cx = 0, 0
-		return
-	}
-
-	lo, hi := 0, len(starts)-1
-	best := 0
-	for lo <= hi {
-		m := (lo + hi) / 2
-		if starts[m] <= pos {
-			best = m
-			lo = m + 1
-		} else {
-			hi = m - 1
-		}
-	}
-
-	e.cy = best
-	e.cx = pos - starts[best]
+	e.cy = e.buffer.LineAt(pos)
+	e.cx = pos - e.buffer.LineStart(e.cy)
 	ll := e.lineLen(e.cy)
 	if e.cx > ll {
 		e.cx = ll
@@ -129,10 +112,5 @@ func (e *Editor) ensureCursorVisible() {
 }
 
 func (e *Editor) lineStartPos(y int) int {
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		return 0
-	}
-	y = clamp(y, 0, len(starts)-1)
-	return starts[y]
+	return e.buffer.LineStart(y)
 }
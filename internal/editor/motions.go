@@ -21,8 +21,7 @@ func (e *Editor) moveRight(n int) {
 }
 
 func (e *Editor) openBelow() {
-	starts := e.lineStarts()
-	lineStart := starts[e.cy]
+	lineStart := e.lineStartPos(e.cy)
 	pos := lineStart + e.lineLen(e.cy)
 	_ = e.buffer.Insert(pos, "\n")
 	e.setCursorFromPos(pos + 1)
@@ -31,8 +30,7 @@ func (e *Editor) openBelow() {
 }
 
 func (e *Editor) openAbove() {
-	starts := e.lineStarts()
-	lineStart := starts[e.cy]
+	lineStart := e.lineStartPos(e.cy)
 	_ = e.buffer.Insert(lineStart, "\n")
 	e.setCursorFromPos(lineStart)
 	e.wantX = 0
@@ -46,7 +44,7 @@ func (e *Editor) moveWordForward(count int, big bool) {
 		count = 1
 	}
 	pos := e.posFromCursor()
-	r := e.textRunes()
+	r := e.runeReader()
 
 	for i := 0; i < count; i++ {
 		pos = wordForwardStart(r, pos, big)
@@ -60,7 +58,7 @@ func (e *Editor) moveWordBack(count int, big bool) {
 		count = 1
 	}
 	pos := e.posFromCursor()
-	r := e.textRunes()
+	r := e.runeReader()
 
 	for i := 0; i < count; i++ {
 		pos = wordBackStart(r, pos, big)
@@ -74,7 +72,7 @@ func (e *Editor) moveWordEnd(count int, big bool) {
 		count = 1
 	}
 	pos := e.posFromCursor()
-	r := e.textRunes()
+	r := e.runeReader()
 
 	for i := 0; i < count; i++ {
 		pos = wordEnd(r, pos, big)
@@ -83,12 +81,12 @@ func (e *Editor) moveWordEnd(count int, big bool) {
 	e.wantX = e.cx
 }
 
-func wordForwardStart(r []rune, pos int, big bool) int {
+func wordForwardStart(r *runeReader, pos int, big bool) int {
 	if pos < 0 {
 		pos = 0
 	}
-	if pos >= len(r) {
-		return len(r)
+	if pos >= r.n {
+		return r.n
 	}
 	isUnit := isWordChar
 	if big {
@@ -96,22 +94,22 @@ func wordForwardStart(r []rune, pos int, big bool) int {
 	}
 
 	// If currently on unit, skip units
-	for pos < len(r) && isUnit(r[pos]) {
+	for pos < r.n && isUnit(r.at(pos)) {
 		pos++
 	}
 	// Skip non-unit (whitespace/punct) to next unit
-	for pos < len(r) && !isUnit(r[pos]) {
+	for pos < r.n && !isUnit(r.at(pos)) {
 		pos++
 	}
 	return pos
 }
 
-func wordBackStart(r []rune, pos int, big bool) int {
+func wordBackStart(r *runeReader, pos int, big bool) int {
 	if pos <= 0 {
 		return 0
 	}
-	if pos > len(r) {
-		pos = len(r)
+	if pos > r.n {
+		pos = r.n
 	}
 	isUnit := isWordChar
 	if big {
@@ -122,22 +120,22 @@ func wordBackStart(r []rune, pos int, big bool) int {
 	pos--
 
 	// skip non-unit backwards
-	for pos > 0 && !isUnit(r[pos]) {
+	for pos > 0 && !isUnit(r.at(pos)) {
 		pos--
 	}
 	// now skip unit backwards to its start
-	for pos > 0 && isUnit(r[pos-1]) {
+	for pos > 0 && isUnit(r.at(pos-1)) {
 		pos--
 	}
 	return pos
 }
 
-func wordEnd(r []rune, pos int, big bool) int {
+func wordEnd(r *runeReader, pos int, big bool) int {
 	if pos < 0 {
 		pos = 0
 	}
-	if pos >= len(r) {
-		return len(r)
+	if pos >= r.n {
+		return r.n
 	}
 	isUnit := isWordChar
 	if big {
@@ -145,14 +143,14 @@ func wordEnd(r []rune, pos int, big bool) int {
 	}
 
 	// if not on unit, move to next unit
-	for pos < len(r) && !isUnit(r[pos]) {
+	for pos < r.n && !isUnit(r.at(pos)) {
 		pos++
 	}
-	if pos >= len(r) {
-		return len(r)
+	if pos >= r.n {
+		return r.n
 	}
 	// move to end of unit (last char)
-	for pos+1 < len(r) && isUnit(r[pos+1]) {
+	for pos+1 < r.n && isUnit(r.at(pos+1)) {
 		pos++
 	}
 	return pos
@@ -165,8 +163,8 @@ func (e *Editor) nextWordStart(pos int, count int, big bool) int {
 		count = 1
 	}
 
-	r := e.textRunes()
-	n := len(r)
+	r := e.runeReader()
+	n := r.n
 	if pos < 0 {
 		pos = 0
 	}
@@ -186,14 +184,14 @@ func (e *Editor) nextWordStart(pos int, count int, big bool) int {
 		}
 
 		// If currently in a word, consume to end of this word
-		if i < n && isWord(r[i]) {
-			for i < n && isWord(r[i]) {
+		if i < n && isWord(r.at(i)) {
+			for i < n && isWord(r.at(i)) {
 				i++
 			}
 		}
 
 		// Then skip non-word until next word start (this is what makes dw eat spaces)
-		for i < n && !isWord(r[i]) {
+		for i < n && !isWord(r.at(i)) {
 			i++
 		}
 	}
@@ -208,8 +206,8 @@ func (e *Editor) prevWordStart(pos int, count int, big bool) int {
 		count = 1
 	}
 
-	r := e.textRunes()
-	n := len(r)
+	r := e.runeReader()
+	n := r.n
 	if pos < 0 {
 		pos = 0
 	}
@@ -232,7 +230,7 @@ func (e *Editor) prevWordStart(pos int, count int, big bool) int {
 		i--
 
 		// Skip any non-word backwards
-		for i >= 0 && !isWord(r[i]) {
+		for i >= 0 && !isWord(r.at(i)) {
 			i--
 		}
 		if i < 0 {
@@ -240,7 +238,7 @@ func (e *Editor) prevWordStart(pos int, count int, big bool) int {
 		}
 
 		// Now we're in a word; move to its start
-		for i >= 0 && isWord(r[i]) {
+		for i >= 0 && isWord(r.at(i)) {
 			i--
 		}
 		i++ // overshot by one
@@ -256,8 +254,8 @@ func (e *Editor) endOfWord(pos int, count int, big bool) int {
 		count = 1
 	}
 
-	r := e.textRunes()
-	n := len(r)
+	r := e.runeReader()
+	n := r.n
 	if pos < 0 {
 		pos = 0
 	}
@@ -275,7 +273,7 @@ func (e *Editor) endOfWord(pos int, count int, big bool) int {
 
 	for c := 0; c < count; c++ {
 		// If we're not on a word, skip forward to next word
-		for i < n && !isWord(r[i]) {
+		for i < n && !isWord(r.at(i)) {
 			i++
 		}
 		if i >= n {
@@ -283,7 +281,7 @@ func (e *Editor) endOfWord(pos int, count int, big bool) int {
 		}
 
 		// Now consume word; end becomes last char of it
-		for i < n && isWord(r[i]) {
+		for i < n && isWord(r.at(i)) {
 			end = i
 			i++
 		}
@@ -429,12 +427,10 @@ func (e *Editor) findCharBackward(ch rune, tillBefore bool, count int) int {
 
 // moveToMatchingBracket jumps to matching (, ), [, ], {, or }
 func (e *Editor) moveToMatchingBracket() {
-	line := e.getLine(e.cy)
-	if e.cx >= len(line) {
+	runes := []rune(e.getLine(e.cy))
+	if e.cx >= len(runes) {
 		return
 	}
-
-	runes := []rune(line)
 	ch := runes[e.cx]
 
 	var matching rune
@@ -470,40 +466,24 @@ func (e *Editor) moveToMatchingBracket() {
 	}
 
 	// Search for matching bracket
-	text := e.buffer.String()
-	startPos := e.posFromCursor()
+	r := e.runeReader()
 	depth := 1
-	pos := startPos
-
-	if forward {
-		pos++
-		for pos < len(text) {
-			if text[pos] == byte(ch) {
-				depth++
-			} else if text[pos] == byte(matching) {
-				depth--
-				if depth == 0 {
-					e.setCursorFromPos(pos)
-					e.wantX = e.cx
-					return
-				}
-			}
-			pos++
-		}
-	} else {
-		pos--
-		for pos >= 0 {
-			if text[pos] == byte(ch) {
-				depth++
-			} else if text[pos] == byte(matching) {
-				depth--
-				if depth == 0 {
-					e.setCursorFromPos(pos)
-					e.wantX = e.cx
-					return
-				}
+	pos := e.posFromCursor()
+	step := 1
+	if !forward {
+		step = -1
+	}
+	for pos += step; pos >= 0 && pos < r.n; pos += step {
+		switch r.at(pos) {
+		case ch:
+			depth++
+		case matching:
+			depth--
+			if depth == 0 {
+				e.setCursorFromPos(pos)
+				e.wantX = e.cx
+				return
 			}
-			pos--
 		}
 	}
 }
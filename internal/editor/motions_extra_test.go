@@ -127,6 +127,20 @@ func TestMatchingBracket(t *testing.T) {
 	}
 }
 
+func TestMatchingBracketAcrossLinesWithUnicode(t *testing.T) {
+	ed := newTestEditor(t, "f(\"é\") {\n\t«x»\n}")
+	ed.cx = 7
+
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, '%', tcell.ModNone))
+	if ed.cy != 2 || ed.cx != 0 {
+		t.Fatalf("expected 2:0, got %d:%d", ed.cy, ed.cx)
+	}
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, '%', tcell.ModNone))
+	if ed.cy != 0 || ed.cx != 7 {
+		t.Fatalf("expected 0:7, got %d:%d", ed.cy, ed.cx)
+	}
+}
+
 func TestGGMotion(t *testing.T) {
 	ed := newTestEditor(t, "line 1\nline 2\nline 3\nline 4\nline 5")
 
@@ -116,7 +116,7 @@ func (e *Editor) applyOperatorTextObject(op rune, prefix rune, unit rune, count
 
 	// apply count by repeating range expansion (simple: apply op count times from cursor)
 	for i := 0; i < max(1, count); i++ {
-		start, end, _, ok := e.textObjectRange(prefix, unit)
+		start, end, kind, ok := e.textObjectRange(prefix, unit)
 		if !ok || end <= start {
 			e.statusMsg = "nothing"
 			return
@@ -124,7 +124,7 @@ func (e *Editor) applyOperatorTextObject(op rune, prefix rune, unit rune, count
 		switch op {
 		case 'd':
 			deleted, _ := e.buffer.Slice(start, end)
-			e.writeDelete(Register{kind: RegCharwise, text: deleted})
+			e.writeDelete(Register{kind: kind, text: deleted})
 			_ = e.buffer.Delete(start, end)
 			e.setCursorFromPos(start)
 			e.wantX = e.cx
@@ -132,11 +132,11 @@ func (e *Editor) applyOperatorTextObject(op rune, prefix rune, unit rune, count
 			e.statusMsg = "deleted"
 		case 'y':
 			yanked, _ := e.buffer.Slice(start, end)
-			e.writeYank(Register{kind: RegCharwise, text: yanked})
+			e.writeYank(Register{kind: kind, text: yanked})
 			e.statusMsg = "yanked"
 		case 'c':
 			deleted, _ := e.buffer.Slice(start, end)
-			e.writeDelete(Register{kind: RegCharwise, text: deleted})
+			e.writeDelete(Register{kind: kind, text: deleted})
 			_ = e.buffer.Delete(start, end)
 			e.setCursorFromPos(start)
 			e.wantX = e.cx
@@ -0,0 +1,378 @@
+package editor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+	"github.com/gdamore/tcell/v2"
+)
+
+// outlineSymbol is a line of the outline panel
+type outlineSymbol struct {
+	name       string
+	kind       lsp.SymbolKind
+	depth      int
+	start, end int // rune offsets of the symbol in the buffer
+	nameAt     int // rune offset of its name
+}
+
+// Outline is the side panel listing the document symbols of the current
+// buffer, as its language server reports them. Its cursor follows the
+// cursor in the buffer.
+type Outline struct {
+	bv      *BufferView       // buffer the symbols are of
+	ds      *lsp.DocumentSync // document they were requested from
+	tick    int               // lspTick of bv they were requested at
+	cancel  func()            // cancels the request running, nil when none
+	symbols []outlineSymbol   // in document order, children after their parent
+	message string            // shown instead of the symbols
+	cursor  int
+	scroll  int
+}
+
+// toggleOutline implements :outline and gO, opening or closing the outline
+// panel on the right
+func (e *Editor) toggleOutline() {
+	if e.outlineOpen {
+		e.closeOutline()
+		return
+	}
+	e.outline = &Outline{message: "loading symbols"}
+	e.outlineOpen = true
+	if e.outlinePanelWidth == 0 {
+		e.outlinePanelWidth = 30
+	}
+	e.saveSplitState()
+	e.initSplits()
+	e.updateOutline()
+}
+
+// closeOutline closes the outline panel and gives the focus back to a
+// buffer split
+func (e *Editor) closeOutline() {
+	if e.outline != nil && e.outline.cancel != nil {
+		e.outline.cancel()
+	}
+	e.outline = nil
+	e.outlineOpen = false
+	if e.focusOutline {
+		e.focusOutline = false
+		e.focusBufferSplit()
+	}
+	e.initSplits()
+}
+
+// focusBufferSplit moves the focus to the split showing the current
+// buffer, or the first buffer split
+func (e *Editor) focusBufferSplit() {
+	target := -1
+	for i, split := range e.splits {
+		if split.splitType != SplitBuffer {
+			continue
+		}
+		if target < 0 || split.bufferIndex == e.currentBuffer {
+			target = i
+		}
+		if split.bufferIndex == e.currentBuffer {
+			break
+		}
+	}
+	if target >= 0 {
+		e.currentSplit = target
+		e.loadSplitState()
+	}
+}
+
+// updateOutline runs on every turn of the main loop. It requests the
+// symbols again when the current buffer or its server changed, or the
+// server was sent changes, and moves the outline cursor to the innermost
+// symbol around the cursor unless the outline has the focus.
+func (e *Editor) updateOutline() {
+	o := e.outline
+	if !e.outlineOpen || o == nil {
+		return
+	}
+	bv := e.buf()
+	var srv *lspServer
+	var ds *lsp.DocumentSync
+	if srv = e.lspCurrentServer(); srv != nil && bv != nil {
+		ds = srv.docs[bv.filename]
+	}
+
+	if o.bv != bv || o.ds != ds || (o.cancel == nil && bv != nil && o.tick != bv.lspTick) {
+		e.requestOutline(o, bv, srv, ds)
+	}
+	if !e.focusOutline {
+		pos := e.posFromCursor()
+		for i, sym := range o.symbols {
+			if sym.start > pos {
+				break
+			}
+			if pos < max(sym.end, sym.start+1) {
+				o.cursor = i
+			}
+		}
+	}
+}
+
+// requestOutline asks the server of bv for its symbols, cancelling a
+// request still running
+func (e *Editor) requestOutline(o *Outline, bv *BufferView, srv *lspServer, ds *lsp.DocumentSync) {
+	if o.cancel != nil {
+		o.cancel()
+		o.cancel = nil
+	}
+	if o.bv != bv {
+		o.symbols, o.message, o.cursor, o.scroll = nil, "loading symbols", 0, 0
+	}
+	o.bv, o.ds = bv, ds
+	if bv != nil {
+		o.tick = bv.lspTick
+	}
+
+	switch {
+	case bv == nil || srv == nil:
+		o.symbols, o.message = nil, "no language server for this buffer"
+		return
+	case ds == nil:
+		o.symbols, o.message = nil, srv.cfg.Command+" is "+srv.state.String()
+		return
+	case !bool(srv.client.Capabilities().DocumentSymbolProvider):
+		o.symbols, o.message = nil, srv.cfg.Command+" does not list symbols"
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	tick := o.tick
+	go func() {
+		symbols, err := ds.DocumentSymbols(ctx)
+		cancel()
+		e.post(func() {
+			if e.outline != o || o.bv != bv || o.ds != ds || o.tick != tick {
+				return // a newer request replaced this one
+			}
+			o.cancel = nil
+			if err != nil {
+				o.symbols, o.message = nil, "lsp: "+err.Error()
+				return
+			}
+			o.symbols = o.symbols[:0]
+			e.flattenOutline(o, ds, symbols, 0)
+			o.message = ""
+			if len(o.symbols) == 0 {
+				o.message = "no symbols"
+			}
+			o.cursor = clamp(o.cursor, 0, max(0, len(o.symbols)-1))
+		})
+	}()
+}
+
+// flattenOutline appends symbols and their children to the outline, each
+// symbol before its children
+func (e *Editor) flattenOutline(o *Outline, ds *lsp.DocumentSync, symbols []lsp.DocumentSymbol, depth int) {
+	buf := o.bv.buffer
+	for _, sym := range symbols {
+		o.symbols = append(o.symbols, outlineSymbol{
+			name:   sym.Name,
+			kind:   sym.Kind,
+			depth:  depth,
+			start:  ds.Offset(buf, sym.Range.Start),
+			end:    ds.Offset(buf, sym.Range.End),
+			nameAt: ds.Offset(buf, sym.SelectionRange.Start),
+		})
+		e.flattenOutline(o, ds, sym.Children, depth+1)
+	}
+}
+
+// handleOutlineInput handles a key when the outline has the focus: j and k
+// move, Enter jumps to the symbol and q closes the outline
+func (e *Editor) handleOutlineInput(k *tcell.EventKey) {
+	o := e.outline
+	switch {
+	case k.Key() == tcell.KeyDown || k.Key() == tcell.KeyRune && k.Rune() == 'j':
+		o.cursor = min(o.cursor+1, max(0, len(o.symbols)-1))
+	case k.Key() == tcell.KeyUp || k.Key() == tcell.KeyRune && k.Rune() == 'k':
+		o.cursor = max(o.cursor-1, 0)
+	case k.Key() == tcell.KeyEnter:
+		if o.cursor >= len(o.symbols) || o.bv == nil {
+			return
+		}
+		sym := o.symbols[o.cursor]
+		e.focusOutline = false
+		e.focusBufferSplit()
+		if e.buf() != o.bv {
+			return
+		}
+		e.addToJumpList(e.cy, e.cx)
+		e.setCursorFromPos(sym.nameAt)
+		e.wantX = e.cx
+	case k.Key() == tcell.KeyRune && k.Rune() == 'q':
+		e.closeOutline()
+	}
+}
+
+// lines returns the lines of the outline panel and the line of its
+// cursor, -1 for none
+func (o *Outline) lines() ([]string, int) {
+	if len(o.symbols) == 0 {
+		return []string{" " + o.message}, -1
+	}
+	lines := make([]string, len(o.symbols))
+	for i, sym := range o.symbols {
+		lines[i] = fmt.Sprintf(" %s%s %s", strings.Repeat("  ", sym.depth), sym.name, sym.kind)
+	}
+	return lines, o.cursor
+}
+
+// drawOutline draws the outline panel in split, with a border on its left
+func (e *Editor) drawOutline(split *Split, scheme *ColorScheme) {
+	o := e.outline
+	if o == nil {
+		return
+	}
+	style := tcell.StyleDefault.Background(scheme.Background).Foreground(scheme.Foreground)
+	kindStyle := style.Foreground(scheme.Comment)
+	cursorStyle := tcell.StyleDefault.Background(scheme.TreeCursorBg).Foreground(scheme.TreeCursor)
+	borderStyle := tcell.StyleDefault.Foreground(scheme.TreeBorder)
+
+	lines, cursor := o.lines()
+	if cursor >= 0 {
+		o.scroll = clamp(o.scroll, cursor-split.height+1, cursor)
+	}
+	o.scroll = clamp(o.scroll, 0, max(0, len(lines)-split.height))
+
+	for y := 0; y < split.height; y++ {
+		e.s.SetContent(split.x-1, split.y+y, '│', nil, borderStyle)
+		i := o.scroll + y
+		var runes []rune
+		if i < len(lines) {
+			runes = []rune(lines[i])
+		}
+		// the kind after the name is dimmed
+		kindAt := len(runes)
+		if i < len(o.symbols) {
+			kindAt -= len([]rune(o.symbols[i].kind.String()))
+		}
+		for x := 0; x < split.width; x++ {
+			r, st := ' ', style
+			if x < len(runes) {
+				r = runes[x]
+				if x >= kindAt {
+					st = kindStyle
+				}
+			}
+			if i == cursor {
+				st = cursorStyle
+			}
+			e.s.SetContent(split.x+x, split.y+y, r, nil, st)
+		}
+	}
+}
+
+// symbolPicker is the state of the workspace symbol picker
+type symbolPicker struct {
+	srv     *lspServer
+	query   []rune
+	symbols []lsp.SymbolInformation
+	seq     int    // sequence number of the last request
+	cancel  func() // cancels the last request
+}
+
+// openSymbolPicker implements :symbols [query], a list of the symbols of
+// the workspace matching what is typed. Enter jumps to one.
+func (e *Editor) openSymbolPicker(query string) {
+	srv, ds := e.lspDocument()
+	if ds == nil {
+		return
+	}
+	if !srv.client.Capabilities().WorkspaceSymbolProvider {
+		e.statusMsg = srv.cfg.Command + " does not search workspace symbols"
+		return
+	}
+	p := &symbolPicker{srv: srv, query: []rune(strings.TrimSpace(query)), cancel: func() {}}
+	e.popupFixedH = 15
+	e.openPopupList("", nil, func(i int) {
+		if i < len(p.symbols) {
+			sym := p.symbols[i]
+			e.jumpToLocation(lspLocation{
+				path: lsp.PathFromURI(sym.Location.URI),
+				pos:  sym.Location.Range.Start,
+				enc:  p.srv.client.PositionEncoding(),
+			})
+		}
+	})
+	e.symbolPicker = p
+	e.requestSymbols(p)
+}
+
+// requestSymbols asks for the symbols matching the query of p, cancelling
+// the previous request
+func (e *Editor) requestSymbols(p *symbolPicker) {
+	p.cancel()
+	p.seq++
+	seq, client, query := p.seq, p.srv.client, string(p.query)
+	e.popupTitle = "SYMBOLS: " + query
+	if client == nil {
+		e.popupLines = []string{"server stopped"}
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	go func() {
+		symbols, err := client.WorkspaceSymbols(ctx, query)
+		cancel()
+		e.post(func() {
+			if e.symbolPicker != p || p.seq != seq {
+				return
+			}
+			if err != nil {
+				p.symbols = nil
+				e.popupLines = []string{"lsp: " + err.Error()}
+				return
+			}
+			p.symbols = symbols
+			e.popupLines = make([]string, len(symbols))
+			for i, sym := range symbols {
+				name := sym.Name
+				if sym.ContainerName != "" {
+					name = sym.ContainerName + "." + name
+				}
+				e.popupLines[i] = fmt.Sprintf("%s %s  %s:%d", name, sym.Kind,
+					relativePath(lsp.PathFromURI(sym.Location.URI)), sym.Location.Range.Start.Line+1)
+			}
+			if len(symbols) == 0 {
+				e.popupLines = []string{"no symbols match"}
+			}
+			e.popupCursor = 0
+		})
+	}()
+}
+
+// handleSymbolPicker handles a key while the symbol picker is open. Typing
+// edits the query; the arrows, Ctrl-N and Ctrl-P select, Enter jumps and
+// Esc closes.
+func (e *Editor) handleSymbolPicker(k *tcell.EventKey) {
+	p := e.symbolPicker
+	switch {
+	case k.Key() == tcell.KeyEnter && len(p.symbols) == 0:
+		e.closePopup()
+	case k.Key() == tcell.KeyEnter || k.Key() == tcell.KeyEsc:
+		e.handlePopupList(k)
+	case k.Key() == tcell.KeyUp || isCtrlP(k):
+		e.popupCursor = max(0, e.popupCursor-1)
+	case k.Key() == tcell.KeyDown || isCtrlN(k):
+		e.popupCursor = min(len(e.popupLines)-1, e.popupCursor+1)
+	case k.Key() == tcell.KeyBackspace || k.Key() == tcell.KeyBackspace2:
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			e.requestSymbols(p)
+		}
+	case k.Key() == tcell.KeyRune:
+		p.query = append(p.query, k.Rune())
+		e.requestSymbols(p)
+	}
+}
@@ -0,0 +1,99 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const outlineSource = "package main\n\ntype server struct {\n\taddr string\n}\n\nfunc (s *server) start() {\n\tlisten(s.addr)\n}\n\nfunc main() {\n}\n"
+
+func TestOutline(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{"go.mod": "module example\n", "main.go": outlineSource})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	pressKeys(e, "gO")
+	if !e.outlineOpen || e.splits[len(e.splits)-1].splitType != SplitOutline {
+		t.Fatal("gO should open the outline split on the right")
+	}
+	waitForLSP(t, e, "the symbols", func() bool {
+		e.updateOutline()
+		return len(e.outline.symbols) > 0
+	})
+	lines, _ := e.outline.lines()
+	want := []string{" server struct", "   addr field", "   start method", " main function"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("outline = %q, want %q", lines, want)
+	}
+
+	// the outline follows the cursor to the innermost symbol
+	e.cy, e.cx = 7, 1
+	e.updateOutline()
+	if e.outline.cursor != 2 {
+		t.Errorf("outline cursor = %d on the body of start, want 2", e.outline.cursor)
+	}
+	e.draw()
+
+	// Ctrl-W l focuses it; Enter jumps to the selected symbol
+	e.handleKey(tcell.NewEventKey(tcell.KeyCtrlW, 0, tcell.ModNone))
+	pressKeys(e, "l")
+	if !e.focusOutline {
+		t.Fatal("Ctrl-W l should focus the outline")
+	}
+	pressKeys(e, "j")
+	e.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	if e.focusOutline || e.cy != 10 || e.cx != 5 {
+		t.Errorf("after Enter: focus on outline %v, cursor %d:%d, want main at 10:5", e.focusOutline, e.cy, e.cx)
+	}
+
+	// changes sent to the server update it
+	e.cy = 11
+	pressKeys(e, "ofunc helper() {}\x1b")
+	e.lspSyncChanges()
+	waitForLSP(t, e, "the new symbol", func() bool {
+		e.updateOutline()
+		return len(e.outline.symbols) == 5
+	})
+
+	pressKeys(e, "gO")
+	if e.outlineOpen || e.outline != nil || len(e.splits) != 1 {
+		t.Errorf("gO should close the outline, %d splits left", len(e.splits))
+	}
+}
+
+func TestSymbolPicker(t *testing.T) {
+	e := newLSPTestEditor(t)
+	dir := writeLSPTestFiles(t, map[string]string{
+		"go.mod":      "module example\n",
+		"main.go":     outlineSource,
+		"pkg/util.go": "package pkg\n\nfunc startAll() {}\n",
+	})
+	main := filepath.Join(dir, "main.go")
+	e.openFile(main)
+	waitForLSP(t, e, "attach", func() bool { return attached(e.lspCurrentServer(), main) })
+
+	e.exec("symbols sta")
+	waitForLSP(t, e, "the symbols", func() bool { return len(e.symbolPicker.symbols) == 2 })
+	if e.popupTitle != "SYMBOLS: sta" || !strings.HasPrefix(e.popupLines[0], "server.start method") {
+		t.Fatalf("picker %q = %q", e.popupTitle, e.popupLines)
+	}
+
+	// typing narrows the query
+	pressKeys(e, "rtA")
+	waitForLSP(t, e, "the narrowed symbols", func() bool { return len(e.symbolPicker.symbols) == 1 })
+	if e.popupTitle != "SYMBOLS: startA" {
+		t.Errorf("title = %q", e.popupTitle)
+	}
+	e.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	if e.popupActive || e.symbolPicker != nil {
+		t.Fatal("Enter should close the picker")
+	}
+	if e.filename != filepath.Join(dir, "pkg", "util.go") || e.cy != 2 || e.cx != 5 {
+		t.Errorf("jumped to %s %d:%d", e.filename, e.cy, e.cx)
+	}
+}
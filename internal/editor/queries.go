@@ -0,0 +1,212 @@
+package editor
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Built-in tree-sitter queries, one directory per grammar.
+//
+//go:embed queries
+var builtinQueries embed.FS
+
+// QueryDir returns the directory users can put query overrides in for lang,
+// e.g. ~/.config/voidabyss/queries/go/.
+func QueryDir(lang string) string {
+	return filepath.Join(config.GetConfigDir(), "queries", lang)
+}
+
+// loadQuerySource returns the source of query name (e.g. "highlights") for
+// lang. A user file replaces the built-in query unless its first line is
+// ";; extends", in which case it is appended to it. Patterns later in a query
+// take precedence, so extensions can override built-in captures.
+func loadQuerySource(lang, name string) (builtin, user string) {
+	builtin = builtinQuery(lang, name)
+	data, err := os.ReadFile(filepath.Join(QueryDir(lang), name+".scm"))
+	if err != nil {
+		return builtin, ""
+	}
+	return builtin, string(data)
+}
+
+// builtinQuery returns the built-in query name for lang. A first line of
+// "; inherits: a,b" prepends the queries of those languages, which is how
+// typescript and tsx share the javascript patterns.
+func builtinQuery(lang, name string) string {
+	data, err := builtinQueries.ReadFile("queries/" + lang + "/" + name + ".scm")
+	if err != nil {
+		return ""
+	}
+	src := string(data)
+	first, _, _ := strings.Cut(src, "\n")
+	parents, ok := strings.CutPrefix(strings.TrimSpace(first), "; inherits:")
+	if !ok {
+		return src
+	}
+	var b strings.Builder
+	for _, parent := range strings.Split(parents, ",") {
+		b.WriteString(builtinQuery(strings.TrimSpace(parent), name))
+		b.WriteString("\n")
+	}
+	b.WriteString(src)
+	return b.String()
+}
+
+func isQueryExtension(src string) bool {
+	first, _, _ := strings.Cut(src, "\n")
+	return strings.TrimSpace(strings.TrimLeft(first, "; ")) == "extends"
+}
+
+// loadQuery compiles query name for lang. If the user's query does not
+// compile, the built-in one is used and the error is returned alongside it.
+func loadQuery(language *sitter.Language, lang, name string) (*query, error) {
+	builtin, user := loadQuerySource(lang, name)
+	src := builtin
+	if user != "" {
+		if isQueryExtension(user) {
+			src = builtin + "\n" + user
+		} else {
+			src = user
+		}
+	}
+	if src == "" {
+		return nil, nil
+	}
+
+	q, qerr := sitter.NewQuery(language, src)
+	if qerr == nil {
+		return newQuery(q), nil
+	}
+	err := errors.New(filepath.Join(QueryDir(lang), name+".scm") + ": " + qerr.Error())
+	if user == "" || builtin == "" {
+		return nil, err
+	}
+	q, qerr = sitter.NewQuery(language, builtin)
+	if qerr != nil {
+		return nil, qerr
+	}
+	return newQuery(q), err
+}
+
+// textSource reads the bytes [start, end) of the text a tree was parsed
+// from.
+type textSource func(start, end int) []byte
+
+// query is a compiled query whose text predicates (#eq?, #match?, #any-of?)
+// read node text through a textSource. go-tree-sitter evaluates them on a
+// []byte of the whole text, which would have to be kept in step with the
+// buffer, so they are taken off the query and checked by nextCapture and
+// nextMatch instead.
+type query struct {
+	*sitter.Query
+	predicates [][]sitter.TextPredicateCapture
+}
+
+func newQuery(q *sitter.Query) *query {
+	predicates := q.TextPredicates
+	q.TextPredicates = make([][]sitter.TextPredicateCapture, len(predicates))
+	return &query{Query: q, predicates: predicates}
+}
+
+// hasProperty reports whether a pattern of q sets key with #set!.
+func (q *query) hasProperty(key string) bool {
+	for i := uint(0); i < q.PatternCount(); i++ {
+		for _, prop := range q.PropertySettings(i) {
+			if prop.Key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextCapture returns the next capture of captures whose match satisfies
+// the text predicates. Failing matches are removed, so none of their
+// captures are returned.
+func (q *query) nextCapture(captures *sitter.QueryCaptures, src textSource) (*sitter.QueryMatch, uint) {
+	for {
+		match, idx := captures.Next()
+		if match == nil || q.satisfies(match, src) {
+			return match, idx
+		}
+		match.Remove()
+	}
+}
+
+// nextMatch returns the next match of matches that satisfies the text
+// predicates.
+func (q *query) nextMatch(matches *sitter.QueryMatches, src textSource) *sitter.QueryMatch {
+	for {
+		match := matches.Next()
+		if match == nil || q.satisfies(match, src) {
+			return match
+		}
+	}
+}
+
+// satisfies reports whether match passes the text predicates of its
+// pattern, with the semantics of QueryMatch.SatisfiesTextPredicate.
+func (q *query) satisfies(match *sitter.QueryMatch, src textSource) bool {
+	text := func(n sitter.Node) []byte {
+		return src(int(n.StartByte()), int(n.EndByte()))
+	}
+	for _, pred := range q.predicates[match.PatternIndex] {
+		if !satisfiesPredicate(match, pred, text) {
+			return false
+		}
+	}
+	return true
+}
+
+func satisfiesPredicate(match *sitter.QueryMatch, pred sitter.TextPredicateCapture, text func(sitter.Node) []byte) bool {
+	nodes := match.NodesForCaptureIndex(pred.CaptureId)
+	// settle tests the nodes in turn until one decides the predicate: a
+	// failing node when all must match, a passing one when any may
+	settle := func(nodes []sitter.Node, test func(i int, n sitter.Node) bool) (ok, settled bool) {
+		for i, n := range nodes {
+			switch pass := test(i, n) == pred.Positive; {
+			case !pass && pred.MatchAllNodes:
+				return false, true
+			case pass && !pred.MatchAllNodes:
+				return true, true
+			}
+		}
+		return false, false
+	}
+
+	switch pred.Type {
+	case sitter.TextPredicateTypeEqCapture:
+		others := match.NodesForCaptureIndex(pred.Value.(uint))
+		n := min(len(nodes), len(others))
+		ok, settled := settle(nodes[:n], func(i int, n sitter.Node) bool {
+			return bytes.Equal(text(n), text(others[i]))
+		})
+		return ok || !settled && len(nodes) == len(others)
+	case sitter.TextPredicateTypeEqString:
+		s := []byte(pred.Value.(string))
+		ok, settled := settle(nodes, func(_ int, n sitter.Node) bool { return bytes.Equal(text(n), s) })
+		return ok || !settled
+	case sitter.TextPredicateTypeMatchString:
+		re := pred.Value.(*regexp.Regexp)
+		ok, settled := settle(nodes, func(_ int, n sitter.Node) bool { return re.Match(text(n)) })
+		return ok || !settled
+	case sitter.TextPredicateTypeAnyString:
+		values := pred.Value.([]string)
+		for _, n := range nodes {
+			if slices.Contains(values, string(text(n))) != pred.Positive {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,4 @@
+; Text objects: @function with .outer and .inner.
+
+(function_definition
+  body: (_) @function.inner) @function.outer
@@ -0,0 +1,16 @@
+; Text objects: @function, @class and @parameter with .outer and .inner.
+
+(function_definition
+  body: (compound_statement) @function.inner) @function.outer
+
+[
+  (struct_specifier body: (field_declaration_list) @class.inner)
+  (union_specifier body: (field_declaration_list) @class.inner)
+  (enum_specifier body: (enumerator_list) @class.inner)
+] @class.outer
+
+(parameter_list
+  (_) @parameter.inner)
+
+(argument_list
+  (_) @parameter.inner)
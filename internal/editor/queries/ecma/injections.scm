@@ -0,0 +1,14 @@
+; Shared by javascript, typescript and tsx. Based on the injections.scm
+; shipped with tree-sitter-javascript v0.25.0 (MIT).
+
+; Parse the contents of tagged template literals using a language inferred
+; from the tag, e.g. html`<p>${name}</p>` or css`color: red;`.
+(call_expression
+  function: [
+    (identifier) @injection.language
+    (member_expression
+      property: (property_identifier) @injection.language)
+  ]
+  arguments: (template_string (string_fragment) @injection.content)
+  (#set! injection.combined)
+  (#set! injection.include-children))
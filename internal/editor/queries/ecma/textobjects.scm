@@ -0,0 +1,22 @@
+; Text objects shared by javascript, typescript and tsx: @function, @class
+; and @parameter with .outer and .inner.
+
+[
+  (function_declaration body: (statement_block) @function.inner)
+  (function_expression body: (statement_block) @function.inner)
+  (generator_function_declaration body: (statement_block) @function.inner)
+  (generator_function body: (statement_block) @function.inner)
+  (arrow_function body: (_) @function.inner)
+  (method_definition body: (statement_block) @function.inner)
+] @function.outer
+
+[
+  (class_declaration body: (class_body) @class.inner)
+  (class body: (class_body) @class.inner)
+] @class.outer
+
+(formal_parameters
+  (_) @parameter.inner)
+
+(arguments
+  (_) @parameter.inner)
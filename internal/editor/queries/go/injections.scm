@@ -0,0 +1,13 @@
+; Templates handed to Parse of html/template or text/template, as in
+; template.Must(template.New("page").Parse(`<p>{{.Title}}</p>`)), are
+; parsed as HTML when they start with a tag. The {{ }} actions stay text.
+((call_expression
+  function: (selector_expression
+    field: (field_identifier) @_parse)
+  arguments: (argument_list
+    .
+    (raw_string_literal
+      (raw_string_literal_content) @injection.content)))
+ (#eq? @_parse "Parse")
+ (#match? @injection.content "^\\s*<")
+ (#set! injection.language "html"))
@@ -0,0 +1,27 @@
+; Text objects: @function, @class and @parameter with .outer and .inner.
+; Inner ranges of bodies drop their braces (see syntaxObjects).
+
+(function_declaration
+  body: (block) @function.inner) @function.outer
+
+(method_declaration
+  body: (block) @function.inner) @function.outer
+
+(func_literal
+  body: (block) @function.inner) @function.outer
+
+(type_declaration
+  (type_spec
+    type: [(struct_type) (interface_type)] @class.inner)) @class.outer
+
+(parameter_list
+  [(parameter_declaration) (variadic_parameter_declaration)] @parameter.inner)
+
+(type_parameter_list
+  (type_parameter_declaration) @parameter.inner)
+
+(argument_list
+  (_) @parameter.inner)
+
+(literal_value
+  (_) @parameter.inner)
@@ -0,0 +1,16 @@
+; Based on the highlights.scm shipped with tree-sitter-html v0.23.2 (MIT).
+; Later patterns take precedence over earlier ones for the same node.
+
+(tag_name) @tag
+(erroneous_end_tag_name) @tag.error
+(doctype) @constant
+(attribute_name) @attribute
+(attribute_value) @string
+(comment) @comment
+
+[
+  "<"
+  ">"
+  "</"
+  "/>"
+] @punctuation.bracket
@@ -0,0 +1,9 @@
+; Based on the injections.scm shipped with tree-sitter-html v0.23.2 (MIT).
+
+((script_element
+  (raw_text) @injection.content)
+ (#set! injection.language "javascript"))
+
+((style_element
+  (raw_text) @injection.content)
+ (#set! injection.language "css"))
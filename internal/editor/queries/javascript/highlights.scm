@@ -0,0 +1,19 @@
+; inherits: ecma,jsx
+; Based on the highlights-params.scm shipped with tree-sitter-javascript
+; v0.25.0 (MIT).
+
+; Parameters
+;-----------
+
+(formal_parameters
+  [
+    (identifier) @variable.parameter
+    (array_pattern
+      (identifier) @variable.parameter)
+    (object_pattern
+      [
+        (pair_pattern value: (identifier) @variable.parameter)
+        (shorthand_property_identifier_pattern) @variable.parameter
+      ])
+  ]
+)
@@ -0,0 +1,19 @@
+; Based on the highlights.scm shipped with tree-sitter-json v0.24.8 (MIT).
+; Later patterns take precedence over earlier ones for the same node.
+
+(string) @string
+
+(pair
+  key: (_) @string.special.key)
+
+(number) @number
+
+[
+  (null)
+  (true)
+  (false)
+] @constant.builtin
+
+(escape_sequence) @escape
+
+(comment) @comment
@@ -0,0 +1,12 @@
+; Folds: a heading down to the next heading of the same level, and the
+; blocks that span lines.
+
+[
+  (section)
+  (fenced_code_block)
+  (indented_code_block)
+  (html_block)
+  (block_quote)
+  (list)
+  (pipe_table)
+] @fold
@@ -0,0 +1,52 @@
+; Based on the highlights.scm shipped with tree-sitter-markdown v0.5.1 (MIT).
+; Inline markup (emphasis, code spans, links) is highlighted by the
+; markdown_inline queries, injected into every (inline) node.
+
+(atx_heading (inline) @markup.heading)
+(setext_heading (paragraph) @markup.heading)
+
+[
+  (atx_h1_marker)
+  (atx_h2_marker)
+  (atx_h3_marker)
+  (atx_h4_marker)
+  (atx_h5_marker)
+  (atx_h6_marker)
+  (setext_h1_underline)
+  (setext_h2_underline)
+] @punctuation.special
+
+[
+  (link_title)
+  (indented_code_block)
+  (fenced_code_block)
+] @markup.raw
+
+(info_string) @label
+
+(fenced_code_block_delimiter) @punctuation.delimiter
+
+(link_destination) @markup.link.url
+
+(link_label) @markup.link.label
+
+[
+  (list_marker_plus)
+  (list_marker_minus)
+  (list_marker_star)
+  (list_marker_dot)
+  (list_marker_parenthesis)
+  (thematic_break)
+] @markup.list
+
+[
+  (task_list_marker_checked)
+  (task_list_marker_unchecked)
+] @markup.list
+
+[
+  (block_continuation)
+  (block_quote_marker)
+] @punctuation.special
+
+(backslash_escape) @escape
@@ -0,0 +1,21 @@
+; Based on the injections.scm shipped with tree-sitter-markdown v0.5.1 (MIT).
+
+; Fenced code blocks are parsed as the language after the fence, e.g. ```go.
+(fenced_code_block
+  (info_string
+    (language) @injection.language)
+  (code_fence_content) @injection.content)
+
+((html_block) @injection.content
+ (#set! injection.language "html"))
+
+; YAML front matter between --- lines
+((minus_metadata) @injection.content
+ (#set! injection.language "yaml"))
+
+; Emphasis, code spans and links are parsed by the inline grammar. The
+; block grammar marks their delimiters as children of the inline node, so
+; they have to be included.
+((inline) @injection.content
+ (#set! injection.language "markdown_inline")
+ (#set! injection.include-children))
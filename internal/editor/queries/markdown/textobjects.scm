@@ -0,0 +1,11 @@
+; Text objects: @class is a heading with the section under it, @function a
+; fenced code block and @parameter a list item.
+
+(section) @class.outer
+
+(fenced_code_block
+  (code_fence_content) @function.inner) @function.outer
+
+(list_item
+  (paragraph
+    (inline) @parameter.inner)) @parameter.outer
@@ -0,0 +1,37 @@
+; Based on the highlights.scm shipped with tree-sitter-markdown v0.5.1 (MIT).
+
+(code_span) @markup.raw
+
+(link_title) @markup.raw
+
+[
+  (emphasis_delimiter)
+  (code_span_delimiter)
+] @punctuation.delimiter
+
+(emphasis) @markup.italic
+
+(strong_emphasis) @markup.strong
+
+(strikethrough) @markup.strikethrough
+
+[
+  (link_destination)
+  (uri_autolink)
+  (email_autolink)
+] @markup.link.url
+
+[
+  (link_label)
+  (link_text)
+  (image_description)
+] @markup.link.label
+
+[
+  (backslash_escape)
+  (hard_line_break)
+] @escape
+
+(image ["!" "[" "]" "(" ")"] @punctuation.delimiter)
+(inline_link ["[" "]" "(" ")"] @punctuation.delimiter)
+(shortcut_link ["[" "]"] @punctuation.delimiter)
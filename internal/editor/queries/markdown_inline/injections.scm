@@ -0,0 +1,5 @@
+; Based on the injections.scm shipped with tree-sitter-markdown v0.5.1 (MIT).
+
+((html_tag) @injection.content
+ (#set! injection.language "html")
+ (#set! injection.include-children))
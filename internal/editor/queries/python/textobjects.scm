@@ -0,0 +1,25 @@
+; Text objects: @function, @class and @parameter with .outer and .inner.
+
+(function_definition
+  body: (block) @function.inner) @function.outer
+
+(decorated_definition
+  definition: (function_definition)) @function.outer
+
+(lambda
+  body: (_) @function.inner) @function.outer
+
+(class_definition
+  body: (block) @class.inner) @class.outer
+
+(decorated_definition
+  definition: (class_definition)) @class.outer
+
+(parameters
+  (_) @parameter.inner)
+
+(lambda_parameters
+  (_) @parameter.inner)
+
+(argument_list
+  (_) @parameter.inner)
@@ -0,0 +1,30 @@
+; Text objects: @function, @class and @parameter with .outer and .inner.
+
+(function_item
+  body: (block) @function.inner) @function.outer
+
+(closure_expression
+  body: (_) @function.inner) @function.outer
+
+[
+  (struct_item body: (field_declaration_list) @class.inner)
+  (enum_item body: (enum_variant_list) @class.inner)
+  (union_item body: (field_declaration_list) @class.inner)
+  (trait_item body: (declaration_list) @class.inner)
+  (impl_item body: (declaration_list) @class.inner)
+] @class.outer
+
+(parameters
+  (_) @parameter.inner)
+
+(closure_parameters
+  (_) @parameter.inner)
+
+(arguments
+  (_) @parameter.inner)
+
+(type_arguments
+  (_) @parameter.inner)
+
+(type_parameters
+  (_) @parameter.inner)
@@ -0,0 +1 @@
+; inherits: typescript,jsx
@@ -0,0 +1 @@
+; inherits: ecma
@@ -0,0 +1,18 @@
+; inherits: ecma
+; TypeScript declarations on top of the shared JavaScript text objects.
+
+(function_signature) @function.outer
+
+(method_signature) @function.outer
+
+[
+  (abstract_class_declaration body: (class_body) @class.inner)
+  (interface_declaration body: (interface_body) @class.inner)
+  (enum_declaration body: (enum_body) @class.inner)
+] @class.outer
+
+(type_arguments
+  (_) @parameter.inner)
+
+(type_parameters
+  (_) @parameter.inner)
@@ -0,0 +1,8 @@
+; Folds: mappings and sequences nested under a key or a list item.
+
+[
+  (block_mapping_pair)
+  (block_sequence_item)
+  (flow_mapping)
+  (flow_sequence)
+] @fold
@@ -0,0 +1,14 @@
+; Text objects: @class is a key with its value, @parameter a list item or
+; the entry of a flow collection.
+
+(block_mapping_pair
+  value: (_) @class.inner) @class.outer
+
+(block_sequence_item
+  (_) @parameter.inner) @parameter.outer
+
+(flow_sequence
+  (flow_node) @parameter.inner @parameter.outer)
+
+(flow_mapping
+  (flow_pair) @parameter.inner @parameter.outer)
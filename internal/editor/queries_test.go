@@ -0,0 +1,169 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+)
+
+// captureAt returns the innermost capture covering the first occurrence of
+// sub in src.
+func captureAt(t *testing.T, p *TreeSitterParser, src, sub string) string {
+	t.Helper()
+	off := strings.Index(src, sub)
+	if off < 0 {
+		t.Fatalf("%q not in source", sub)
+	}
+	capture := ""
+	for _, hl := range p.GetHighlights(0, len(src)) {
+		if off >= hl.StartByte && off < hl.EndByte {
+			capture = hl.Capture
+		}
+	}
+	return capture
+}
+
+func writeUserQuery(t *testing.T, lang, src string) {
+	t.Helper()
+	writeUserQueryFile(t, lang, "highlights", src)
+}
+
+func writeUserQueryFile(t *testing.T, lang, name, src string) {
+	t.Helper()
+	dir := QueryDir(lang)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".scm"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryHighlightsGo(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	src := "package main\n\nfunc add(a int) int {\n\treturn len(s.name) + helper(a)\n}\n"
+	p, err := NewTreeSitterParser("go")
+	if err != nil || p == nil {
+		t.Fatalf("go parser: %v", err)
+	}
+	defer p.Close()
+	p.Parse(src)
+
+	cases := []struct{ sub, want string }{
+		{"package", "keyword"},
+		{"add", "function"},
+		{"a int", "variable.parameter"},
+		{"int {", "type.builtin"},
+		{"len", "function.builtin"},
+		{"name", "property"},
+		{"helper", "function"},
+	}
+	for _, c := range cases {
+		if got := captureAt(t, p, src, c.sub); got != c.want {
+			t.Errorf("%q: got capture %q, want %q", c.sub, got, c.want)
+		}
+	}
+}
+
+func TestUserQueryReplacesBuiltin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeUserQuery(t, "go", "(comment) @comment\n")
+
+	src := "package main\n\n// hi\nfunc f() {}\n"
+	p, _ := NewTreeSitterParser("go")
+	defer p.Close()
+	p.Parse(src)
+
+	hls := p.GetHighlights(0, len(src))
+	if len(hls) != 1 || hls[0].Capture != "comment" {
+		t.Fatalf("expected only the comment, got %v", hls)
+	}
+}
+
+func TestUserQueryExtendsBuiltin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeUserQuery(t, "go", ";; extends\n((identifier) @constant (#match? @constant \"^[A-Z_]+$\"))\n")
+
+	src := "package main\n\nvar x = MAX_SIZE + y\n"
+	p, _ := NewTreeSitterParser("go")
+	defer p.Close()
+	p.Parse(src)
+
+	if got := captureAt(t, p, src, "MAX_SIZE"); got != "constant" {
+		t.Errorf("MAX_SIZE: got %q, want constant", got)
+	}
+	if got := captureAt(t, p, src, "y\n"); got != "variable" {
+		t.Errorf("y: got %q, want variable", got)
+	}
+	if got := captureAt(t, p, src, "var"); got != "keyword" {
+		t.Errorf("var: got %q, want keyword", got)
+	}
+}
+
+func TestQueryPredicatesReadBuffer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeUserQuery(t, "go", ";; extends\n((identifier) @constant (#match? @constant \"^[A-Z_]+$\"))\n")
+
+	b := buffer.NewFromString("package main\n\n// é\nvar x = size + y\n")
+	p, _ := NewTreeSitterParser("go")
+	defer p.Close()
+	p.ParseBuffer(b)
+	b.Subscribe(func(ed buffer.Edit) { p.Edit(ed) })
+	if got := captureAt(t, p, b.String(), "size"); got != "variable" {
+		t.Fatalf("size: got %q, want variable", got)
+	}
+
+	// the predicate sees the edited text without a reparse from scratch
+	src := b.String()
+	start := utf8.RuneCountInString(src[:strings.Index(src, "size")])
+	_ = b.Delete(start, start+len("size"))
+	_ = b.Insert(start, "MAX_SIZE")
+	p.ParseBuffer(b)
+	src = b.String()
+	if got := captureAt(t, p, src, "MAX_SIZE"); got != "constant" {
+		t.Errorf("MAX_SIZE: got %q, want constant", got)
+	}
+	if got := captureAt(t, p, src, "y\n"); got != "variable" {
+		t.Errorf("y: got %q, want variable", got)
+	}
+}
+
+func TestUserQueryErrorFallsBack(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeUserQuery(t, "go", "(not_a_node) @comment\n")
+
+	p, err := NewTreeSitterParser("go")
+	if err != nil || p == nil {
+		t.Fatalf("go parser: %v", err)
+	}
+	defer p.Close()
+	if p.QueryError() == nil {
+		t.Fatal("expected the query error to be reported")
+	}
+	src := "package main\n"
+	p.Parse(src)
+	if got := captureAt(t, p, src, "package"); got != "keyword" {
+		t.Errorf("expected built-in highlights, got %q", got)
+	}
+}
+
+func TestCaptureStyleFallsBackToParent(t *testing.T) {
+	e := newTestEditor(t, "")
+	e.config = &config.Config{ColorScheme: "default"}
+	style, ok := e.captureStyle("function.method.call")
+	want, _ := e.captureStyle("function")
+	if !ok || style == nil || *style != *want {
+		t.Fatalf("function.method.call should use the function style")
+	}
+	if style, ok := e.captureStyle("punctuation.bracket"); !ok || style != nil {
+		t.Fatal("punctuation should be unstyled")
+	}
+	if _, ok := e.captureStyle("unknown"); ok {
+		t.Fatal("unknown captures should fall back to the highlight type")
+	}
+}
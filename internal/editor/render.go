@@ -3,6 +3,7 @@ package editor
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -17,45 +18,30 @@ func (e *Editor) renderBufferRegion(bv *BufferView, x, y, width, height int, sch
 	highlightStyle := tcell.StyleDefault.Background(scheme.SearchBg).Foreground(scheme.Search)
 	visualStyle := tcell.StyleDefault.Background(scheme.VisualBg).Foreground(scheme.Visual)
 	lineNumStyle := tcell.StyleDefault.Foreground(scheme.LineNumber).Background(scheme.Background)
+	hintStyle := style.Foreground(scheme.Comment).Italic(true)
 
-	// Calculate line number column width
-	lineNumWidth := 0
-	if e.config != nil && e.config.ShowLineNumbers {
-		totalLines := len(strings.Split(bv.buffer.String(), "\n"))
-		lineNumWidth = len(fmt.Sprintf("%d", totalLines)) + 2 // +2 for fold indicator and spacing
-		if lineNumWidth < 5 {
-			lineNumWidth = 5
-		}
-	}
+	totalLines := bv.buffer.LineCount()
+	signWidth, lineNumWidth := e.gutterWidths(bv)
 
 	// Get syntax highlights for entire visible viewport
 	var highlights []Highlight
 	if bv.parser != nil {
-		// Calculate byte range for entire visible viewport
-		lines := strings.Split(bv.buffer.String(), "\n")
 		firstLine := bv.rowOffset
-		lastLine := min(bv.rowOffset+height, len(lines)-1)
-		if firstLine < len(lines) && lastLine >= firstLine {
-			startPos := 0
-			for i := 0; i < firstLine && i < len(lines); i++ {
-				startPos += len(lines[i]) + 1 // +1 for newline
-			}
+		lastLine := min(bv.rowOffset+height, totalLines-1)
+		if firstLine < totalLines && lastLine >= firstLine {
+			startPos := bv.buffer.LineStart(firstLine)
 			endPos := bv.buffer.Len()
-			if lastLine < len(lines)-1 {
-				endPos = startPos
-				for i := firstLine; i <= lastLine && i < len(lines); i++ {
-					endPos += len(lines[i]) + 1
-				}
+			if lastLine < totalLines-1 {
+				endPos = bv.buffer.LineStart(lastLine + 1)
 			}
-			highlights = bv.parser.GetHighlights(startPos, endPos)
+			highlights = bv.parser.GetHighlights(bv.buffer.ByteOffset(startPos), bv.buffer.ByteOffset(endPos))
 		}
 	}
 
-	lines := strings.Split(bv.buffer.String(), "\n")
 	visualLine := 0
 	actualLine := bv.rowOffset
 
-	for visualLine < height && actualLine < len(lines) {
+	for visualLine < height && actualLine < totalLines {
 		// Skip folded lines (use buffer's fold ranges)
 		if isLineFoldedInBuffer(bv, actualLine) {
 			actualLine++
@@ -65,8 +51,22 @@ func (e *Editor) renderBufferRegion(bv *BufferView, x, y, width, height int, sch
 		lineIndex := actualLine
 		screenY := y + visualLine
 
+		// Draw the sign of the most severe diagnostic on the line
+		var lineDiags []diagnostic
+		if signWidth > 0 {
+			lineDiags = lineDiagnostics(bv, lineIndex)
+			sign, signStyle := ' ', style
+			if len(lineDiags) > 0 {
+				sign = severitySigns[lineDiags[0].severity]
+				signStyle = style.Foreground(severityColor(scheme, lineDiags[0].severity)).Bold(true)
+			}
+			e.s.SetContent(x, screenY, sign, nil, signStyle)
+			e.s.SetContent(x+1, screenY, ' ', nil, style)
+		}
+
 		// Draw line number with fold indicator
 		if lineNumWidth > 0 {
+			numX := x + signWidth
 			var lineNum string
 			if e.config.RelativeLineNums {
 				// Relative line numbers
@@ -86,54 +86,75 @@ func (e *Editor) renderBufferRegion(bv *BufferView, x, y, width, height int, sch
 
 			// Draw line number
 			for i, r := range lineNum {
-				if x+i < x+width {
-					e.s.SetContent(x+i, screenY, r, nil, lineNumStyle)
+				if numX+i < x+width {
+					e.s.SetContent(numX+i, screenY, r, nil, lineNumStyle)
 				}
 			}
 
 			// Draw fold indicator (use buffer's fold ranges)
 			foldIndicator := getFoldIndicatorForBuffer(bv, lineIndex)
-			if x+lineNumWidth-2 < x+width {
-				e.s.SetContent(x+lineNumWidth-2, screenY, []rune(foldIndicator)[0], nil, lineNumStyle)
+			if numX+lineNumWidth-2 < x+width {
+				e.s.SetContent(numX+lineNumWidth-2, screenY, []rune(foldIndicator)[0], nil, lineNumStyle)
 			}
 
 			// Add spacing after line number
-			if x+lineNumWidth-1 < x+width {
-				e.s.SetContent(x+lineNumWidth-1, screenY, ' ', nil, style)
+			if numX+lineNumWidth-1 < x+width {
+				e.s.SetContent(numX+lineNumWidth-1, screenY, ' ', nil, style)
 			}
 		}
 
-		runes := []rune(lines[lineIndex])
+		runes := []rune(bv.buffer.Line(lineIndex))
 		start := min(bv.colOffset, len(runes))
 		visible := runes[start:]
 
-		// Calculate absolute position for highlight matching
-		lineStartPos := 0
-		for i := 0; i < lineIndex && i < len(lines); i++ {
-			lineStartPos += len(lines[i]) + 1
+		// Calculate absolute position for highlight matching; tree-sitter
+		// highlights are in bytes, so track the byte offset alongside
+		lineStartPos := bv.buffer.LineStart(lineIndex)
+		absByte := bv.buffer.ByteOffset(lineStartPos + start)
+
+		// Content starts after the gutter; inlay hints are drawn between
+		// the characters and push the rest of the line to the right
+		textStartX := x + signWidth + lineNumWidth
+		screenX := textStartX
+		hints := lineInlayHints(bv, lineStartPos+start, lineStartPos+len(runes))
+		drawHints := func(pos int) {
+			for ; len(hints) > 0 && hints[0].pos <= pos; hints = hints[1:] {
+				for _, r := range hints[0].text {
+					if screenX < x+width {
+						e.s.SetContent(screenX, screenY, r, nil, hintStyle)
+					}
+					screenX++
+				}
+			}
 		}
 
-		// Adjust content start and width for line numbers
-		textStartX := x + lineNumWidth
-		textWidth := width - lineNumWidth
-
-		for col := 0; col < textWidth && col < len(visible); col++ {
-			screenX := textStartX + col
+		for col := 0; col < len(visible); col++ {
+			absPos := lineStartPos + start + col
+			drawHints(absPos)
 			if screenX >= x+width {
 				break
 			}
 
-			absPos := lineStartPos + start + col
 			cellStyle := style
 
 			// Check syntax highlighting first (lowest priority)
 			if len(highlights) > 0 {
-				syntaxStyle := e.getSyntaxStyle(absPos, highlights)
+				syntaxStyle := e.getSyntaxStyle(absByte, highlights)
 				if syntaxStyle != nil {
 					cellStyle = *syntaxStyle
 				}
 			}
 
+			// Semantic tokens from the language server refine it
+			if tok, ok := semanticTokenAt(bv, absPos); ok {
+				cellStyle = e.semanticStyle(tok, cellStyle, style)
+			}
+
+			// Underline diagnostics in the color of their severity
+			if d, ok := diagnosticAt(bv, absPos); ok {
+				cellStyle = cellStyle.Underline(tcell.UnderlineStyleCurly, severityColor(scheme, d.severity))
+			}
+
 			// Check if this position is in visual selection (takes priority)
 			if e.isInVisualSelection(absPos) {
 				cellStyle = visualStyle
@@ -143,13 +164,30 @@ func (e *Editor) renderBufferRegion(bv *BufferView, x, y, width, height int, sch
 			}
 
 			e.s.SetContent(screenX, screenY, visible[col], nil, cellStyle)
+			screenX++
+			absByte += utf8.RuneLen(visible[col])
 		}
+		drawHints(lineStartPos + len(runes))
 
 		// Clear rest of line in this region
-		for col := textStartX + len(visible); col < x+width; col++ {
+		for col := screenX; col < x+width; col++ {
 			e.s.SetContent(col, screenY, ' ', nil, style)
 		}
 
+		// Show the message of the most severe diagnostic on the cursor
+		// line after its text
+		if lineIndex == bv.cy && len(lineDiags) > 0 {
+			msgStyle := style.Foreground(severityColor(scheme, lineDiags[0].severity)).Italic(true)
+			col := screenX + 2
+			for _, r := range "■ " + diagnosticMessage(lineDiags[0]) {
+				if col >= x+width {
+					break
+				}
+				e.s.SetContent(col, screenY, r, nil, msgStyle)
+				col++
+			}
+		}
+
 		visualLine++
 		actualLine++
 	}
@@ -164,6 +202,24 @@ func (e *Editor) renderBufferRegion(bv *BufferView, x, y, width, height int, sch
 	}
 }
 
+// gutterWidths returns the widths of the sign column, shown while bv has
+// diagnostics, and of the line number column of bv
+func (e *Editor) gutterWidths(bv *BufferView) (signWidth, lineNumWidth int) {
+	if bv == nil {
+		return 0, 0
+	}
+	if len(bv.diagnostics) > 0 {
+		signWidth = 2 // sign and spacing
+	}
+	if e.config != nil && e.config.ShowLineNumbers {
+		lineNumWidth = len(fmt.Sprintf("%d", bv.buffer.LineCount())) + 2 // +2 for fold indicator and spacing
+		if lineNumWidth < 5 {
+			lineNumWidth = 5
+		}
+	}
+	return signWidth, lineNumWidth
+}
+
 func (e *Editor) draw() {
 	e.s.Clear()
 	w, h := e.s.Size()
@@ -247,6 +303,10 @@ func (e *Editor) draw() {
 			// File tree rendering is already done above, skip
 			continue
 		}
+		if split.splitType == SplitOutline {
+			e.drawOutline(split, scheme)
+			continue
+		}
 
 		// Render buffer split
 		var bv *BufferView
@@ -257,14 +317,17 @@ func (e *Editor) draw() {
 		// Create a temporary BufferView with the split's view state
 		if bv != nil {
 			tempBv := &BufferView{
-				buffer:        bv.buffer,
-				filename:      bv.filename,
-				dirty:         bv.dirty,
-				marks:         bv.marks,
-				jumpList:      bv.jumpList,
-				jumpListIndex: bv.jumpListIndex,
-				parser:        bv.parser,
-				foldRanges:    bv.foldRanges,
+				buffer:         bv.buffer,
+				filename:       bv.filename,
+				dirty:          bv.dirty,
+				marks:          bv.marks,
+				jumpList:       bv.jumpList,
+				jumpListIndex:  bv.jumpListIndex,
+				parser:         bv.parser,
+				foldRanges:     bv.foldRanges,
+				diagnostics:    bv.diagnostics,
+				semanticTokens: bv.semanticTokens,
+				inlayHints:     bv.inlayHints,
 			}
 
 			// Use split's view state (cursor, offsets)
@@ -321,41 +384,16 @@ func (e *Editor) draw() {
 
 	e.drawStatus(w, h)
 
+	e.drawFloat(w, h, scheme)
+
 	if e.popupActive {
 		e.drawPopup(w, h)
 	}
 
-	// Position cursor in the active split
-	if len(e.splits) > 0 && e.currentSplit < len(e.splits) {
-		split := e.splits[e.currentSplit]
-
-		// Calculate line number width for cursor positioning
-		cursorLineNumWidth := 0
-		if e.config != nil && e.config.ShowLineNumbers {
-			totalLines := e.lineCount()
-			cursorLineNumWidth = len(fmt.Sprintf("%d", totalLines)) + 2
-			if cursorLineNumWidth < 5 {
-				cursorLineNumWidth = 5
-			}
-		}
-
-		// Calculate cursor position within the split
-		splitX := split.x
-		screenX := e.cx - e.colOffset + splitX + cursorLineNumWidth
-		screenY := e.cy - e.rowOffset + split.y
-
-		// Bounds check
-		screenX = max(splitX+cursorLineNumWidth, screenX)
-		screenX = min(screenX, splitX+split.width-1)
-		screenY = max(split.y, screenY)
-		screenY = min(screenY, split.y+split.height-1)
-
-		// Only show cursor if buffer has focus (not file tree)
-		if !e.focusTree {
-			e.s.ShowCursor(screenX, screenY)
-		} else {
-			e.s.HideCursor()
-		}
+	// Position cursor in the active split; only show it if the buffer has
+	// focus (not file tree or outline)
+	if screenX, screenY, ok := e.cursorScreenPos(); ok && !e.focusTree && !e.focusOutline {
+		e.s.ShowCursor(screenX, screenY)
 	} else {
 		e.s.HideCursor()
 	}
@@ -363,6 +401,36 @@ func (e *Editor) draw() {
 	e.s.Show()
 }
 
+// cursorScreenPos returns the screen position of the cursor in the active
+// split
+func (e *Editor) cursorScreenPos() (screenX, screenY int, ok bool) {
+	if len(e.splits) == 0 || e.currentSplit >= len(e.splits) {
+		return 0, 0, false
+	}
+	split := e.splits[e.currentSplit]
+
+	// Calculate gutter width for cursor positioning
+	signWidth, lineNumWidth := e.gutterWidths(e.buf())
+	cursorLineNumWidth := signWidth + lineNumWidth
+
+	// Calculate cursor position within the split, after the inlay hints
+	// drawn before it
+	splitX := split.x
+	screenX = e.cx - e.colOffset + splitX + cursorLineNumWidth
+	if bv := e.buf(); bv != nil && len(bv.inlayHints) > 0 && e.cy < e.buffer.LineCount() {
+		lineStart := e.buffer.LineStart(e.cy)
+		screenX += inlayHintsWidth(bv, lineStart+e.colOffset, lineStart+e.cx)
+	}
+	screenY = e.cy - e.rowOffset + split.y
+
+	// Bounds check
+	screenX = max(splitX+cursorLineNumWidth, screenX)
+	screenX = min(screenX, splitX+split.width-1)
+	screenY = max(split.y, screenY)
+	screenY = min(screenY, split.y+split.height-1)
+	return screenX, screenY, true
+}
+
 func (e *Editor) drawStatus(w, h int) {
 	modeStr := map[Mode]string{
 		ModeNormal:  "NORMAL",
@@ -435,12 +503,30 @@ func (e *Editor) openPopup(title string, lines []string) {
 	e.popupScroll = 0
 }
 
+// openPopupList opens a popup whose lines can be selected with j/k; Enter
+// closes it and calls onSelect with the index of the selected line
+func (e *Editor) openPopupList(title string, lines []string, onSelect func(int)) {
+	e.openPopup(title, lines)
+	e.popupCursor = 0
+	e.popupSelect = onSelect
+}
+
 func (e *Editor) closePopup() {
+	if e.symbolPicker != nil {
+		e.symbolPicker.cancel()
+		e.symbolPicker = nil
+	}
+	if e.hierarchy != nil {
+		e.hierarchy.cancel()
+		e.hierarchy = nil
+	}
 	e.popupActive = false
 	e.popupTitle = ""
 	e.popupLines = nil
 	e.popupFixedH = 0
 	e.popupScroll = 0
+	e.popupCursor = 0
+	e.popupSelect = nil
 }
 
 func (e *Editor) drawPopup(w, h int) {
@@ -547,6 +633,11 @@ func (e *Editor) drawPopup(w, h int) {
 	if len(lines) > visualH {
 		maxScroll = len(lines) - visualH
 	}
+	if e.popupSelect != nil {
+		// keep the selected line in view
+		e.popupCursor = clamp(e.popupCursor, 0, max(0, len(lines)-1))
+		e.popupScroll = clamp(e.popupScroll, e.popupCursor-visualH+1, e.popupCursor)
+	}
 	e.popupScroll = clamp(e.popupScroll, 0, maxScroll)
 
 	for i := 0; i < visualH; i++ {
@@ -566,25 +657,354 @@ func (e *Editor) drawPopup(w, h int) {
 			}
 		}
 
+		lineStyle := textStyle
+		if e.popupSelect != nil && idx == e.popupCursor {
+			lineStyle = tcell.StyleDefault
+			for j := -1; j <= contentW; j++ {
+				e.s.SetContent(startX+j, startY+i, ' ', nil, lineStyle)
+			}
+		}
 		for j, r := range runes {
-			e.s.SetContent(startX+j, startY+i, r, nil, textStyle)
+			e.s.SetContent(startX+j, startY+i, r, nil, lineStyle)
+		}
+	}
+}
+
+// floatKind is what a floating window shows
+type floatKind int
+
+const (
+	floatHover floatKind = iota
+	floatSignature
+	floatCompletion // documentation of the selected completion
+)
+
+// floatWindow is a small bordered window drawn next to the cursor, for
+// documentation from language servers. It is anchored at the cursor
+// position it was opened at and closes when the cursor moves away.
+type floatWindow struct {
+	kind      floatKind
+	lines     []floatLine
+	scroll    int
+	height    int // content lines shown at the last draw
+	bv        *BufferView
+	line, col int // cursor position it was opened at
+	mode      Mode
+}
+
+type floatLineKind int
+
+const (
+	floatText floatLineKind = iota
+	floatCode
+	floatHeading
+	floatRule
+)
+
+// floatLine is a line of a float. hlStart and hlEnd are a rune span to
+// highlight, such as the active parameter of a signature.
+type floatLine struct {
+	text           string
+	kind           floatLineKind
+	hlStart, hlEnd int
+}
+
+// openFloat shows lines in a float at the cursor
+func (e *Editor) openFloat(kind floatKind, lines []floatLine) {
+	e.float = &floatWindow{kind: kind, lines: lines, bv: e.buf(), line: e.cy, col: e.cx, mode: e.mode}
+}
+
+func (e *Editor) closeFloat() {
+	e.float = nil
+}
+
+// closeFloatIfMoved closes the float when the cursor has left it: hovers
+// close on any movement, signature help and completion documentation when
+// the cursor leaves the line or insert mode ends
+func (e *Editor) closeFloatIfMoved() {
+	f := e.float
+	if f == nil {
+		return
+	}
+	if e.buf() != f.bv || e.mode != f.mode || e.cy != f.line || f.kind == floatHover && e.cx != f.col {
+		e.float = nil
+	}
+}
+
+// scrollFloat scrolls the float by a page
+func (e *Editor) scrollFloat(down bool) {
+	page := max(1, e.float.height-1)
+	if !down {
+		page = -page
+	}
+	e.float.scroll += page // clamped when drawn
+}
+
+// markdownLines converts markdown into float lines: code blocks lose
+// their fences, headings their #, and inline markup is dropped. Runs of
+// blank lines are collapsed.
+func markdownLines(text string) []floatLine {
+	var lines []floatLine
+	inCode := false
+	blank := true // drop leading blank lines
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			inCode = !inCode
+			continue
+		case inCode:
+			lines = append(lines, floatLine{text: strings.ReplaceAll(line, "\t", "    "), kind: floatCode})
+		case trimmed == "":
+			if !blank {
+				lines = append(lines, floatLine{})
+			}
+			blank = true
+			continue
+		case trimmed == "---" || trimmed == "***" || trimmed == "___":
+			lines = append(lines, floatLine{kind: floatRule})
+		case strings.HasPrefix(trimmed, "#"):
+			lines = append(lines, floatLine{text: markdownInline(strings.TrimLeft(trimmed, "# ")), kind: floatHeading})
+		default:
+			lines = append(lines, floatLine{text: markdownInline(line)})
+		}
+		blank = false
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == (floatLine{}) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// markdownInline drops backticks, ** emphasis and backslash escapes from
+// a line of markdown and replaces links with their text
+func markdownInline(s string) string {
+	var b strings.Builder
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		switch {
+		case rs[i] == '\\' && i+1 < len(rs) && strings.ContainsRune("\\`*_{}[]()#+-.!<>|~", rs[i+1]):
+			i++
+			b.WriteRune(rs[i])
+		case rs[i] == '`':
+		case rs[i] == '*' && i+1 < len(rs) && rs[i+1] == '*':
+			i++
+		case rs[i] == '[':
+			// [text](target)
+			text, after, ok := strings.Cut(string(rs[i+1:]), "](")
+			end := strings.IndexByte(after, ')')
+			if !ok || end < 0 || strings.ContainsRune(text, ']') {
+				b.WriteRune(rs[i])
+				continue
+			}
+			b.WriteString(text)
+			i += utf8.RuneCountInString(text) + utf8.RuneCountInString(after[:end]) + 3 // up to the )
+		default:
+			b.WriteRune(rs[i])
+		}
+	}
+	return b.String()
+}
+
+// wrapFloatLines breaks lines longer than width, at a space if there is
+// one. Highlight spans move with the text.
+func wrapFloatLines(lines []floatLine, width int) []floatLine {
+	var out []floatLine
+	for _, line := range lines {
+		rs := []rune(line.text)
+		if line.kind == floatRule || len(rs) <= width {
+			out = append(out, line)
+			continue
+		}
+		for off := 0; off < len(rs); {
+			end := min(off+width, len(rs))
+			if end < len(rs) && line.kind != floatCode {
+				if sp := strings.LastIndex(string(rs[off:end]), " "); sp > 0 {
+					end = off + utf8.RuneCountInString(string(rs[off:end])[:sp]) + 1
+				}
+			}
+			part := floatLine{text: string(rs[off:end]), kind: line.kind}
+			if line.hlEnd > off && line.hlStart < end {
+				part.hlStart, part.hlEnd = max(line.hlStart, off)-off, min(line.hlEnd, end)-off
+			}
+			out = append(out, part)
+			off = end
+		}
+	}
+	return out
+}
+
+// floatMaxHeight is the most lines of content a float shows at once
+const floatMaxHeight = 12
+
+// drawFloat draws the float below the cursor line, or above it when there
+// is more room there. Signature help prefers above so it does not cover
+// the lines being typed.
+func (e *Editor) drawFloat(w, h int, scheme *ColorScheme) {
+	f := e.float
+	if f == nil || len(f.lines) == 0 {
+		return
+	}
+	cursorX, cursorY, ok := e.cursorScreenPos()
+	if !ok {
+		return
+	}
+
+	contentW := 0
+	for _, line := range f.lines {
+		contentW = max(contentW, utf8.RuneCountInString(line.text))
+	}
+	contentW = max(1, min(contentW, 80, w-4))
+	lines := wrapFloatLines(f.lines, contentW)
+
+	below := h - 1 - (cursorY + 1) // the status line stays visible
+	above := cursorY
+	boxH := min(len(lines), floatMaxHeight) + 2
+	var y0 int
+	if f.kind == floatSignature && above >= boxH || f.kind != floatSignature && below < boxH && above > below {
+		boxH = min(boxH, above)
+		y0 = cursorY - boxH
+	} else {
+		boxH = min(boxH, below)
+		y0 = cursorY + 1
+	}
+	if boxH < 3 {
+		return
+	}
+	boxW := contentW + 4
+	x0 := max(0, min(cursorX, w-boxW))
+
+	f.height = boxH - 2
+	f.scroll = clamp(f.scroll, 0, max(0, len(lines)-f.height))
+
+	base := tcell.StyleDefault.Background(scheme.Background).Foreground(scheme.Foreground)
+	border := base.Foreground(scheme.TreeBorder)
+	for yy := 0; yy < boxH; yy++ {
+		for xx := 0; xx < boxW; xx++ {
+			r := ' '
+			switch {
+			case yy == 0 && xx == 0:
+				r = '┌'
+			case yy == 0 && xx == boxW-1:
+				r = '┐'
+			case yy == boxH-1 && xx == 0:
+				r = '└'
+			case yy == boxH-1 && xx == boxW-1:
+				r = '┘'
+			case yy == 0 || yy == boxH-1:
+				r = '─'
+			case xx == 0 || xx == boxW-1:
+				r = '│'
+			}
+			e.s.SetContent(x0+xx, y0+yy, r, nil, border)
+		}
+	}
+	if len(lines) > f.height {
+		pos := []rune(fmt.Sprintf(" %d/%d ", f.scroll+f.height, len(lines)))
+		for i, r := range pos {
+			if x := x0 + boxW - 2 - len(pos) + i; x > x0 {
+				e.s.SetContent(x, y0+boxH-1, r, nil, border)
+			}
+		}
+	}
+
+	for i := 0; i < f.height; i++ {
+		line := lines[f.scroll+i]
+		y := y0 + 1 + i
+		if line.kind == floatRule {
+			for xx := 0; xx < contentW; xx++ {
+				e.s.SetContent(x0+2+xx, y, '─', nil, border)
+			}
+			continue
+		}
+		style := base
+		switch line.kind {
+		case floatCode:
+			style = base.Foreground(scheme.Function)
+		case floatHeading:
+			style = base.Bold(true)
+		}
+		for j, r := range []rune(line.text) {
+			st := style
+			if j >= line.hlStart && j < line.hlEnd {
+				st = st.Bold(true).Underline(true)
+			}
+			e.s.SetContent(x0+2+j, y, r, nil, st)
 		}
 	}
 }
 
-// getSyntaxStyle returns the appropriate style for a given position based on syntax highlighting
-func (e *Editor) getSyntaxStyle(pos int, highlights []Highlight) *tcell.Style {
+// getSyntaxStyle returns the appropriate style for a given byte offset based on syntax highlighting
+func (e *Editor) getSyntaxStyle(bytePos int, highlights []Highlight) *tcell.Style {
 	// Find the most specific (smallest/innermost) highlight that contains this position
 	// Search backwards since children are added after parents
 	for i := len(highlights) - 1; i >= 0; i-- {
 		hl := highlights[i]
-		if pos >= hl.StartByte && pos < hl.EndByte {
+		if bytePos >= hl.StartByte && bytePos < hl.EndByte {
+			if style, ok := e.captureStyle(hl.Capture); ok {
+				return style
+			}
 			return e.highlightTypeToStyle(hl.Type)
 		}
 	}
 	return nil
 }
 
+// captureStyles maps highlight query capture names to styles. A nil entry
+// leaves the text unstyled.
+var captureStyles = map[string]func(s *ColorScheme) tcell.Style{
+	"keyword":              func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Keyword).Bold(true) },
+	"function":             func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Function) },
+	"function.builtin":     func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Function).Bold(true) },
+	"constructor":          func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Type) },
+	"type":                 func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Type) },
+	"type.builtin":         func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Type).Bold(true) },
+	"string":               func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.String) },
+	"escape":               func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Constant) },
+	"number":               func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Number) },
+	"comment":              func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Comment).Dim(true) },
+	"constant":             func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Constant).Bold(true) },
+	"property":             func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Property) },
+	"attribute":            func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Property) },
+	"tag":                  func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Keyword) },
+	"variable.builtin":     func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Keyword) },
+	"variable.parameter":   func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Italic(true) },
+	"markup.heading":       func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Keyword).Bold(true) },
+	"markup.raw":           func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.String) },
+	"markup.link":          func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Function) },
+	"markup.link.url":      func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Function).Underline(true) },
+	"markup.list":          func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Keyword) },
+	"markup.italic":        func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Italic(true) },
+	"markup.strong":        func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Bold(true) },
+	"markup.strikethrough": func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.StrikeThrough(true) },
+	"label":                func(s *ColorScheme) tcell.Style { return tcell.StyleDefault.Foreground(s.Constant) },
+	"variable":             nil,
+	"operator":             nil,
+	"punctuation":          nil,
+	"embedded":             nil,
+}
+
+// captureStyle returns the style for a capture name, falling back to its
+// parent groups ("function.method.call" -> "function.method" -> "function").
+// ok is false when no group of the name is known.
+func (e *Editor) captureStyle(capture string) (style *tcell.Style, ok bool) {
+	for name := capture; name != ""; {
+		if fn, found := captureStyles[name]; found {
+			if fn == nil {
+				return nil, true
+			}
+			st := fn(GetColorScheme(e.config.ColorScheme))
+			return &st, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return nil, false
+}
+
 // highlightTypeToStyle converts a HighlightType to a tcell.Style
 func (e *Editor) highlightTypeToStyle(hlType HighlightType) *tcell.Style {
 	scheme := GetColorScheme(e.config.ColorScheme)
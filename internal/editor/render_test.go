@@ -1,7 +1,44 @@
 package editor
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+	"github.com/gdamore/tcell/v2"
+)
 
 func TestRender_Placeholder(t *testing.T) {
 	t.Skip("render tests: add once styles/selection rendering stabilizes")
 }
+
+func TestRender_SyntaxHighlightNonASCII(t *testing.T) {
+	e := newTestEditor(t, "package p\nvar é = \"s\"\n")
+	e.config = &config.Config{ColorScheme: "default"}
+
+	parser, err := NewTreeSitterParser("go")
+	if err != nil || parser == nil {
+		t.Fatalf("go parser: %v", err)
+	}
+	defer parser.Close()
+	if err := parser.Parse(e.buffer.String()); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	bv := &BufferView{buffer: e.buffer, parser: parser}
+	scheme := GetColorScheme("default")
+	e.renderBufferRegion(bv, 0, 0, 80, 5, scheme)
+
+	sim := e.s.(tcell.SimulationScreen)
+	// line 1 is `var é = "s"`; the opening quote is rune column 8 but byte column 9
+	for col := 8; col <= 10; col++ {
+		_, _, style, _ := sim.GetContent(col, 1)
+		fg, _, _ := style.Decompose()
+		if fg != scheme.String {
+			t.Fatalf("col %d: expected string color, got %v", col, fg)
+		}
+	}
+	_, _, style, _ := sim.GetContent(7, 1)
+	if fg, _, _ := style.Decompose(); fg == scheme.String {
+		t.Fatalf("col 7 should not be highlighted as string")
+	}
+}
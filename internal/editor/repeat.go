@@ -4,7 +4,7 @@ func (e *Editor) repeatLast() {
 	switch e.last.kind {
 	case RepeatOpMotion:
 		// text object repeat
-		if e.last.textObjPrefix != 0 && (e.last.textObjUnit == 'w' || e.last.textObjUnit == 'W') {
+		if e.last.textObjPrefix != 0 {
 			// restore explicit register (if any)
 			if e.last.reg != 0 {
 				e.regOverrideSet = true
@@ -3,127 +3,140 @@ package editor
 import (
 	"fmt"
 	"regexp"
-	"strings"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"unicode/utf8"
 )
 
-// searchNext finds the next occurrence of searchQuery
-// skipCurrent: if true, skip a match at current position (for 'n'/'N')
-func (e *Editor) searchNext(forward bool, skipCurrent bool) {
-	if e.searchQuery == "" {
-		return
-	}
-
-	// Add current position to jump list before jumping to search result
-	e.addToJumpList(e.cy, e.cx)
-
-	text := e.buffer.String()
-	query := e.searchQuery
-
-	// Try to compile as regex first, fall back to literal search
-	re, err := regexp.Compile(query)
-	useRegex := err == nil
+// searchMatch is a match of the search pattern, in rune offsets
+type searchMatch struct {
+	start, end int
+}
 
-	// Start position: current cursor position
-	currentPos := e.posFromCursor()
+// searchWindow is the number of lines a match may span when the pattern
+// can match a line break
+const searchWindow = 32
 
-	var matchPos int
-	found := false
+// searchPattern is a compiled search pattern
+type searchPattern struct {
+	*regexp.Regexp
+	multiline bool // the pattern can match a line break
+}
 
-	if forward {
-		// Search forward
-		startPos := currentPos
-		if skipCurrent {
-			startPos = currentPos + 1
-		}
+// searchRegexp compiles a search pattern; patterns that are not valid
+// regular expressions are searched for literally. ^ and $ match at line
+// boundaries and negated classes do not match a line break, so only
+// patterns naming one, like \n or \s, match across lines.
+func searchRegexp(query string) *searchPattern {
+	r, err := syntax.Parse("(?m)"+query, syntax.Perl&^syntax.ClassNL)
+	if err != nil {
+		return &searchPattern{Regexp: regexp.MustCompile(regexp.QuoteMeta(query))}
+	}
+	return &searchPattern{Regexp: regexp.MustCompile(r.String()), multiline: matchesNewline(r)}
+}
 
-		if startPos <= len(text) {
-			if useRegex {
-				loc := re.FindStringIndex(text[startPos:])
-				if loc != nil {
-					matchPos = startPos + loc[0]
-					found = true
-				}
-			} else {
-				idx := strings.Index(text[startPos:], query)
-				if idx != -1 {
-					matchPos = startPos + idx
-					found = true
-				}
+// matchesNewline reports whether r can match a line break
+func matchesNewline(r *syntax.Regexp) bool {
+	switch r.Op {
+	case syntax.OpLiteral:
+		return slices.Contains(r.Rune, '\n')
+	case syntax.OpCharClass:
+		for i := 0; i+1 < len(r.Rune); i += 2 {
+			if r.Rune[i] <= '\n' && '\n' <= r.Rune[i+1] {
+				return true
 			}
 		}
+	case syntax.OpAnyChar:
+		return true
+	}
+	return slices.ContainsFunc(r.Sub, matchesNewline)
+}
 
-		// Wrap around to beginning if not found
-		if !found {
-			if useRegex {
-				loc := re.FindStringIndex(text)
-				if loc != nil && loc[0] < currentPos {
-					matchPos = loc[0]
-					found = true
-					e.statusMsg = "search wrapped"
-				}
-			} else {
-				idx := strings.Index(text, query)
-				if idx != -1 && idx < currentPos {
-					matchPos = idx
-					found = true
-					e.statusMsg = "search wrapped"
-				}
-			}
+// matchesIn returns the matches of re in text, which starts at rune
+// offset pos
+func matchesIn(re *regexp.Regexp, text string, pos int) []searchMatch {
+	var matches []searchMatch
+	b := 0 // byte offset in text of pos
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		pos += utf8.RuneCountInString(text[b:loc[0]])
+		matches = append(matches, searchMatch{pos, pos + utf8.RuneCountInString(text[loc[0]:loc[1]])})
+		b = loc[0]
+	}
+	return matches
+}
+
+// lineMatches returns the matches of re starting in line y. Only
+// multiline patterns look past the end of the line, up to searchWindow
+// lines.
+func (e *Editor) lineMatches(re *searchPattern, y int) []searchMatch {
+	if !re.multiline {
+		return matchesIn(re.Regexp, e.buffer.Line(y), e.buffer.LineStart(y))
+	}
+	last := min(y+searchWindow, e.buffer.LineCount()) - 1
+	text, _ := e.buffer.Slice(e.buffer.LineStart(y), e.buffer.LineEnd(last))
+	matches := matchesIn(re.Regexp, text, e.buffer.LineStart(y))
+	end := e.buffer.LineEnd(y) // a match at the line break starts in line y
+	i := 0
+	for i < len(matches) && matches[i].start <= end {
+		i++
+	}
+	return matches[:i]
+}
+
+// findMatch returns the first match of re after pos, or the last one
+// before it when searching backward, scanning line by line from the
+// cursor. A match at pos counts unless skip is set. With wrap the search
+// goes on from the other end of the buffer.
+func (e *Editor) findMatch(re *searchPattern, pos int, forward, skip, wrap bool) (m searchMatch, wrapped, ok bool) {
+	n := e.buffer.LineCount()
+	y := e.buffer.LineAt(pos)
+	for i := 0; i <= n; i++ {
+		line := y + i
+		if !forward {
+			line = y - i
 		}
-	} else {
-		// Search backward
-		searchEnd := currentPos
-		if skipCurrent && currentPos > 0 {
-			searchEnd = currentPos - 1
+		wrapped = line < 0 || line >= n
+		if wrapped && !wrap {
+			break
 		}
-
-		if searchEnd > 0 {
-			if useRegex {
-				// Find all matches up to searchEnd, take the last one
-				allMatches := re.FindAllStringIndex(text[:searchEnd+1], -1)
-				if len(allMatches) > 0 {
-					loc := allMatches[len(allMatches)-1]
-					matchPos = loc[0]
-					found = true
-				}
-			} else {
-				idx := strings.LastIndex(text[:searchEnd+1], query)
-				if idx != -1 {
-					matchPos = idx
-					found = true
-				}
+		matches := e.lineMatches(re, (line+n)%n)
+		for j := range matches {
+			m := matches[j]
+			if !forward {
+				m = matches[len(matches)-1-j]
 			}
-		}
-
-		// Wrap around to end if not found
-		if !found {
-			if useRegex {
-				allMatches := re.FindAllStringIndex(text, -1)
-				if len(allMatches) > 0 {
-					loc := allMatches[len(allMatches)-1]
-					if loc[0] > currentPos {
-						matchPos = loc[0]
-						found = true
-						e.statusMsg = "search wrapped"
-					}
-				}
-			} else {
-				idx := strings.LastIndex(text, query)
-				if idx != -1 && idx > currentPos {
-					matchPos = idx
-					found = true
-					e.statusMsg = "search wrapped"
-				}
+			switch {
+			case wrapped && forward && m.start < pos, wrapped && !forward && m.start > pos:
+				return m, true, true
+			case wrapped:
+			case m.start == pos && !skip, forward && m.start > pos, !forward && m.start < pos:
+				return m, false, true
 			}
 		}
 	}
+	return searchMatch{}, false, false
+}
+
+// searchNext finds the next occurrence of searchQuery
+// skipCurrent: if true, skip a match at current position (for 'n'/'N')
+func (e *Editor) searchNext(forward bool, skipCurrent bool) {
+	if e.searchQuery == "" {
+		return
+	}
+
+	// Add current position to jump list before jumping to search result
+	e.addToJumpList(e.cy, e.cx)
 
+	query := e.searchQuery
+	m, wrapped, found := e.findMatch(searchRegexp(query), e.posFromCursor(), forward, skipCurrent, true)
 	if found {
-		e.setCursorFromPos(matchPos)
+		e.setCursorFromPos(m.start)
 		e.wantX = e.cx
 		e.updateSearchHighlights()
-		// Don't overwrite "search wrapped" message
-		if e.statusMsg != "search wrapped" {
+		if wrapped {
+			e.statusMsg = "search wrapped"
+		} else {
 			e.updateSearchStatus()
 		}
 	} else {
@@ -132,39 +145,20 @@ func (e *Editor) searchNext(forward bool, skipCurrent bool) {
 	}
 }
 
-// updateSearchHighlights finds all matches in the buffer
+// updateSearchHighlights finds all matches in the buffer, line by line
+// unless the pattern can span lines
 func (e *Editor) updateSearchHighlights() {
+	e.searchMatches = nil
 	if e.searchQuery == "" {
-		e.searchMatches = nil
 		return
 	}
-
-	text := e.buffer.String()
-	query := e.searchQuery
-	e.searchMatches = nil
-
-	// Try regex first, fall back to literal
-	re, err := regexp.Compile(query)
-
-	if err == nil {
-		// Regex search - find all matches
-		allMatches := re.FindAllStringIndex(text, -1)
-		for _, loc := range allMatches {
-			e.searchMatches = append(e.searchMatches, loc[0])
-		}
-	} else {
-		// Literal search - find all matches
-		offset := 0
-		searchText := text
-		for {
-			idx := strings.Index(searchText, query)
-			if idx == -1 {
-				break
-			}
-			e.searchMatches = append(e.searchMatches, offset+idx)
-			searchText = searchText[idx+len(query):]
-			offset += idx + len(query)
-		}
+	re := searchRegexp(e.searchQuery)
+	if re.multiline {
+		e.searchMatches = matchesIn(re.Regexp, e.buffer.String(), 0)
+		return
+	}
+	for y := 0; y < e.buffer.LineCount(); y++ {
+		e.searchMatches = append(e.searchMatches, e.lineMatches(re, y)...)
 	}
 }
 
@@ -178,8 +172,8 @@ func (e *Editor) updateSearchStatus() {
 	currentPos := e.posFromCursor()
 	currentMatch := -1
 
-	for i, matchPos := range e.searchMatches {
-		if matchPos >= currentPos {
+	for i, m := range e.searchMatches {
+		if m.start >= currentPos {
 			currentMatch = i + 1
 			break
 		}
@@ -195,41 +189,11 @@ func (e *Editor) updateSearchStatus() {
 
 // isSearchMatch checks if the given absolute position is part of a search match
 func (e *Editor) isSearchMatch(pos int) bool {
-	if e.searchQuery == "" || len(e.searchMatches) == 0 {
-		return false
-	}
-
-	// Try regex to get match length
-	re, err := regexp.Compile(e.searchQuery)
-	var queryLen int
-
-	if err == nil {
-		// For regex, we need to check each match individually
-		text := e.buffer.String()
-		for _, matchPos := range e.searchMatches {
-			if matchPos > pos {
-				break
-			}
-			// Get the actual match at this position
-			loc := re.FindStringIndex(text[matchPos:])
-			if loc != nil && loc[0] == 0 {
-				matchLen := loc[1]
-				if pos >= matchPos && pos < matchPos+matchLen {
-					return true
-				}
-			}
-		}
-		return false
-	} else {
-		// Literal search - fixed length
-		queryLen = len(e.searchQuery)
-		for _, matchPos := range e.searchMatches {
-			if pos >= matchPos && pos < matchPos+queryLen {
-				return true
-			}
-		}
+	if e.searchQuery == "" {
 		return false
 	}
+	i := sort.Search(len(e.searchMatches), func(i int) bool { return e.searchMatches[i].end > pos })
+	return i < len(e.searchMatches) && e.searchMatches[i].start <= pos
 }
 
 // performIncrementalSearch performs search as user types (for / and ? modes)
@@ -241,52 +205,10 @@ func (e *Editor) performIncrementalSearch() {
 	}
 
 	query := string(e.searchBuf)
-	text := e.buffer.String()
-	currentPos := e.posFromCursor()
-
-	// Try to find first match from current position
-	re, err := regexp.Compile(query)
-	useRegex := err == nil
-
-	var matchPos int
-	found := false
-
-	if e.searchForward {
-		// Forward search from current position
-		if useRegex {
-			loc := re.FindStringIndex(text[currentPos:])
-			if loc != nil {
-				matchPos = currentPos + loc[0]
-				found = true
-			}
-		} else {
-			idx := strings.Index(text[currentPos:], query)
-			if idx != -1 {
-				matchPos = currentPos + idx
-				found = true
-			}
-		}
-	} else {
-		// Backward search from current position
-		if useRegex {
-			allMatches := re.FindAllStringIndex(text[:currentPos+1], -1)
-			if len(allMatches) > 0 {
-				loc := allMatches[len(allMatches)-1]
-				matchPos = loc[0]
-				found = true
-			}
-		} else {
-			idx := strings.LastIndex(text[:currentPos+1], query)
-			if idx != -1 {
-				matchPos = idx
-				found = true
-			}
-		}
-	}
-
+	m, _, found := e.findMatch(searchRegexp(query), e.posFromCursor(), e.searchForward, false, false)
 	if found {
 		// Temporarily move cursor to show the match
-		e.setCursorFromPos(matchPos)
+		e.setCursorFromPos(m.start)
 		e.wantX = e.cx
 
 		// Update search query and highlights
@@ -296,8 +218,6 @@ func (e *Editor) performIncrementalSearch() {
 	} else {
 		// No match found
 		e.searchMatches = nil
-		if len(query) > 0 {
-			e.statusMsg = fmt.Sprintf("/%s [0/0]", query)
-		}
+		e.statusMsg = fmt.Sprintf("/%s [0/0]", query)
 	}
 }
@@ -250,3 +250,76 @@ func TestSearch_Backspace(t *testing.T) {
 		t.Fatalf("expected searchBuf 'ab', got %q", string(ed.searchBuf))
 	}
 }
+
+func TestSearch_AcrossLinesWithUnicode(t *testing.T) {
+	ed := newTestEditor(t, "héllo wörld\nfoo\nwörld wörld")
+
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, '/', tcell.ModNone))
+	for _, r := range "w.rld" {
+		ed.handleKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	ed.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	if ed.cy != 0 || ed.cx != 6 {
+		t.Fatalf("first match: expected 0:6, got %d:%d", ed.cy, ed.cx)
+	}
+	if len(ed.searchMatches) != 3 || !ed.isSearchMatch(ed.buffer.LineStart(2)+10) || ed.isSearchMatch(ed.buffer.LineStart(2)+5) {
+		t.Fatalf("matches = %v", ed.searchMatches)
+	}
+
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, 'n', tcell.ModNone))
+	if ed.cy != 2 || ed.cx != 0 {
+		t.Fatalf("second match: expected 2:0, got %d:%d", ed.cy, ed.cx)
+	}
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, 'n', tcell.ModNone))
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, 'n', tcell.ModNone))
+	if ed.cy != 0 || ed.cx != 6 || ed.statusMsg != "search wrapped" {
+		t.Fatalf("after wrapping: %d:%d %q", ed.cy, ed.cx, ed.statusMsg)
+	}
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, 'N', tcell.ModNone))
+	if ed.cy != 2 || ed.cx != 6 {
+		t.Fatalf("N: expected 2:6, got %d:%d", ed.cy, ed.cx)
+	}
+}
+
+func TestSearch_PatternAcrossLines(t *testing.T) {
+	ed := newTestEditor(t, "foo\nbar foo\n  bar\n\"a\nb\"")
+
+	search := func(query string) {
+		ed.handleKey(tcell.NewEventKey(tcell.KeyRune, '/', tcell.ModNone))
+		for _, r := range query {
+			ed.handleKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+		ed.handleKey(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	}
+
+	search(`foo\nbar`)
+	if ed.cy != 0 || ed.cx != 0 {
+		t.Fatalf(`foo\nbar: expected 0:0, got %d:%d`, ed.cy, ed.cx)
+	}
+	if len(ed.searchMatches) != 1 || !ed.isSearchMatch(ed.buffer.LineStart(1)+2) {
+		t.Fatalf(`foo\nbar: matches = %v`, ed.searchMatches)
+	}
+
+	search(`foo\s+bar`)
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, 'n', tcell.ModNone))
+	if ed.cy != 1 || ed.cx != 4 {
+		t.Fatalf(`foo\s+bar: expected 1:4, got %d:%d`, ed.cy, ed.cx)
+	}
+	if len(ed.searchMatches) != 2 || !ed.isSearchMatch(ed.buffer.LineStart(2)+4) {
+		t.Fatalf(`foo\s+bar: matches = %v`, ed.searchMatches)
+	}
+	ed.handleKey(tcell.NewEventKey(tcell.KeyRune, 'N', tcell.ModNone))
+	if ed.cy != 0 || ed.cx != 0 {
+		t.Fatalf(`N: expected 0:0, got %d:%d`, ed.cy, ed.cx)
+	}
+
+	// negated classes stay within a line
+	search(`"[^"]*"`)
+	if ed.statusMsg != `pattern not found: "[^"]*"` {
+		t.Fatalf(`"[^"]*": status %q, cursor %d:%d`, ed.statusMsg, ed.cy, ed.cx)
+	}
+	search(`a$`)
+	if ed.cy != 3 || ed.cx != 1 {
+		t.Fatalf(`a$: expected 3:1, got %d:%d`, ed.cy, ed.cx)
+	}
+}
@@ -8,9 +8,11 @@ type SplitType int
 const (
 	SplitBuffer SplitType = iota
 	SplitFileTree
+	SplitOutline
 )
 
-// Split represents a window split showing a buffer or file tree
+// Split represents a window split showing a buffer, the file tree or the
+// symbol outline
 type Split struct {
 	splitType   SplitType // type of split (buffer or file tree)
 	bufferIndex int       // index into Editor.buffers (only for SplitBuffer)
@@ -32,13 +34,47 @@ type Split struct {
 	visualKind   VisualKind
 }
 
+// outlineSplitWidth returns the width of the outline panel on a screen w
+// columns wide, 0 when it is closed
+func (e *Editor) outlineSplitWidth(w int) int {
+	if !e.outlineOpen {
+		return 0
+	}
+	return clamp(e.outlinePanelWidth, 20, max(20, w/2))
+}
+
+// withOutlineSplit appends the outline panel to the splits when it is open
+func (e *Editor) withOutlineSplit(splits []*Split, w, height int) []*Split {
+	outlineWidth := e.outlineSplitWidth(w)
+	if outlineWidth == 0 {
+		return splits
+	}
+	outline := &Split{
+		splitType: SplitOutline,
+		width:     outlineWidth,
+		height:    height,
+		x:         w - outlineWidth,
+		y:         0,
+	}
+	if e.focusOutline {
+		e.currentSplit = len(splits)
+	}
+	return append(splits, outline)
+}
+
 // initSplits initializes the split system with a single split
 func (e *Editor) initSplits() {
-	w, h := e.s.Size()
+	screenW, h := e.s.Size()
 
 	// Reserve space for status line
 	height := h - 1
 
+	// and for the outline panel on the right
+	w := screenW
+	if outlineWidth := e.outlineSplitWidth(screenW); outlineWidth > 0 {
+		w -= outlineWidth + 1
+	}
+
 	// If file tree is open, create it as the first split
 	if e.treeOpen && e.fileTree != nil {
 		treeWidth := e.treePanelWidth
@@ -100,11 +136,10 @@ func (e *Editor) initSplits() {
 		// Adjust currentSplit index (tree is now at index 0)
 		if e.focusTree {
 			e.currentSplit = 0
-		} else if e.currentSplit < len(e.splits) {
-			e.currentSplit = e.currentSplit // Keep existing if valid
-		} else {
+		} else if e.currentSplit >= len(e.splits) {
 			e.currentSplit = 1 // Default to first buffer split
 		}
+		e.splits = e.withOutlineSplit(e.splits, screenW, height)
 	} else {
 		// No tree - preserve existing buffer splits
 		var bufferSplits []*Split
@@ -151,6 +186,7 @@ func (e *Editor) initSplits() {
 		if e.currentSplit < 0 {
 			e.currentSplit = 0
 		}
+		e.splits = e.withOutlineSplit(e.splits, screenW, height)
 	}
 }
 
@@ -176,6 +212,8 @@ func (e *Editor) resizeSplits() {
 		hasTree = true
 	}
 
+	outlineWidth := e.outlineSplitWidth(w)
+
 	var bufferSplits []*Split
 	for _, split := range e.splits {
 		if split.splitType == SplitFileTree && hasTree {
@@ -183,6 +221,11 @@ func (e *Editor) resizeSplits() {
 			split.height = height
 			split.x = 0
 			split.y = 0
+		} else if split.splitType == SplitOutline {
+			split.width = outlineWidth
+			split.height = height
+			split.x = w - outlineWidth
+			split.y = 0
 		} else if split.splitType == SplitBuffer {
 			bufferSplits = append(bufferSplits, split)
 		}
@@ -192,6 +235,9 @@ func (e *Editor) resizeSplits() {
 	if len(bufferSplits) > 0 {
 		startX := 0
 		availableWidth := w
+		if outlineWidth > 0 {
+			availableWidth -= outlineWidth + 1
+		}
 		if hasTree {
 			startX = treeWidth + 1
 			availableWidth = w - treeWidth - 1
@@ -238,9 +284,9 @@ func (e *Editor) vsplit() {
 
 	currentSplit := e.splits[e.currentSplit]
 
-	// Can't split file tree
-	if currentSplit.splitType == SplitFileTree {
-		e.statusMsg = "cannot split file tree"
+	// Can't split file tree or outline
+	if currentSplit.splitType != SplitBuffer {
+		e.statusMsg = "cannot split file tree or outline"
 		return
 	}
 
@@ -293,9 +339,9 @@ func (e *Editor) split() {
 
 	currentSplit := e.splits[e.currentSplit]
 
-	// Can't split file tree
-	if currentSplit.splitType == SplitFileTree {
-		e.statusMsg = "cannot split file tree"
+	// Can't split file tree or outline
+	if currentSplit.splitType != SplitBuffer {
+		e.statusMsg = "cannot split file tree or outline"
 		return
 	}
 
@@ -357,6 +403,10 @@ func (e *Editor) closeSplit() {
 		e.statusMsg = "cannot close file tree (use :tree to toggle)"
 		return
 	}
+	if e.splits[e.currentSplit].splitType == SplitOutline {
+		e.statusMsg = "cannot close outline (use :outline to toggle)"
+		return
+	}
 
 	// Remove current split
 	e.splits = append(e.splits[:e.currentSplit], e.splits[e.currentSplit+1:]...)
@@ -380,32 +430,10 @@ func (e *Editor) closeSplit() {
 	e.statusMsg = fmt.Sprintf("%d splits remaining", len(e.splits))
 }
 
-// redistributeSplitSpace evenly distributes space among splits
+// redistributeSplitSpace evenly distributes space among the buffer
+// splits, stacked horizontally between the file tree and outline panels
 func (e *Editor) redistributeSplitSpace() {
-	if len(e.splits) == 0 {
-		return
-	}
-
-	w, h := e.s.Size()
-	height := h - 1 // Reserve space for status line
-
-	// Simple case: stack all splits horizontally
-	splitWidth := w / len(e.splits)
-	x := 0
-
-	for i, split := range e.splits {
-		split.x = x
-		split.y = 0
-		split.width = splitWidth
-		split.height = height
-
-		// Give remaining pixels to last split
-		if i == len(e.splits)-1 {
-			split.width = w - x
-		}
-
-		x += split.width
-	}
+	e.resizeSplits()
 }
 
 // nextSplit moves focus to the next split
@@ -601,14 +629,16 @@ func (e *Editor) loadSplitState() {
 
 	split := e.splits[e.currentSplit]
 
-	// Handle file tree split
-	if split.splitType == SplitFileTree {
-		e.focusTree = true
+	// Handle file tree and outline splits
+	if split.splitType == SplitFileTree || split.splitType == SplitOutline {
+		e.focusTree = split.splitType == SplitFileTree
+		e.focusOutline = split.splitType == SplitOutline
 		return
 	}
 
 	// Handle buffer split
 	e.focusTree = false
+	e.focusOutline = false
 
 	// Switch to split's buffer if different
 	if split.bufferIndex != e.currentBuffer && split.bufferIndex < len(e.buffers) {
@@ -661,6 +691,7 @@ func (e *Editor) loadBufferState(bv *BufferView) {
 		return
 	}
 	e.buffer = bv.buffer
+	e.trackUndoCursor()
 	e.filename = bv.filename
 	e.dirty = bv.dirty
 	e.cx = bv.cx
@@ -1,8 +1,13 @@
 package editor
 
 func (e *Editor) textObjectRange(prefix rune, unit rune) (start, end int, kind RegisterKind, ok bool) {
+	// Handle tree-sitter text objects: function, class, argument
+	if _, ok := syntaxObjectUnits[unit]; ok {
+		return e.syntaxObjectRange(prefix, unit)
+	}
+
 	pos := e.posFromCursor()
-	r := e.textRunes()
+	r := e.runeReader()
 
 	// Handle paired delimiter text objects: ", (, {, [
 	if unit == '"' || unit == '(' || unit == ')' || unit == '{' || unit == '}' || unit == '[' || unit == ']' {
@@ -23,25 +28,25 @@ func (e *Editor) textObjectRange(prefix rune, unit rune) (start, end int, kind R
 
 	// Find a unit near cursor: if cursor on non-unit, search right then left a bit
 	p := pos
-	if p >= len(r) {
-		p = len(r) - 1
+	if p >= r.n {
+		p = r.n - 1
 	}
 	if p < 0 {
 		return pos, pos, RegCharwise, false
 	}
 
-	if !isUnit(r[p]) {
+	if !isUnit(r.at(p)) {
 		// search right
 		q := p
-		for q < len(r) && !isUnit(r[q]) {
+		for q < r.n && !isUnit(r.at(q)) {
 			q++
 		}
-		if q < len(r) {
+		if q < r.n {
 			p = q
 		} else {
 			// search left
 			q = p
-			for q >= 0 && !isUnit(r[q]) {
+			for q >= 0 && !isUnit(r.at(q)) {
 				q--
 			}
 			if q >= 0 {
@@ -54,11 +59,11 @@ func (e *Editor) textObjectRange(prefix rune, unit rune) (start, end int, kind R
 
 	// expand to unit bounds
 	s := p
-	for s > 0 && isUnit(r[s-1]) {
+	for s > 0 && isUnit(r.at(s-1)) {
 		s--
 	}
 	ei := p
-	for ei+1 < len(r) && isUnit(r[ei+1]) {
+	for ei+1 < r.n && isUnit(r.at(ei+1)) {
 		ei++
 	}
 	selStart, selEnd := s, ei+1 // end exclusive
@@ -66,7 +71,7 @@ func (e *Editor) textObjectRange(prefix rune, unit rune) (start, end int, kind R
 	if prefix == 'a' {
 		// include surrounding whitespace: prefer trailing whitespace, else leading
 		t := selEnd
-		for t < len(r) && isSpace(r[t]) && r[t] != '\n' {
+		for t < r.n && isSpace(r.at(t)) && r.at(t) != '\n' {
 			t++
 		}
 		if t != selEnd {
@@ -74,7 +79,7 @@ func (e *Editor) textObjectRange(prefix rune, unit rune) (start, end int, kind R
 		} else {
 			// include leading spaces (not newline)
 			ls := selStart
-			for ls > 0 && isSpace(r[ls-1]) && r[ls-1] != '\n' {
+			for ls > 0 && isSpace(r.at(ls-1)) && r.at(ls-1) != '\n' {
 				ls--
 			}
 			selStart = ls
@@ -85,7 +90,7 @@ func (e *Editor) textObjectRange(prefix rune, unit rune) (start, end int, kind R
 }
 
 // textObjectPaired handles paired delimiters like quotes, parens, brackets, braces
-func (e *Editor) textObjectPaired(prefix rune, unit rune, pos int, r []rune) (start, end int, kind RegisterKind, ok bool) {
+func (e *Editor) textObjectPaired(prefix rune, unit rune, pos int, r *runeReader) (start, end int, kind RegisterKind, ok bool) {
 	// Normalize closing delimiters to opening ones
 	var openCh, closeCh rune
 	switch unit {
@@ -101,8 +106,8 @@ func (e *Editor) textObjectPaired(prefix rune, unit rune, pos int, r []rune) (st
 		return pos, pos, RegCharwise, false
 	}
 
-	if pos >= len(r) {
-		pos = len(r) - 1
+	if pos >= r.n {
+		pos = r.n - 1
 	}
 	if pos < 0 {
 		return 0, 0, RegCharwise, false
@@ -118,15 +123,15 @@ func (e *Editor) textObjectPaired(prefix rune, unit rune, pos int, r []rune) (st
 	searchFrom := pos
 
 	// If we're on a closing delimiter, start searching from before it
-	if pos < len(r) && r[pos] == closeCh && openCh != closeCh {
+	if pos < r.n && r.at(pos) == closeCh && openCh != closeCh {
 		searchFrom = pos - 1
 	}
 
 	for i := searchFrom; i >= 0; i-- {
-		if r[i] == closeCh && openCh != closeCh {
+		if r.at(i) == closeCh && openCh != closeCh {
 			// If we hit a closing delimiter going backward, increase depth
 			depth++
-		} else if r[i] == openCh {
+		} else if r.at(i) == openCh {
 			if depth == 0 {
 				// Found the matching opening delimiter
 				startPos = i
@@ -142,10 +147,10 @@ func (e *Editor) textObjectPaired(prefix rune, unit rune, pos int, r []rune) (st
 	// If we found an opening delimiter, search forward for its matching closing delimiter
 	if startPos != -1 {
 		depth = 0
-		for i := startPos + 1; i < len(r); i++ {
-			if r[i] == openCh && openCh != closeCh {
+		for i := startPos + 1; i < r.n; i++ {
+			if r.at(i) == openCh && openCh != closeCh {
 				depth++
-			} else if r[i] == closeCh {
+			} else if r.at(i) == closeCh {
 				if depth == 0 {
 					endPos = i
 					break
@@ -171,8 +176,8 @@ func (e *Editor) textObjectPaired(prefix rune, unit rune, pos int, r []rune) (st
 }
 
 // textObjectParagraph handles ip/ap (paragraph text objects)
-func (e *Editor) textObjectParagraph(prefix rune, pos int, r []rune) (start, end int, kind RegisterKind, ok bool) {
-	if len(r) == 0 {
+func (e *Editor) textObjectParagraph(prefix rune, pos int, r *runeReader) (start, end int, kind RegisterKind, ok bool) {
+	if r.n == 0 {
 		return pos, pos, RegCharwise, false
 	}
 
@@ -182,14 +187,14 @@ func (e *Editor) textObjectParagraph(prefix rune, pos int, r []rune) (start, end
 	for start > 0 {
 		// Check if we hit a blank line
 		lineStart := start
-		for lineStart > 0 && r[lineStart-1] != '\n' {
+		for lineStart > 0 && r.at(lineStart-1) != '\n' {
 			lineStart--
 		}
 
 		// Check if this line is blank (only whitespace)
 		isBlank := true
-		for i := lineStart; i < len(r) && r[i] != '\n'; i++ {
-			if !isSpace(r[i]) {
+		for i := lineStart; i < r.n && r.at(i) != '\n'; i++ {
+			if !isSpace(r.at(i)) {
 				isBlank = false
 				break
 			}
@@ -199,7 +204,7 @@ func (e *Editor) textObjectParagraph(prefix rune, pos int, r []rune) (start, end
 			// Found blank line before cursor, paragraph starts after it
 			start = lineStart
 			// Skip past the newline
-			if start < len(r) && r[start] == '\n' {
+			if start < r.n && r.at(start) == '\n' {
 				start++
 			}
 			break
@@ -215,21 +220,21 @@ func (e *Editor) textObjectParagraph(prefix rune, pos int, r []rune) (start, end
 
 	// Find the end of the paragraph (first blank line going forward)
 	end = pos
-	for end < len(r) {
+	for end < r.n {
 		// Find end of current line
 		lineEnd := end
-		for lineEnd < len(r) && r[lineEnd] != '\n' {
+		for lineEnd < r.n && r.at(lineEnd) != '\n' {
 			lineEnd++
 		}
 
 		// Check if this line is blank
 		isBlank := true
 		lineStart := end
-		for lineStart > 0 && r[lineStart-1] != '\n' {
+		for lineStart > 0 && r.at(lineStart-1) != '\n' {
 			lineStart--
 		}
 		for i := lineStart; i < lineEnd; i++ {
-			if !isSpace(r[i]) {
+			if !isSpace(r.at(i)) {
 				isBlank = false
 				break
 			}
@@ -242,8 +247,8 @@ func (e *Editor) textObjectParagraph(prefix rune, pos int, r []rune) (start, end
 		}
 
 		// Move to next line
-		if lineEnd >= len(r) {
-			end = len(r)
+		if lineEnd >= r.n {
+			end = r.n
 			break
 		}
 		end = lineEnd + 1
@@ -252,16 +257,16 @@ func (e *Editor) textObjectParagraph(prefix rune, pos int, r []rune) (start, end
 	// For 'a' (around), include surrounding blank lines
 	if prefix == 'a' {
 		// Include trailing blank lines
-		for end < len(r) {
+		for end < r.n {
 			lineEnd := end
-			for lineEnd < len(r) && r[lineEnd] != '\n' {
+			for lineEnd < r.n && r.at(lineEnd) != '\n' {
 				lineEnd++
 			}
 
 			// Check if blank
 			isBlank := true
 			for i := end; i < lineEnd; i++ {
-				if !isSpace(r[i]) {
+				if !isSpace(r.at(i)) {
 					isBlank = false
 					break
 				}
@@ -272,10 +277,10 @@ func (e *Editor) textObjectParagraph(prefix rune, pos int, r []rune) (start, end
 			}
 
 			// Include this blank line
-			if lineEnd < len(r) {
+			if lineEnd < r.n {
 				end = lineEnd + 1
 			} else {
-				end = len(r)
+				end = r.n
 				break
 			}
 		}
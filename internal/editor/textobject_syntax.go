@@ -0,0 +1,264 @@
+package editor
+
+import (
+	"sort"
+	"strings"
+
+	sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// syntaxObjectUnits maps text object and motion keys to textobjects.scm
+// capture groups.
+var syntaxObjectUnits = map[rune]string{
+	'f': "function",
+	'c': "class",
+	'a': "parameter",
+}
+
+// TextObjects returns the ranges captured as name (e.g. "function.outer") by
+// the textobjects.scm query, including those of injected languages, ordered
+// by start and then outermost first.
+func (p *TreeSitterParser) TextObjects(name string) []sitter.Range {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tree == nil {
+		return nil
+	}
+	if !p.textobjectsLoaded {
+		p.textobjectsLoaded = true
+		p.textobjects, _ = loadQuery(p.language, p.lang, "textobjects")
+	}
+
+	var ranges []sitter.Range
+	if p.textobjects != nil {
+		cursor := sitter.NewQueryCursor()
+		defer cursor.Close()
+
+		names := p.textobjects.CaptureNames()
+		captures := cursor.Captures(p.textobjects.Query, p.tree.RootNode(), nil)
+		for {
+			match, idx := p.textobjects.nextCapture(&captures, p.src)
+			if match == nil {
+				break
+			}
+			c := match.Captures[idx]
+			if names[c.Index] == name {
+				ranges = append(ranges, c.Node.Range())
+			}
+		}
+	}
+	if !p.layersStale {
+		for _, l := range p.layers {
+			ranges = append(ranges, l.parser.TextObjects(name)...)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].StartByte != ranges[j].StartByte {
+			return ranges[i].StartByte < ranges[j].StartByte
+		}
+		return ranges[i].EndByte > ranges[j].EndByte
+	})
+	return ranges
+}
+
+// syntaxObjects returns the rune ranges [start, end) of the current buffer's
+// text objects of a group ("function", "class" or "parameter"). Inner ranges
+// of bodies wrapped in braces are shrunk to what is between the braces.
+func (e *Editor) syntaxObjects(group string, inner bool) [][2]int {
+	parser := e.syntaxParser()
+	if parser == nil {
+		return nil
+	}
+	name := group + ".outer"
+	if inner {
+		name = group + ".inner"
+	}
+	var objs [][2]int
+	for _, r := range parser.TextObjects(name) {
+		start := e.buffer.PosFromByte(int(r.StartByte))
+		end := e.buffer.PosFromByte(int(r.EndByte))
+		if inner && group != "parameter" {
+			start, end = e.innerBody(start, end)
+		}
+		objs = append(objs, [2]int{start, end})
+	}
+	return objs
+}
+
+// syntaxParser returns the current buffer's parser with a tree that matches
+// the buffer, or nil when the buffer has none.
+func (e *Editor) syntaxParser() *TreeSitterParser {
+	bv := e.buf()
+	if bv == nil || bv.parser == nil {
+		return nil
+	}
+	if bv.parser.Pending() {
+		// operators do not reparse after each change
+		e.reparseBuffer()
+	}
+	return bv.parser
+}
+
+// innerBody shrinks a body such as "{ ... }" to the text between its braces,
+// without the whitespace around it.
+func (e *Editor) innerBody(start, end int) (int, int) {
+	text, _ := e.buffer.Slice(start, end)
+	open := strings.IndexByte(text, '{')
+	if open < 0 || !strings.HasSuffix(text, "}") {
+		return start, end
+	}
+	body := []rune(text[open+1 : len(text)-1])
+	s, t := 0, len(body)
+	for s < t && isSpace(body[s]) {
+		s++
+	}
+	for t > s && isSpace(body[t-1]) {
+		t--
+	}
+	base := start + len([]rune(text[:open+1]))
+	return base + s, base + t
+}
+
+// syntaxObjectRange returns the tree-sitter text object unit (f, c or a)
+// around the cursor, or the next one after it when the cursor is in none.
+// Outer functions and classes that fill their lines are taken linewise.
+func (e *Editor) syntaxObjectRange(prefix rune, unit rune) (start, end int, kind RegisterKind, ok bool) {
+	group := syntaxObjectUnits[unit]
+	pos := e.posFromCursor()
+	if unit == 'a' && prefix == 'a' {
+		// aa is the argument plus its separator
+		start, end, ok = e.pickSyntaxObject(e.syntaxObjects(group, true), pos)
+		if !ok {
+			return pos, pos, RegCharwise, false
+		}
+		start, end = e.withSeparator(start, end)
+		return start, end, RegCharwise, true
+	}
+
+	start, end, ok = e.pickSyntaxObject(e.syntaxObjects(group, prefix == 'i'), pos)
+	if !ok {
+		return pos, pos, RegCharwise, false
+	}
+	if prefix == 'a' {
+		if s, t, whole := e.wholeLines(start, end); whole {
+			return s, t, RegLinewise, true
+		}
+	}
+	return start, end, RegCharwise, true
+}
+
+// pickSyntaxObject returns the innermost object containing pos, or the first
+// one starting after it.
+func (e *Editor) pickSyntaxObject(objs [][2]int, pos int) (start, end int, ok bool) {
+	best := -1
+	for i, o := range objs {
+		if o[0] <= pos && pos < o[1] && (best < 0 || o[1]-o[0] <= objs[best][1]-objs[best][0]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		for i, o := range objs {
+			if o[0] > pos {
+				best = i
+				break
+			}
+		}
+	}
+	if best < 0 {
+		return 0, 0, false
+	}
+	return objs[best][0], objs[best][1], true
+}
+
+// withSeparator extends an argument to the comma after it and the space
+// following that, or to the comma before it when it is the last one.
+func (e *Editor) withSeparator(start, end int) (int, int) {
+	r := e.runeReader()
+	t := end
+	for t < r.n && isSpace(r.at(t)) && r.at(t) != '\n' {
+		t++
+	}
+	if t < r.n && r.at(t) == ',' {
+		t++
+		for t < r.n && isSpace(r.at(t)) && r.at(t) != '\n' {
+			t++
+		}
+		return start, t
+	}
+	s := start
+	for s > 0 && isSpace(r.at(s-1)) {
+		s--
+	}
+	if s > 0 && r.at(s-1) == ',' {
+		return s - 1, end
+	}
+	return start, end
+}
+
+// wholeLines reports whether [start, end) is alone on its lines, and if so
+// returns the range of those lines including the final newline.
+func (e *Editor) wholeLines(start, end int) (int, int, bool) {
+	startLine := e.buffer.LineAt(start)
+	endLine := e.buffer.LineAt(max(start, end-1))
+	head, _ := e.buffer.Slice(e.buffer.LineStart(startLine), start)
+	lineEnd := e.buffer.LineStart(endLine) + e.lineLen(endLine)
+	tail, _ := e.buffer.Slice(end, lineEnd)
+	if strings.TrimSpace(head) != "" || strings.TrimSpace(tail) != "" {
+		return start, end, false
+	}
+	if endLine+1 < e.lineCount() {
+		return e.buffer.LineStart(startLine), e.buffer.LineStart(endLine + 1), true
+	}
+	return e.buffer.LineStart(startLine), e.buffer.Len(), true
+}
+
+// gotoSyntaxObject implements ]f, [f, ]c and [c: it moves to the start of
+// the count'th next or previous function or class.
+func (e *Editor) gotoSyntaxObject(unit rune, forward bool, count int) {
+	group, ok := syntaxObjectUnits[unit]
+	if !ok || unit == 'a' {
+		e.statusMsg = "unknown motion"
+		return
+	}
+	objs := e.syntaxObjects(group, false)
+	if len(objs) == 0 {
+		e.statusMsg = "no " + group + "s"
+		return
+	}
+	pos := e.posFromCursor()
+	target := -1
+	for n := 0; n < max(1, count); n++ {
+		next := -1
+		if forward {
+			for _, o := range objs {
+				if o[0] > pos {
+					next = o[0]
+					break
+				}
+			}
+		} else {
+			for i := len(objs) - 1; i >= 0; i-- {
+				if objs[i][0] < pos {
+					next = objs[i][0]
+					break
+				}
+			}
+		}
+		if next < 0 {
+			break
+		}
+		pos, target = next, next
+	}
+	if target < 0 {
+		e.statusMsg = "no more " + group + "s"
+		return
+	}
+	e.setCursorFromPos(target)
+	e.wantX = e.cx
+}
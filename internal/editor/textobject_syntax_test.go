@@ -0,0 +1,130 @@
+package editor
+
+import "testing"
+
+const textObjectGoSource = `package main
+
+type Point struct {
+	X, Y int
+}
+
+func add(a, b int) int {
+	return a + b
+}
+
+func main() {
+	println(add(1, 2))
+}
+`
+
+func TestDeleteAroundFunction(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", textObjectGoSource), nil)
+	e.cy, e.cx = 7, 2 // inside add
+	pressKeys(e, "daf")
+
+	want := "package main\n\ntype Point struct {\n\tX, Y int\n}\n\n\nfunc main() {\n\tprintln(add(1, 2))\n}\n"
+	if got := e.buffer.String(); got != want {
+		t.Fatalf("daf:\ngot  %q\nwant %q", got, want)
+	}
+	if e.regs.unnamed.kind != RegLinewise {
+		t.Errorf("daf should delete linewise")
+	}
+}
+
+func TestChangeInsideFunction(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", textObjectGoSource), nil)
+	e.cy, e.cx = 6, 0 // on "func add"
+	pressKeys(e, "cifreturn 0\x1b")
+
+	want := "func add(a, b int) int {\n\treturn 0\n}"
+	if got := e.buffer.String(); !contains(got, want) {
+		t.Fatalf("cif: got %q", got)
+	}
+}
+
+func TestYankClassAndArguments(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", textObjectGoSource), nil)
+	e.cy, e.cx = 3, 1
+	pressKeys(e, "yic")
+	if got := e.regs.unnamed.text; got != "X, Y int" {
+		t.Errorf("yic: got %q", got)
+	}
+
+	e.cy, e.cx = 11, 13 // on "1" in add(1, 2)
+	pressKeys(e, "yia")
+	if got := e.regs.unnamed.text; got != "1" {
+		t.Errorf("yia: got %q", got)
+	}
+	// the last argument takes the comma before it
+	e.cy, e.cx = 11, 16 // on "2"
+	pressKeys(e, "daa")
+	if got := e.getLine(11); got != "\tprintln(add(1))" {
+		t.Errorf("daa on last argument: got %q", got)
+	}
+	pressKeys(e, "u")
+	e.cy, e.cx = 11, 13
+	pressKeys(e, "daa")
+	if got := e.getLine(11); got != "\tprintln(add(2))" {
+		t.Errorf("daa: got %q", got)
+	}
+}
+
+func TestVisualSelectFunction(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", textObjectGoSource), nil)
+	e.cy, e.cx = 11, 1
+	pressKeys(e, "vafd")
+
+	want := "package main\n\ntype Point struct {\n\tX, Y int\n}\n\nfunc add(a, b int) int {\n\treturn a + b\n}\n\n"
+	if got := e.buffer.String(); got != want {
+		t.Fatalf("vafd:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestSyntaxMotions(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", textObjectGoSource), nil)
+	e.cy, e.cx = 0, 0
+
+	pressKeys(e, "]f")
+	if e.cy != 6 || e.cx != 0 {
+		t.Fatalf("]f: at %d:%d, want 6:0", e.cy, e.cx)
+	}
+	pressKeys(e, "]f")
+	if e.cy != 10 {
+		t.Fatalf("second ]f: at line %d, want 10", e.cy)
+	}
+	pressKeys(e, "[c")
+	if e.cy != 2 {
+		t.Fatalf("[c: at line %d, want 2", e.cy)
+	}
+	pressKeys(e, "2]f")
+	if e.cy != 10 {
+		t.Fatalf("2]f: at line %d, want 10", e.cy)
+	}
+}
+
+func TestSyntaxTextObjectsOtherLanguages(t *testing.T) {
+	cases := []struct {
+		name, src  string
+		line, col  int
+		keys, want string
+	}{
+		{"a.py", "def f(x, y):\n    return x\n", 0, 6, "yia", "x"},
+		{"a.ts", "class A {\n  m(a: number) { return a; }\n}\n", 1, 4, "yia", "a: number"},
+		{"a.rs", "fn f() {\n    g();\n}\n", 1, 4, "yif", "g();"},
+		{"a.c", "int f(int a) {\n    return a;\n}\n", 0, 0, "yif", "return a;"},
+		{"a.yaml", "db:\n  host: x\n  port: 1\n", 1, 8, "yic", "x"},
+		{"a.yaml", "ports:\n  - 80\n  - 443\n", 2, 4, "yia", "443"},
+		{"a.md", "# A\n\n```go\nx := 1\n```\n", 3, 0, "yif", "x := 1\n"},
+		{"a.md", "- one\n- two\n", 1, 3, "yia", "two"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := newFileTestEditor(t, writeTestFile(t, c.name, c.src), nil)
+			e.cy, e.cx = c.line, c.col
+			pressKeys(e, c.keys)
+			if got := e.regs.unnamed.text; got != c.want {
+				t.Errorf("%s: got %q, want %q", c.keys, got, c.want)
+			}
+		})
+	}
+}
@@ -1,50 +1,132 @@
 package editor
 
 import (
+	"sort"
+	"strings"
 	"sync"
 
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
 	sitter "github.com/tree-sitter/go-tree-sitter"
-	tree_sitter_go "github.com/tree-sitter/tree-sitter-go/bindings/go"
-	tree_sitter_javascript "github.com/tree-sitter/tree-sitter-javascript/bindings/go"
-	tree_sitter_python "github.com/tree-sitter/tree-sitter-python/bindings/go"
 )
 
-// TreeSitterParser manages tree-sitter parsing for a buffer
+// TreeSitterParser manages tree-sitter parsing for a buffer.
+//
+// Edits are fed in as they happen (see Edit) and applied to the current tree,
+// so the next parse can reuse every subtree the edits did not touch. The
+// parser also caches the highlights of the last requested range and only
+// recollects the parts that edits or the reparse changed.
+//
+// Highlights come from the grammar's highlights.scm query (see loadQuery).
+// Regions its injections.scm query marks as another language are parsed by
+// child parsers, whose highlights are merged into GetHighlights.
 type TreeSitterParser struct {
 	parser   *sitter.Parser
 	tree     *sitter.Tree
 	language *sitter.Language
 	mu       sync.RWMutex
+
+	lang       string // query directory, e.g. "go"
+	highlights *query // nil when the grammar has no highlights query
+	injections *query // nil when the grammar has no injections query
+	queryErr   error  // why the user's query was not used
+
+	// textobjects.scm, compiled on first use by TextObjects
+	textobjects       *query
+	textobjectsLoaded bool
+
+	// folds.scm, compiled on first use by FoldRanges
+	folds       *query
+	foldsLoaded bool
+
+	layers      []*injectionLayer // embedded regions as of the last parse
+	layersStale bool              // edited since the layers were parsed
+	layer       int               // injection depth, 0 for the buffer's own language
+
+	// reads the text of tree, for query predicates such as #match? and
+	// injection layers
+	src textSource
+
+	// edits applied to tree since the last parse, in order
+	edits []sitter.InputEdit
+
+	// what the last parse changed, for incremental consumers (folds)
+	lastEdits   []sitter.InputEdit
+	changed     []sitter.Range
+	incremental bool
+
+	// highlights overlapping [hlStart, hlEnd] as of the current tree
+	hl      []Highlight
+	hlStart int
+	hlEnd   int
+	hlValid bool
+	hlDirty []span // byte ranges whose highlights must be recollected
+}
+
+// span is an inclusive range of bytes or lines.
+type span struct {
+	start, end int
+}
+
+func (s span) overlaps(start, end int) bool {
+	return s.end >= start && s.start <= end
 }
 
+// parseChunk is how many bytes a parse reads at a time.
+const parseChunk = 4096
+
 // NewTreeSitterParser creates a new parser for the given language
 func NewTreeSitterParser(langName string) (*TreeSitterParser, error) {
-	parser := sitter.NewParser()
-
-	var language *sitter.Language
-	switch langName {
-	case "go":
-		language = sitter.NewLanguage(tree_sitter_go.Language())
-	case "python":
-		language = sitter.NewLanguage(tree_sitter_python.Language())
-	case "javascript", "typescript", "jsx", "tsx":
-		language = sitter.NewLanguage(tree_sitter_javascript.Language())
-	default:
+	g, language, ok := lookupGrammar(langName)
+	if !ok {
 		// Return nil parser for unsupported languages
 		return nil, nil
 	}
 
+	parser := sitter.NewParser()
 	if err := parser.SetLanguage(language); err != nil {
+		parser.Close()
 		return nil, err
 	}
 
+	highlights, queryErr := loadQuery(language, g.name, "highlights")
+	if highlights == nil && queryErr != nil {
+		parser.Close()
+		return nil, queryErr
+	}
+	injections, err := loadQuery(language, g.name, "injections")
+	if injections == nil && err != nil {
+		parser.Close()
+		if highlights != nil {
+			highlights.Close()
+		}
+		return nil, err
+	}
+	if queryErr == nil {
+		queryErr = err
+	}
+
 	return &TreeSitterParser{
-		parser:   parser,
-		language: language,
+		parser:     parser,
+		language:   language,
+		lang:       g.name,
+		highlights: highlights,
+		injections: injections,
+		queryErr:   queryErr,
 	}, nil
 }
 
-// Parse parses the given source code
+// QueryError reports a user query that failed to compile. The built-in query
+// is used in its place.
+func (p *TreeSitterParser) QueryError() error {
+	if p == nil {
+		return nil
+	}
+	return p.queryErr
+}
+
+// Parse parses the given source code. Without edits recorded since the last
+// parse the old tree cannot be trusted to match source, so it parses from
+// scratch.
 func (p *TreeSitterParser) Parse(source string) error {
 	if p == nil || p.parser == nil {
 		return nil
@@ -53,14 +135,136 @@ func (p *TreeSitterParser) Parse(source string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	sourceBytes := []byte(source)
+	text := []byte(source)
+	p.src = func(start, end int) []byte {
+		return text[min(start, len(text)):min(end, len(text))]
+	}
+	p.parse()
+	return nil
+}
+
+// ParseBuffer parses buf, reading it a chunk at a time. Query predicates
+// read node text from buf too, so it must not change between the parse and
+// the next Edit.
+func (p *TreeSitterParser) ParseBuffer(buf *buffer.Buffer) error {
+	if p == nil || p.parser == nil {
+		return nil
+	}
 
-	tree := p.parser.Parse(sourceBytes, p.tree)
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	p.tree = tree
+	p.src = buf.ByteSlice
+	p.parse()
 	return nil
 }
 
+// parse parses the text p.src reads, reusing the old tree when edits were
+// recorded. Callers hold p.mu.
+func (p *TreeSitterParser) parse() {
+	read := func(i int, _ sitter.Point) []byte {
+		return p.src(i, i+parseChunk)
+	}
+
+	old := p.tree
+	p.incremental = old != nil && len(p.edits) > 0
+	if !p.incremental {
+		old = nil
+	}
+
+	tree := p.parser.ParseWithOptions(read, old, nil)
+
+	p.changed = nil
+	if p.incremental {
+		p.changed = old.ChangedRanges(tree)
+		for _, r := range p.changed {
+			p.hlDirty = append(p.hlDirty, span{int(r.StartByte), int(r.EndByte)})
+		}
+	} else {
+		p.hlValid = false
+	}
+	if p.tree != nil {
+		p.tree.Close()
+	}
+	p.tree = tree
+	p.lastEdits = p.edits
+	p.edits = nil
+	p.updateInjections()
+}
+
+// Edit applies a change of the source to the current tree. It must
+// be called for every change between parses, in order.
+func (p *TreeSitterParser) Edit(ed buffer.Edit) {
+	if p == nil {
+		return
+	}
+
+	p.applyEdit(inputEdit(ed))
+}
+
+// applyEdit applies edit to the tree and forwards it to the injection
+// layers, whose ranges and trees move with it so they can be reparsed
+// incrementally.
+func (p *TreeSitterParser) applyEdit(edit sitter.InputEdit) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tree == nil {
+		return
+	}
+	p.tree.Edit(&edit)
+	p.layersStale = true
+	p.edits = append(p.edits, edit)
+	p.shiftHighlights(edit)
+	for _, l := range p.layers {
+		for i, r := range l.ranges {
+			l.ranges[i] = shiftRange(r, edit)
+		}
+		l.parser.applyEdit(edit)
+	}
+}
+
+// Pending reports whether edits were made since the last parse.
+func (p *TreeSitterParser) Pending() bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.edits) > 0
+}
+
+// LastParse reports the edits folded into the last parse and the byte ranges
+// whose syntax changed. incremental is false when the last parse started
+// from scratch, in which case everything must be considered changed.
+func (p *TreeSitterParser) LastParse() (edits []sitter.InputEdit, changed []sitter.Range, incremental bool) {
+	if p == nil {
+		return nil, nil, false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.lastEdits, p.changed, p.incremental
+}
+
+// inputEdit converts a buffer change into a tree-sitter edit.
+func inputEdit(ed buffer.Edit) sitter.InputEdit {
+	point := func(pt buffer.Point) sitter.Point {
+		return sitter.Point{Row: uint(pt.Line), Column: uint(pt.ByteCol)}
+	}
+	return sitter.InputEdit{
+		StartByte:      uint(ed.Start.Byte),
+		OldEndByte:     uint(ed.OldEnd.Byte),
+		NewEndByte:     uint(ed.NewEnd.Byte),
+		StartPosition:  point(ed.Start),
+		OldEndPosition: point(ed.OldEnd),
+		NewEndPosition: point(ed.NewEnd),
+	}
+}
+
 // GetTree returns the current parse tree
 func (p *TreeSitterParser) GetTree() *sitter.Tree {
 	if p == nil {
@@ -79,24 +283,139 @@ func (p *TreeSitterParser) GetHighlights(startByte, endByte int) []Highlight {
 		return nil
 	}
 
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
 	root := p.tree.RootNode()
 	if root == nil {
 		return nil
 	}
 
+	if !p.hlValid || startByte < p.hlStart || endByte > p.hlEnd {
+		// nothing reusable: collect the whole range
+		p.hl = p.collectHighlights(root, startByte, endByte, p.hl[:0])
+		p.hl = sortHighlights(p.hl)
+		p.hlStart, p.hlEnd = startByte, endByte
+		p.hlValid = true
+		p.hlDirty = nil
+	} else if len(p.hlDirty) > 0 {
+		p.refreshHighlights(root)
+	}
+
 	var highlights []Highlight
-	p.collectHighlights(root, startByte, endByte, &highlights)
+	for _, hl := range p.hl {
+		if hl.EndByte >= startByte && hl.StartByte <= endByte {
+			highlights = append(highlights, hl)
+		}
+	}
+	if injected := p.injectedHighlights(startByte, endByte); len(injected) > 0 {
+		highlights = sortHighlights(append(highlights, injected...))
+	}
 	return highlights
 }
 
+// refreshHighlights recollects the cached highlights inside dirty ranges.
+func (p *TreeSitterParser) refreshHighlights(root *sitter.Node) {
+	for _, d := range p.hlDirty {
+		if !d.overlaps(p.hlStart, p.hlEnd) {
+			continue
+		}
+		d.start, d.end = max(d.start, p.hlStart), min(d.end, p.hlEnd)
+		kept := p.hl[:0]
+		for _, hl := range p.hl {
+			if !d.overlaps(hl.StartByte, hl.EndByte) {
+				kept = append(kept, hl)
+			}
+		}
+		p.hl = p.collectHighlights(root, d.start, d.end, kept)
+	}
+	p.hl = sortHighlights(p.hl)
+	p.hlDirty = nil
+}
+
+// shiftHighlights moves the cached highlights and dirty ranges past an edit
+// and marks the edited range dirty. Callers hold p.mu.
+func (p *TreeSitterParser) shiftHighlights(edit sitter.InputEdit) {
+	if !p.hlValid {
+		return
+	}
+	start, oldEnd, newEnd := int(edit.StartByte), int(edit.OldEndByte), int(edit.NewEndByte)
+	delta := newEnd - oldEnd
+
+	// highlights touching the edit are dropped and their whole extent is
+	// recollected, even where it reaches outside the edit
+	dirty := span{start, newEnd}
+	kept := p.hl[:0]
+	for _, hl := range p.hl {
+		switch {
+		case hl.EndByte < start:
+			kept = append(kept, hl)
+		case hl.StartByte > oldEnd:
+			hl.StartByte += delta
+			hl.EndByte += delta
+			kept = append(kept, hl)
+		default:
+			dirty.start = min(dirty.start, hl.StartByte)
+			dirty.end = max(dirty.end, hl.EndByte+delta)
+		}
+	}
+	p.hl = kept
+
+	shift := func(s span) span {
+		switch {
+		case s.end < start:
+			return s
+		case s.start > oldEnd:
+			return span{s.start + delta, s.end + delta}
+		default:
+			return span{min(s.start, start), max(s.end+delta, newEnd)}
+		}
+	}
+	cache := shift(span{p.hlStart, p.hlEnd})
+	p.hlStart, p.hlEnd = cache.start, cache.end
+	for i := range p.hlDirty {
+		p.hlDirty[i] = shift(p.hlDirty[i])
+	}
+	p.hlDirty = append(p.hlDirty, dirty)
+}
+
+// sortHighlights orders highlights outer spans first, so later entries are
+// innermost. Of identical spans only one is kept: the most deeply injected
+// layer's, then the last pattern's.
+func sortHighlights(hls []Highlight) []Highlight {
+	sort.SliceStable(hls, func(i, j int) bool {
+		a, b := hls[i], hls[j]
+		if a.StartByte != b.StartByte {
+			return a.StartByte < b.StartByte
+		}
+		if a.EndByte != b.EndByte {
+			return a.EndByte > b.EndByte
+		}
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		return a.pattern < b.pattern
+	})
+	out := hls[:0]
+	for _, hl := range hls {
+		if n := len(out); n > 0 && out[n-1].StartByte == hl.StartByte && out[n-1].EndByte == hl.EndByte {
+			out[n-1] = hl
+			continue
+		}
+		out = append(out, hl)
+	}
+	return out
+}
+
 // Highlight represents a syntax highlight span
 type Highlight struct {
 	StartByte int
 	EndByte   int
 	Type      HighlightType
+	Capture   string // query capture name, e.g. "function.method"
+
+	pattern uint // index of the query pattern, later patterns take precedence
+	layer   int  // injection depth of the parser that produced it
 }
 
 // HighlightType represents different syntax element types
@@ -116,89 +435,69 @@ const (
 	HighlightProperty
 )
 
-// collectHighlights recursively collects highlights from the tree
-func (p *TreeSitterParser) collectHighlights(node *sitter.Node, startByte, endByte int, highlights *[]Highlight) {
-	if node == nil {
-		return
-	}
+// captureTypes maps the top-level group of a capture name to a HighlightType.
+var captureTypes = map[string]HighlightType{
+	"keyword":     HighlightKeyword,
+	"function":    HighlightFunction,
+	"constructor": HighlightType_,
+	"type":        HighlightType_,
+	"string":      HighlightString,
+	"escape":      HighlightConstant,
+	"number":      HighlightNumber,
+	"comment":     HighlightComment,
+	"variable":    HighlightVariable,
+	"operator":    HighlightOperator,
+	"constant":    HighlightConstant,
+	"property":    HighlightProperty,
+	"attribute":   HighlightProperty,
+	"tag":         HighlightKeyword,
+}
 
-	nodeStart := int(node.StartByte())
-	nodeEnd := int(node.EndByte())
+// highlightTypeOf returns the HighlightType of a capture name.
+func highlightTypeOf(capture string) HighlightType {
+	group, _, _ := strings.Cut(capture, ".")
+	return captureTypes[group]
+}
 
-	// Skip nodes outside the range
-	if nodeEnd < startByte || nodeStart > endByte {
-		return
+// collectHighlights appends the highlights query captures overlapping
+// [startByte, endByte] to hls. Captures whose name starts with "_" are
+// helpers for predicates and are skipped.
+func (p *TreeSitterParser) collectHighlights(root *sitter.Node, startByte, endByte int, hls []Highlight) []Highlight {
+	if p.highlights == nil {
+		return hls
 	}
 
-	// Get highlight type for this node
-	nodeType := node.GrammarName()
-	highlightType := p.getHighlightType(nodeType)
-
-	if highlightType != HighlightNone {
-		*highlights = append(*highlights, Highlight{
-			StartByte: nodeStart,
-			EndByte:   nodeEnd,
-			Type:      highlightType,
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	// the cursor's range is half-open and skips nodes ending at its start
+	cursor.SetByteRange(uint(max(0, startByte-1)), uint(endByte+1))
+
+	names := p.highlights.CaptureNames()
+	captures := cursor.Captures(p.highlights.Query, root, nil)
+	for {
+		match, idx := p.highlights.nextCapture(&captures, p.src)
+		if match == nil {
+			break
+		}
+		c := match.Captures[idx]
+		name := names[c.Index]
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		start, end := int(c.Node.StartByte()), int(c.Node.EndByte())
+		if end < startByte || start > endByte {
+			continue
+		}
+		hls = append(hls, Highlight{
+			StartByte: start,
+			EndByte:   end,
+			Type:      highlightTypeOf(name),
+			Capture:   name,
+			pattern:   match.PatternIndex,
+			layer:     p.layer,
 		})
 	}
-
-	// Process children
-	childCount := node.ChildCount()
-	for i := uint(0); i < childCount; i++ {
-		child := node.Child(i)
-		p.collectHighlights(child, startByte, endByte, highlights)
-	}
-}
-
-// getHighlightType maps tree-sitter node types to highlight types
-func (p *TreeSitterParser) getHighlightType(nodeType string) HighlightType {
-	// Keywords
-	keywords := map[string]bool{
-		"func": true, "package": true, "import": true, "var": true,
-		"const": true, "type": true, "struct": true, "interface": true,
-		"if": true, "else": true, "for": true, "range": true,
-		"return": true, "break": true, "continue": true, "switch": true,
-		"case": true, "default": true, "go": true, "defer": true,
-		"select": true, "chan": true, "map": true,
-		// Python
-		"def": true, "class": true, "lambda": true, "pass": true,
-		"with": true, "as": true, "yield": true, "async": true, "await": true,
-		"raise": true, "raise_from": true, "try": true, "except": true, "finally": true,
-		"while": true, "in": true, "is": true, "not": true, "and": true, "or": true,
-		// JavaScript
-		"function": true, "let": true, "const_decl": true, "new": true,
-		"this": true, "super": true, "extends": true, "implements": true,
-		"typeof": true, "instanceof": true, "delete": true,
-		"export": true, "import_statement": true, "from_import": true, "default_export": true,
-	}
-
-	if keywords[nodeType] {
-		return HighlightKeyword
-	}
-
-	// Node type patterns
-	switch nodeType {
-	case "function_declaration", "function_definition", "method_declaration":
-		return HighlightFunction
-	case "type_identifier", "type_spec", "primitive_type":
-		return HighlightType_
-	case "string_literal", "interpreted_string_literal", "raw_string_literal", "string":
-		return HighlightString
-	case "int_literal", "float_literal", "number":
-		return HighlightNumber
-	case "comment", "line_comment", "block_comment":
-		return HighlightComment
-	case "identifier":
-		return HighlightVariable
-	case "const_spec", "const_declaration":
-		return HighlightConstant
-	case "field_identifier", "property_identifier":
-		return HighlightProperty
-	case "+", "-", "*", "/", "=", "==", "!=", "<", ">", "<=", ">=":
-		return HighlightOperator
-	}
-
-	return HighlightNone
+	return hls
 }
 
 // Close releases resources
@@ -219,4 +518,27 @@ func (p *TreeSitterParser) Close() {
 		p.parser.Close()
 		p.parser = nil
 	}
+
+	if p.highlights != nil {
+		p.highlights.Close()
+		p.highlights = nil
+	}
+
+	if p.injections != nil {
+		p.injections.Close()
+		p.injections = nil
+	}
+
+	if p.textobjects != nil {
+		p.textobjects.Close()
+		p.textobjects = nil
+	}
+
+	if p.folds != nil {
+		p.folds.Close()
+		p.folds = nil
+	}
+
+	closeLayers(p.layers)
+	p.layers = nil
 }
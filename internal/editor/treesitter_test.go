@@ -0,0 +1,146 @@
+package editor
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
+)
+
+const incrementalGoSource = `package main
+
+import "fmt"
+
+// Point is a point.
+type Point struct {
+	X, Y int
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("(%d, %d)", p.X, p.Y)
+}
+
+func main() {
+	for i := 0; i < 3; i++ {
+		if i%2 == 0 {
+			fmt.Println(Point{i, i})
+		}
+	}
+}
+`
+
+// randomGoEdit makes a small edit biased towards syntax that changes structure.
+func randomGoEdit(rng *rand.Rand, b *buffer.Buffer) {
+	snippets := []string{"x", "{", "}", "\n", "\"", "// c\n", "func f() {\n}\n", "é", "(", ")"}
+	n := b.Len()
+	if n > 0 && rng.Intn(3) == 0 {
+		start := rng.Intn(n)
+		_ = b.Delete(start, start+1+rng.Intn(min(4, n-start)))
+		return
+	}
+	_ = b.Insert(rng.Intn(n+1), snippets[rng.Intn(len(snippets))])
+}
+
+func TestTreeSitterIncrementalMatchesFullParse(t *testing.T) {
+	b := buffer.NewFromString(incrementalGoSource)
+	p, err := NewTreeSitterParser("go")
+	if err != nil || p == nil {
+		t.Fatalf("go parser: %v", err)
+	}
+	defer p.Close()
+	if err := p.ParseBuffer(b); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	b.Subscribe(func(ed buffer.Edit) { p.Edit(ed) })
+
+	rng := rand.New(rand.NewSource(7))
+	for step := 0; step < 150; step++ {
+		// prime the highlight cache on a window, then edit
+		lo := b.ByteOffset(b.LineStart(2))
+		hi := b.ByteOffset(b.LineStart(16))
+		p.GetHighlights(lo, hi)
+
+		for i := rng.Intn(3); i >= 0; i-- {
+			randomGoEdit(rng, b)
+		}
+		if err := p.ParseBuffer(b); err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if _, _, incremental := p.LastParse(); !incremental {
+			t.Fatalf("step %d: expected an incremental parse", step)
+		}
+
+		fresh, _ := NewTreeSitterParser("go")
+		fresh.Parse(b.String())
+
+		if got, want := p.GetTree().RootNode().ToSexp(), fresh.GetTree().RootNode().ToSexp(); got != want {
+			t.Fatalf("step %d: tree mismatch\ngot  %s\nwant %s", step, got, want)
+		}
+		lo = b.ByteOffset(b.LineStart(2))
+		hi = b.ByteOffset(b.LineStart(16))
+		if got, want := p.GetHighlights(lo, hi), fresh.GetHighlights(lo, hi); !reflect.DeepEqual(got, want) {
+			t.Fatalf("step %d: highlights differ\ngot  %v\nwant %v", step, got, want)
+		}
+		fresh.Close()
+	}
+}
+
+func TestTreeSitterParseWithoutEditsStartsOver(t *testing.T) {
+	p, _ := NewTreeSitterParser("go")
+	defer p.Close()
+	p.Parse("package a\n")
+	// the text changed but no edit was reported; the old tree must not be reused
+	p.Parse("package a\n\nfunc f() {}\n")
+	if _, _, incremental := p.LastParse(); incremental {
+		t.Fatal("expected a full parse")
+	}
+	if got := p.GetTree().RootNode().ChildCount(); got != 2 {
+		t.Fatalf("expected 2 top-level nodes, got %d", got)
+	}
+}
+
+func TestRefreshFoldsMatchesFullRecompute(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", incrementalGoSource), nil)
+	if e.buf().parser == nil {
+		t.Fatal("expected a go parser")
+	}
+
+	rng := rand.New(rand.NewSource(11))
+	for step := 0; step < 150; step++ {
+		randomGoEdit(rng, e.buffer)
+		e.reparseBuffer()
+
+		want := make(map[int]*FoldRange)
+		ranges := e.GetFoldableRanges()
+		for i := range ranges {
+			addFold(want, &ranges[i])
+		}
+		if len(want) != len(e.foldRanges) {
+			t.Fatalf("step %d: %d folds, want %d", step, len(e.foldRanges), len(want))
+		}
+		for line, f := range want {
+			got, ok := e.foldRanges[line]
+			if !ok || got.startLine != f.startLine || got.endLine != f.endLine {
+				t.Fatalf("step %d: fold at %d = %+v, want %+v", step, line, got, f)
+			}
+		}
+	}
+}
+
+func TestRefreshFoldsKeepsFoldedStateAcrossShift(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", incrementalGoSource), nil)
+	start := 13 // func main() {
+	fold, ok := e.foldRanges[start]
+	if !ok {
+		t.Fatalf("no fold at line %d: %v", start, e.foldRanges)
+	}
+	fold.folded = true
+
+	// two new lines above main shift it down
+	_ = e.buffer.Insert(e.buffer.LineStart(1), "\n\n")
+	e.reparseBuffer()
+	if f, ok := e.foldRanges[start+2]; !ok || !f.folded {
+		t.Fatalf("expected folded fold at %d, got %+v", start+2, f)
+	}
+}
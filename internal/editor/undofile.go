@@ -0,0 +1,90 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+)
+
+// undoFileData is the on-disk form of a buffer's undo history. Hash is the
+// SHA-256 of the text the history was written for; the history is only
+// restored when the file still has exactly that content.
+type undoFileData struct {
+	Path    string          `json:"path"`
+	Hash    string          `json:"hash"`
+	History json.RawMessage `json:"history"`
+}
+
+// undoFileEnabled reports whether the undofile option is on.
+func (e *Editor) undoFileEnabled() bool {
+	return e.config != nil && e.config.Options != nil && e.config.Options.UndoFile
+}
+
+// undoFilePath returns where the undo history for path is stored.
+func undoFilePath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(config.GetUndoDir(), hex.EncodeToString(sum[:])+".json")
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeUndoFile stores the undo history of bv for its current content.
+func (e *Editor) writeUndoFile(bv *BufferView) error {
+	if !e.undoFileEnabled() || bv == nil || bv.filename == "" {
+		return nil
+	}
+	history, err := bv.buffer.MarshalUndo()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(undoFileData{
+		Path:    bv.filename,
+		Hash:    contentHash(bv.buffer.String()),
+		History: history,
+	})
+	if err != nil {
+		return err
+	}
+
+	path := undoFilePath(bv.filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Atomic write: write to temp file then rename
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// loadUndoFile restores the undo history of bv if one was written for its
+// current content. Missing or stale undo files are ignored.
+func (e *Editor) loadUndoFile(bv *BufferView) bool {
+	if !e.undoFileEnabled() || bv == nil || bv.filename == "" {
+		return false
+	}
+	data, err := os.ReadFile(undoFilePath(bv.filename))
+	if err != nil {
+		return false
+	}
+	var f undoFileData
+	if err := json.Unmarshal(data, &f); err != nil {
+		return false
+	}
+	if f.Path != bv.filename || f.Hash != contentHash(bv.buffer.String()) {
+		return false
+	}
+	return bv.buffer.UnmarshalUndo(f.History) == nil
+}
@@ -0,0 +1,70 @@
+package editor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+)
+
+// withUndoFile configures a test editor with undofile on.
+func withUndoFile(o *config.Options) { o.UndoFile = true }
+
+func TestUndoFileRoundTrip(t *testing.T) {
+	path := writeTestFile(t, "notes.txt", "one")
+
+	e := newFileTestEditor(t, path, withUndoFile)
+	_ = e.buffer.Insert(3, " two")
+	_ = e.buffer.Insert(7, " three")
+	e.save()
+
+	if _, err := os.Stat(undoFilePath(path)); err != nil {
+		t.Fatalf("undo file not written: %v", err)
+	}
+
+	// a fresh session picks the history back up
+	e2 := newFileTestEditor(t, path, withUndoFile)
+	if got := e2.buffer.UndoSeq(); got != 2 {
+		t.Fatalf("expected restored seq 2, got %d", got)
+	}
+	e2.undo()
+	if got := e2.buffer.String(); got != "one two" {
+		t.Fatalf("after undo: got %q", got)
+	}
+	e2.undo()
+	e2.redo()
+	e2.redo()
+	if got := e2.buffer.String(); got != "one two three" {
+		t.Fatalf("after redo: got %q", got)
+	}
+}
+
+func TestUndoFileIgnoredWhenContentChanged(t *testing.T) {
+	path := writeTestFile(t, "notes.txt", "one")
+
+	e := newFileTestEditor(t, path, withUndoFile)
+	_ = e.buffer.Insert(3, " two")
+	e.save()
+
+	// edited outside the editor: the stored history no longer applies
+	if err := os.WriteFile(path, []byte("something else"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := newFileTestEditor(t, path, withUndoFile)
+	if got := e2.buffer.UndoSeqLast(); got != 0 {
+		t.Fatalf("expected empty history, got %d states", got)
+	}
+}
+
+func TestUndoFileDisabled(t *testing.T) {
+	path := writeTestFile(t, "notes.txt", "one")
+
+	e := newFileTestEditor(t, path, nil)
+	_ = e.buffer.Insert(3, " two")
+	e.save()
+
+	if _, err := os.Stat(undoFilePath(path)); !os.IsNotExist(err) {
+		t.Fatalf("undo file written with undofile off: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// undoUnits maps :earlier/:later suffixes to durations.
+var undoUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+}
+
+// parseUndoArg parses the argument of :earlier/:later: a plain count ("3")
+// or a duration ("10s", "5m", "1h", "2d"). An empty argument means one step;
+// zero is rejected.
+func parseUndoArg(arg string) (count int, d time.Duration, err error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return 1, 0, nil
+	}
+	unit, ok := undoUnits[arg[len(arg)-1]]
+	if ok {
+		arg = arg[:len(arg)-1]
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, 0, fmt.Errorf("invalid argument: %s", arg)
+	}
+	if ok {
+		return 0, time.Duration(n) * unit, nil
+	}
+	return n, 0, nil
+}
+
+// earlier implements :earlier and g-.
+func (e *Editor) earlier(arg string) {
+	e.timeTravel(arg, e.buffer.Earlier, e.buffer.EarlierBy, "Already at oldest change")
+}
+
+// later implements :later and g+.
+func (e *Editor) later(arg string) {
+	e.timeTravel(arg, e.buffer.Later, e.buffer.LaterBy, "Already at newest change")
+}
+
+func (e *Editor) timeTravel(arg string, byCount func(int) bool, byTime func(time.Duration) bool, limit string) {
+	if e.buffer == nil {
+		return
+	}
+	n, d, err := parseUndoArg(arg)
+	if err != nil {
+		e.statusMsg = err.Error()
+		return
+	}
+	var ok bool
+	if d > 0 {
+		ok = byTime(d)
+	} else {
+		ok = byCount(n)
+	}
+	if !ok {
+		e.statusMsg = limit
+		return
+	}
+	e.afterUndoMove(e.undoPosition())
+}
+
+// undoPosition describes the current undo state for the status line.
+func (e *Editor) undoPosition() string {
+	seq := e.buffer.UndoSeq()
+	if seq == 0 {
+		return "original text"
+	}
+	return fmt.Sprintf("#%d of %d, %s", seq, e.buffer.UndoSeqLast(), formatAgo(time.Since(e.buffer.UndoTime())))
+}
+
+// afterUndoMove refreshes the editor after the buffer moved in its undo tree.
+// The cursor goes back to where the crossed change was made when the buffer
+// recorded it, otherwise it is just clamped.
+func (e *Editor) afterUndoMove(msg string) {
+	pos, ok := e.buffer.UndoCursor()
+	if !ok {
+		pos = min(e.posFromCursor(), e.buffer.Len())
+	}
+	e.setCursorFromPos(pos)
+	e.wantX = e.cx
+	e.dirty = true
+	e.statusMsg = msg
+	e.clearPending()
+	e.reparseBuffer()
+	e.FireTextChanged()
+}
+
+// formatUndoTree lists the branch tips of the undo tree, newest first, the
+// way :undolist does in Vim. The current state is marked with '>'.
+func (e *Editor) formatUndoTree() []string {
+	states := e.buffer.UndoStates()
+	lines := []string{"   seq  changes  when"}
+	now := time.Now()
+	for i := len(states) - 1; i >= 0; i-- {
+		s := states[i]
+		if !s.Leaf && !s.Current {
+			continue
+		}
+		marker := " "
+		if s.Current {
+			marker = ">"
+		}
+		when := formatAgo(now.Sub(s.Time)) + " ago"
+		if s.Seq == 0 {
+			when = "original"
+		}
+		lines = append(lines, fmt.Sprintf("%s %5d  %7d  %s", marker, s.Seq, s.Changes, when))
+	}
+	return lines
+}
+
+func (e *Editor) showUndoTree() {
+	if e.buffer == nil {
+		return
+	}
+	e.popupFixedH = 10
+	e.openPopup("UNDO TREE", e.formatUndoTree())
+}
+
+// formatAgo renders a duration with one unit, e.g. "42s" or "3m".
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+}
+
+// trackUndoCursor lets the current buffer record the cursor in its undo
+// states. The source goes quiet once the buffer is no longer current.
+func (e *Editor) trackUndoCursor() {
+	buf := e.buffer
+	if buf == nil {
+		return
+	}
+	buf.SetCursorSource(func() int {
+		if e.buffer != buf {
+			return -1
+		}
+		return e.cursorOffset()
+	})
+}
+
+// cursorOffset is posFromCursor without clamping the editor's cursor, so it
+// is safe to call while a change is in progress.
+func (e *Editor) cursorOffset() int {
+	y := clamp(e.cy, 0, e.lineCount()-1)
+	return e.buffer.LineStart(y) + clamp(e.cx, 0, e.lineLen(y))
+}
+
+// settleUndoCursor records where the last command left the cursor as the
+// after-position of the change it made.
+func (e *Editor) settleUndoCursor() {
+	if e.buffer != nil {
+		e.buffer.SettleCursor()
+	}
+}
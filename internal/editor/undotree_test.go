@@ -0,0 +1,136 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseUndoArg(t *testing.T) {
+	tests := []struct {
+		arg   string
+		count int
+		d     time.Duration
+	}{
+		{"", 1, 0},
+		{"3", 3, 0},
+		{"10s", 0, 10 * time.Second},
+		{"5m", 0, 5 * time.Minute},
+		{"2h", 0, 2 * time.Hour},
+		{"1d", 0, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		n, d, err := parseUndoArg(tt.arg)
+		if err != nil || n != tt.count || d != tt.d {
+			t.Errorf("parseUndoArg(%q) = %d, %v, %v", tt.arg, n, d, err)
+		}
+	}
+	for _, arg := range []string{"5x", "0", "0s", "-1"} {
+		if _, _, err := parseUndoArg(arg); err == nil {
+			t.Errorf("expected error for %q", arg)
+		}
+	}
+}
+
+func TestEarlierLaterKeys(t *testing.T) {
+	e := newTestEditor(t, "x")
+	_ = e.buffer.Insert(1, "a")
+	_ = e.buffer.Insert(2, "b")
+	e.buffer.Undo()
+	_ = e.buffer.Insert(2, "c")
+
+	// g- walks back through the abandoned branch
+	e.handleKey(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+	e.handleKey(tcell.NewEventKey(tcell.KeyRune, '-', tcell.ModNone))
+	if got := e.buffer.String(); got != "xab" {
+		t.Fatalf("after g-: got %q", got)
+	}
+	e.handleKey(tcell.NewEventKey(tcell.KeyRune, 'g', tcell.ModNone))
+	e.handleKey(tcell.NewEventKey(tcell.KeyRune, '+', tcell.ModNone))
+	if got := e.buffer.String(); got != "xac" {
+		t.Fatalf("after g+: got %q", got)
+	}
+
+	e.exec("earlier 3")
+	if got := e.buffer.String(); got != "x" {
+		t.Fatalf("after :earlier 3: got %q", got)
+	}
+	e.exec("later 1h")
+	if got := e.buffer.String(); got != "xac" {
+		t.Fatalf("after :later 1h: got %q", got)
+	}
+}
+
+func TestUndoListPopup(t *testing.T) {
+	e := newTestEditor(t, "")
+	_ = e.buffer.Insert(0, "a")
+	_ = e.buffer.Insert(1, "b")
+	e.buffer.Undo()
+	_ = e.buffer.Insert(1, "c")
+
+	e.exec("undolist")
+	if !e.popupActive || e.popupTitle != "UNDO TREE" {
+		t.Fatalf("expected undo tree popup, got %q", e.popupTitle)
+	}
+	// header + two leaves
+	if len(e.popupLines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", e.popupLines)
+	}
+	if !strings.HasPrefix(e.popupLines[1], ">") || !strings.Contains(e.popupLines[1], "3") {
+		t.Fatalf("current leaf not marked first: %q", e.popupLines[1])
+	}
+}
+
+func pressKeys(e *Editor, keys string) {
+	for _, r := range keys {
+		if r == 0x1b {
+			e.handleKey(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone))
+			continue
+		}
+		e.handleKey(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+}
+
+func TestUndoRestoresCursor(t *testing.T) {
+	e := newTestEditor(t, "one\ntwo\nthree\nfour")
+
+	// dd on "three", then wander off
+	e.cy, e.cx = 2, 2
+	pressKeys(e, "dd")
+	pressKeys(e, "gg")
+
+	pressKeys(e, "u")
+	if e.cy != 2 || e.cx != 2 {
+		t.Fatalf("after u: cursor (%d,%d), want (2,2)", e.cy, e.cx)
+	}
+
+	e.cy, e.cx = 0, 0
+	e.handleKey(tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModNone))
+	if e.cy != 2 || e.cx != 0 {
+		t.Fatalf("after Ctrl-R: cursor (%d,%d), want (2,0)", e.cy, e.cx)
+	}
+}
+
+func TestUndoRestoresCursorForInsertGroup(t *testing.T) {
+	e := newTestEditor(t, "alpha\nbeta")
+
+	e.cy, e.cx = 1, 4
+	pressKeys(e, "a!!\x1b")
+	if got := e.buffer.String(); got != "alpha\nbeta!!" {
+		t.Fatalf("got %q", got)
+	}
+	afterY, afterX := e.cy, e.cx
+	pressKeys(e, "gg")
+
+	pressKeys(e, "u")
+	if e.cy != 1 || e.cx != 4 {
+		t.Fatalf("after u: cursor (%d,%d), want (1,4)", e.cy, e.cx)
+	}
+	pressKeys(e, "gg")
+	e.handleKey(tcell.NewEventKey(tcell.KeyCtrlR, 0, tcell.ModNone))
+	if e.cy != afterY || e.cx != afterX {
+		t.Fatalf("after Ctrl-R: cursor (%d,%d), want (%d,%d)", e.cy, e.cx, afterY, afterX)
+	}
+}
@@ -0,0 +1,188 @@
+package editor
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// viewData is the saved view of a file: where the cursor and the window
+// were, which folds were closed and where the marks were. It is stored in
+// config.State under viewKey of the file's path.
+type viewData struct {
+	Line       int                 `json:"line"`
+	Col        int                 `json:"col"`
+	TopLine    int                 `json:"top_line"`
+	LeftCol    int                 `json:"left_col"`
+	FoldMethod string              `json:"fold_method"`
+	Folds      []viewFold          `json:"folds"`
+	Marks      map[string]viewMark `json:"marks"`
+}
+
+type viewFold struct {
+	Start  int  `json:"start"`
+	End    int  `json:"end"`
+	Closed bool `json:"closed"`
+}
+
+type viewMark struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+var errNoView = errors.New("no view for this file")
+
+// viewKey returns the state key of the view of the file at path.
+func viewKey(path string) string {
+	return "view:" + path
+}
+
+// autoViewEnabled reports whether the autoview option is on.
+func (e *Editor) autoViewEnabled() bool {
+	return e.config != nil && e.config.Options != nil && e.config.Options.AutoView
+}
+
+// writeView stores the view of bv. The current buffer must have been synced
+// to bv first.
+func (e *Editor) writeView(bv *BufferView) error {
+	if e.config == nil || e.config.State == nil {
+		return errors.New("no state storage")
+	}
+	if bv == nil || bv.filename == "" {
+		return errors.New("no file name")
+	}
+
+	view := viewData{
+		Line:       bv.cy,
+		Col:        bv.cx,
+		TopLine:    bv.rowOffset,
+		LeftCol:    bv.colOffset,
+		FoldMethod: bv.foldMethod,
+		Folds:      []viewFold{},
+		Marks:      make(map[string]viewMark, len(bv.marks)),
+	}
+	for _, f := range bv.foldRanges {
+		view.Folds = append(view.Folds, viewFold{Start: f.startLine, End: f.endLine, Closed: f.folded})
+	}
+	sort.Slice(view.Folds, func(i, j int) bool {
+		return view.Folds[i].Start < view.Folds[j].Start
+	})
+	for name, m := range bv.marks {
+		view.Marks[string(name)] = viewMark{Line: m.line, Col: m.col}
+	}
+
+	e.config.State.Set(viewKey(bv.filename), view)
+	return nil
+}
+
+// readView returns the stored view of the file at path.
+func (e *Editor) readView(path string) (viewData, error) {
+	var view viewData
+	if e.config == nil || e.config.State == nil {
+		return view, errNoView
+	}
+	val := e.config.State.Get(viewKey(path), nil)
+	if val == nil {
+		return view, errNoView
+	}
+	// a view is a viewData until the state is reloaded from disk, and
+	// generic JSON values after that
+	data, err := json.Marshal(val)
+	if err != nil {
+		return view, err
+	}
+	if err := json.Unmarshal(data, &view); err != nil {
+		return view, err
+	}
+	return view, nil
+}
+
+// makeView implements :mkview for the current buffer.
+func (e *Editor) makeView() {
+	e.updateFolds()
+	e.syncToBuffer()
+	if err := e.writeView(e.buf()); err != nil {
+		e.statusMsg = "mkview: " + err.Error()
+		return
+	}
+	e.statusMsg = "view saved"
+}
+
+// loadView implements :loadview: it restores the cursor, scroll offsets,
+// folds and marks of the current buffer from its stored view. Positions
+// past the end of the buffer are clamped.
+func (e *Editor) loadView() error {
+	view, err := e.readView(e.filename)
+	if err != nil {
+		return err
+	}
+
+	lines := max(1, e.lineCount())
+	e.cy = clamp(view.Line, 0, lines-1)
+	e.cx = clamp(view.Col, 0, e.lineLen(e.cy))
+	e.wantX = e.cx
+	e.rowOffset = clamp(view.TopLine, 0, lines-1)
+	e.colOffset = max(0, view.LeftCol)
+
+	for name, m := range view.Marks {
+		r := []rune(name)
+		if len(r) != 1 || r[0] < 'a' || r[0] > 'z' {
+			continue
+		}
+		line := clamp(m.Line, 0, lines-1)
+		e.marks[r[0]] = Mark{line: line, col: clamp(m.Col, 0, e.lineLen(line))}
+	}
+
+	e.updateFolds()
+	if e.foldMethod() == foldManual && view.FoldMethod == foldManual {
+		// manual folds are the saved ones
+		clear(e.foldRanges)
+		for _, f := range view.Folds {
+			if f.Start >= 0 && f.End > f.Start && f.End < lines {
+				addFold(e.foldRanges, &FoldRange{startLine: f.Start, endLine: f.End, folded: f.Closed})
+			}
+		}
+	} else {
+		// computed folds take the state of the saved fold on their line
+		for _, f := range view.Folds {
+			if fold, ok := e.foldRanges[f.Start]; ok {
+				fold.folded = f.Closed
+			}
+		}
+	}
+	e.cursorOutOfFolds()
+	e.syncToBuffer()
+	return nil
+}
+
+// autoLoadView restores the current buffer's view after it was read when
+// the autoview option is on.
+func (e *Editor) autoLoadView() {
+	if e.autoViewEnabled() {
+		_ = e.loadView()
+	}
+}
+
+// autoWriteView stores the current buffer's view when the autoview option
+// is on.
+func (e *Editor) autoWriteView() {
+	if !e.autoViewEnabled() {
+		return
+	}
+	e.updateFolds()
+	e.syncToBuffer()
+	_ = e.writeView(e.buf())
+}
+
+// autoWriteViews stores the views of all buffers when the autoview option
+// is on.
+func (e *Editor) autoWriteViews() {
+	if !e.autoViewEnabled() {
+		return
+	}
+	e.updateFolds()
+	e.syncToBuffer()
+	for _, bv := range e.buffers {
+		_ = e.writeView(bv)
+	}
+}
@@ -0,0 +1,78 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/dragonbytelabs/voidabyss/internal/config"
+)
+
+func TestMkviewAndLoadview(t *testing.T) {
+	path := writeTestFile(t, "config.yaml", foldYAMLSource)
+
+	e := newFileTestEditor(t, path, nil)
+	e.cy, e.cx = 8, 2
+	pressKeys(e, "ma")
+	e.cy = 2
+	pressKeys(e, "zc")
+	e.cy, e.cx = 6, 3
+	e.exec("mkview")
+	if e.statusMsg != "view saved" {
+		t.Fatalf("mkview: %q", e.statusMsg)
+	}
+
+	e = newFileTestEditor(t, path, nil)
+	e.updateFolds()
+	if e.cy != 0 || e.foldRanges[2].folded {
+		t.Fatal("the view should not be restored without autoview")
+	}
+	e.exec("loadview")
+	if e.statusMsg != "view loaded" {
+		t.Fatalf("loadview: %q", e.statusMsg)
+	}
+	if e.cy != 6 || e.cx != 3 {
+		t.Errorf("cursor at %d:%d, want 6:3", e.cy, e.cx)
+	}
+	if !e.foldRanges[2].folded || e.foldRanges[0].folded {
+		t.Errorf("fold state not restored")
+	}
+	if m, ok := e.marks['a']; !ok || m.line != 8 || m.col != 2 {
+		t.Errorf("mark a = %+v, want 8:2", m)
+	}
+	// the buffer view holds the restored state too
+	if bv := e.buf(); bv.cy != 6 || !bv.foldRanges[2].folded {
+		t.Errorf("buffer view not updated")
+	}
+}
+
+func TestLoadviewWithoutView(t *testing.T) {
+	path := writeTestFile(t, "config.yaml", foldYAMLSource)
+	e := newFileTestEditor(t, path, nil)
+	e.exec("loadview")
+	if e.statusMsg != "loadview: no view for this file" {
+		t.Fatalf("got %q", e.statusMsg)
+	}
+}
+
+func TestAutoViewRestoresManualFolds(t *testing.T) {
+	path := writeTestFile(t, "config.yaml", foldYAMLSource)
+
+	manual := func(o *config.Options) { o.AutoView, o.FoldMethod = true, "manual" }
+	e := newFileTestEditor(t, path, manual)
+	e.cy = 7
+	pressKeys(e, "Vjzf")
+	e.cy = 1
+	e.autoWriteViews() // as on quit
+
+	e = newFileTestEditor(t, path, manual)
+	if f, ok := e.foldRanges[7]; !ok || f.endLine != 8 || !f.folded {
+		t.Fatalf("manual fold not restored: %v", foldSpans(e))
+	}
+
+	// with the default fold method the view is restored on open
+	e.cy = 4
+	e.autoWriteViews()
+	e = newFileTestEditor(t, path, func(o *config.Options) { o.AutoView = true })
+	if e.cy != 4 {
+		t.Fatalf("cursor at line %d after reopening, want 4", e.cy)
+	}
+}
@@ -4,12 +4,14 @@ func (e *Editor) visualEnter(kind VisualKind) {
 	e.visualActive = true
 	e.visualKind = kind
 	e.visualAnchor = e.posFromCursor()
+	e.visualStack = nil
 	e.mode = ModeVisual
 	e.FireVisualEnter()
 }
 
 func (e *Editor) visualExit() {
 	e.visualActive = false
+	e.visualStack = nil
 	e.mode = ModeNormal
 	e.statusMsg = ""
 	e.FireVisualLeave()
@@ -42,24 +44,26 @@ func (e *Editor) visualRange() (start, end int, kind RegisterKind) {
 	return a, b + 1, RegCharwise
 }
 
-func (e *Editor) lineIndexForPos(pos int) int {
-	starts := e.lineStarts()
-	if len(starts) == 0 {
-		return 0
+// visualSelectTextObject replaces the selection with a text object, e.g. vaf
+// selects the function around the cursor.
+func (e *Editor) visualSelectTextObject(prefix, unit rune) {
+	start, end, kind, ok := e.textObjectRange(prefix, unit)
+	if !ok || end <= start {
+		e.statusMsg = "nothing"
+		return
 	}
-	pos = clamp(pos, 0, e.buffer.Len())
-	lo, hi := 0, len(starts)-1
-	best := 0
-	for lo <= hi {
-		m := (lo + hi) / 2
-		if starts[m] <= pos {
-			best = m
-			lo = m + 1
-		} else {
-			hi = m - 1
-		}
+	e.visualAnchor = start
+	e.setCursorFromPos(end - 1)
+	e.wantX = e.cx
+	if kind == RegLinewise {
+		e.visualKind = VisualLine
+	} else {
+		e.visualKind = VisualChar
 	}
-	return best
+}
+
+func (e *Editor) lineIndexForPos(pos int) int {
+	return e.buffer.LineAt(pos)
 }
 
 // visualGetLineRange returns the start and end line indices for the visual selection
@@ -0,0 +1,85 @@
+package editor
+
+// visualSelection is a charwise selection saved by expandSelection.
+type visualSelection struct {
+	kind           VisualKind
+	anchor, cursor int
+}
+
+// EnclosingNode returns the byte range of the smallest named node that
+// contains [start, end) and is larger than it. Injected languages are
+// searched too, so the selection can grow inside a <script> element before
+// reaching the HTML around it.
+func (p *TreeSitterParser) EnclosingNode(start, end int) (nodeStart, nodeEnd int, ok bool) {
+	if p == nil {
+		return 0, 0, false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.tree == nil {
+		return 0, 0, false
+	}
+	node := p.tree.RootNode().NamedDescendantForByteRange(uint(start), uint(end))
+	for node != nil {
+		s, t := int(node.StartByte()), int(node.EndByte())
+		if s <= start && t >= end && t-s > end-start {
+			nodeStart, nodeEnd, ok = s, t, true
+			break
+		}
+		node = node.Parent()
+	}
+
+	if p.layersStale {
+		return nodeStart, nodeEnd, ok
+	}
+	for _, l := range p.layers {
+		if !l.overlaps(start, end) {
+			continue
+		}
+		s, t, found := l.parser.EnclosingNode(start, end)
+		if found && (!ok || t-s < nodeEnd-nodeStart) {
+			nodeStart, nodeEnd, ok = s, t, true
+		}
+	}
+	return nodeStart, nodeEnd, ok
+}
+
+// expandSelection grows the visual selection to the enclosing syntax node,
+// remembering the old selection for shrinkSelection.
+func (e *Editor) expandSelection() {
+	parser := e.syntaxParser()
+	if parser == nil {
+		e.statusMsg = "no syntax tree"
+		return
+	}
+	start, end, _ := e.visualRange()
+	s, t, ok := parser.EnclosingNode(e.buffer.ByteOffset(start), e.buffer.ByteOffset(end))
+	if !ok {
+		e.statusMsg = "no larger node"
+		return
+	}
+
+	e.visualStack = append(e.visualStack, visualSelection{e.visualKind, e.visualAnchor, e.posFromCursor()})
+	start, end = e.buffer.PosFromByte(s), e.buffer.PosFromByte(t)
+	e.visualKind = VisualChar
+	e.visualAnchor = start
+	e.setCursorFromPos(max(start, end-1))
+	e.wantX = e.cx
+}
+
+// shrinkSelection restores the selection from before the last expansion.
+func (e *Editor) shrinkSelection() {
+	n := len(e.visualStack)
+	if n == 0 {
+		e.statusMsg = "nothing to shrink"
+		return
+	}
+	sel := e.visualStack[n-1]
+	e.visualStack = e.visualStack[:n-1]
+	e.visualKind = sel.kind
+	e.visualAnchor = sel.anchor
+	e.setCursorFromPos(sel.cursor)
+	e.wantX = e.cx
+}
@@ -0,0 +1,82 @@
+package editor
+
+import "testing"
+
+// selectionText returns the text of the current visual selection.
+func selectionText(e *Editor) string {
+	start, end, _ := e.visualRange()
+	s, _ := e.buffer.Slice(start, end)
+	return s
+}
+
+func TestExpandAndShrinkSelection(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", textObjectGoSource), nil)
+	e.cy, e.cx = 11, 13 // on "1" in println(add(1, 2))
+
+	pressKeys(e, "v+")
+	if got := selectionText(e); got != "(1, 2)" {
+		t.Fatalf("first expansion: got %q", got)
+	}
+	pressKeys(e, "+")
+	if got := selectionText(e); got != "add(1, 2)" {
+		t.Fatalf("second expansion: got %q", got)
+	}
+	pressKeys(e, "++")
+	if got := selectionText(e); got != "println(add(1, 2))" {
+		t.Fatalf("fourth expansion: got %q", got)
+	}
+
+	pressKeys(e, "---")
+	if got := selectionText(e); got != "(1, 2)" {
+		t.Fatalf("after shrinking: got %q", got)
+	}
+	pressKeys(e, "-")
+	if got := selectionText(e); got != "1" {
+		t.Fatalf("back to the start: got %q", got)
+	}
+	pressKeys(e, "-")
+	if e.statusMsg != "nothing to shrink" {
+		t.Fatalf("expected nothing to shrink, got %q", e.statusMsg)
+	}
+
+	// operators act on the expanded selection
+	pressKeys(e, "++y")
+	if got := e.regs.unnamed.text; got != "add(1, 2)" {
+		t.Fatalf("yank: got %q", got)
+	}
+}
+
+func TestExpandSelectionFromLinewise(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "main.go", textObjectGoSource), nil)
+	e.cy, e.cx = 7, 1 // "\treturn a + b"
+
+	pressKeys(e, "V+")
+	if e.visualKind != VisualChar {
+		t.Fatal("expansion should switch to charwise selection")
+	}
+	if got := selectionText(e); got != "{\n\treturn a + b\n}" {
+		t.Fatalf("got %q", got)
+	}
+	pressKeys(e, "-")
+	if e.visualKind != VisualLine {
+		t.Fatal("shrinking should restore the linewise selection")
+	}
+}
+
+func TestExpandSelectionInInjection(t *testing.T) {
+	e := newFileTestEditor(t, writeTestFile(t, "index.html", "<div>\n<script>let a = f(1);</script>\n</div>\n"), nil)
+	e.cy, e.cx = 1, 18 // on "1"
+
+	pressKeys(e, "v+")
+	if got := selectionText(e); got != "(1)" {
+		t.Fatalf("expansion inside the script: got %q", got)
+	}
+	pressKeys(e, "+++")
+	if got := selectionText(e); got != "let a = f(1);" {
+		t.Fatalf("script contents: got %q", got)
+	}
+	pressKeys(e, "+")
+	if got := selectionText(e); got != "<script>let a = f(1);</script>" {
+		t.Fatalf("script element: got %q", got)
+	}
+}
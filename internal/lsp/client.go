@@ -2,14 +2,39 @@ package lsp
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
 )
 
+// DefaultTimeout is how long a request waits for its response when its
+// context has no deadline
+const DefaultTimeout = 30 * time.Second
+
+// CloseTimeout limits how long Close lets the server shut down: the
+// shutdown request and the wait for the process to exit share it
+const CloseTimeout = time.Second
+
+// killTimeout limits the wait for the output to close after Close killed
+// the server
+const killTimeout = 200 * time.Millisecond
+
+// RequestHandler answers a request the server sends to the client. It runs
+// on its own goroutine. A *ResponseError it returns is sent as it is; other
+// errors are sent as internal errors.
+type RequestHandler func(params json.RawMessage) (interface{}, error)
+
 // Client represents an LSP client connected to a language server
 type Client struct {
 	cmd            *exec.Cmd
@@ -20,9 +45,15 @@ type Client struct {
 	nextID         int32
 	pending        map[int]chan *Response
 	onNotification func(method string, params json.RawMessage)
+	handlers       map[string]RequestHandler
+	settings       map[string]interface{}
 	initialized    bool
 	capabilities   ServerCapabilities
 	rootURI        string
+	log            Log
+	stderrDone     chan struct{} // closed when the server's stderr has closed
+	done           chan struct{} // closed when the server process has exited
+	exitErr        error
 }
 
 // NewClient creates a new LSP client for the given language server command
@@ -39,20 +70,36 @@ func NewClient(serverCmd string, args []string, rootURI string) (*Client, error)
 		return nil, fmt.Errorf("stdout pipe: %w", err)
 	}
 
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stderr pipe: %w", err)
+	}
+
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("start server: %w", err)
 	}
 
 	client := &Client{
-		cmd:     cmd,
-		stdin:   stdin,
-		stdout:  stdout,
-		reader:  bufio.NewReader(stdout),
-		pending: make(map[int]chan *Response),
-		rootURI: rootURI,
+		cmd:        cmd,
+		stdin:      stdin,
+		stdout:     stdout,
+		reader:     bufio.NewReader(stdout),
+		pending:    make(map[int]chan *Response),
+		rootURI:    rootURI,
+		stderrDone: make(chan struct{}),
+		done:       make(chan struct{}),
 	}
+	client.handlers = map[string]RequestHandler{
+		"client/registerCapability":      acceptRequest,
+		"client/unregisterCapability":    acceptRequest,
+		"window/workDoneProgress/create": acceptRequest,
+		"window/showMessageRequest":      acceptRequest, // no action chosen
+		"workspace/configuration":        client.configuration,
+	}
+	client.log.Printf("started %s", strings.Join(cmd.Args, " "))
 
 	// Start reading responses in background
+	go client.readStderr(stderr)
 	go client.readLoop()
 
 	return client, nil
@@ -64,17 +111,69 @@ func (c *Client) Initialize() error {
 		ProcessID: nil, // null means don't kill on process exit
 		RootURI:   c.rootURI,
 		Capabilities: ClientCapabilities{
+			General: &GeneralClientCapabilities{
+				PositionEncodings: []string{PositionEncodingUTF8, PositionEncodingUTF16},
+			},
+			Workspace: &WorkspaceClientCapabilities{
+				ApplyEdit:     c.handles("workspace/applyEdit"),
+				WorkspaceEdit: &WorkspaceEditClientCapabilities{DocumentChanges: true},
+				Configuration: true,
+			},
 			TextDocument: TextDocumentClientCapabilities{
 				Definition: &DefinitionClientCapabilities{
 					DynamicRegistration: false,
 					LinkSupport:         true,
 				},
+				Hover: &HoverClientCapabilities{
+					ContentFormat: []string{MarkupKindMarkdown, MarkupKindPlainText},
+				},
+				PublishDiagnostics: &PublishDiagnosticsClientCapabilities{
+					VersionSupport: true,
+				},
+				SignatureHelp: &SignatureHelpClientCapabilities{
+					SignatureInformation: &SignatureInformationCapabilities{
+						DocumentationFormat:  []string{MarkupKindMarkdown, MarkupKindPlainText},
+						ParameterInformation: &ParameterInformationSupport{LabelOffsetSupport: true},
+						ActiveParameter:      true,
+					},
+					ContextSupport: true,
+				},
+				Completion: &CompletionClientCapabilities{
+					CompletionItem: &CompletionItemCapabilities{
+						SnippetSupport:      false,
+						DocumentationFormat: []string{MarkupKindMarkdown, MarkupKindPlainText},
+						ResolveSupport: &CompletionResolveSupport{
+							Properties: []string{"documentation", "detail", "additionalTextEdits"},
+						},
+					},
+					ContextSupport: true,
+				},
+				CodeAction: &CodeActionClientCapabilities{
+					CodeActionLiteralSupport: codeActionLiteralSupport(),
+					IsPreferredSupport:       true,
+					DisabledSupport:          true,
+					DataSupport:              true,
+					ResolveSupport:           &CodeActionResolveSupport{Properties: []string{"edit"}},
+				},
+				DocumentSymbol: &DocumentSymbolClientCapabilities{
+					HierarchicalDocumentSymbolSupport: true,
+				},
+				SemanticTokens: &SemanticTokensClientCapabilities{
+					Requests:             SemanticTokensRequests{Range: true, Full: true},
+					TokenTypes:           semanticTokenTypes,
+					TokenModifiers:       semanticTokenModifiers,
+					Formats:              []string{TokenFormatRelative},
+					AugmentsSyntaxTokens: true,
+				},
+				InlayHint:     &InlayHintClientCapabilities{},
+				CallHierarchy: &CallHierarchyClientCapabilities{},
+				TypeHierarchy: &TypeHierarchyClientCapabilities{},
 			},
 		},
 	}
 
 	var result InitializeResult
-	if err := c.Call("initialize", params, &result); err != nil {
+	if err := c.Call(context.Background(), "initialize", params, &result); err != nil {
 		return fmt.Errorf("initialize: %w", err)
 	}
 
@@ -86,11 +185,142 @@ func (c *Client) Initialize() error {
 		return fmt.Errorf("initialized notification: %w", err)
 	}
 
+	// servers that don't ask for their settings get them pushed
+	c.mu.Lock()
+	settings := c.settings
+	c.mu.Unlock()
+	if len(settings) > 0 {
+		if err := c.Notify("workspace/didChangeConfiguration", DidChangeConfigurationParams{Settings: settings}); err != nil {
+			return fmt.Errorf("configuration: %w", err)
+		}
+	}
+
 	return nil
 }
 
-// Call sends a request and waits for the response
-func (c *Client) Call(method string, params interface{}, result interface{}) error {
+// codeActionLiteralSupport announces the code action kinds the client
+// shows
+func codeActionLiteralSupport() *CodeActionLiteralSupport {
+	s := &CodeActionLiteralSupport{}
+	s.CodeActionKind.ValueSet = []string{
+		"", CodeActionQuickFix, CodeActionRefactor, CodeActionRefactorExtract, CodeActionRefactorInline,
+		CodeActionRefactorRewrite, CodeActionSource, CodeActionSourceOrganizeImports,
+	}
+	return s
+}
+
+// semanticTokenTypes and semanticTokenModifiers are the standard token
+// types and modifiers; servers may send others as well
+var (
+	semanticTokenTypes = []string{
+		"namespace", "type", "class", "enum", "interface", "struct", "typeParameter",
+		"parameter", "variable", "property", "enumMember", "event", "function",
+		"method", "macro", "keyword", "modifier", "comment", "string", "number",
+		"regexp", "operator", "decorator",
+	}
+	semanticTokenModifiers = []string{
+		"declaration", "definition", "readonly", "static", "deprecated",
+		"abstract", "async", "modification", "documentation", "defaultLibrary",
+	}
+)
+
+// Capabilities returns the capabilities the server announced in its
+// initialize response
+func (c *Client) Capabilities() ServerCapabilities {
+	return c.capabilities
+}
+
+// SetNotificationHandler sets the function called for every notification
+// from the server. It runs on the client's read goroutine.
+func (c *Client) SetNotificationHandler(fn func(method string, params json.RawMessage)) {
+	c.mu.Lock()
+	c.onNotification = fn
+	c.mu.Unlock()
+}
+
+// HandleRequest sets the handler for requests of method from the server,
+// replacing any default one. Handlers set before Initialize decide which
+// capabilities are announced, such as workspace/applyEdit.
+func (c *Client) HandleRequest(method string, fn RequestHandler) {
+	c.mu.Lock()
+	c.handlers[method] = fn
+	c.mu.Unlock()
+}
+
+// handles reports whether requests of method have a handler
+func (c *Client) handles(method string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handlers[method] != nil
+}
+
+// SetSettings sets the settings the client answers workspace/configuration
+// with. Set before Initialize, they are also sent with
+// workspace/didChangeConfiguration once the server is initialized.
+func (c *Client) SetSettings(settings map[string]interface{}) {
+	c.mu.Lock()
+	c.settings = settings
+	c.mu.Unlock()
+}
+
+// acceptRequest answers a request with null
+func acceptRequest(json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// configuration answers workspace/configuration with the settings of each
+// section asked for; a dotted section such as "gopls.ui" looks up nested
+// tables. Unknown sections are null.
+func (c *Client) configuration(raw json.RawMessage) (interface{}, error) {
+	var params ConfigurationParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	settings := c.settings
+	c.mu.Unlock()
+
+	result := make([]interface{}, len(params.Items))
+	for i, item := range params.Items {
+		var value interface{} = settings
+		for _, key := range strings.Split(item.Section, ".") {
+			if key == "" {
+				continue
+			}
+			table, _ := value.(map[string]interface{})
+			value = table[key]
+		}
+		if table, ok := value.(map[string]interface{}); ok && table == nil {
+			value = nil
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// PositionEncoding returns the buffer encoding for Position.Character
+// as negotiated with the server. Servers that don't answer use UTF-16.
+func (c *Client) PositionEncoding() buffer.Encoding {
+	switch c.capabilities.PositionEncoding {
+	case PositionEncodingUTF8:
+		return buffer.EncodingUTF8
+	case PositionEncodingUTF32:
+		return buffer.EncodingRune
+	default:
+		return buffer.EncodingUTF16
+	}
+}
+
+// Call sends a request and waits for the response. When ctx is done
+// first, or after DefaultTimeout when it has no deadline, the server is
+// sent $/cancelRequest and Call returns the context's error. Calls fail
+// when the server exits.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
 	id := int(atomic.AddInt32(&c.nextID, 1))
 
 	req := &Request{
@@ -112,10 +342,21 @@ func (c *Client) Call(method string, params interface{}, result interface{}) err
 		return err
 	}
 
-	resp := <-respChan
+	var resp *Response
+	select {
+	case resp = <-respChan:
+	case <-c.done:
+		return fmt.Errorf("%s: server exited", method)
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		_ = c.Notify("$/cancelRequest", CancelParams{ID: id})
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
 
 	if resp.Error != nil {
-		return fmt.Errorf("rpc error: %s", resp.Error.Message)
+		return fmt.Errorf("rpc error: %w", resp.Error)
 	}
 
 	if result != nil && resp.Result != nil {
@@ -125,6 +366,62 @@ func (c *Client) Call(method string, params interface{}, result interface{}) err
 	return nil
 }
 
+// ExecuteCommand asks the server to run a command, such as the command of a
+// code action
+func (c *Client) ExecuteCommand(ctx context.Context, cmd Command) error {
+	params := ExecuteCommandParams{Command: cmd.Command, Arguments: cmd.Arguments}
+	if err := c.Call(ctx, "workspace/executeCommand", params, nil); err != nil {
+		return fmt.Errorf("execute command %s: %w", cmd.Command, err)
+	}
+	return nil
+}
+
+// WorkspaceSymbols asks the server for the symbols of the workspace
+// matching query
+func (c *Client) WorkspaceSymbols(ctx context.Context, query string) ([]SymbolInformation, error) {
+	var result []SymbolInformation
+	if err := c.Call(ctx, "workspace/symbol", WorkspaceSymbolParams{Query: query}, &result); err != nil {
+		return nil, fmt.Errorf("workspace symbol request: %w", err)
+	}
+	return result, nil
+}
+
+// IncomingCalls requests the callers of a call hierarchy item
+func (c *Client) IncomingCalls(ctx context.Context, item HierarchyItem) ([]CallHierarchyIncomingCall, error) {
+	var result []CallHierarchyIncomingCall
+	if err := c.Call(ctx, "callHierarchy/incomingCalls", HierarchyItemParams{Item: item}, &result); err != nil {
+		return nil, fmt.Errorf("incoming calls request: %w", err)
+	}
+	return result, nil
+}
+
+// OutgoingCalls requests the functions a call hierarchy item calls
+func (c *Client) OutgoingCalls(ctx context.Context, item HierarchyItem) ([]CallHierarchyOutgoingCall, error) {
+	var result []CallHierarchyOutgoingCall
+	if err := c.Call(ctx, "callHierarchy/outgoingCalls", HierarchyItemParams{Item: item}, &result); err != nil {
+		return nil, fmt.Errorf("outgoing calls request: %w", err)
+	}
+	return result, nil
+}
+
+// Supertypes requests the supertypes of a type hierarchy item
+func (c *Client) Supertypes(ctx context.Context, item HierarchyItem) ([]HierarchyItem, error) {
+	var result []HierarchyItem
+	if err := c.Call(ctx, "typeHierarchy/supertypes", HierarchyItemParams{Item: item}, &result); err != nil {
+		return nil, fmt.Errorf("supertypes request: %w", err)
+	}
+	return result, nil
+}
+
+// Subtypes requests the subtypes of a type hierarchy item
+func (c *Client) Subtypes(ctx context.Context, item HierarchyItem) ([]HierarchyItem, error) {
+	var result []HierarchyItem
+	if err := c.Call(ctx, "typeHierarchy/subtypes", HierarchyItemParams{Item: item}, &result); err != nil {
+		return nil, fmt.Errorf("subtypes request: %w", err)
+	}
+	return result, nil
+}
+
 // Notify sends a notification (no response expected)
 func (c *Client) Notify(method string, params interface{}) error {
 	var rawParams json.RawMessage
@@ -152,6 +449,7 @@ func (c *Client) send(msg interface{}) error {
 	}
 
 	header := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(data))
+	c.log.Printf("--> %s", abbreviate(data))
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -167,37 +465,103 @@ func (c *Client) send(msg interface{}) error {
 	return nil
 }
 
-// readLoop reads messages from the server
+// readLoop reads messages from the server until its output closes, then
+// waits for the process to exit. Calls still waiting fail then.
 func (c *Client) readLoop() {
+	defer func() {
+		<-c.stderrDone
+		c.exitErr = c.cmd.Wait()
+		if c.exitErr != nil {
+			c.log.Printf("exited: %v", c.exitErr)
+		} else {
+			c.log.Printf("exited")
+		}
+		c.mu.Lock()
+		clear(c.pending)
+		c.mu.Unlock()
+		close(c.done)
+	}()
 	for {
 		msg, err := c.readMessage()
 		if err != nil {
-			if err != io.EOF {
-				// Server disconnected or error
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
+				c.log.Printf("read: %v", err)
 			}
 			return
 		}
+		c.log.Printf("<-- %s", abbreviate(msg))
 		c.handleMessage(msg)
 	}
 }
 
-// readMessage reads a single LSP message (header + content)
+// readStderr logs what the server writes to stderr, line by line
+func (c *Client) readStderr(stderr io.Reader) {
+	defer close(c.stderrDone)
+	r := bufio.NewReader(stderr)
+	for {
+		line, err := r.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			c.log.Printf("stderr: %s", line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Log returns the last lines the client logged: the server's stderr and the
+// messages exchanged with it, abbreviated
+func (c *Client) Log() []string {
+	return c.log.Lines()
+}
+
+// Done returns a channel that is closed when the server process has exited
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// ExitError returns the error the server process exited with. It is only
+// meaningful once Done is closed.
+func (c *Client) ExitError() error {
+	return c.exitErr
+}
+
+// Pid returns the process ID of the server
+func (c *Client) Pid() int {
+	if c.cmd.Process == nil {
+		return 0
+	}
+	return c.cmd.Process.Pid
+}
+
+// readMessage reads a single LSP message (header + content). Header lines
+// may end in "\r\n" or "\n"; headers other than Content-Length are
+// ignored.
 func (c *Client) readMessage() (json.RawMessage, error) {
 	// Read headers
-	var contentLength int
+	contentLength := -1
 	for {
 		line, err := c.reader.ReadString('\n')
 		if err != nil {
 			return nil, err
 		}
-		if line == "\r\n" {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if contentLength < 0 {
+				continue // stray blank line between messages
+			}
 			break // End of headers
 		}
-		var key, value string
-		if _, err := fmt.Sscanf(line, "%s %d", &key, &value); err == nil {
-			if key == "Content-Length:" {
-				fmt.Sscanf(value, "%d", &contentLength)
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("bad header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(key), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("bad Content-Length %q", value)
 			}
+			contentLength = n
 		}
 	}
 
@@ -210,11 +574,26 @@ func (c *Client) readMessage() (json.RawMessage, error) {
 	return content, nil
 }
 
-// handleMessage processes a message from the server
+// handleMessage processes a message from the server: a response to a call,
+// a request, which has a method and an id, or a notification
 func (c *Client) handleMessage(data json.RawMessage) {
-	// Try to parse as response first
-	var resp Response
-	if err := json.Unmarshal(data, &resp); err == nil && resp.ID != nil {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.log.Printf("bad message: %v", err)
+		return
+	}
+	hasID := len(msg.ID) > 0 && string(msg.ID) != "null"
+
+	switch {
+	case msg.Method == "":
+		var resp Response
+		if err := json.Unmarshal(data, &resp); err != nil || resp.ID == nil {
+			return
+		}
 		c.mu.Lock()
 		ch, ok := c.pending[*resp.ID]
 		if ok {
@@ -226,31 +605,67 @@ func (c *Client) handleMessage(data json.RawMessage) {
 			ch <- &resp
 			close(ch)
 		}
-		return
+	case hasID:
+		go c.handleRequest(msg.ID, msg.Method, msg.Params)
+	default:
+		c.mu.Lock()
+		fn := c.onNotification
+		c.mu.Unlock()
+		if fn != nil {
+			fn(msg.Method, msg.Params)
+		}
 	}
+}
+
+// handleRequest answers a request from the server with its handler. The
+// response carries the id as the server sent it, number or string.
+func (c *Client) handleRequest(id json.RawMessage, method string, params json.RawMessage) {
+	c.mu.Lock()
+	fn := c.handlers[method]
+	c.mu.Unlock()
 
-	// Parse as notification
-	var notif Notification
-	if err := json.Unmarshal(data, &notif); err == nil {
-		if c.onNotification != nil {
-			c.onNotification(notif.Method, notif.Params)
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if fn == nil {
+		resp["error"] = &ResponseError{Code: CodeMethodNotFound, Message: "method not found: " + method}
+	} else if result, err := fn(params); err != nil {
+		var rpcErr *ResponseError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &ResponseError{Code: CodeInternalError, Message: err.Error()}
 		}
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
 	}
+	_ = c.send(resp)
 }
 
-// Close shuts down the language server
+// Close shuts down the language server: it asks for shutdown, sends exit
+// and kills the process if it has not ended CloseTimeout after Close was
+// called. The wait after the kill is limited as well, since a child of the
+// server may keep its output open. Close blocks for that long, so callers
+// on the editor's loop run it on a goroutine.
 func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), CloseTimeout)
+	defer cancel()
 	if c.initialized {
-		_ = c.Notify("shutdown", nil)
+		_ = c.Call(ctx, "shutdown", nil, nil)
 		_ = c.Notify("exit", nil)
 	}
 
 	c.stdin.Close()
-	c.stdout.Close()
 
-	if c.cmd.Process != nil {
-		return c.cmd.Process.Kill()
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+	}
+	if err := c.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	select {
+	case <-c.done:
+	case <-time.After(killTimeout):
+		c.log.Printf("output still open after the kill")
 	}
-
 	return nil
 }
@@ -0,0 +1,36 @@
+package lsp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	input := "Content-Length: 2\r\n\r\n{}" +
+		// bare \n, lower case and another header
+		"content-length:  7\nContent-Type: application/vscode-jsonrpc; charset=utf-8\n\n[1,2,3]" +
+		"\r\nContent-Length: 4\r\n\r\nnull"
+	c := &Client{reader: bufio.NewReader(strings.NewReader(input))}
+	for _, want := range []string{"{}", "[1,2,3]", "null"} {
+		msg, err := c.readMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != want {
+			t.Errorf("message = %q, want %q", msg, want)
+		}
+	}
+	if _, err := c.readMessage(); !errors.Is(err, io.EOF) {
+		t.Errorf("after the last message: %v", err)
+	}
+
+	for _, bad := range []string{"Content-Length: x\r\n\r\n", "Content-Length: -1\r\n\r\n", "garbage\r\n\r\n"} {
+		c := &Client{reader: bufio.NewReader(strings.NewReader(bad))}
+		if _, err := c.readMessage(); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("%q: err = %v", bad, err)
+		}
+	}
+}
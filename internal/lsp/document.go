@@ -1,27 +1,72 @@
 package lsp
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/dragonbytelabs/voidabyss/core/buffer"
 )
 
+// maxChanges is the number of unsent ranged changes after which Flush
+// sends the full text instead
+const maxChanges = 128
+
 // DocumentSync handles document synchronization with the language server
 type DocumentSync struct {
 	client  *Client
 	version int
 	uri     string
+
+	// the buffer tracked with Track and its changes since the last Flush
+	buf         *buffer.Buffer
+	unsubscribe func()
+	changes     []TextDocumentContentChangeEvent
+	changed     bool
+	full        bool // too many changes; send the text
 }
 
 // NewDocumentSync creates a new document sync handler
 func NewDocumentSync(client *Client, filepath string) *DocumentSync {
-	uri := "file://" + filepath
 	return &DocumentSync{
 		client:  client,
-		uri:     uri,
+		uri:     URIFromPath(filepath),
 		version: 0,
 	}
 }
 
+// URI returns the document's file URI
+func (ds *DocumentSync) URI() string {
+	return ds.uri
+}
+
+// Version returns the version of the last text sent to the server
+func (ds *DocumentSync) Version() int {
+	return ds.version
+}
+
+// URIFromPath converts an absolute file path into a file URI
+func URIFromPath(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+// PathFromURI converts a file URI into a file path. Other URIs are
+// returned unchanged.
+func PathFromURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
 // DidOpen notifies the server that a document was opened
 func (ds *DocumentSync) DidOpen(languageID, text string) error {
 	ds.version = 1
@@ -38,11 +83,16 @@ func (ds *DocumentSync) DidOpen(languageID, text string) error {
 	return ds.client.Notify("textDocument/didOpen", params)
 }
 
-// DidChange notifies the server that a document was changed
+// DidChange notifies the server that the document's text was replaced by
+// text. Changes of the tracked buffer recorded so far are dropped.
 func (ds *DocumentSync) DidChange(text string) error {
-	ds.version++
+	ds.changes, ds.changed, ds.full = nil, false, false
+	return ds.didChange([]TextDocumentContentChangeEvent{{Text: text}})
+}
 
-	// Full document sync (sending entire content)
+// didChange sends changes as the next version of the document
+func (ds *DocumentSync) didChange(changes []TextDocumentContentChangeEvent) error {
+	ds.version++
 	params := DidChangeTextDocumentParams{
 		TextDocument: VersionedTextDocumentIdentifier{
 			TextDocumentIdentifier: TextDocumentIdentifier{
@@ -50,16 +100,82 @@ func (ds *DocumentSync) DidChange(text string) error {
 			},
 			Version: ds.version,
 		},
-		ContentChanges: []TextDocumentContentChangeEvent{
-			{
-				Text: text,
-			},
-		},
+		ContentChanges: changes,
 	}
 
 	return ds.client.Notify("textDocument/didChange", params)
 }
 
+// Track records the edits of buf, which holds the text sent with DidOpen,
+// until Untrack or DidClose. Flush sends them.
+func (ds *DocumentSync) Track(buf *buffer.Buffer) {
+	ds.Untrack()
+	ds.buf = buf
+	ds.unsubscribe = buf.Subscribe(ds.record)
+}
+
+// Untrack stops recording the edits of the tracked buffer
+func (ds *DocumentSync) Untrack() {
+	if ds.unsubscribe != nil {
+		ds.unsubscribe()
+	}
+	ds.buf, ds.unsubscribe = nil, nil
+	ds.changes, ds.changed, ds.full = nil, false, false
+}
+
+// record is subscribed to the tracked buffer. Servers syncing
+// incrementally get each edit as a ranged change in their position
+// encoding; edit positions refer to the text after the edits before it,
+// as the changes of one didChange do.
+func (ds *DocumentSync) record(ed buffer.Edit) {
+	ds.changed = true
+	if ds.full || ds.client.Capabilities().TextDocumentSync.Change != TextDocumentSyncIncremental {
+		return
+	}
+	if len(ds.changes) == maxChanges {
+		ds.changes, ds.full = nil, true
+		return
+	}
+	enc := ds.Encoding()
+	ds.changes = append(ds.changes, TextDocumentContentChangeEvent{
+		Range: &Range{Start: pointPosition(ed.Start, enc), End: pointPosition(ed.OldEnd, enc)},
+		Text:  ed.Text,
+	})
+}
+
+// pointPosition returns p as an LSP position with the column in enc units
+func pointPosition(p buffer.Point, enc buffer.Encoding) Position {
+	switch enc {
+	case buffer.EncodingUTF8:
+		return Position{Line: p.Line, Character: p.ByteCol}
+	case buffer.EncodingUTF16:
+		return Position{Line: p.Line, Character: p.UTF16Col}
+	default:
+		return Position{Line: p.Line, Character: p.Col}
+	}
+}
+
+// Flush sends the edits of the tracked buffer since the last Flush as one
+// didChange, as ranged changes when the server syncs incrementally and as
+// the full text otherwise. Servers that take no changes get nothing.
+func (ds *DocumentSync) Flush() error {
+	if !ds.changed || ds.buf == nil {
+		return nil
+	}
+	changes, full := ds.changes, ds.full
+	ds.changes, ds.changed, ds.full = nil, false, false
+
+	switch ds.client.Capabilities().TextDocumentSync.Change {
+	case TextDocumentSyncNone:
+		return nil
+	case TextDocumentSyncIncremental:
+		if !full {
+			return ds.didChange(changes)
+		}
+	}
+	return ds.didChange([]TextDocumentContentChangeEvent{{Text: ds.buf.String()}})
+}
+
 // DidSave notifies the server that a document was saved
 func (ds *DocumentSync) DidSave(text string) error {
 	params := DidSaveTextDocumentParams{
@@ -72,8 +188,10 @@ func (ds *DocumentSync) DidSave(text string) error {
 	return ds.client.Notify("textDocument/didSave", params)
 }
 
-// DidClose notifies the server that a document was closed
+// DidClose notifies the server that a document was closed and stops
+// tracking its buffer
 func (ds *DocumentSync) DidClose() error {
+	ds.Untrack()
 	params := DidCloseTextDocumentParams{
 		TextDocument: TextDocumentIdentifier{
 			URI: ds.uri,
@@ -84,7 +202,7 @@ func (ds *DocumentSync) DidClose() error {
 }
 
 // Definition requests the definition location for a position in the document
-func (ds *DocumentSync) Definition(line, character int) ([]Location, error) {
+func (ds *DocumentSync) Definition(ctx context.Context, line, character int) ([]Location, error) {
 	params := TextDocumentPositionParams{
 		TextDocument: TextDocumentIdentifier{
 			URI: ds.uri,
@@ -95,19 +213,718 @@ func (ds *DocumentSync) Definition(line, character int) ([]Location, error) {
 		},
 	}
 
+	var raw json.RawMessage
+	if err := ds.client.Call(ctx, "textDocument/definition", params, &raw); err != nil {
+		return nil, fmt.Errorf("definition request: %w", err)
+	}
+	return parseLocations(raw)
+}
+
+// parseLocations decodes the Location | Location[] | LocationLink[] | null
+// result of definition-like requests
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	raw = json.RawMessage(strings.TrimSpace(string(raw)))
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '{' {
+		var loc Location
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			return nil, fmt.Errorf("decode location: %w", err)
+		}
+		return []Location{loc}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("decode locations: %w", err)
+	}
+	locs := make([]Location, 0, len(items))
+	for _, item := range items {
+		var link LocationLink
+		if err := json.Unmarshal(item, &link); err == nil && link.TargetURI != "" {
+			locs = append(locs, Location{URI: link.TargetURI, Range: link.TargetSelectionRange})
+			continue
+		}
+		var loc Location
+		if err := json.Unmarshal(item, &loc); err != nil {
+			return nil, fmt.Errorf("decode location: %w", err)
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// Hover requests hover documentation for a position in the document. It
+// returns the text of the hover contents, or "" when there is none.
+func (ds *DocumentSync) Hover(ctx context.Context, line, character int) (string, error) {
+	params := TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+		Position: Position{
+			Line:      line,
+			Character: character,
+		},
+	}
+
+	var result *Hover
+	if err := ds.client.Call(ctx, "textDocument/hover", params, &result); err != nil {
+		return "", fmt.Errorf("hover request: %w", err)
+	}
+	if result == nil {
+		return "", nil
+	}
+	return HoverText(result.Contents), nil
+}
+
+// HoverText returns the text of hover contents, which servers send as
+// MarkupContent, a MarkedString or an array of MarkedStrings. Code blocks
+// of marked strings are fenced with their language.
+func HoverText(contents json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(contents, &text); err == nil {
+		return text
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(contents, &items); err == nil {
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			if t := HoverText(item); t != "" {
+				parts = append(parts, t)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+
+	var marked struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	if err := json.Unmarshal(contents, &marked); err != nil {
+		return ""
+	}
+	if marked.Language != "" {
+		return "```" + marked.Language + "\n" + marked.Value + "\n```"
+	}
+	return marked.Value
+}
+
+// SignatureHelp requests the signatures of the call at a position in the
+// document. trigger may be nil. It returns nil when the position is not in
+// a call.
+func (ds *DocumentSync) SignatureHelp(ctx context.Context, line, character int, trigger *SignatureHelpContext) (*SignatureHelp, error) {
+	params := SignatureHelpParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{
+				URI: ds.uri,
+			},
+			Position: Position{
+				Line:      line,
+				Character: character,
+			},
+		},
+		Context: trigger,
+	}
+
+	var result *SignatureHelp
+	if err := ds.client.Call(ctx, "textDocument/signatureHelp", params, &result); err != nil {
+		return nil, fmt.Errorf("signature help request: %w", err)
+	}
+	if result == nil || len(result.Signatures) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// Active returns the active signature and the index of its active
+// parameter, or -1 when no parameter is active
+func (h *SignatureHelp) Active() (SignatureInformation, int) {
+	sig := h.Signatures[max(0, min(h.ActiveSignature, len(h.Signatures)-1))]
+	param := h.ActiveParameter
+	if sig.ActiveParameter != nil {
+		param = *sig.ActiveParameter
+	}
+	if param < 0 || param >= len(sig.Parameters) {
+		param = -1
+	}
+	return sig, param
+}
+
+// ParameterSpan returns the rune offsets of parameter i in the label of
+// sig
+func (sig SignatureInformation) ParameterSpan(i int) (start, end int, ok bool) {
+	if i < 0 || i >= len(sig.Parameters) {
+		return 0, 0, false
+	}
+	label := []rune(sig.Label)
+
+	var offsets [2]int
+	if err := json.Unmarshal(sig.Parameters[i].Label, &offsets); err == nil {
+		// UTF-16 offsets into the label
+		units, start, end := 0, -1, -1
+		for j, r := range label {
+			if units == offsets[0] {
+				start = j
+			}
+			if units == offsets[1] {
+				end = j
+			}
+			units += utf16.RuneLen(r)
+		}
+		if units == offsets[1] {
+			end = len(label)
+		}
+		return start, end, start >= 0 && end >= start
+	}
+
+	var name string
+	if err := json.Unmarshal(sig.Parameters[i].Label, &name); err != nil || name == "" {
+		return 0, 0, false
+	}
+	// the first occurrence after the earlier parameters
+	from := 0
+	if i > 0 {
+		if _, prevEnd, ok := sig.ParameterSpan(i - 1); ok {
+			from = prevEnd
+		}
+	}
+	idx := strings.Index(string(label[from:]), name)
+	if idx < 0 {
+		return 0, 0, false
+	}
+	start = from + utf8.RuneCountInString(string(label[from:])[:idx])
+	return start, start + utf8.RuneCountInString(name), true
+}
+
+// Completion requests completions at a position in the document. trigger
+// may be nil. The items of a CompletionItem[] result are returned as a
+// complete list.
+func (ds *DocumentSync) Completion(ctx context.Context, line, character int, trigger *CompletionContext) (*CompletionList, error) {
+	params := CompletionParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{
+				URI: ds.uri,
+			},
+			Position: Position{
+				Line:      line,
+				Character: character,
+			},
+		},
+		Context: trigger,
+	}
+
+	var raw json.RawMessage
+	if err := ds.client.Call(ctx, "textDocument/completion", params, &raw); err != nil {
+		return nil, fmt.Errorf("completion request: %w", err)
+	}
+	return parseCompletion(raw)
+}
+
+// parseCompletion decodes the CompletionItem[] | CompletionList | null
+// result of a completion request
+func parseCompletion(raw json.RawMessage) (*CompletionList, error) {
+	raw = json.RawMessage(strings.TrimSpace(string(raw)))
+	list := &CompletionList{}
+	if len(raw) == 0 || string(raw) == "null" {
+		return list, nil
+	}
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &list.Items); err != nil {
+			return nil, fmt.Errorf("completion result: %w", err)
+		}
+		return list, nil
+	}
+	if err := json.Unmarshal(raw, list); err != nil {
+		return nil, fmt.Errorf("completion result: %w", err)
+	}
+	return list, nil
+}
+
+// ResolveCompletion asks the server to fill in the properties of item it
+// left out of the completion result, such as its documentation
+func (ds *DocumentSync) ResolveCompletion(ctx context.Context, item CompletionItem) (CompletionItem, error) {
+	var result CompletionItem
+	if err := ds.client.Call(ctx, "completionItem/resolve", item, &result); err != nil {
+		return item, fmt.Errorf("completion resolve request: %w", err)
+	}
+	return result, nil
+}
+
+// Text returns the text inserted by the item: the text of its edit, its
+// insert text or its label. Snippets are reduced to their plain text.
+func (it CompletionItem) Text() string {
+	text := it.Label
+	switch {
+	case it.TextEdit != nil:
+		text = it.TextEdit.NewText
+	case it.InsertText != "":
+		text = it.InsertText
+	}
+	if it.InsertTextFormat == InsertTextFormatSnippet {
+		text = SnippetText(text)
+	}
+	return text
+}
+
+// SnippetText returns the text of a snippet with placeholders replaced by
+// their default text and tabstops and variables removed
+func SnippetText(snippet string) string {
+	rs := []rune(snippet)
+	var b strings.Builder
+	isName := func(r rune) bool {
+		return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+	}
+
+	// parse writes the text from i up to the closing brace of a placeholder
+	// when nested, and returns the index after it
+	var parse func(i int, nested bool) int
+	parse = func(i int, nested bool) int {
+		for i < len(rs) {
+			r := rs[i]
+			switch {
+			case r == '\\' && i+1 < len(rs):
+				b.WriteRune(rs[i+1])
+				i += 2
+			case r == '}' && nested:
+				return i + 1
+			case r == '$' && i+1 < len(rs) && isName(rs[i+1]):
+				// $1 or $VAR
+				i++
+				for i < len(rs) && isName(rs[i]) {
+					i++
+				}
+			case r == '$' && i+1 < len(rs) && rs[i+1] == '{':
+				j := i + 2
+				for j < len(rs) && isName(rs[j]) {
+					j++
+				}
+				switch {
+				case j < len(rs) && rs[j] == ':':
+					// ${1:default}
+					i = parse(j+1, true)
+				case j < len(rs) && rs[j] == '|':
+					// ${1|first,second|}
+					j++
+					for j < len(rs) && rs[j] != ',' && rs[j] != '|' {
+						b.WriteRune(rs[j])
+						j++
+					}
+					for j < len(rs) && rs[j] != '}' {
+						j++
+					}
+					i = j + 1
+				default:
+					for j < len(rs) && rs[j] != '}' {
+						j++
+					}
+					i = j + 1
+				}
+			default:
+				b.WriteRune(r)
+				i++
+			}
+		}
+		return i
+	}
+	parse(0, false)
+	return b.String()
+}
+
+// References requests the locations referring to the symbol at a position
+// in the document
+func (ds *DocumentSync) References(ctx context.Context, line, character int, includeDeclaration bool) ([]Location, error) {
+	params := ReferenceParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{
+				URI: ds.uri,
+			},
+			Position: Position{
+				Line:      line,
+				Character: character,
+			},
+		},
+		Context: ReferenceContext{IncludeDeclaration: includeDeclaration},
+	}
+
 	var result []Location
-	if err := ds.client.Call("textDocument/definition", params, &result); err != nil {
-		// Try single location format
-		var singleResult Location
-		if err := ds.client.Call("textDocument/definition", params, &singleResult); err != nil {
-			return nil, fmt.Errorf("definition request: %w", err)
+	if err := ds.client.Call(ctx, "textDocument/references", params, &result); err != nil {
+		return nil, fmt.Errorf("references request: %w", err)
+	}
+	return result, nil
+}
+
+// Rename requests the edit renaming the symbol at a position in the
+// document to newName. It returns nil when there is nothing to rename.
+func (ds *DocumentSync) Rename(ctx context.Context, line, character int, newName string) (*WorkspaceEdit, error) {
+	params := RenameParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{
+				URI: ds.uri,
+			},
+			Position: Position{
+				Line:      line,
+				Character: character,
+			},
+		},
+		NewName: newName,
+	}
+
+	var result *WorkspaceEdit
+	if err := ds.client.Call(ctx, "textDocument/rename", params, &result); err != nil {
+		return nil, fmt.Errorf("rename request: %w", err)
+	}
+	return result, nil
+}
+
+// DocumentEdits returns the edits of the workspace edit by document, from
+// either of its forms. Resource operations such as creating or renaming
+// files are not supported.
+func (we *WorkspaceEdit) DocumentEdits() ([]TextDocumentEdit, error) {
+	var edits []TextDocumentEdit
+	for _, raw := range we.DocumentChanges {
+		var op struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(raw, &op); err != nil {
+			return nil, fmt.Errorf("document change: %w", err)
+		}
+		if op.Kind != "" {
+			return nil, fmt.Errorf("unsupported resource operation %q", op.Kind)
+		}
+		var edit TextDocumentEdit
+		if err := json.Unmarshal(raw, &edit); err != nil {
+			return nil, fmt.Errorf("document change: %w", err)
+		}
+		edits = append(edits, edit)
+	}
+	if len(we.DocumentChanges) > 0 {
+		return edits, nil
+	}
+
+	uris := make([]string, 0, len(we.Changes))
+	for uri := range we.Changes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		edits = append(edits, TextDocumentEdit{
+			TextDocument: OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: TextDocumentIdentifier{URI: uri}},
+			Edits:        we.Changes[uri],
+		})
+	}
+	return edits, nil
+}
+
+// CodeActions requests the code actions for a range of the document, with
+// the diagnostics in it
+func (ds *DocumentSync) CodeActions(ctx context.Context, r Range, diagnostics []Diagnostic) ([]CodeAction, error) {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	params := CodeActionParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+		Range:   r,
+		Context: CodeActionContext{Diagnostics: diagnostics},
+	}
+
+	var result []CodeAction
+	if err := ds.client.Call(ctx, "textDocument/codeAction", params, &result); err != nil {
+		return nil, fmt.Errorf("code action request: %w", err)
+	}
+	return result, nil
+}
+
+// ResolveCodeAction asks the server to fill in the edit of action
+func (ds *DocumentSync) ResolveCodeAction(ctx context.Context, action CodeAction) (CodeAction, error) {
+	var result CodeAction
+	if err := ds.client.Call(ctx, "codeAction/resolve", action, &result); err != nil {
+		return action, fmt.Errorf("code action resolve request: %w", err)
+	}
+	return result, nil
+}
+
+// Formatting requests the edits formatting the whole document
+func (ds *DocumentSync) Formatting(ctx context.Context, opts FormattingOptions) ([]TextEdit, error) {
+	params := DocumentFormattingParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+		Options: opts,
+	}
+
+	var result []TextEdit
+	if err := ds.client.Call(ctx, "textDocument/formatting", params, &result); err != nil {
+		return nil, fmt.Errorf("formatting request: %w", err)
+	}
+	return result, nil
+}
+
+// RangeFormatting requests the edits formatting a range of the document
+func (ds *DocumentSync) RangeFormatting(ctx context.Context, r Range, opts FormattingOptions) ([]TextEdit, error) {
+	params := DocumentRangeFormattingParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+		Range:   r,
+		Options: opts,
+	}
+
+	var result []TextEdit
+	if err := ds.client.Call(ctx, "textDocument/rangeFormatting", params, &result); err != nil {
+		return nil, fmt.Errorf("range formatting request: %w", err)
+	}
+	return result, nil
+}
+
+// DocumentSymbols requests the symbols of the document. Servers that
+// answer with a flat SymbolInformation[] get each symbol returned as a
+// DocumentSymbol without children.
+func (ds *DocumentSync) DocumentSymbols(ctx context.Context) ([]DocumentSymbol, error) {
+	params := DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+	}
+
+	var raw json.RawMessage
+	if err := ds.client.Call(ctx, "textDocument/documentSymbol", params, &raw); err != nil {
+		return nil, fmt.Errorf("document symbol request: %w", err)
+	}
+	return parseDocumentSymbols(raw)
+}
+
+// parseDocumentSymbols decodes a documentSymbol result, which is
+// DocumentSymbol[], SymbolInformation[] or null
+func parseDocumentSymbols(raw json.RawMessage) ([]DocumentSymbol, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil || len(items) == 0 {
+		return nil, err
+	}
+	var probe struct {
+		Location *json.RawMessage `json:"location"`
+	}
+	if err := json.Unmarshal(items[0], &probe); err != nil {
+		return nil, err
+	}
+	if probe.Location == nil {
+		var symbols []DocumentSymbol
+		err := json.Unmarshal(raw, &symbols)
+		return symbols, err
+	}
+
+	var infos []SymbolInformation
+	if err := json.Unmarshal(raw, &infos); err != nil {
+		return nil, err
+	}
+	symbols := make([]DocumentSymbol, len(infos))
+	for i, info := range infos {
+		symbols[i] = DocumentSymbol{
+			Name:           info.Name,
+			Detail:         info.ContainerName,
+			Kind:           info.Kind,
+			Range:          info.Location.Range,
+			SelectionRange: info.Location.Range,
+		}
+	}
+	return symbols, nil
+}
+
+// PrepareCallHierarchy requests the call hierarchy items of the symbol
+// at a position in the document, the roots of its incoming and outgoing
+// calls
+func (ds *DocumentSync) PrepareCallHierarchy(ctx context.Context, line, character int) ([]HierarchyItem, error) {
+	var result []HierarchyItem
+	if err := ds.client.Call(ctx, "textDocument/prepareCallHierarchy", ds.positionParams(line, character), &result); err != nil {
+		return nil, fmt.Errorf("prepare call hierarchy request: %w", err)
+	}
+	return result, nil
+}
+
+// PrepareTypeHierarchy requests the type hierarchy items of the symbol at
+// a position in the document, the roots of its supertypes and subtypes
+func (ds *DocumentSync) PrepareTypeHierarchy(ctx context.Context, line, character int) ([]HierarchyItem, error) {
+	var result []HierarchyItem
+	if err := ds.client.Call(ctx, "textDocument/prepareTypeHierarchy", ds.positionParams(line, character), &result); err != nil {
+		return nil, fmt.Errorf("prepare type hierarchy request: %w", err)
+	}
+	return result, nil
+}
+
+func (ds *DocumentSync) positionParams(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+		Position: Position{
+			Line:      line,
+			Character: character,
+		},
+	}
+}
+
+// SemanticToken is a decoded semantic token. Its columns are in the
+// negotiated position encoding; Type and Modifiers are named from the
+// server's legend.
+type SemanticToken struct {
+	Line      int
+	Start     int
+	Length    int
+	Type      string
+	Modifiers []string
+}
+
+// SemanticTokens requests the semantic tokens of the whole document
+func (ds *DocumentSync) SemanticTokens(ctx context.Context) ([]SemanticToken, error) {
+	params := SemanticTokensParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+	}
+
+	var result *SemanticTokens
+	if err := ds.client.Call(ctx, "textDocument/semanticTokens/full", params, &result); err != nil {
+		return nil, fmt.Errorf("semantic tokens request: %w", err)
+	}
+	return ds.decodeSemanticTokens(result), nil
+}
+
+// SemanticTokensRange requests the semantic tokens of a range of the
+// document
+func (ds *DocumentSync) SemanticTokensRange(ctx context.Context, r Range) ([]SemanticToken, error) {
+	params := SemanticTokensRangeParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+		Range: r,
+	}
+
+	var result *SemanticTokens
+	if err := ds.client.Call(ctx, "textDocument/semanticTokens/range", params, &result); err != nil {
+		return nil, fmt.Errorf("semantic tokens range request: %w", err)
+	}
+	return ds.decodeSemanticTokens(result), nil
+}
+
+func (ds *DocumentSync) decodeSemanticTokens(tokens *SemanticTokens) []SemanticToken {
+	if tokens == nil {
+		return nil
+	}
+	var legend SemanticTokensLegend
+	if opts := ds.client.Capabilities().SemanticTokensProvider; opts != nil {
+		legend = opts.Legend
+	}
+	return DecodeSemanticTokens(tokens.Data, legend)
+}
+
+// DecodeSemanticTokens turns the relative encoding of semantic tokens into
+// tokens with absolute positions. Each token is five integers: the line
+// relative to the previous token, the start relative to the previous
+// token's start when on the same line, the length, the type index and the
+// modifier bits. Tokens of types missing from the legend are dropped.
+func DecodeSemanticTokens(data []uint32, legend SemanticTokensLegend) []SemanticToken {
+	tokens := make([]SemanticToken, 0, len(data)/5)
+	line, start := 0, 0
+	for i := 0; i+5 <= len(data); i += 5 {
+		if data[i] > 0 {
+			line += int(data[i])
+			start = 0
+		}
+		start += int(data[i+1])
+		typ := int(data[i+3])
+		if typ >= len(legend.TokenTypes) {
+			continue
 		}
-		result = []Location{singleResult}
+		tok := SemanticToken{
+			Line:   line,
+			Start:  start,
+			Length: int(data[i+2]),
+			Type:   legend.TokenTypes[typ],
+		}
+		for bit, mod := range legend.TokenModifiers {
+			if data[i+4]&(1<<bit) != 0 {
+				tok.Modifiers = append(tok.Modifiers, mod)
+			}
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// InlayHints requests the inlay hints of a range of the document
+func (ds *DocumentSync) InlayHints(ctx context.Context, r Range) ([]InlayHint, error) {
+	params := InlayHintParams{
+		TextDocument: TextDocumentIdentifier{
+			URI: ds.uri,
+		},
+		Range: r,
 	}
 
+	var result []InlayHint
+	if err := ds.client.Call(ctx, "textDocument/inlayHint", params, &result); err != nil {
+		return nil, fmt.Errorf("inlay hint request: %w", err)
+	}
 	return result, nil
 }
 
+// PositionAt converts rune offset pos in buf into an LSP position with
+// columns measured in enc units.
+func PositionAt(buf *buffer.Buffer, pos int, enc buffer.Encoding) Position {
+	line, col := buf.LineCol(pos, enc)
+	return Position{Line: line, Character: col}
+}
+
+// OffsetAt converts an LSP position with columns in enc units into a rune
+// offset in buf.
+func OffsetAt(buf *buffer.Buffer, p Position, enc buffer.Encoding) int {
+	return buf.PosFromLineCol(p.Line, p.Character, enc)
+}
+
+// Position converts rune offset pos in buf into a position using the
+// client's negotiated encoding.
+func (ds *DocumentSync) Position(buf *buffer.Buffer, pos int) Position {
+	return PositionAt(buf, pos, ds.client.PositionEncoding())
+}
+
+// Encoding returns the position encoding negotiated with the server
+func (ds *DocumentSync) Encoding() buffer.Encoding {
+	return ds.client.PositionEncoding()
+}
+
+// Offset converts a server position into a rune offset in buf.
+func (ds *DocumentSync) Offset(buf *buffer.Buffer, p Position) int {
+	return OffsetAt(buf, p, ds.client.PositionEncoding())
+}
+
+// languageIDs maps the filetype names that differ from their LSP language
+// identifier
+var languageIDs = map[string]string{
+	"shell": "shellscript",
+	"sh":    "shellscript",
+	"bash":  "shellscript",
+	"zsh":   "shellscript",
+	"tsx":   "typescriptreact",
+	"jsx":   "javascriptreact",
+	"make":  "makefile",
+	"cs":    "csharp",
+}
+
+// LanguageID returns the LSP language identifier of a document: the one
+// of its filetype when known, else one derived from the extension
+func LanguageID(filetype, filename string) string {
+	if filetype == "" {
+		return GetLanguageID(filename)
+	}
+	if id, ok := languageIDs[filetype]; ok {
+		return id
+	}
+	return filetype
+}
+
 // GetLanguageID returns the language ID for a file extension
 func GetLanguageID(filename string) string {
 	ext := filepath.Ext(filename)
@@ -116,10 +933,14 @@ func GetLanguageID(filename string) string {
 		return "go"
 	case ".py":
 		return "python"
-	case ".js", ".jsx":
+	case ".js":
 		return "javascript"
-	case ".ts", ".tsx":
+	case ".jsx":
+		return "javascriptreact"
+	case ".ts":
 		return "typescript"
+	case ".tsx":
+		return "typescriptreact"
 	case ".rs":
 		return "rust"
 	case ".c":
@@ -128,6 +949,8 @@ func GetLanguageID(filename string) string {
 		return "cpp"
 	case ".java":
 		return "java"
+	case ".sh", ".bash", ".zsh":
+		return "shellscript"
 	default:
 		return ""
 	}
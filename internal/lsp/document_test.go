@@ -0,0 +1,323 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseLocations(t *testing.T) {
+	loc := `{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":4}}}`
+	link := `{"targetUri":"file:///b.go","targetRange":{"start":{"line":0,"character":0},"end":{"line":9,"character":0}},` +
+		`"targetSelectionRange":{"start":{"line":3,"character":5},"end":{"line":3,"character":8}}}`
+
+	tests := []struct {
+		name string
+		raw  string
+		want []Location
+	}{
+		{"null", `null`, nil},
+		{"single", loc, []Location{{URI: "file:///a.go", Range: Range{Start: Position{1, 2}, End: Position{1, 4}}}}},
+		{"list", "[" + loc + "]", []Location{{URI: "file:///a.go", Range: Range{Start: Position{1, 2}, End: Position{1, 4}}}}},
+		{"links", "[" + link + "]", []Location{{URI: "file:///b.go", Range: Range{Start: Position{3, 5}, End: Position{3, 8}}}}},
+	}
+	for _, tt := range tests {
+		got, err := parseLocations(json.RawMessage(tt.raw))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestHoverText(t *testing.T) {
+	tests := map[string]string{
+		`"plain"`:                                    "plain",
+		`{"kind":"markdown","value":"**bold**"}`:     "**bold**",
+		`{"language":"go","value":"func f()"}`:       "```go\nfunc f()\n```",
+		`["doc", {"language":"go","value":"var x"}]`: "doc\n\n```go\nvar x\n```",
+	}
+	for raw, want := range tests {
+		if got := HoverText(json.RawMessage(raw)); got != want {
+			t.Errorf("HoverText(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestURIs(t *testing.T) {
+	path := "/home/me/my project/main.go"
+	uri := URIFromPath(path)
+	if uri != "file:///home/me/my%20project/main.go" {
+		t.Errorf("URIFromPath = %q", uri)
+	}
+	if got := PathFromURI(uri); got != path {
+		t.Errorf("PathFromURI = %q, want %q", got, path)
+	}
+}
+
+func TestSupportUnmarshal(t *testing.T) {
+	var caps ServerCapabilities
+	if err := json.Unmarshal([]byte(`{"hoverProvider":true,"definitionProvider":{"workDoneProgress":true}}`), &caps); err != nil {
+		t.Fatal(err)
+	}
+	if !caps.HoverProvider || !caps.DefinitionProvider {
+		t.Errorf("got %+v", caps)
+	}
+
+	for data, want := range map[string]CodeActionSupport{
+		`{}`:                           {},
+		`{"codeActionProvider":false}`: {},
+		`{"codeActionProvider":true}`:  {Supported: true},
+		`{"codeActionProvider":{"resolveProvider":true}}`: {Supported: true, ResolveProvider: true},
+	} {
+		var caps ServerCapabilities
+		if err := json.Unmarshal([]byte(data), &caps); err != nil {
+			t.Fatal(err)
+		}
+		if caps.CodeActionProvider != want {
+			t.Errorf("%s: got %+v", data, caps.CodeActionProvider)
+		}
+	}
+
+	for data, want := range map[string]TextDocumentSyncKind{
+		`{}`:                                TextDocumentSyncNone,
+		`{"textDocumentSync":1}`:            TextDocumentSyncFull,
+		`{"textDocumentSync":{"change":2}}`: TextDocumentSyncIncremental,
+		`{"textDocumentSync":{"openClose":true}}`: TextDocumentSyncNone,
+	} {
+		var caps ServerCapabilities
+		if err := json.Unmarshal([]byte(data), &caps); err != nil {
+			t.Fatal(err)
+		}
+		if caps.TextDocumentSync.Change != want {
+			t.Errorf("%s: got %+v", data, caps.TextDocumentSync)
+		}
+	}
+}
+
+func TestWorkspaceEditDocumentEdits(t *testing.T) {
+	var we WorkspaceEdit
+	data := `{"changes":{"file:///b.go":[{"range":{"start":{"line":0,"character":0},"end":{"line":0,"character":1}},"newText":"x"}],` +
+		`"file:///a.go":[]}}`
+	if err := json.Unmarshal([]byte(data), &we); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := we.DocumentEdits()
+	if err != nil || len(docs) != 2 || docs[0].TextDocument.URI != "file:///a.go" || docs[1].Edits[0].NewText != "x" {
+		t.Fatalf("changes = %+v, %v", docs, err)
+	}
+	if docs[0].TextDocument.Version != nil {
+		t.Error("changes should have no version")
+	}
+
+	// documentChanges take precedence
+	data = `{"changes":{"file:///b.go":[]},"documentChanges":[{"textDocument":{"uri":"file:///c.go","version":3},"edits":[]}]}`
+	we = WorkspaceEdit{}
+	if err := json.Unmarshal([]byte(data), &we); err != nil {
+		t.Fatal(err)
+	}
+	docs, err = we.DocumentEdits()
+	if err != nil || len(docs) != 1 || docs[0].TextDocument.URI != "file:///c.go" || *docs[0].TextDocument.Version != 3 {
+		t.Fatalf("documentChanges = %+v, %v", docs, err)
+	}
+
+	we = WorkspaceEdit{}
+	if err := json.Unmarshal([]byte(`{"documentChanges":[{"kind":"create","uri":"file:///d.go"}]}`), &we); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := we.DocumentEdits(); err == nil {
+		t.Error("resource operations should be an error")
+	}
+}
+
+func TestCodeActionUnmarshal(t *testing.T) {
+	var actions []CodeAction
+	data := `[{"title":"Organize imports","command":"source.organize","arguments":["x"]},` +
+		`{"title":"Fix","kind":"quickfix","isPreferred":true,"command":{"title":"Fix","command":"fix"},"data":1}]`
+	if err := json.Unmarshal([]byte(data), &actions); err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 2 {
+		t.Fatalf("got %d actions", len(actions))
+	}
+	if a := actions[0]; a.Title != "Organize imports" || a.Command == nil || a.Command.Command != "source.organize" || a.Kind != "" {
+		t.Errorf("bare command = %+v", a)
+	}
+	if a := actions[1]; a.Kind != CodeActionQuickFix || !a.IsPreferred || a.Command == nil || a.Command.Command != "fix" {
+		t.Errorf("code action = %+v", a)
+	}
+
+	// resolving sends the action back as the server sent it
+	out, err := json.Marshal(actions[1])
+	if err != nil || !strings.Contains(string(out), `"data":1`) {
+		t.Errorf("marshaled = %s, %v", out, err)
+	}
+}
+
+func TestSignatureParameterSpan(t *testing.T) {
+	var help SignatureHelp
+	data := `{"signatures":[{"label":"f(a int, aa int)","parameters":[{"label":"a int"},{"label":"aa int"}]},` +
+		`{"label":"g(é string, n int)","parameters":[{"label":[2,10]},{"label":[12,17]}],"activeParameter":1}],` +
+		`"activeSignature":1,"activeParameter":0}`
+	if err := json.Unmarshal([]byte(data), &help); err != nil {
+		t.Fatal(err)
+	}
+
+	sig, param := help.Active()
+	if sig.Label != "g(é string, n int)" || param != 1 {
+		t.Fatalf("active = %q, %d", sig.Label, param)
+	}
+	if start, end, ok := sig.ParameterSpan(0); !ok || string([]rune(sig.Label)[start:end]) != "é string" {
+		t.Errorf("offset label: %d-%d", start, end)
+	}
+	if start, end, ok := sig.ParameterSpan(1); !ok || string([]rune(sig.Label)[start:end]) != "n int" {
+		t.Errorf("offset label at the end: %d-%d", start, end)
+	}
+
+	// string labels are searched after the previous parameter
+	f := help.Signatures[0]
+	if start, end, ok := f.ParameterSpan(1); !ok || start != 9 || end != 15 {
+		t.Errorf("string label: %d-%d", start, end)
+	}
+	if _, _, ok := f.ParameterSpan(2); ok {
+		t.Error("no third parameter")
+	}
+}
+
+func TestParseCompletion(t *testing.T) {
+	list, err := parseCompletion(json.RawMessage(`[{"label":"a"},{"label":"b","kind":3}]`))
+	if err != nil || len(list.Items) != 2 || list.Items[1].Kind.String() != "function" {
+		t.Fatalf("array: %+v, %v", list, err)
+	}
+	list, err = parseCompletion(json.RawMessage(`{"isIncomplete":true,"items":[{"label":"c","data":{"id":7},"command":{"title":"x"}}]}`))
+	if err != nil || !list.IsIncomplete || len(list.Items) != 1 {
+		t.Fatalf("list: %+v, %v", list, err)
+	}
+	// items go back to the server as they came, with the unknown fields
+	data, _ := json.Marshal(list.Items[0])
+	if string(data) != `{"label":"c","data":{"id":7},"command":{"title":"x"}}` {
+		t.Errorf("marshalled item = %s", data)
+	}
+	if list, err := parseCompletion(json.RawMessage(`null`)); err != nil || len(list.Items) != 0 {
+		t.Errorf("null: %+v, %v", list, err)
+	}
+}
+
+func TestCompletionItemText(t *testing.T) {
+	tests := []struct {
+		item CompletionItem
+		want string
+	}{
+		{CompletionItem{Label: "Println"}, "Println"},
+		{CompletionItem{Label: "Println", InsertText: "Println()"}, "Println()"},
+		{CompletionItem{Label: "x", InsertText: "y", TextEdit: &TextEdit{NewText: "z"}}, "z"},
+		{CompletionItem{Label: "f", InsertText: "f(${1:a int}, ${2:b})$0", InsertTextFormat: InsertTextFormatSnippet}, "f(a int, b)"},
+		{CompletionItem{Label: "g", InsertText: "g(${1:x[${2:i}]}) \\$5 ${3|one,two|} $TM_FILENAME ${4}", InsertTextFormat: InsertTextFormatSnippet}, "g(x[i]) $5 one  "},
+	}
+	for _, tt := range tests {
+		if got := tt.item.Text(); got != tt.want {
+			t.Errorf("Text(%+v) = %q, want %q", tt.item, got, tt.want)
+		}
+	}
+}
+
+func TestParseDocumentSymbols(t *testing.T) {
+	nested := `[{"name":"T","kind":23,"range":{"start":{"line":0,"character":0},"end":{"line":3,"character":1}},
+		"selectionRange":{"start":{"line":0,"character":5},"end":{"line":0,"character":6}},
+		"children":[{"name":"x","kind":8,"range":{"start":{"line":1,"character":1},"end":{"line":1,"character":2}},
+		"selectionRange":{"start":{"line":1,"character":1},"end":{"line":1,"character":2}}}]}]`
+	symbols, err := parseDocumentSymbols(json.RawMessage(nested))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) != 1 || symbols[0].Kind.String() != "struct" || len(symbols[0].Children) != 1 ||
+		symbols[0].Children[0].Name != "x" || symbols[0].SelectionRange.Start.Character != 5 {
+		t.Errorf("nested symbols = %+v", symbols)
+	}
+
+	flat := `[{"name":"main","kind":12,"containerName":"pkg","location":{"uri":"file:///a.go",
+		"range":{"start":{"line":4,"character":0},"end":{"line":6,"character":1}}}}]`
+	symbols, err = parseDocumentSymbols(json.RawMessage(flat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) != 1 || symbols[0].Name != "main" || symbols[0].Detail != "pkg" ||
+		symbols[0].Range.End.Line != 6 || symbols[0].SelectionRange.Start.Line != 4 {
+		t.Errorf("flat symbols = %+v", symbols)
+	}
+
+	for _, empty := range []string{"null", "[]"} {
+		if symbols, err := parseDocumentSymbols(json.RawMessage(empty)); err != nil || symbols != nil {
+			t.Errorf("%s: %v, %v", empty, symbols, err)
+		}
+	}
+}
+
+func TestDecodeSemanticTokens(t *testing.T) {
+	legend := SemanticTokensLegend{
+		TokenTypes:     []string{"function", "parameter"},
+		TokenModifiers: []string{"declaration", "readonly"},
+	}
+	data := []uint32{
+		2, 5, 4, 0, 1, // line 2, col 5: function, declaration
+		0, 5, 1, 1, 0, // same line, col 10: parameter
+		0, 3, 2, 7, 0, // unknown type, dropped
+		1, 2, 3, 1, 3, // line 3, col 2: parameter, declaration and readonly
+	}
+	got := DecodeSemanticTokens(data, legend)
+	want := []SemanticToken{
+		{Line: 2, Start: 5, Length: 4, Type: "function", Modifiers: []string{"declaration"}},
+		{Line: 2, Start: 10, Length: 1, Type: "parameter"},
+		{Line: 3, Start: 2, Length: 3, Type: "parameter", Modifiers: []string{"declaration", "readonly"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("tokens = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Line != w.Line || g.Start != w.Start || g.Length != w.Length || g.Type != w.Type ||
+			strings.Join(g.Modifiers, ",") != strings.Join(w.Modifiers, ",") {
+			t.Errorf("token %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestInlayHintLabel(t *testing.T) {
+	var hints []InlayHint
+	raw := `[{"position":{"line":0,"character":4},"label":"int","paddingLeft":true},
+		{"position":{"line":1,"character":2},"label":[{"value":"name"},{"value":":"}],"kind":2,"paddingRight":true}]`
+	if err := json.Unmarshal([]byte(raw), &hints); err != nil {
+		t.Fatal(err)
+	}
+	if len(hints) != 2 || hints[0].Text() != " int" || hints[1].Text() != "name: " || hints[1].Kind != InlayHintKindParameter {
+		t.Errorf("hints = %+v", hints)
+	}
+}
+
+func TestLanguageID(t *testing.T) {
+	tests := []struct {
+		filetype, filename, want string
+	}{
+		{"go", "main.go", "go"},
+		{"shell", "run.sh", "shellscript"},
+		{"bash", "run", "shellscript"},
+		{"typescriptreact", "app.tsx", "typescriptreact"},
+		{"tsx", "app.tsx", "typescriptreact"},
+		{"", "app.tsx", "typescriptreact"},
+		{"", "app.jsx", "javascriptreact"},
+		{"", "run.zsh", "shellscript"},
+		{"", "notes.txt", ""},
+	}
+	for _, tt := range tests {
+		if got := LanguageID(tt.filetype, tt.filename); got != tt.want {
+			t.Errorf("LanguageID(%q, %q) = %q, want %q", tt.filetype, tt.filename, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxLogLines   = 2000 // lines a Log keeps
+	maxLoggedBody = 300  // bytes of a message kept in the log
+)
+
+// Log keeps the last lines a client logged: what the server wrote to
+// stderr and the messages exchanged with it. It is safe for concurrent
+// use.
+type Log struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// Printf adds a line with the time to the log
+func (l *Log) Printf(format string, args ...interface{}) {
+	line := time.Now().Format("15:04:05.000") + " " + fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+	if len(l.lines) > maxLogLines+maxLogLines/4 {
+		l.lines = append([]string(nil), l.lines[len(l.lines)-maxLogLines:]...)
+	}
+}
+
+// Lines returns the lines of the log, oldest first
+func (l *Log) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	start := max(0, len(l.lines)-maxLogLines)
+	return append([]string(nil), l.lines[start:]...)
+}
+
+// abbreviate returns the start of the message data for the log
+func abbreviate(data []byte) string {
+	if len(data) <= maxLoggedBody {
+		return string(data)
+	}
+	n := maxLoggedBody
+	for n > 0 && !utf8.RuneStart(data[n]) {
+		n--
+	}
+	return fmt.Sprintf("%s… (%d bytes)", data[:n], len(data))
+}
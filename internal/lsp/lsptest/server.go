@@ -0,0 +1,1185 @@
+// Package lsptest implements a small language server for tests. It keeps
+// the text of open documents, synced incrementally unless VB_LSPTEST_SYNC
+// is "full", reports every synchronization notification and change back
+// to the client as a window/logMessage and answers hover and
+// definition requests from the identifiers in the text, and signature
+// help requests from the go-style func declarations. Completion offers
+// the identifiers of the document, and the members of a few packages after
+// "fmt." and the like with an edit importing the package. References and
+// renames cover the open documents and the .go files under the root
+// directory. The words ERROR, WARNING, INFO and HINT in a document are
+// published as diagnostics of that severity; ERROR has a code action
+// replacing it and WARNING one whose command removes it with a
+// workspace/applyEdit request. Formatting trims trailing whitespace; when
+// VB_LSPTEST_FORMAT is "apply" it first inserts a line with a
+// workspace/applyEdit request and answers once the client did.
+// Document and workspace symbols are the funcs, methods, types and struct
+// fields declared at the start of lines. Semantic tokens mark the declared
+// funcs, deprecated after a "// Deprecated:" line, and their parameters;
+// semanticTokens/full is not offered when VB_LSPTEST_SEMANTIC is "range".
+// Inlay hints name the parameters of the arguments of calls to the funcs.
+// The call hierarchy links the funcs by the calls in their bodies, the
+// type hierarchy the types by the struct fields embedding one in another.
+// The server asks for its "lsptest" settings once initialized, logs the
+// responses and cancellations it gets, writes a line to stderr on
+// initialize and never answers lsptest/hang, nor shutdown when
+// VB_LSPTEST_HANG is "shutdown".
+package lsptest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+)
+
+// Server is the state of one running test server
+type Server struct {
+	w        io.Writer
+	nextID   int                    // id of the last request to the client
+	root     string                 // root directory from initialize
+	docs     map[string]string      // text by URI
+	versions map[string]int         // version by URI
+	replies  map[string]interface{} // held back responses, by the request they wait for
+}
+
+type message struct {
+	ID     *json.RawMessage `json:"id,omitempty"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params,omitempty"`
+}
+
+// Serve runs a server reading requests from r and writing to w until it
+// receives exit or r is closed.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &Server{w: w, docs: make(map[string]string), versions: make(map[string]int), replies: make(map[string]interface{})}
+	tr := textproto.NewReader(bufio.NewReader(r))
+	for {
+		header, err := tr.ReadMIMEHeader()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		n, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return fmt.Errorf("content length: %w", err)
+		}
+		body := make([]byte, n)
+		if _, err := io.ReadFull(tr.R, body); err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if msg.Method == "" {
+			s.log("response %s", body)
+			var id string
+			if msg.ID != nil && json.Unmarshal(*msg.ID, &id) == nil && s.replies[id] != nil {
+				if err := s.write(s.replies[id]); err != nil {
+					return err
+				}
+				delete(s.replies, id)
+			}
+			continue
+		}
+		if msg.Method == "lsptest/hang" || msg.Method == "shutdown" && os.Getenv("VB_LSPTEST_HANG") == "shutdown" {
+			continue
+		}
+		result, rpcErr := s.handle(msg.Method, msg.Params)
+		if msg.ID == nil {
+			continue
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID}
+		if rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		if msg.Method == "textDocument/formatting" && os.Getenv("VB_LSPTEST_FORMAT") == "apply" {
+			// the answer waits for the client to apply an edit first
+			var p lsp.DocumentFormattingParams
+			_ = json.Unmarshal(msg.Params, &p)
+			s.replies[s.request("workspace/applyEdit", lsp.ApplyWorkspaceEditParams{
+				Label: "format",
+				Edit: lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+					p.TextDocument.URI: {{NewText: "// formatted\n"}},
+				}},
+			})] = resp
+			continue
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) write(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// request sends a request to the client with a string id and returns the
+// id; its response is logged when it arrives
+func (s *Server) request(method string, params interface{}) string {
+	s.nextID++
+	id := fmt.Sprintf("lsptest-%d", s.nextID)
+	_ = s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+	return id
+}
+
+// log sends a window/logMessage notification
+func (s *Server) log(format string, args ...interface{}) {
+	_ = s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "window/logMessage",
+		"params":  map[string]interface{}{"type": 3, "message": fmt.Sprintf(format, args...)},
+	})
+}
+
+func (s *Server) handle(method string, params json.RawMessage) (interface{}, *lsp.ResponseError) {
+	switch method {
+	case "initialize":
+		var p lsp.InitializeParams
+		_ = json.Unmarshal(params, &p)
+		if p.RootURI != "" {
+			s.root = lsp.PathFromURI(p.RootURI)
+		}
+		fmt.Fprintf(os.Stderr, "lsptest: initialize %s\n", s.root)
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   s.syncKind(),
+				"hoverProvider":      true,
+				"definitionProvider": map[string]interface{}{},
+				"signatureHelpProvider": map[string]interface{}{
+					"triggerCharacters":   []string{"("},
+					"retriggerCharacters": []string{","},
+				},
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+					"resolveProvider":   true,
+				},
+				"referencesProvider":              true,
+				"renameProvider":                  map[string]interface{}{},
+				"codeActionProvider":              map[string]interface{}{"resolveProvider": true},
+				"documentFormattingProvider":      true,
+				"documentRangeFormattingProvider": true,
+				"documentSymbolProvider":          true,
+				"workspaceSymbolProvider":         true,
+				"semanticTokensProvider": map[string]interface{}{
+					"legend": lsp.SemanticTokensLegend{
+						TokenTypes:     tokenTypes,
+						TokenModifiers: tokenModifiers,
+					},
+					"full":  os.Getenv("VB_LSPTEST_SEMANTIC") != "range",
+					"range": true,
+				},
+				"inlayHintProvider":     map[string]interface{}{},
+				"callHierarchyProvider": true,
+				"typeHierarchyProvider": true,
+				"executeCommandProvider": map[string]interface{}{
+					"commands": []string{"lsptest.remove"},
+				},
+			},
+			"serverInfo": map[string]interface{}{"name": "lsptest"},
+		}, nil
+
+	case "initialized":
+		s.request("workspace/configuration", lsp.ConfigurationParams{
+			Items: []lsp.ConfigurationItem{{Section: "lsptest"}},
+		})
+		return nil, nil
+
+	case "shutdown":
+		return nil, nil
+
+	case "$/cancelRequest":
+		var p lsp.CancelParams
+		_ = json.Unmarshal(params, &p)
+		s.log("cancel %d", p.ID)
+		return nil, nil
+
+	case "workspace/executeCommand":
+		// lsptest.remove deletes the range of a document
+		var p lsp.ExecuteCommandParams
+		_ = json.Unmarshal(params, &p)
+		if p.Command != "lsptest.remove" || len(p.Arguments) != 2 {
+			return nil, &lsp.ResponseError{Code: -32602, Message: "unknown command " + p.Command}
+		}
+		var uri string
+		var r lsp.Range
+		_ = json.Unmarshal(p.Arguments[0], &uri)
+		_ = json.Unmarshal(p.Arguments[1], &r)
+		s.request("workspace/applyEdit", lsp.ApplyWorkspaceEditParams{
+			Label: "remove",
+			Edit:  lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{uri: {{Range: r}}}},
+		})
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p lsp.DidOpenTextDocumentParams
+		_ = json.Unmarshal(params, &p)
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		s.versions[p.TextDocument.URI] = p.TextDocument.Version
+		s.log("didOpen %s %s %d", lsp.PathFromURI(p.TextDocument.URI), p.TextDocument.LanguageID, p.TextDocument.Version)
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var p lsp.DidChangeTextDocumentParams
+		_ = json.Unmarshal(params, &p)
+		s.log("didChange %s %d", lsp.PathFromURI(p.TextDocument.URI), p.TextDocument.Version)
+		for _, ch := range p.ContentChanges {
+			if ch.Range == nil {
+				s.docs[p.TextDocument.URI] = ch.Text
+				s.log("change full")
+				continue
+			}
+			text := s.docs[p.TextDocument.URI]
+			start, end := offsetOf(text, ch.Range.Start), offsetOf(text, ch.Range.End)
+			s.docs[p.TextDocument.URI] = text[:start] + ch.Text + text[max(start, end):]
+			r := ch.Range
+			s.log("change %d:%d-%d:%d %q", r.Start.Line, r.Start.Character, r.End.Line, r.End.Character, ch.Text)
+		}
+		s.versions[p.TextDocument.URI] = p.TextDocument.Version
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/didSave":
+		var p lsp.DidSaveTextDocumentParams
+		_ = json.Unmarshal(params, &p)
+		s.log("didSave %s", lsp.PathFromURI(p.TextDocument.URI))
+		return nil, nil
+
+	case "textDocument/didClose":
+		var p lsp.DidCloseTextDocumentParams
+		_ = json.Unmarshal(params, &p)
+		delete(s.docs, p.TextDocument.URI)
+		delete(s.versions, p.TextDocument.URI)
+		s.log("didClose %s", lsp.PathFromURI(p.TextDocument.URI))
+		return nil, nil
+
+	case "textDocument/hover":
+		var p lsp.TextDocumentPositionParams
+		_ = json.Unmarshal(params, &p)
+		word, _ := s.wordAt(p.TextDocument.URI, p.Position)
+		if word == "" {
+			return nil, nil
+		}
+		return map[string]interface{}{
+			"contents": lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "`" + word + "`"},
+		}, nil
+
+	case "textDocument/definition":
+		var p lsp.TextDocumentPositionParams
+		_ = json.Unmarshal(params, &p)
+		word, _ := s.wordAt(p.TextDocument.URI, p.Position)
+		if word == "" {
+			return nil, nil
+		}
+		r, ok := s.firstWord(p.TextDocument.URI, word)
+		if !ok {
+			return nil, nil
+		}
+		return []lsp.Location{{URI: p.TextDocument.URI, Range: r}}, nil
+
+	case "textDocument/signatureHelp":
+		var p lsp.SignatureHelpParams
+		_ = json.Unmarshal(params, &p)
+		return s.signatureHelp(p.TextDocument.URI, p.Position), nil
+
+	case "textDocument/completion":
+		var p lsp.CompletionParams
+		_ = json.Unmarshal(params, &p)
+		return s.completion(p.TextDocument.URI, p.Position), nil
+
+	case "completionItem/resolve":
+		// keep the fields of the item, documenting what its data names
+		var item map[string]interface{}
+		_ = json.Unmarshal(params, &item)
+		data, _ := item["data"].(map[string]interface{})
+		name, _ := data["name"].(string)
+		item["documentation"] = lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "Documentation of `" + name + "`."}
+		return item, nil
+
+	case "textDocument/references":
+		var p lsp.ReferenceParams
+		_ = json.Unmarshal(params, &p)
+		word, _ := s.wordAt(p.TextDocument.URI, p.Position)
+		if word == "" {
+			return nil, nil
+		}
+		locs := []lsp.Location{}
+		for _, f := range s.files() {
+			for _, r := range wordRanges(f.text, word) {
+				locs = append(locs, lsp.Location{URI: f.uri, Range: r})
+			}
+		}
+		return locs, nil
+
+	case "textDocument/rename":
+		var p lsp.RenameParams
+		_ = json.Unmarshal(params, &p)
+		word, _ := s.wordAt(p.TextDocument.URI, p.Position)
+		if word == "" {
+			return nil, &lsp.ResponseError{Code: -32803, Message: "no identifier to rename"}
+		}
+		changes := []lsp.TextDocumentEdit{}
+		for _, f := range s.files() {
+			var edits []lsp.TextEdit
+			for _, r := range wordRanges(f.text, word) {
+				edits = append(edits, lsp.TextEdit{Range: r, NewText: p.NewName})
+			}
+			if len(edits) == 0 {
+				continue
+			}
+			id := lsp.OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: lsp.TextDocumentIdentifier{URI: f.uri}}
+			if v, ok := s.versions[f.uri]; ok {
+				id.Version = &v
+			}
+			changes = append(changes, lsp.TextDocumentEdit{TextDocument: id, Edits: edits})
+		}
+		return map[string]interface{}{"documentChanges": changes}, nil
+
+	case "textDocument/codeAction":
+		var p lsp.CodeActionParams
+		_ = json.Unmarshal(params, &p)
+		return s.codeActions(p), nil
+
+	case "codeAction/resolve":
+		// the uppercase action gets its edit
+		var action map[string]interface{}
+		_ = json.Unmarshal(params, &action)
+		if action["data"] == nil {
+			return action, nil
+		}
+		var data struct {
+			URI   string    `json:"uri"`
+			Range lsp.Range `json:"range"`
+			Word  string    `json:"word"`
+		}
+		raw, _ := json.Marshal(action["data"])
+		_ = json.Unmarshal(raw, &data)
+		action["edit"] = lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+			data.URI: {{Range: data.Range, NewText: strings.ToUpper(data.Word)}},
+		}}
+		return action, nil
+
+	case "textDocument/formatting":
+		var p lsp.DocumentFormattingParams
+		_ = json.Unmarshal(params, &p)
+		return s.format(p.TextDocument.URI, 0, -1), nil
+
+	case "textDocument/documentSymbol":
+		var p lsp.DocumentSymbolParams
+		_ = json.Unmarshal(params, &p)
+		return documentSymbols(s.docs[p.TextDocument.URI]), nil
+
+	case "workspace/symbol":
+		var p lsp.WorkspaceSymbolParams
+		_ = json.Unmarshal(params, &p)
+		symbols := []lsp.SymbolInformation{}
+		var add func(uri, container string, docSymbols []lsp.DocumentSymbol)
+		add = func(uri, container string, docSymbols []lsp.DocumentSymbol) {
+			for _, sym := range docSymbols {
+				if strings.Contains(strings.ToLower(sym.Name), strings.ToLower(p.Query)) {
+					symbols = append(symbols, lsp.SymbolInformation{
+						Name:          sym.Name,
+						Kind:          sym.Kind,
+						Location:      lsp.Location{URI: uri, Range: sym.SelectionRange},
+						ContainerName: container,
+					})
+				}
+				add(uri, sym.Name, sym.Children)
+			}
+		}
+		for _, f := range s.files() {
+			add(f.uri, "", documentSymbols(f.text))
+		}
+		return symbols, nil
+
+	case "textDocument/semanticTokens/full":
+		var p lsp.SemanticTokensParams
+		_ = json.Unmarshal(params, &p)
+		return lsp.SemanticTokens{Data: semanticTokens(s.docs[p.TextDocument.URI], 0, -1)}, nil
+
+	case "textDocument/semanticTokens/range":
+		var p lsp.SemanticTokensRangeParams
+		_ = json.Unmarshal(params, &p)
+		s.log("semantic tokens %d-%d", p.Range.Start.Line, p.Range.End.Line)
+		return lsp.SemanticTokens{Data: semanticTokens(s.docs[p.TextDocument.URI], p.Range.Start.Line, p.Range.End.Line)}, nil
+
+	case "textDocument/inlayHint":
+		var p lsp.InlayHintParams
+		_ = json.Unmarshal(params, &p)
+		return inlayHints(s.docs[p.TextDocument.URI], p.Range.Start.Line, p.Range.End.Line), nil
+
+	case "textDocument/prepareCallHierarchy", "textDocument/prepareTypeHierarchy":
+		var p lsp.TextDocumentPositionParams
+		_ = json.Unmarshal(params, &p)
+		word, _ := s.wordAt(p.TextDocument.URI, p.Position)
+		kind := lsp.SymbolKind(12) // function
+		if method == "textDocument/prepareTypeHierarchy" {
+			kind = 23 // struct
+		}
+		items := []lsp.HierarchyItem{}
+		for _, it := range s.hierarchyItems() {
+			if it.Name == word && it.Kind == kind {
+				items = append(items, it.HierarchyItem)
+			}
+		}
+		return items, nil
+
+	case "callHierarchy/incomingCalls":
+		var p lsp.HierarchyItemParams
+		_ = json.Unmarshal(params, &p)
+		calls := []lsp.CallHierarchyIncomingCall{}
+		for _, it := range s.hierarchyItems() {
+			if ranges := it.calls(p.Item.Name); it.Kind == 12 && len(ranges) > 0 {
+				calls = append(calls, lsp.CallHierarchyIncomingCall{From: it.HierarchyItem, FromRanges: ranges})
+			}
+		}
+		return calls, nil
+
+	case "callHierarchy/outgoingCalls":
+		var p lsp.HierarchyItemParams
+		_ = json.Unmarshal(params, &p)
+		calls := []lsp.CallHierarchyOutgoingCall{}
+		items := s.hierarchyItems()
+		for _, caller := range items {
+			if caller.URI != p.Item.URI || caller.Name != p.Item.Name {
+				continue
+			}
+			for _, it := range items {
+				if ranges := caller.calls(it.Name); it.Kind == 12 && len(ranges) > 0 {
+					calls = append(calls, lsp.CallHierarchyOutgoingCall{To: it.HierarchyItem, FromRanges: ranges})
+				}
+			}
+		}
+		return calls, nil
+
+	case "typeHierarchy/supertypes":
+		var p lsp.HierarchyItemParams
+		_ = json.Unmarshal(params, &p)
+		types := []lsp.HierarchyItem{}
+		items := s.hierarchyItems()
+		for _, it := range items {
+			if it.URI != p.Item.URI || it.Name != p.Item.Name {
+				continue
+			}
+			for _, super := range items {
+				if super.Kind == 23 && slices.Contains(it.embedded(), super.Name) {
+					types = append(types, super.HierarchyItem)
+				}
+			}
+		}
+		return types, nil
+
+	case "typeHierarchy/subtypes":
+		var p lsp.HierarchyItemParams
+		_ = json.Unmarshal(params, &p)
+		types := []lsp.HierarchyItem{}
+		for _, it := range s.hierarchyItems() {
+			if it.Kind == 23 && slices.Contains(it.embedded(), p.Item.Name) {
+				types = append(types, it.HierarchyItem)
+			}
+		}
+		return types, nil
+
+	case "textDocument/rangeFormatting":
+		var p lsp.DocumentRangeFormattingParams
+		_ = json.Unmarshal(params, &p)
+		last := p.Range.End.Line
+		if p.Range.End.Character == 0 && last > p.Range.Start.Line {
+			last-- // the range ends at the start of the next line
+		}
+		return s.format(p.TextDocument.URI, p.Range.Start.Line, last), nil
+	}
+
+	return nil, &lsp.ResponseError{Code: -32601, Message: "method not found: " + method}
+}
+
+// diagnosticWords are the words published as diagnostics
+var diagnosticWords = map[string]lsp.DiagnosticSeverity{
+	"ERROR":   lsp.SeverityError,
+	"WARNING": lsp.SeverityWarning,
+	"INFO":    lsp.SeverityInformation,
+	"HINT":    lsp.SeverityHint,
+}
+
+// publishDiagnostics sends a diagnostic for every diagnostic word in the
+// document
+func (s *Server) publishDiagnostics(uri string) {
+	diags := []lsp.Diagnostic{}
+	for i, text := range strings.Split(s.docs[uri], "\n") {
+		line := []rune(text)
+		for col := 0; col < len(line); {
+			if !isWordRune(line[col]) {
+				col++
+				continue
+			}
+			end := col
+			for end < len(line) && isWordRune(line[end]) {
+				end++
+			}
+			word := string(line[col:end])
+			if severity, ok := diagnosticWords[word]; ok {
+				diags = append(diags, lsp.Diagnostic{
+					Range: lsp.Range{
+						Start: lsp.Position{Line: i, Character: utf16Col(line, col)},
+						End:   lsp.Position{Line: i, Character: utf16Col(line, end)},
+					},
+					Severity: severity,
+					Source:   "lsptest",
+					Message:  strings.ToLower(word) + " here",
+				})
+			}
+			col = end
+		}
+	}
+	version := s.versions[uri]
+	_ = s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/publishDiagnostics",
+		"params":  lsp.PublishDiagnosticsParams{URI: uri, Version: &version, Diagnostics: diags},
+	})
+}
+
+// signatureHelp returns the signature of the function whose call
+// arguments contain p, from its "func name(...)" declaration
+func (s *Server) signatureHelp(uri string, p lsp.Position) *lsp.SignatureHelp {
+	lines := strings.Split(s.docs[uri], "\n")
+	if p.Line < 0 || p.Line >= len(lines) {
+		return nil
+	}
+	line := []rune(lines[p.Line])
+	col := runeCol(line, p.Character)
+
+	// find the open parenthesis of the call, counting the arguments before
+	depth, active, open := 0, 0, -1
+	for i := col - 1; i >= 0 && open < 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth == 0 {
+				open = i
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				active++
+			}
+		}
+	}
+	if open <= 0 || !isWordRune(line[open-1]) {
+		return nil
+	}
+	name, _ := s.wordAt(uri, lsp.Position{Line: p.Line, Character: utf16Col(line, open-1)})
+
+	for _, text := range lines {
+		start := strings.Index(text, "func "+name+"(")
+		if start < 0 {
+			continue
+		}
+		end := strings.Index(text[start:], ")")
+		if end < 0 {
+			continue
+		}
+		label := text[start : start+end+1]
+		args := label[len("func "+name+"(") : len(label)-1]
+		var params []lsp.ParameterInformation
+		if args != "" {
+			for _, arg := range strings.Split(args, ", ") {
+				data, _ := json.Marshal(arg)
+				params = append(params, lsp.ParameterInformation{Label: data})
+			}
+		}
+		doc, _ := json.Marshal(lsp.MarkupContent{Kind: lsp.MarkupKindMarkdown, Value: "Calls `" + name + "`."})
+		return &lsp.SignatureHelp{
+			Signatures:      []lsp.SignatureInformation{{Label: label, Documentation: doc, Parameters: params}},
+			ActiveParameter: active,
+		}
+	}
+	return nil
+}
+
+// packages are the members completed after "name."
+var packages = map[string][]string{
+	"fmt":     {"Println", "Sprintf"},
+	"strings": {"Fields", "TrimSpace"},
+}
+
+// completion returns the completions of the word before p: package members
+// after a package name and a dot, the identifiers of the document
+// otherwise. Matching ignores case.
+func (s *Server) completion(uri string, p lsp.Position) *lsp.CompletionList {
+	text := s.docs[uri]
+	lines := strings.Split(text, "\n")
+	if p.Line < 0 || p.Line >= len(lines) {
+		return nil
+	}
+	line := []rune(lines[p.Line])
+	col := runeCol(line, p.Character)
+	start := col
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	prefix := strings.ToLower(string(line[start:col]))
+	replace := lsp.Range{Start: lsp.Position{Line: p.Line, Character: utf16Col(line, start)}, End: p}
+
+	list := &lsp.CompletionList{Items: []lsp.CompletionItem{}}
+	add := func(label, name string, kind lsp.CompletionItemKind, detail string) *lsp.CompletionItem {
+		data, _ := json.Marshal(map[string]string{"name": name})
+		list.Items = append(list.Items, lsp.CompletionItem{
+			Label:    label,
+			Kind:     kind,
+			Detail:   detail,
+			TextEdit: &lsp.TextEdit{Range: replace, NewText: label},
+			Data:     data,
+		})
+		return &list.Items[len(list.Items)-1]
+	}
+
+	if start > 0 && line[start-1] == '.' {
+		pkg := ""
+		if start > 1 {
+			pkg, _ = s.wordAt(uri, lsp.Position{Line: p.Line, Character: utf16Col(line, start-2)})
+		}
+		for _, member := range packages[pkg] {
+			if !strings.HasPrefix(strings.ToLower(member), prefix) {
+				continue
+			}
+			item := add(member, pkg+"."+member, 3, "func")
+			if !strings.Contains(text, `import "`+pkg+`"`) {
+				item.AdditionalTextEdits = []lsp.TextEdit{{
+					Range:   lsp.Range{Start: lsp.Position{Line: 1}, End: lsp.Position{Line: 1}},
+					NewText: `import "` + pkg + `"` + "\n",
+				}}
+			}
+		}
+		return list
+	}
+
+	seen := map[string]bool{}
+	for _, w := range strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) }) {
+		if seen[w] || !strings.HasPrefix(strings.ToLower(w), prefix) || strings.ToLower(w) == prefix {
+			continue
+		}
+		seen[w] = true
+		kind := lsp.CompletionItemKind(6) // variable
+		if strings.Contains(text, "func "+w+"(") {
+			kind = 3 // function
+		}
+		add(w, w, kind, "lsptest")
+	}
+	return list
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wordAt returns the identifier at p and its start as a rune column
+func (s *Server) wordAt(uri string, p lsp.Position) (string, int) {
+	lines := strings.Split(s.docs[uri], "\n")
+	if p.Line < 0 || p.Line >= len(lines) {
+		return "", 0
+	}
+	line := []rune(lines[p.Line])
+	col := runeCol(line, p.Character)
+	if col >= len(line) || !isWordRune(line[col]) {
+		return "", 0
+	}
+	start, end := col, col
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWordRune(line[end]) {
+		end++
+	}
+	return string(line[start:end]), start
+}
+
+// firstWord returns the range of the first whole-word occurrence of word
+func (s *Server) firstWord(uri, word string) (lsp.Range, bool) {
+	ranges := wordRanges(s.docs[uri], word)
+	if len(ranges) == 0 {
+		return lsp.Range{}, false
+	}
+	return ranges[0], true
+}
+
+// wordRanges returns the ranges of the whole-word occurrences of word in
+// doc
+func wordRanges(doc, word string) []lsp.Range {
+	var ranges []lsp.Range
+	w := []rune(word)
+	for i, text := range strings.Split(doc, "\n") {
+		line := []rune(text)
+		for col := 0; col+len(w) <= len(line); col++ {
+			if string(line[col:col+len(w)]) != word {
+				continue
+			}
+			if col > 0 && isWordRune(line[col-1]) || col+len(w) < len(line) && isWordRune(line[col+len(w)]) {
+				continue
+			}
+			ranges = append(ranges, lsp.Range{
+				Start: lsp.Position{Line: i, Character: utf16Col(line, col)},
+				End:   lsp.Position{Line: i, Character: utf16Col(line, col+len(w))},
+			})
+		}
+	}
+	return ranges
+}
+
+type file struct {
+	uri, text string
+}
+
+// files returns the open documents and the .go files under the root
+// directory, sorted by URI
+func (s *Server) files() []file {
+	texts := make(map[string]string)
+	if s.root != "" {
+		_ = filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.HasSuffix(path, ".go") {
+				if data, err := os.ReadFile(path); err == nil {
+					texts[lsp.URIFromPath(path)] = string(data)
+				}
+			}
+			return nil
+		})
+	}
+	for uri, text := range s.docs {
+		texts[uri] = text
+	}
+	files := make([]file, 0, len(texts))
+	for uri, text := range texts {
+		files = append(files, file{uri, text})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].uri < files[j].uri })
+	return files
+}
+
+// codeActions offers to replace the ERROR of error diagnostics with nil,
+// to remove the WARNING of warnings with a command, and to uppercase the word at the start of the range; that action's edit
+// comes with codeAction/resolve
+func (s *Server) codeActions(p lsp.CodeActionParams) []map[string]interface{} {
+	actions := []map[string]interface{}{}
+	for _, d := range p.Context.Diagnostics {
+		if d.Severity != lsp.SeverityError || d.Source != "lsptest" {
+			continue
+		}
+		actions = append(actions, map[string]interface{}{
+			"title":       "Replace ERROR with nil",
+			"kind":        lsp.CodeActionQuickFix,
+			"isPreferred": true,
+			"diagnostics": []lsp.Diagnostic{d},
+			"edit": lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{
+				p.TextDocument.URI: {{Range: d.Range, NewText: "nil"}},
+			}},
+		})
+	}
+	for _, d := range p.Context.Diagnostics {
+		if d.Severity != lsp.SeverityWarning || d.Source != "lsptest" {
+			continue
+		}
+		uri, _ := json.Marshal(p.TextDocument.URI)
+		r, _ := json.Marshal(d.Range)
+		actions = append(actions, map[string]interface{}{
+			"title":       "Remove WARNING",
+			"kind":        lsp.CodeActionQuickFix,
+			"diagnostics": []lsp.Diagnostic{d},
+			"command": lsp.Command{
+				Title:     "Remove WARNING",
+				Command:   "lsptest.remove",
+				Arguments: []json.RawMessage{uri, r},
+			},
+		})
+	}
+	word, start := s.wordAt(p.TextDocument.URI, p.Range.Start)
+	if word != "" && word != strings.ToUpper(word) {
+		line := []rune(strings.Split(s.docs[p.TextDocument.URI], "\n")[p.Range.Start.Line])
+		r := lsp.Range{
+			Start: lsp.Position{Line: p.Range.Start.Line, Character: utf16Col(line, start)},
+			End:   lsp.Position{Line: p.Range.Start.Line, Character: utf16Col(line, start+len([]rune(word)))},
+		}
+		actions = append(actions, map[string]interface{}{
+			"title": "Uppercase " + word,
+			"kind":  lsp.CodeActionRefactorRewrite,
+			"data":  map[string]interface{}{"uri": p.TextDocument.URI, "range": r, "word": word},
+		})
+	}
+	return actions
+}
+
+// documentSymbols returns the funcs and types declared at the start of the
+// lines of text. Methods are children of their receiver's type when it is
+// declared before them, fields children of their struct. A declaration
+// ends at the next line that is "}", or on its line when that ends with
+// "}".
+func documentSymbols(text string) []lsp.DocumentSymbol {
+	lines := strings.Split(text, "\n")
+	symbols := []lsp.DocumentSymbol{}
+	types := make(map[string]int) // index in symbols by name
+	nameRange := func(i int, name string) lsp.Range {
+		line := []rune(lines[i])
+		start := strings.Index(lines[i], name)
+		start = len([]rune(lines[i][:start]))
+		return lsp.Range{
+			Start: lsp.Position{Line: i, Character: utf16Col(line, start)},
+			End:   lsp.Position{Line: i, Character: utf16Col(line, start+len([]rune(name)))},
+		}
+	}
+	end := func(i int) lsp.Position {
+		if !strings.HasSuffix(lines[i], "}") {
+			for j := i + 1; j < len(lines); j++ {
+				if lines[j] == "}" {
+					i = j
+					break
+				}
+			}
+		}
+		return lsp.Position{Line: i, Character: utf16Col([]rune(lines[i]), len([]rune(lines[i])))}
+	}
+
+	for i, line := range lines {
+		decl, ok := strings.CutPrefix(line, "func ")
+		kind := lsp.SymbolKind(12) // function
+		if !ok {
+			if decl, ok = strings.CutPrefix(line, "type "); !ok {
+				continue
+			}
+			kind = 23 // struct
+		}
+		receiver := ""
+		if kind == 12 && strings.HasPrefix(decl, "(") {
+			r, rest, _ := strings.Cut(decl[1:], ")")
+			fields := strings.Fields(r)
+			if len(fields) > 0 {
+				receiver = strings.TrimPrefix(fields[len(fields)-1], "*")
+			}
+			decl, kind = strings.TrimSpace(rest), 6 // method
+		}
+		name := strings.FieldsFunc(decl, func(r rune) bool { return !isWordRune(r) })
+		if len(name) == 0 {
+			continue
+		}
+		sym := lsp.DocumentSymbol{
+			Name:           name[0],
+			Kind:           kind,
+			Range:          lsp.Range{Start: lsp.Position{Line: i}, End: end(i)},
+			SelectionRange: nameRange(i, name[0]),
+		}
+		if kind == 23 && strings.HasSuffix(line, "{") {
+			for j := i + 1; j < sym.Range.End.Line; j++ {
+				if field := strings.Fields(lines[j]); len(field) > 0 {
+					r := nameRange(j, field[0])
+					sym.Children = append(sym.Children, lsp.DocumentSymbol{
+						Name: field[0], Kind: 8, Range: r, SelectionRange: r, // field
+					})
+				}
+			}
+		}
+		if t, ok := types[receiver]; ok {
+			symbols[t].Children = append(symbols[t].Children, sym)
+			continue
+		}
+		if kind == 23 {
+			types[sym.Name] = len(symbols)
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols
+}
+
+// hierarchyItem is a func or type declared in a file, as an item of the
+// call and type hierarchies
+type hierarchyItem struct {
+	lsp.HierarchyItem
+	sym   lsp.DocumentSymbol
+	lines []string // of the file
+}
+
+// hierarchyItems returns the funcs and types of the files
+func (s *Server) hierarchyItems() []hierarchyItem {
+	var items []hierarchyItem
+	for _, f := range s.files() {
+		lines := strings.Split(f.text, "\n")
+		for _, sym := range documentSymbols(f.text) {
+			items = append(items, hierarchyItem{
+				HierarchyItem: lsp.HierarchyItem{
+					Name:           sym.Name,
+					Kind:           sym.Kind,
+					URI:            f.uri,
+					Range:          sym.Range,
+					SelectionRange: sym.SelectionRange,
+				},
+				sym:   sym,
+				lines: lines,
+			})
+		}
+	}
+	return items
+}
+
+// calls returns the ranges of the calls to the func name in the body of it
+func (it hierarchyItem) calls(name string) []lsp.Range {
+	var ranges []lsp.Range
+	for i := it.Range.Start.Line + 1; i <= it.Range.End.Line && i < len(it.lines); i++ {
+		line := []rune(it.lines[i])
+		for _, r := range wordRanges(it.lines[i], name) {
+			if end := runeCol(line, r.End.Character); end < len(line) && line[end] == '(' {
+				r.Start.Line, r.End.Line = i, i
+				ranges = append(ranges, r)
+			}
+		}
+	}
+	return ranges
+}
+
+// embedded returns the names of the types the struct it embeds, its
+// fields without a type
+func (it hierarchyItem) embedded() []string {
+	var names []string
+	for _, field := range it.sym.Children {
+		if field.Kind == 8 && len(strings.Fields(it.lines[field.Range.Start.Line])) == 1 {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// funcDecl is a "func name(params)" declaration at the start of a line
+type funcDecl struct {
+	name       string
+	params     []string // parameter names
+	line, end  int      // lines of the declaration and of its closing "}"
+	deprecated bool     // the line before starts with "// Deprecated:"
+}
+
+// funcDecls returns the func declarations of lines, methods excepted
+func funcDecls(lines []string) []funcDecl {
+	var decls []funcDecl
+	for i, line := range lines {
+		decl, ok := strings.CutPrefix(line, "func ")
+		if !ok || strings.HasPrefix(decl, "(") {
+			continue
+		}
+		name, rest, ok := strings.Cut(decl, "(")
+		args, _, _ := strings.Cut(rest, ")")
+		if !ok || name == "" {
+			continue
+		}
+		fd := funcDecl{name: name, line: i, end: i}
+		if args != "" {
+			for _, arg := range strings.Split(args, ",") {
+				if f := strings.Fields(arg); len(f) > 0 {
+					fd.params = append(fd.params, f[0])
+				}
+			}
+		}
+		if !strings.HasSuffix(line, "}") {
+			for fd.end < len(lines)-1 && lines[fd.end] != "}" {
+				fd.end++
+			}
+		}
+		fd.deprecated = i > 0 && strings.HasPrefix(lines[i-1], "// Deprecated:")
+		decls = append(decls, fd)
+	}
+	return decls
+}
+
+// tokenTypes and tokenModifiers are the semantic token legend
+var (
+	tokenTypes     = []string{"function", "parameter"}
+	tokenModifiers = []string{"declaration", "deprecated"}
+)
+
+// semanticTokens returns the encoded tokens of the lines from first to
+// last; last -1 is the end of the document. Every use of a declared func
+// is a function token, every use of a parameter inside its func a
+// parameter token.
+func semanticTokens(text string, first, last int) []uint32 {
+	type token struct {
+		r    lsp.Range
+		typ  uint32
+		mods uint32
+	}
+	lines := strings.Split(text, "\n")
+	if last < 0 || last >= len(lines) {
+		last = len(lines) - 1
+	}
+	var tokens []token
+	for _, fd := range funcDecls(lines) {
+		for _, r := range wordRanges(text, fd.name) {
+			var mods uint32
+			if r.Start.Line == fd.line {
+				mods |= 1 // declaration
+			}
+			if fd.deprecated {
+				mods |= 2
+			}
+			tokens = append(tokens, token{r, 0, mods})
+		}
+		for _, param := range fd.params {
+			for _, r := range wordRanges(text, param) {
+				if r.Start.Line >= fd.line && r.Start.Line <= fd.end {
+					tokens = append(tokens, token{r, 1, 0})
+				}
+			}
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		a, b := tokens[i].r.Start, tokens[j].r.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+	})
+
+	data := []uint32{}
+	prevLine, prevStart := 0, 0
+	for _, tok := range tokens {
+		start := tok.r.Start
+		if start.Line < first || start.Line > last {
+			continue
+		}
+		deltaStart := start.Character
+		if start.Line == prevLine {
+			deltaStart -= prevStart
+		}
+		data = append(data, uint32(start.Line-prevLine), uint32(deltaStart),
+			uint32(tok.r.End.Character-start.Character), tok.typ, tok.mods)
+		prevLine, prevStart = start.Line, start.Character
+	}
+	return data
+}
+
+// inlayHints returns hints naming the parameter of every argument of the
+// calls to declared funcs on the lines from first to last
+func inlayHints(text string, first, last int) []lsp.InlayHint {
+	hints := []lsp.InlayHint{}
+	lines := strings.Split(text, "\n")
+	decls := funcDecls(lines)
+	for i := max(first, 0); i <= last && i < len(lines); i++ {
+		line := []rune(lines[i])
+		for _, fd := range decls {
+			if i == fd.line || len(fd.params) == 0 {
+				continue
+			}
+			call := []rune(fd.name + "(")
+			for col := 0; col+len(call) <= len(line); col++ {
+				if string(line[col:col+len(call)]) != string(call) || col > 0 && isWordRune(line[col-1]) {
+					continue
+				}
+				arg, depth := 0, 0
+				for j := col + len(call); j < len(line) && depth >= 0 && arg < len(fd.params); j++ {
+					if depth == 0 && line[j] != ' ' && line[j] != ')' && (line[j-1] == '(' || line[j-1] == ' ' && line[j-2] == ',') {
+						hints = append(hints, lsp.InlayHint{
+							Position:     lsp.Position{Line: i, Character: utf16Col(line, j)},
+							Label:        lsp.InlayHintLabel(fd.params[arg] + ":"),
+							Kind:         lsp.InlayHintKindParameter,
+							PaddingRight: true,
+						})
+						arg++
+					}
+					switch line[j] {
+					case '(':
+						depth++
+					case ')':
+						depth--
+					}
+				}
+			}
+		}
+	}
+	return hints
+}
+
+// format returns edits trimming the trailing whitespace of the lines from
+// first to last; last -1 is the end of the document
+func (s *Server) format(uri string, first, last int) []lsp.TextEdit {
+	edits := []lsp.TextEdit{}
+	lines := strings.Split(s.docs[uri], "\n")
+	if last < 0 || last >= len(lines) {
+		last = len(lines) - 1
+	}
+	for i := first; i <= last; i++ {
+		line := []rune(lines[i])
+		trimmed := []rune(strings.TrimRight(lines[i], " \t"))
+		if len(trimmed) == len(line) {
+			continue
+		}
+		edits = append(edits, lsp.TextEdit{Range: lsp.Range{
+			Start: lsp.Position{Line: i, Character: utf16Col(line, len(trimmed))},
+			End:   lsp.Position{Line: i, Character: utf16Col(line, len(line))},
+		}})
+	}
+	return edits
+}
+
+// syncKind is the textDocumentSync kind the server announces: incremental,
+// or full when VB_LSPTEST_SYNC is "full"
+func (s *Server) syncKind() lsp.TextDocumentSyncKind {
+	if os.Getenv("VB_LSPTEST_SYNC") == "full" {
+		return lsp.TextDocumentSyncFull
+	}
+	return lsp.TextDocumentSyncIncremental
+}
+
+// offsetOf returns the byte offset of p in text
+func offsetOf(text string, p lsp.Position) int {
+	offset := 0
+	lines := strings.SplitAfter(text, "\n")
+	for i := 0; i < p.Line && i < len(lines); i++ {
+		offset += len(lines[i])
+	}
+	if p.Line >= len(lines) {
+		return len(text)
+	}
+	line := []rune(strings.TrimSuffix(lines[p.Line], "\n"))
+	return offset + len(string(line[:runeCol(line, p.Character)]))
+}
+
+// runeCol converts a UTF-16 column in line to a rune column
+func runeCol(line []rune, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// utf16Col converts a rune column in line to a UTF-16 column
+func utf16Col(line []rune, col int) int {
+	return len(utf16.Encode(line[:col]))
+}
@@ -1,6 +1,9 @@
 package lsp
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // Request represents a JSON-RPC request
 type Request struct {
@@ -25,6 +28,23 @@ type ResponseError struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// Error implements error, so request handlers can return a ResponseError
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// Error codes of responses
+const (
+	CodeMethodNotFound   = -32601
+	CodeInternalError    = -32603
+	CodeRequestCancelled = -32800
+)
+
+// CancelParams represents params for the $/cancelRequest notification
+type CancelParams struct {
+	ID int `json:"id"`
+}
+
 // Notification represents a JSON-RPC notification
 type Notification struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -41,12 +61,81 @@ type InitializeParams struct {
 
 // ClientCapabilities represents client capabilities
 type ClientCapabilities struct {
+	General      *GeneralClientCapabilities     `json:"general,omitempty"`
+	Workspace    *WorkspaceClientCapabilities   `json:"workspace,omitempty"`
 	TextDocument TextDocumentClientCapabilities `json:"textDocument,omitempty"`
 }
 
+// WorkspaceClientCapabilities represents workspace capabilities
+type WorkspaceClientCapabilities struct {
+	ApplyEdit     bool                             `json:"applyEdit,omitempty"`
+	WorkspaceEdit *WorkspaceEditClientCapabilities `json:"workspaceEdit,omitempty"`
+	Configuration bool                             `json:"configuration,omitempty"`
+}
+
+// WorkspaceEditClientCapabilities describes the workspace edits the client
+// can apply
+type WorkspaceEditClientCapabilities struct {
+	DocumentChanges bool `json:"documentChanges,omitempty"`
+}
+
+// GeneralClientCapabilities represents general client capabilities
+type GeneralClientCapabilities struct {
+	// PositionEncodings lists supported position encodings in order of preference
+	PositionEncodings []string `json:"positionEncodings,omitempty"`
+}
+
+// Position encoding kinds (LSP 3.17)
+const (
+	PositionEncodingUTF8  = "utf-8"
+	PositionEncodingUTF16 = "utf-16"
+	PositionEncodingUTF32 = "utf-32"
+)
+
 // TextDocumentClientCapabilities represents text document capabilities
 type TextDocumentClientCapabilities struct {
-	Definition *DefinitionClientCapabilities `json:"definition,omitempty"`
+	Definition         *DefinitionClientCapabilities         `json:"definition,omitempty"`
+	Hover              *HoverClientCapabilities              `json:"hover,omitempty"`
+	PublishDiagnostics *PublishDiagnosticsClientCapabilities `json:"publishDiagnostics,omitempty"`
+	SignatureHelp      *SignatureHelpClientCapabilities      `json:"signatureHelp,omitempty"`
+	Completion         *CompletionClientCapabilities         `json:"completion,omitempty"`
+	CodeAction         *CodeActionClientCapabilities         `json:"codeAction,omitempty"`
+	DocumentSymbol     *DocumentSymbolClientCapabilities     `json:"documentSymbol,omitempty"`
+	SemanticTokens     *SemanticTokensClientCapabilities     `json:"semanticTokens,omitempty"`
+	InlayHint          *InlayHintClientCapabilities          `json:"inlayHint,omitempty"`
+	CallHierarchy      *CallHierarchyClientCapabilities      `json:"callHierarchy,omitempty"`
+	TypeHierarchy      *TypeHierarchyClientCapabilities      `json:"typeHierarchy,omitempty"`
+}
+
+// DocumentSymbolClientCapabilities represents document symbol capabilities
+type DocumentSymbolClientCapabilities struct {
+	// HierarchicalDocumentSymbolSupport means DocumentSymbol results with
+	// children are understood
+	HierarchicalDocumentSymbolSupport bool `json:"hierarchicalDocumentSymbolSupport,omitempty"`
+}
+
+// CodeActionClientCapabilities represents code action capabilities
+type CodeActionClientCapabilities struct {
+	// CodeActionLiteralSupport means CodeAction results are understood, not
+	// just Commands
+	CodeActionLiteralSupport *CodeActionLiteralSupport `json:"codeActionLiteralSupport,omitempty"`
+	IsPreferredSupport       bool                      `json:"isPreferredSupport,omitempty"`
+	DisabledSupport          bool                      `json:"disabledSupport,omitempty"`
+	DataSupport              bool                      `json:"dataSupport,omitempty"`
+	ResolveSupport           *CodeActionResolveSupport `json:"resolveSupport,omitempty"`
+}
+
+// CodeActionLiteralSupport lists the code action kinds the client knows
+type CodeActionLiteralSupport struct {
+	CodeActionKind struct {
+		ValueSet []string `json:"valueSet"`
+	} `json:"codeActionKind"`
+}
+
+// CodeActionResolveSupport lists the action properties the client can
+// resolve lazily with codeAction/resolve
+type CodeActionResolveSupport struct {
+	Properties []string `json:"properties"`
 }
 
 // DefinitionClientCapabilities represents definition capabilities
@@ -55,6 +144,67 @@ type DefinitionClientCapabilities struct {
 	LinkSupport         bool `json:"linkSupport,omitempty"`
 }
 
+// HoverClientCapabilities represents hover capabilities
+type HoverClientCapabilities struct {
+	// ContentFormat lists supported content formats in order of preference
+	ContentFormat []string `json:"contentFormat,omitempty"`
+}
+
+// PublishDiagnosticsClientCapabilities represents diagnostics capabilities
+type PublishDiagnosticsClientCapabilities struct {
+	// VersionSupport means the client uses the version of published
+	// diagnostics
+	VersionSupport bool `json:"versionSupport,omitempty"`
+}
+
+// SignatureHelpClientCapabilities represents signature help capabilities
+type SignatureHelpClientCapabilities struct {
+	SignatureInformation *SignatureInformationCapabilities `json:"signatureInformation,omitempty"`
+	ContextSupport       bool                              `json:"contextSupport,omitempty"`
+}
+
+// SignatureInformationCapabilities describes what the client supports in
+// the signatures of a signature help
+type SignatureInformationCapabilities struct {
+	DocumentationFormat  []string                     `json:"documentationFormat,omitempty"`
+	ParameterInformation *ParameterInformationSupport `json:"parameterInformation,omitempty"`
+	ActiveParameter      bool                         `json:"activeParameterSupport,omitempty"`
+}
+
+// ParameterInformationSupport describes what the client supports in the
+// parameters of a signature
+type ParameterInformationSupport struct {
+	// LabelOffsetSupport means parameter labels may be offsets into the
+	// signature label
+	LabelOffsetSupport bool `json:"labelOffsetSupport,omitempty"`
+}
+
+// CompletionClientCapabilities represents completion capabilities
+type CompletionClientCapabilities struct {
+	CompletionItem *CompletionItemCapabilities `json:"completionItem,omitempty"`
+	ContextSupport bool                        `json:"contextSupport,omitempty"`
+}
+
+// CompletionItemCapabilities describes what the client supports in
+// completion items
+type CompletionItemCapabilities struct {
+	SnippetSupport      bool                      `json:"snippetSupport"`
+	DocumentationFormat []string                  `json:"documentationFormat,omitempty"`
+	ResolveSupport      *CompletionResolveSupport `json:"resolveSupport,omitempty"`
+}
+
+// CompletionResolveSupport lists the item properties the client can
+// resolve lazily with completionItem/resolve
+type CompletionResolveSupport struct {
+	Properties []string `json:"properties"`
+}
+
+// Markup kinds
+const (
+	MarkupKindPlainText = "plaintext"
+	MarkupKindMarkdown  = "markdown"
+)
+
 // InitializeResult represents the initialize response
 type InitializeResult struct {
 	Capabilities ServerCapabilities `json:"capabilities"`
@@ -62,14 +212,119 @@ type InitializeResult struct {
 
 // ServerCapabilities represents server capabilities
 type ServerCapabilities struct {
-	DefinitionProvider bool `json:"definitionProvider,omitempty"`
+	PositionEncoding   string                  `json:"positionEncoding,omitempty"`
+	TextDocumentSync   TextDocumentSyncOptions `json:"textDocumentSync"`
+	DefinitionProvider Support                 `json:"definitionProvider,omitempty"`
+	HoverProvider      Support                 `json:"hoverProvider,omitempty"`
+
+	SignatureHelpProvider *SignatureHelpOptions `json:"signatureHelpProvider,omitempty"`
+	CompletionProvider    *CompletionOptions    `json:"completionProvider,omitempty"`
+
+	ReferencesProvider              Support           `json:"referencesProvider,omitempty"`
+	RenameProvider                  Support           `json:"renameProvider,omitempty"`
+	CodeActionProvider              CodeActionSupport `json:"codeActionProvider"`
+	DocumentFormattingProvider      Support           `json:"documentFormattingProvider,omitempty"`
+	DocumentRangeFormattingProvider Support           `json:"documentRangeFormattingProvider,omitempty"`
+	DocumentSymbolProvider          Support           `json:"documentSymbolProvider,omitempty"`
+	WorkspaceSymbolProvider         Support           `json:"workspaceSymbolProvider,omitempty"`
+
+	SemanticTokensProvider *SemanticTokensOptions `json:"semanticTokensProvider,omitempty"`
+	InlayHintProvider      Support                `json:"inlayHintProvider,omitempty"`
+	CallHierarchyProvider  Support                `json:"callHierarchyProvider,omitempty"`
+	TypeHierarchyProvider  Support                `json:"typeHierarchyProvider,omitempty"`
 	// Add more as needed
 }
 
+// TextDocumentSyncKind is how a server wants document changes sent
+type TextDocumentSyncKind int
+
+// Text document sync kinds
+const (
+	TextDocumentSyncNone        TextDocumentSyncKind = 0 // no didChange
+	TextDocumentSyncFull        TextDocumentSyncKind = 1 // the whole text
+	TextDocumentSyncIncremental TextDocumentSyncKind = 2 // ranged changes
+)
+
+// TextDocumentSyncOptions is the textDocumentSync capability. Servers
+// announce it either as a TextDocumentSyncKind or as an object.
+type TextDocumentSyncOptions struct {
+	OpenClose bool                 `json:"openClose,omitempty"`
+	Change    TextDocumentSyncKind `json:"change,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *TextDocumentSyncOptions) UnmarshalJSON(data []byte) error {
+	var kind TextDocumentSyncKind
+	if err := json.Unmarshal(data, &kind); err == nil {
+		*o = TextDocumentSyncOptions{OpenClose: true, Change: kind}
+		return nil
+	}
+	type options TextDocumentSyncOptions
+	return json.Unmarshal(data, (*options)(o))
+}
+
+// Support is a capability servers announce either as a boolean or as an
+// options object; an object means the feature is supported.
+type Support bool
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *Support) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = Support(b)
+		return nil
+	}
+	*s = string(data) != "null"
+	return nil
+}
+
+// SignatureHelpOptions are the signature help options of a server
+type SignatureHelpOptions struct {
+	// TriggerCharacters start signature help when typed
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+	// RetriggerCharacters update signature help that is already shown
+	RetriggerCharacters []string `json:"retriggerCharacters,omitempty"`
+}
+
+// CompletionOptions are the completion options of a server
+type CompletionOptions struct {
+	// TriggerCharacters start completion when typed
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+	// ResolveProvider means the server fills in items with
+	// completionItem/resolve
+	ResolveProvider bool `json:"resolveProvider,omitempty"`
+}
+
+// CodeActionSupport is the code action support of a server, announced as
+// a boolean or an options object
+type CodeActionSupport struct {
+	Supported bool
+	// ResolveProvider means the server fills in actions with
+	// codeAction/resolve
+	ResolveProvider bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *CodeActionSupport) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = CodeActionSupport{Supported: b}
+		return nil
+	}
+	var opts struct {
+		ResolveProvider bool `json:"resolveProvider"`
+	}
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return err
+	}
+	*s = CodeActionSupport{Supported: string(data) != "null", ResolveProvider: opts.ResolveProvider}
+	return nil
+}
+
 // Position represents a position in a text document
 type Position struct {
 	Line      int `json:"line"`      // 0-based
-	Character int `json:"character"` // 0-based
+	Character int `json:"character"` // 0-based, in the negotiated encoding (UTF-16 by default)
 }
 
 // Range represents a range in a text document
@@ -84,6 +339,27 @@ type Location struct {
 	Range Range  `json:"range"`
 }
 
+// LocationLink represents a link to a location, as returned by servers
+// when the client announces linkSupport
+type LocationLink struct {
+	OriginSelectionRange *Range `json:"originSelectionRange,omitempty"`
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+// Hover represents the result of a hover request
+type Hover struct {
+	Contents json.RawMessage `json:"contents"`
+	Range    *Range          `json:"range,omitempty"`
+}
+
+// MarkupContent represents formatted documentation
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
 // TextDocumentIdentifier identifies a text document
 type TextDocumentIdentifier struct {
 	URI string `json:"uri"`
@@ -137,3 +413,582 @@ type DidSaveTextDocumentParams struct {
 type DidCloseTextDocumentParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
+
+// DiagnosticSeverity is the severity of a diagnostic
+type DiagnosticSeverity int
+
+// Diagnostic severities
+const (
+	SeverityError       DiagnosticSeverity = 1
+	SeverityWarning     DiagnosticSeverity = 2
+	SeverityInformation DiagnosticSeverity = 3
+	SeverityHint        DiagnosticSeverity = 4
+)
+
+// Diagnostic represents a compiler error, warning or other message about
+// a range of a document
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Code     json.RawMessage    `json:"code,omitempty"` // number or string
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// PublishDiagnosticsParams represents params for the
+// textDocument/publishDiagnostics notification. The diagnostics replace
+// all earlier ones of the document.
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Signature help trigger kinds
+const (
+	SignatureHelpInvoked          = 1
+	SignatureHelpTriggerCharacter = 2
+	SignatureHelpContentChange    = 3
+)
+
+// SignatureHelpContext tells the server why signature help was requested
+type SignatureHelpContext struct {
+	TriggerKind      int    `json:"triggerKind"`
+	TriggerCharacter string `json:"triggerCharacter,omitempty"`
+	IsRetrigger      bool   `json:"isRetrigger"`
+}
+
+// SignatureHelpParams represents params for textDocument/signatureHelp
+type SignatureHelpParams struct {
+	TextDocumentPositionParams
+	Context *SignatureHelpContext `json:"context,omitempty"`
+}
+
+// SignatureHelp represents the signatures of the call at a position
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature,omitempty"`
+	ActiveParameter int                    `json:"activeParameter,omitempty"`
+}
+
+// SignatureInformation represents the signature of something callable
+type SignatureInformation struct {
+	Label           string                 `json:"label"`
+	Documentation   json.RawMessage        `json:"documentation,omitempty"` // string or MarkupContent
+	Parameters      []ParameterInformation `json:"parameters,omitempty"`
+	ActiveParameter *int                   `json:"activeParameter,omitempty"`
+}
+
+// ParameterInformation represents a parameter of a signature
+type ParameterInformation struct {
+	// Label is a substring of the signature label or a [start, end]
+	// pair of UTF-16 offsets into it
+	Label         json.RawMessage `json:"label"`
+	Documentation json.RawMessage `json:"documentation,omitempty"`
+}
+
+// TextEdit is a change to a text document
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// Completion trigger kinds
+const (
+	CompletionInvoked                         = 1
+	CompletionTriggerCharacter                = 2
+	CompletionTriggerForIncompleteCompletions = 3
+)
+
+// CompletionContext tells the server why completion was requested
+type CompletionContext struct {
+	TriggerKind      int    `json:"triggerKind"`
+	TriggerCharacter string `json:"triggerCharacter,omitempty"`
+}
+
+// CompletionParams represents params for textDocument/completion
+type CompletionParams struct {
+	TextDocumentPositionParams
+	Context *CompletionContext `json:"context,omitempty"`
+}
+
+// CompletionList represents the result of a completion request
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// Insert text formats
+const (
+	InsertTextFormatPlainText = 1
+	InsertTextFormatSnippet   = 2
+)
+
+// CompletionItem represents a completion offered by the server. Items keep
+// the JSON they were decoded from, so completionItem/resolve gets back the
+// fields the client does not know about.
+type CompletionItem struct {
+	Label               string             `json:"label"`
+	Kind                CompletionItemKind `json:"kind,omitempty"`
+	Detail              string             `json:"detail,omitempty"`
+	Documentation       json.RawMessage    `json:"documentation,omitempty"` // string or MarkupContent
+	SortText            string             `json:"sortText,omitempty"`
+	FilterText          string             `json:"filterText,omitempty"`
+	InsertText          string             `json:"insertText,omitempty"`
+	InsertTextFormat    int                `json:"insertTextFormat,omitempty"`
+	TextEdit            *TextEdit          `json:"textEdit,omitempty"`
+	AdditionalTextEdits []TextEdit         `json:"additionalTextEdits,omitempty"`
+	Data                json.RawMessage    `json:"data,omitempty"`
+
+	raw json.RawMessage
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (it *CompletionItem) UnmarshalJSON(data []byte) error {
+	type plain CompletionItem
+	if err := json.Unmarshal(data, (*plain)(it)); err != nil {
+		return err
+	}
+	it.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (it CompletionItem) MarshalJSON() ([]byte, error) {
+	if it.raw != nil {
+		return it.raw, nil
+	}
+	type plain CompletionItem
+	return json.Marshal(plain(it))
+}
+
+// CompletionItemKind is the kind of a completion item
+type CompletionItemKind int
+
+var completionItemKinds = []string{
+	"", "text", "method", "function", "constructor", "field", "variable",
+	"class", "interface", "module", "property", "unit", "value", "enum",
+	"keyword", "snippet", "color", "file", "reference", "folder",
+	"enum member", "constant", "struct", "event", "operator", "type parameter",
+}
+
+// String returns the name of the kind, or "" when it is unknown
+func (k CompletionItemKind) String() string {
+	if k < 0 || int(k) >= len(completionItemKinds) {
+		return ""
+	}
+	return completionItemKinds[k]
+}
+
+// ReferenceContext tells the server what to include in references
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+// ReferenceParams represents params for textDocument/references
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+// RenameParams represents params for textDocument/rename
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+// WorkspaceEdit is a change to many documents. Servers send it either as a
+// map of edits by URI or as a list of document changes.
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []json.RawMessage     `json:"documentChanges,omitempty"`
+}
+
+// OptionalVersionedTextDocumentIdentifier identifies a document at a
+// version; a nil version means the text on disk
+type OptionalVersionedTextDocumentIdentifier struct {
+	TextDocumentIdentifier
+	Version *int `json:"version"`
+}
+
+// TextDocumentEdit is the edits of one document in a workspace edit
+type TextDocumentEdit struct {
+	TextDocument OptionalVersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                              `json:"edits"`
+}
+
+// Command is a command the server can execute with
+// workspace/executeCommand
+type Command struct {
+	Title     string            `json:"title"`
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// ExecuteCommandParams represents params for workspace/executeCommand
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// CodeActionContext carries the diagnostics a code action request is for
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only,omitempty"`
+}
+
+// CodeActionParams represents params for textDocument/codeAction
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+// Code action kinds
+const (
+	CodeActionQuickFix              = "quickfix"
+	CodeActionRefactor              = "refactor"
+	CodeActionRefactorExtract       = "refactor.extract"
+	CodeActionRefactorInline        = "refactor.inline"
+	CodeActionRefactorRewrite       = "refactor.rewrite"
+	CodeActionSource                = "source"
+	CodeActionSourceOrganizeImports = "source.organizeImports"
+)
+
+// CodeAction is a change the server offers for a range. It has an edit, a
+// command or both; servers with a resolve provider may leave the edit out
+// until codeAction/resolve. Actions keep the JSON they were decoded from,
+// like completion items.
+type CodeAction struct {
+	Title       string `json:"title"`
+	Kind        string `json:"kind,omitempty"`
+	IsPreferred bool   `json:"isPreferred,omitempty"`
+	Disabled    *struct {
+		Reason string `json:"reason"`
+	} `json:"disabled,omitempty"`
+	Edit    *WorkspaceEdit  `json:"edit,omitempty"`
+	Command *Command        `json:"command,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+
+	raw json.RawMessage
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A bare Command becomes an
+// action running it.
+func (a *CodeAction) UnmarshalJSON(data []byte) error {
+	var probe struct {
+		Command json.RawMessage `json:"command"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	if len(probe.Command) > 0 && probe.Command[0] == '"' {
+		var cmd Command
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			return err
+		}
+		*a = CodeAction{Title: cmd.Title, Command: &cmd}
+		return nil
+	}
+	type plain CodeAction
+	if err := json.Unmarshal(data, (*plain)(a)); err != nil {
+		return err
+	}
+	a.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (a CodeAction) MarshalJSON() ([]byte, error) {
+	if a.raw != nil {
+		return a.raw, nil
+	}
+	type plain CodeAction
+	return json.Marshal(plain(a))
+}
+
+// FormattingOptions describe how the document is indented
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+// DocumentFormattingParams represents params for textDocument/formatting
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// DocumentRangeFormattingParams represents params for
+// textDocument/rangeFormatting
+type DocumentRangeFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// ConfigurationItem is one setting section asked for by
+// workspace/configuration
+type ConfigurationItem struct {
+	ScopeURI string `json:"scopeUri,omitempty"`
+	Section  string `json:"section,omitempty"`
+}
+
+// ConfigurationParams represents params for the workspace/configuration
+// request. The result has one value per item.
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+// DidChangeConfigurationParams represents params for the
+// workspace/didChangeConfiguration notification
+type DidChangeConfigurationParams struct {
+	Settings interface{} `json:"settings"`
+}
+
+// ApplyWorkspaceEditParams represents params for the workspace/applyEdit
+// request
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+// ApplyWorkspaceEditResult is the result of the workspace/applyEdit request
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// MessageActionItem is an action offered by window/showMessageRequest
+type MessageActionItem struct {
+	Title string `json:"title"`
+}
+
+// ShowMessageRequestParams represents params for the
+// window/showMessageRequest request. The result is the chosen action or
+// null.
+type ShowMessageRequestParams struct {
+	Type    int                 `json:"type"`
+	Message string              `json:"message"`
+	Actions []MessageActionItem `json:"actions,omitempty"`
+}
+
+// SymbolKind is the kind of a symbol
+type SymbolKind int
+
+var symbolKinds = []string{
+	"", "file", "module", "namespace", "package", "class", "method",
+	"property", "field", "constructor", "enum", "interface", "function",
+	"variable", "constant", "string", "number", "boolean", "array", "object",
+	"key", "null", "enum member", "struct", "event", "operator",
+	"type parameter",
+}
+
+// String returns the name of the kind, or "" when it is unknown
+func (k SymbolKind) String() string {
+	if k < 0 || int(k) >= len(symbolKinds) {
+		return ""
+	}
+	return symbolKinds[k]
+}
+
+// DocumentSymbolParams represents params for textDocument/documentSymbol
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentSymbol is a symbol of a document with the symbols it contains.
+// Range covers all of it, SelectionRange its name.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// SymbolInformation is a symbol and its location, as found by
+// workspace/symbol or, flat, by textDocument/documentSymbol
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+// WorkspaceSymbolParams represents params for workspace/symbol
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// SemanticTokensClientCapabilities lists the semantic token requests,
+// types and modifiers the client understands
+type SemanticTokensClientCapabilities struct {
+	Requests                SemanticTokensRequests `json:"requests"`
+	TokenTypes              []string               `json:"tokenTypes"`
+	TokenModifiers          []string               `json:"tokenModifiers"`
+	Formats                 []string               `json:"formats"`
+	MultilineTokenSupport   bool                   `json:"multilineTokenSupport,omitempty"`
+	OverlappingTokenSupport bool                   `json:"overlappingTokenSupport,omitempty"`
+	// AugmentsSyntaxTokens means the tokens are layered over the client's
+	// own highlighting, so servers may leave out what it already knows
+	AugmentsSyntaxTokens bool `json:"augmentsSyntaxTokens,omitempty"`
+}
+
+// SemanticTokensRequests lists the semantic token requests the client
+// sends
+type SemanticTokensRequests struct {
+	Range bool `json:"range,omitempty"`
+	Full  bool `json:"full,omitempty"`
+}
+
+// InlayHintClientCapabilities represents inlay hint capabilities
+type InlayHintClientCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+// SemanticTokensOptions are the semantic token options of a server
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	// Range and Full tell which of the requests the server answers
+	Range Support `json:"range,omitempty"`
+	Full  Support `json:"full,omitempty"`
+}
+
+// SemanticTokensLegend names the token types and modifiers a server
+// encodes as indices and bits
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+// TokenFormatRelative is the semantic token format of LSP 3.16, the only
+// one defined
+const TokenFormatRelative = "relative"
+
+// SemanticTokensParams represents params for
+// textDocument/semanticTokens/full
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SemanticTokensRangeParams represents params for
+// textDocument/semanticTokens/range
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// SemanticTokens is the result of the semantic token requests. Data holds
+// five integers per token, each relative to the token before it.
+type SemanticTokens struct {
+	ResultID string   `json:"resultId,omitempty"`
+	Data     []uint32 `json:"data"`
+}
+
+// InlayHintParams represents params for textDocument/inlayHint
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// InlayHintKind is the kind of an inlay hint
+type InlayHintKind int
+
+// Inlay hint kinds
+const (
+	InlayHintKindType      InlayHintKind = 1
+	InlayHintKindParameter InlayHintKind = 2
+)
+
+// InlayHint is text a server suggests showing at a position without it
+// being part of the document, such as an inferred type or a parameter
+// name
+type InlayHint struct {
+	Position     Position       `json:"position"`
+	Label        InlayHintLabel `json:"label"`
+	Kind         InlayHintKind  `json:"kind,omitempty"`
+	PaddingLeft  bool           `json:"paddingLeft,omitempty"`
+	PaddingRight bool           `json:"paddingRight,omitempty"`
+}
+
+// InlayHintLabel is the label of an inlay hint, sent either as a string or
+// as parts that are joined
+type InlayHintLabel string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *InlayHintLabel) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = InlayHintLabel(s)
+		return nil
+	}
+	var parts []struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(part.Value)
+	}
+	*l = InlayHintLabel(b.String())
+	return nil
+}
+
+// Text returns the label with the padding the hint asks for
+func (h InlayHint) Text() string {
+	text := string(h.Label)
+	if h.PaddingLeft {
+		text = " " + text
+	}
+	if h.PaddingRight {
+		text += " "
+	}
+	return text
+}
+
+// CallHierarchyClientCapabilities represents call hierarchy capabilities
+type CallHierarchyClientCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+// TypeHierarchyClientCapabilities represents type hierarchy capabilities
+type TypeHierarchyClientCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+}
+
+// HierarchyItem is a symbol of a call or type hierarchy, which have the
+// same fields. Data is kept for the server and sent back with the item.
+type HierarchyItem struct {
+	Name           string          `json:"name"`
+	Kind           SymbolKind      `json:"kind"`
+	Detail         string          `json:"detail,omitempty"`
+	URI            string          `json:"uri"`
+	Range          Range           `json:"range"`
+	SelectionRange Range           `json:"selectionRange"`
+	Data           json.RawMessage `json:"data,omitempty"`
+}
+
+// HierarchyItemParams represents params for the requests about a
+// hierarchy item: callHierarchy/incomingCalls, callHierarchy/outgoingCalls,
+// typeHierarchy/supertypes and typeHierarchy/subtypes
+type HierarchyItemParams struct {
+	Item HierarchyItem `json:"item"`
+}
+
+// CallHierarchyIncomingCall is a caller of an item and the ranges of the
+// calls in it
+type CallHierarchyIncomingCall struct {
+	From       HierarchyItem `json:"from"`
+	FromRanges []Range       `json:"fromRanges"`
+}
+
+// CallHierarchyOutgoingCall is a function an item calls and the ranges of
+// the calls in the item
+type CallHierarchyOutgoingCall struct {
+	To         HierarchyItem `json:"to"`
+	FromRanges []Range       `json:"fromRanges"`
+}
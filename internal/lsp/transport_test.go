@@ -0,0 +1,172 @@
+package lsp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dragonbytelabs/voidabyss/internal/lsp"
+	"github.com/dragonbytelabs/voidabyss/internal/lsp/lsptest"
+)
+
+// TestMain runs the test binary as a language server when the tests start
+// it as one
+func TestMain(m *testing.M) {
+	if os.Getenv("VB_LSPTEST_SERVER") == "1" {
+		if os.Getenv("VB_LSPTEST_ORPHAN") == "1" {
+			// a child outliving the server, holding its output open
+			child := exec.Command("sleep", "5")
+			child.Stdout, child.Stderr = os.Stdout, os.Stderr
+			_ = child.Start()
+		}
+		if err := lsptest.Serve(os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		if os.Getenv("VB_LSPTEST_HANG") == "shutdown" {
+			// a server stuck shutting down, until it is killed
+			time.Sleep(10 * time.Second)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// startTestClient starts the test binary as a language server and
+// initializes it
+func startTestClient(t *testing.T) *lsp.Client {
+	t.Helper()
+	t.Setenv("VB_LSPTEST_SERVER", "1")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := lsp.NewClient(exe, nil, lsp.URIFromPath(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// waitForLog waits until the log of c has a line containing s
+func waitForLog(t *testing.T, c *lsp.Client, s string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if strings.Contains(strings.Join(c.Log(), "\n"), s) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("log lacks %s:\n%s", s, strings.Join(c.Log(), "\n"))
+}
+
+func TestCallCancel(t *testing.T) {
+	c := startTestClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := c.Call(ctx, "lsptest/hang", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the deadline", err)
+	}
+	waitForLog(t, c, "cancel 2") // the server got $/cancelRequest
+
+	// the client still works
+	var result interface{}
+	if err := c.Call(context.Background(), "shutdown", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	// unknown methods are errors from the server
+	err = c.Call(context.Background(), "lsptest/unknown", nil, nil)
+	var rpcErr *lsp.ResponseError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != lsp.CodeMethodNotFound {
+		t.Errorf("err = %v, want method not found", err)
+	}
+}
+
+func TestCallFailsWhenServerExits(t *testing.T) {
+	c := startTestClient(t)
+
+	errc := make(chan error, 1)
+	go func() { errc <- c.Call(context.Background(), "lsptest/hang", nil, nil) }()
+	waitForLog(t, c, `"method":"lsptest/hang"`)
+	proc, err := os.FindProcess(c.Pid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = proc.Kill()
+
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), "server exited") {
+			t.Errorf("err = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call still waits after the server exited")
+	}
+	<-c.Done()
+	if err := c.Call(context.Background(), "shutdown", nil, nil); err == nil {
+		t.Error("a call after the exit succeeded")
+	}
+}
+
+func TestClose(t *testing.T) {
+	c := startTestClient(t)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	log := strings.Join(c.Log(), "\n")
+	if !regexp.MustCompile(`"id":\d+,"method":"shutdown"`).MatchString(log) {
+		t.Errorf("shutdown should be sent as a request:\n%s", log)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("the server still runs after Close")
+	}
+}
+
+func TestCloseDeadline(t *testing.T) {
+	t.Setenv("VB_LSPTEST_HANG", "shutdown")
+	c := startTestClient(t)
+
+	// the unanswered shutdown and the wait for the exit share CloseTimeout
+	start := time.Now()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > lsp.CloseTimeout+500*time.Millisecond {
+		t.Errorf("Close took %v with a server stuck in shutdown", d)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("the server still runs after Close")
+	}
+}
+
+func TestCloseWithOrphanedOutput(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("no sleep command")
+	}
+	t.Setenv("VB_LSPTEST_ORPHAN", "1")
+	c := startTestClient(t)
+
+	start := time.Now()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 2*lsp.CloseTimeout {
+		t.Errorf("Close took %v while a child held the output open", d)
+	}
+}